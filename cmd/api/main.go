@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os/signal"
+	"syscall"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/winQe/uniswap-fee-tracker/internal/api"
@@ -31,23 +33,53 @@ func main() {
 
 	// Initialize dbQuerier from sqlc
 	dbQuerier := db.New(connPool)
+	// Transactions are written in bulk, chunk by chunk
+	txWriter := db.NewBulkStore(connPool)
+
+	// Seed the pool registry so transactions can reference their pool
+	if err := service.RegisterPools(context.Background(), dbQuerier, config.Pools()); err != nil {
+		log.Fatalf("Failed to register pools: %v", err)
+	}
 
 	// Initialize all price related dependencies
-	priceCache := cache.NewRateCache(config.RedisURL, config.RedisPassword)
-	binanceClient := client.NewKlineClient()
-	priceManager := domain.NewPriceManager(priceCache, binanceClient)
+	priceClient, err := client.NewPriceClient(config)
+	if err != nil {
+		log.Fatalf("Failed to create price client: %v", err)
+	}
+	// Cached prices are reused within a third of the price resolution, 5 minutes for 15 minute candles
+	priceCache := cache.NewRateCache(config.RedisURL, config.RedisPassword, priceClient.Resolution()/3)
+	priceManager := domain.NewPriceManager(priceCache, priceClient)
 
-	// Initialize all transactions related dependencies
-	etherscanClient := client.NewEtherscanClient(config.EtherscanAPIKey, config.WETHUSDCPoolAddress)
-	txManager := domain.NewTransactionManager(etherscanClient, priceManager)
+	// Initialize all transactions related dependencies, the daily usage of the API keys is shared through Redis
+	keyUsageCache := cache.NewKeyUsageCache(config.RedisURL, config.RedisPassword)
+	chainSources, err := domain.NewChainSources(config, keyUsageCache)
+	if err != nil {
+		log.Fatalf("Failed to create transaction clients: %v", err)
+	}
+	txManager := domain.NewTransactionManager(chainSources, priceManager)
+
+	// Batch jobs and in-flight requests are canceled on shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
 	// Initialize batch job relatd dependencies
 	jobsCache := cache.NewJobCache(config.RedisURL, config.RedisPassword)
-	batchDataProcessor := service.NewBatchDataProcessor(dbQuerier, jobsCache, txManager)
+	batchDataProcessor := service.NewBatchDataProcessor(txWriter, jobsCache, txManager)
 
-	txHandler := api.NewTransactionHandler(dbQuerier)
-	batchDataHandler := *api.NewBatchJobHandler(dbQuerier, jobsCache, txManager, batchDataProcessor)
-	server := server.NewServer(config.ServerPort, txHandler, &batchDataHandler)
+	// Transactions missing upstream are remembered for a minute, so repeated lookups don't reach the clients
+	txMissCache := cache.NewTxMissCache(config.RedisURL, config.RedisPassword)
+	txHandler := api.NewTransactionHandler(dbQuerier, txWriter, txManager, txMissCache)
+	batchDataHandler := api.NewBatchJobHandler(ctx, dbQuerier, jobsCache, txManager, batchDataProcessor)
+	coverageHandler := api.NewCoverageHandler(dbQuerier, txManager)
+	healthHandler := api.NewHealthHandler(client.BreakerStates)
+	statsHandler := api.NewStatsHandler(dbQuerier)
+	server := server.NewServer(config.ServerPort, txHandler, batchDataHandler, coverageHandler, healthHandler, statsHandler)
+
+	if err := server.Run(ctx); err != nil {
+		log.Fatalf("API server failed: %v", err)
+	}
 
-	server.Run()
+	// Let the canceled batch jobs record their status before exiting
+	batchDataHandler.Wait()
+	log.Println("API server has shut down gracefully.")
 }
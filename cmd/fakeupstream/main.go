@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/winQe/uniswap-fee-tracker/internal/fakeupstream"
+)
+
+// Serves stand-ins of the Etherscan and Binance APIs, point ETHERSCAN_BASE_URL at <addr>/api and BINANCE_BASE_URL at <addr>
+func main() {
+	config := fakeupstream.DefaultConfig()
+
+	addr := flag.String("addr", ":8081", "address to listen on")
+	flag.Int64Var(&config.Seed, "seed", config.Seed, "seed of the synthetic chain")
+	flag.Uint64Var(&config.GenesisBlock, "genesis-block", config.GenesisBlock, "number of the first block")
+	genesisTime := flag.String("genesis-time", config.GenesisTime.Format(time.RFC3339), "timestamp of the first block (RFC 3339)")
+	flag.DurationVar(&config.BlockTime, "block-time", config.BlockTime, "time between two blocks")
+	pools := flag.String("pools", strings.Join(config.Pools, ","), "comma separated pools swapped in")
+	flag.Float64Var(&config.SwapsPerBlock, "swaps-per-block", config.SwapsPerBlock, "average swaps per block in each pool")
+	flag.Float64Var(&config.BasePrice, "base-price", config.BasePrice, "ETH/USDT price the price moves around")
+	flag.Float64Var(&config.PriceAmplitude, "price-amplitude", config.PriceAmplitude, "relative swing of the price")
+	flag.DurationVar(&config.PricePeriod, "price-period", config.PricePeriod, "period of the price swing")
+	flag.Float64Var(&config.BaseFeeGwei, "base-fee-gwei", config.BaseFeeGwei, "average base fee of the blocks in gwei")
+	flag.Float64Var(&config.ErrorRate, "error-rate", config.ErrorRate, "share of requests failing with a 503")
+	flag.Float64Var(&config.RateLimitRate, "rate-limit-rate", config.RateLimitRate, "share of requests rejected as rate limited")
+	flag.IntVar(&config.EtherscanRPS, "etherscan-rps", config.EtherscanRPS, "requests per second allowed to each Etherscan key, 0 for unlimited")
+	flag.IntVar(&config.BinanceWeightPerMinute, "binance-weight", config.BinanceWeightPerMinute, "request weight allowed per minute on Binance, 0 for unlimited")
+	flag.DurationVar(&config.Latency, "latency", config.Latency, "delay before every response")
+	fixtures := flag.String("fixtures", "", "comma separated cassettes answering the requests they recorded")
+	flag.Parse()
+
+	var err error
+	if config.GenesisTime, err = time.Parse(time.RFC3339, *genesisTime); err != nil {
+		log.Fatalf("Invalid genesis time: %v", err)
+	}
+	config.Pools = splitList(*pools)
+	config.Fixtures = splitList(*fixtures)
+
+	handler, err := fakeupstream.NewServer(config)
+	if err != nil {
+		log.Fatalf("Failed to create fake upstream: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{Addr: *addr, Handler: handler}
+	go func() {
+		<-ctx.Done()
+		log.Println("Shutting down fake upstream")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("Fake upstream listening on %s", *addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("Fake upstream failed: %v", err)
+	}
+}
+
+// splitList splits a comma separated list, dropping empty entries
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -32,22 +32,35 @@ func main() {
 
 	// Initialize dbQuerier from sqlc
 	dbQuerier := db.New(connPool)
+	// Transactions are written in bulk, chunk by chunk
+	txWriter := db.NewBulkStore(connPool)
+
+	// Seed the pool registry so transactions can reference their pool
+	if err := service.RegisterPools(context.Background(), dbQuerier, config.Pools()); err != nil {
+		log.Fatalf("Failed to register pools: %v", err)
+	}
 
 	// Initialize all price related dependencies
-	priceCache := cache.NewRateCache(config.RedisURL, config.RedisPassword)
-	binanceClient := client.NewKlineClient()
-	priceManager := domain.NewPriceManager(priceCache, binanceClient)
+	priceClient, err := client.NewPriceClient(config)
+	if err != nil {
+		log.Fatalf("Failed to create price client: %v", err)
+	}
+	// Cached prices are reused within a third of the price resolution, 5 minutes for 15 minute candles
+	priceCache := cache.NewRateCache(config.RedisURL, config.RedisPassword, priceClient.Resolution()/3)
+	priceManager := domain.NewPriceManager(priceCache, priceClient)
 
-	// Initialize all transactions related dependencies
-	etherscanClient := client.NewEtherscanClient(config.EtherscanAPIKey, config.WETHUSDCPoolAddress)
-	txManager := domain.NewTransactionManager(etherscanClient, priceManager)
+	// Initialize all transactions related dependencies, the daily usage of the API keys is shared through Redis
+	keyUsageCache := cache.NewKeyUsageCache(config.RedisURL, config.RedisPassword)
+	chainSources, err := domain.NewChainSources(config, keyUsageCache)
+	if err != nil {
+		log.Fatalf("Failed to create transaction clients: %v", err)
+	}
+	txManager := domain.NewTransactionManager(chainSources, priceManager)
 
-	// Initialize LiveDataRecorder
-	liveDataRecorder := service.NewLiveDataRecorder(dbQuerier, txManager)
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Handle OS signals for graceful shutdown
+	// Handle OS signals for graceful shutdown, in-flight requests are aborted
 	go func() {
 		sigChan := make(chan os.Signal, 1)
 		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
@@ -56,6 +69,18 @@ func main() {
 		cancel()
 	}()
 
+	// Initialize LiveDataRecorder
+	liveDataRecorder := service.NewLiveDataRecorder(ctx, dbQuerier, txWriter, txManager)
+	// Chains with a WebSocket endpoint are recorded as soon as a new head is pushed, at most once per head interval
+	if config.HeadInterval > 0 {
+		liveDataRecorder.DebounceHeads(config.HeadInterval)
+	}
+	for _, chain := range config.Chains {
+		if chain.WSURL != "" {
+			liveDataRecorder.UseHeadSource(chain.ChainID, client.NewHeadSubscriber(chain.WSURL))
+		}
+	}
+
 	// Run the LiveDataRecorder in a separate goroutine
 	go liveDataRecorder.Run(ctx)
 
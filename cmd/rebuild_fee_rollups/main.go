@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	db "github.com/winQe/uniswap-fee-tracker/internal/db/sqlc"
+	"github.com/winQe/uniswap-fee-tracker/internal/service"
+	"github.com/winQe/uniswap-fee-tracker/internal/utils"
+)
+
+// Recomputes the fee rollups of a time range from the stored transactions, e.g. after migrating or repairing data
+func main() {
+	chainID := flag.Int64("chain-id", 0, "chain to rebuild, 0 for every configured chain")
+	start := flag.String("start", "", "start of the range (RFC 3339)")
+	end := flag.String("end", "", "end of the range (RFC 3339), defaults to now")
+	flag.Parse()
+
+	startTime, err := time.Parse(time.RFC3339, *start)
+	if err != nil {
+		log.Fatalf("Invalid start: %v", err)
+	}
+	endTime := time.Now()
+	if *end != "" {
+		if endTime, err = time.Parse(time.RFC3339, *end); err != nil {
+			log.Fatalf("Invalid end: %v", err)
+		}
+	}
+
+	config, err := utils.LoadConfig()
+	if err != nil {
+		log.Fatalf("Error loading config: %v\n", err)
+	}
+
+	connPool, err := pgxpool.New(context.Background(), fmt.Sprintf("postgresql://%s:%s@%s:%s/%s?sslmode=disable", config.DBUser, config.DBPassword, config.DBAddress, config.DBPort, config.DBName))
+	if err != nil {
+		log.Fatalf("Failed to connect to the database: %v", err)
+	}
+	defer connPool.Close()
+	dbQuerier := db.New(connPool)
+
+	chainIDs := []int64{*chainID}
+	if *chainID == 0 {
+		chainIDs = nil
+		for _, chain := range config.Chains {
+			chainIDs = append(chainIDs, chain.ChainID)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	for _, id := range chainIDs {
+		if err := service.RebuildFeeRollups(ctx, dbQuerier, id, startTime, endTime); err != nil {
+			log.Fatalf("Failed to rebuild the fee rollups: %v", err)
+		}
+	}
+}
@@ -55,7 +55,7 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "Schedule a new batch job for historical data recording.",
+                "description": "Schedule a new batch job for historical data recording. Max timestamp range is 1 week",
                 "consumes": [
                     "application/json"
                 ],
@@ -152,6 +152,192 @@ const docTemplate = `{
                         }
                     }
                 }
+            },
+            "delete": {
+                "description": "Abort a batch job running on this server. The transactions it fetched so far are still stored, and its status becomes canceled.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Batch Jobs"
+                ],
+                "summary": "Cancel a running batch job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Batch Job ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Cancellation requested",
+                        "schema": {
+                            "$ref": "#/definitions/api.MessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid Batch Job ID",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Batch job not running on this server",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/coverage": {
+            "get": {
+                "description": "Report the ingested block ranges of every chain and the ranges with no coverage, up to the confirmed head.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "coverage"
+                ],
+                "summary": "Get the ingestion coverage",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Only report this chain",
+                        "name": "chain_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.ChainCoverageResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/health/upstreams": {
+            "get": {
+                "description": "Report the circuit breaker state of every upstream called by the API server. Requests to an open upstream fail fast and batch jobs pause until it closes. Breakers are kept per process, those of the live recorder are not reported.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Get the health of the upstream APIs",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.UpstreamHealthResponse"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/stats/fees": {
+            "get": {
+                "description": "Retrieve the transaction count, gas used and fee distribution of every bucket of the interval between the start and end Unix epoch timestamps, read from the fee rollups. Buckets without transactions are omitted.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "stats"
+                ],
+                "summary": "Get fee statistics",
+                "parameters": [
+                    {
+                        "enum": [
+                            "1m",
+                            "1h",
+                            "1d"
+                        ],
+                        "type": "string",
+                        "default": "1h",
+                        "description": "Size of the buckets",
+                        "name": "interval",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start timestamp in Unix epoch seconds, the bucket holding it is included",
+                        "name": "start",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "End timestamp in Unix epoch seconds",
+                        "name": "end",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Only return the statistics of this chain",
+                        "name": "chain_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/api.FeeStatsResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
             }
         },
         "/transactions": {
@@ -181,6 +367,18 @@ const docTemplate = `{
                         "name": "end",
                         "in": "query",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only return transactions of this pool address",
+                        "name": "pool",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Only return transactions of this chain",
+                        "name": "chain_id",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -228,6 +426,18 @@ const docTemplate = `{
                         "description": "Number of transactions to retrieve",
                         "name": "limit",
                         "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only return transactions of this pool address",
+                        "name": "pool",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Only return transactions of this chain",
+                        "name": "chain_id",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -240,6 +450,12 @@ const docTemplate = `{
                             }
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -251,7 +467,7 @@ const docTemplate = `{
         },
         "/transactions/{hash}": {
             "get": {
-                "description": "Retrieve a specific transaction using its hash.",
+                "description": "Retrieve a specific transaction using its hash. Transactions not recorded yet are fetched, priced and stored on demand.",
                 "consumes": [
                     "application/json"
                 ],
@@ -295,12 +511,66 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/api.ErrorResponse"
                         }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
                     }
                 }
             }
         }
     },
     "definitions": {
+        "api.BlockRangeResponse": {
+            "type": "object",
+            "properties": {
+                "end_block": {
+                    "description": "The last block of the range",
+                    "type": "integer"
+                },
+                "start_block": {
+                    "description": "The first block of the range",
+                    "type": "integer"
+                }
+            }
+        },
+        "api.ChainCoverageResponse": {
+            "type": "object",
+            "properties": {
+                "chain_id": {
+                    "description": "The ID of the chain",
+                    "type": "integer"
+                },
+                "checkpoint_block": {
+                    "description": "The last block processed by the live recorder, null before its first run",
+                    "type": "integer"
+                },
+                "checkpoint_updated_at": {
+                    "description": "When the checkpoint last moved (Unix epoch time in seconds), null before the first run",
+                    "type": "integer"
+                },
+                "confirmed_head": {
+                    "description": "The most recent confirmed block of the chain, null when it could not be fetched",
+                    "type": "integer"
+                },
+                "gaps": {
+                    "description": "The block ranges with no ingestion coverage, between the first ingested block and the confirmed head",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.BlockRangeResponse"
+                    }
+                },
+                "ranges": {
+                    "description": "The block ranges ingested, in order",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.BlockRangeResponse"
+                    }
+                }
+            }
+        },
         "api.ErrorResponse": {
             "type": "object",
             "properties": {
@@ -309,6 +579,154 @@ const docTemplate = `{
                 }
             }
         },
+        "api.FeeBreakdownResponse": {
+            "type": "object",
+            "properties": {
+                "base_fee_per_gas_wei": {
+                    "description": "The base fee per gas of the block in Wei",
+                    "type": "string"
+                },
+                "burned_fee_eth": {
+                    "description": "The base fee burned in Ether",
+                    "type": "string"
+                },
+                "burned_fee_usdt": {
+                    "description": "The base fee burned in USDT",
+                    "type": "string"
+                },
+                "effective_tip_per_gas_wei": {
+                    "description": "The tip per gas actually paid above the base fee in Wei",
+                    "type": "string"
+                },
+                "max_fee_per_gas_wei": {
+                    "description": "The maximum fee per gas declared by the transaction in Wei, omitted for legacy transactions",
+                    "type": "string"
+                },
+                "max_priority_fee_per_gas_wei": {
+                    "description": "The maximum priority fee per gas declared by the transaction in Wei, omitted for legacy transactions",
+                    "type": "string"
+                },
+                "tip_fee_eth": {
+                    "description": "The priority tip paid to the block builder in Ether",
+                    "type": "string"
+                },
+                "tip_fee_usdt": {
+                    "description": "The priority tip paid to the block builder in USDT",
+                    "type": "string"
+                }
+            }
+        },
+        "api.FeeStatsResponse": {
+            "type": "object",
+            "properties": {
+                "bucket_start": {
+                    "description": "The start of the bucket (Unix epoch time in seconds), buckets are aligned on UTC",
+                    "type": "integer"
+                },
+                "chain_id": {
+                    "description": "The ID of the chain",
+                    "type": "integer"
+                },
+                "fee_eth": {
+                    "description": "The statistics of the transaction fees in Ether",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/api.FeeSummaryResponse"
+                        }
+                    ]
+                },
+                "fee_usdt": {
+                    "description": "The statistics of the transaction fees in USDT, null when no transaction of the bucket was priced",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/api.FeeSummaryResponse"
+                        }
+                    ]
+                },
+                "gas_used_sum": {
+                    "description": "The total gas used by the transactions of the bucket",
+                    "type": "string"
+                },
+                "tx_count": {
+                    "description": "The number of transactions of the bucket",
+                    "type": "integer"
+                }
+            }
+        },
+        "api.FeeSummaryResponse": {
+            "type": "object",
+            "properties": {
+                "avg": {
+                    "description": "The average fee",
+                    "type": "string"
+                },
+                "max": {
+                    "description": "The highest fee",
+                    "type": "string"
+                },
+                "min": {
+                    "description": "The lowest fee",
+                    "type": "string"
+                },
+                "p50": {
+                    "description": "The median fee",
+                    "type": "string"
+                },
+                "p95": {
+                    "description": "The 95th percentile of the fees",
+                    "type": "string"
+                }
+            }
+        },
+        "api.MessageResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.SwapResponse": {
+            "type": "object",
+            "properties": {
+                "amount0": {
+                    "description": "The signed token0 delta of the pool",
+                    "type": "string"
+                },
+                "amount1": {
+                    "description": "The signed token1 delta of the pool",
+                    "type": "string"
+                },
+                "liquidity": {
+                    "description": "The in-range liquidity of the pool after the swap",
+                    "type": "string"
+                },
+                "log_index": {
+                    "description": "The index of the Swap log within its block",
+                    "type": "integer"
+                },
+                "pool_address": {
+                    "description": "The pool that emitted the event",
+                    "type": "string"
+                },
+                "recipient": {
+                    "description": "The address that received the output",
+                    "type": "string"
+                },
+                "sender": {
+                    "description": "The address that initiated the swap",
+                    "type": "string"
+                },
+                "sqrt_price_x96": {
+                    "description": "The pool price after the swap as a Q64.96 square root",
+                    "type": "string"
+                },
+                "tick": {
+                    "description": "The pool tick after the swap",
+                    "type": "integer"
+                }
+            }
+        },
         "api.TransactionResponse": {
             "type": "object",
             "properties": {
@@ -316,18 +734,65 @@ const docTemplate = `{
                     "description": "The block number where the transaction was included",
                     "type": "integer"
                 },
+                "chain_id": {
+                    "description": "The ID of the chain the transaction was executed on",
+                    "type": "integer"
+                },
                 "eth_usdt_price": {
                     "description": "The Ether to USDT price at the time of the transaction",
                     "type": "number"
                 },
+                "eth_usdt_price_decimal": {
+                    "description": "The exact Ether to USDT price, as a decimal string",
+                    "type": "string"
+                },
+                "fee_breakdown": {
+                    "description": "The split of the fee between the burned base fee and the priority tip, null when the base fee is unknown",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/api.FeeBreakdownResponse"
+                        }
+                    ]
+                },
                 "gas_price_wei": {
-                    "description": "The gas price in Wei",
+                    "description": "The gas price in Wei, as an integer of any size",
                     "type": "integer"
                 },
                 "gas_used": {
                     "description": "The amount of gas used by the transaction",
                     "type": "integer"
                 },
+                "origin": {
+                    "description": "Where a transaction looked up by hash came from, store when already recorded or live when fetched on demand",
+                    "type": "string",
+                    "enum": [
+                        "store",
+                        "live"
+                    ]
+                },
+                "pool_address": {
+                    "description": "The tracked pool the transaction was recorded for",
+                    "type": "string"
+                },
+                "price_source": {
+                    "description": "The sources of the Ether to USDT price, e.g. binance or binance,pool:\u003caddress\u003e when aggregated",
+                    "type": "string"
+                },
+                "price_spread": {
+                    "description": "The relative spread between the aggregated price sources",
+                    "type": "number"
+                },
+                "price_suspect": {
+                    "description": "Whether the price sources disagree beyond the divergence threshold",
+                    "type": "boolean"
+                },
+                "swaps": {
+                    "description": "The Uniswap V3 swaps executed by the transaction",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.SwapResponse"
+                    }
+                },
                 "timestamp": {
                     "description": "The timestamp of the transaction (Unix epoch time in seconds)",
                     "type": "integer"
@@ -336,16 +801,57 @@ const docTemplate = `{
                     "description": "The transaction fee in Ether",
                     "type": "number"
                 },
+                "transaction_fee_eth_decimal": {
+                    "description": "The exact transaction fee in Ether, as a decimal string",
+                    "type": "string"
+                },
                 "transaction_fee_usdt": {
                     "description": "The transaction fee in USDT",
                     "type": "number"
                 },
+                "transaction_fee_usdt_decimal": {
+                    "description": "The exact transaction fee in USDT, as a decimal string",
+                    "type": "string"
+                },
+                "transaction_fee_wei": {
+                    "description": "The transaction fee in Wei, as a decimal string",
+                    "type": "string"
+                },
                 "transaction_hash": {
                     "description": "The hash of the transaction",
                     "type": "string"
                 }
             }
         },
+        "api.UpstreamHealthResponse": {
+            "type": "object",
+            "properties": {
+                "consecutive_failures": {
+                    "description": "The number of requests that failed in a row",
+                    "type": "integer"
+                },
+                "last_error": {
+                    "description": "The last failure, without the request URL, empty once a request succeeds",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "The name of the breaker, e.g. etherscan:1 or binance",
+                    "type": "string"
+                },
+                "opened_at": {
+                    "description": "When the breaker opened (Unix epoch time in seconds), null while closed",
+                    "type": "integer"
+                },
+                "retry_at": {
+                    "description": "When the breaker lets probes through (Unix epoch time in seconds), null while closed",
+                    "type": "integer"
+                },
+                "state": {
+                    "description": "The state of the breaker: closed, open or half_open",
+                    "type": "string"
+                }
+            }
+        },
         "cache.BatchJob": {
             "type": "object",
             "properties": {
@@ -361,6 +867,13 @@ const docTemplate = `{
                     "description": "Unique identifier for the batch job",
                     "type": "string"
                 },
+                "progress": {
+                    "description": "Block ranges fetched per chain and pool. Ranges holding more results than the explorer pages through\nare marked split and followed by their two halves.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.RangeProgress"
+                    }
+                },
                 "result": {
                     "description": "Result of the batch job",
                     "type": "string"
@@ -370,14 +883,61 @@ const docTemplate = `{
                     "type": "integer"
                 },
                 "status": {
-                    "description": "Current status of the job (e.g., pending, completed, failed)",
+                    "description": "Current status of the job (e.g., pending, running, paused, completed, failed, canceled)",
                     "type": "string"
                 },
+                "stored": {
+                    "description": "Transactions written by the job, inserted, updated or skipped when already stored unchanged",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/db.UpsertResult"
+                        }
+                    ]
+                },
                 "updated_at": {
                     "description": "Last update timestamp",
                     "type": "integer"
                 }
             }
+        },
+        "db.UpsertResult": {
+            "type": "object",
+            "properties": {
+                "inserted": {
+                    "type": "integer"
+                },
+                "skipped": {
+                    "description": "Already stored unchanged, or repeated in the write",
+                    "type": "integer"
+                },
+                "updated": {
+                    "type": "integer"
+                }
+            }
+        },
+        "types.RangeProgress": {
+            "type": "object",
+            "properties": {
+                "chain_id": {
+                    "type": "integer"
+                },
+                "end_block": {
+                    "type": "integer"
+                },
+                "pool_address": {
+                    "type": "string"
+                },
+                "start_block": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "transactions": {
+                    "description": "Transactions found in the range, once done",
+                    "type": "integer"
+                }
+            }
         }
     }
 }`
@@ -1,7 +1,9 @@
 package api
 
 import (
+	"context"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -19,15 +21,22 @@ type BatchJobHandler struct {
 	jobCache           cache.JobsStore
 	txManager          domain.TransactionManagerInterface
 	batchDataProcessor service.BatchDataProcessor
+	jobsCtx            context.Context               // Lives as long as the server, the running jobs are canceled with it
+	cancels            map[string]context.CancelFunc // Cancels the jobs running in this process by ID
+	mu                 sync.Mutex                    // Protects cancels
+	running            sync.WaitGroup
 }
 
 // NewBatchJobHandler initializes a new BatchJobHandler with the given dependencies.
-func NewBatchJobHandler(txDbQuery db.Querier, jobCache cache.JobsStore, txManager domain.TransactionManagerInterface, batchDataProcessor service.BatchDataProcessor) *BatchJobHandler {
+// The jobs run until they end or ctx is canceled, on shutdown.
+func NewBatchJobHandler(ctx context.Context, txDbQuery db.Querier, jobCache cache.JobsStore, txManager domain.TransactionManagerInterface, batchDataProcessor service.BatchDataProcessor) *BatchJobHandler {
 	return &BatchJobHandler{
 		txDbQuery:          txDbQuery,
 		jobCache:           jobCache,
 		txManager:          txManager,
 		batchDataProcessor: batchDataProcessor,
+		jobsCtx:            ctx,
+		cancels:            make(map[string]context.CancelFunc),
 	}
 }
 
@@ -98,18 +107,74 @@ func (bh *BatchJobHandler) CreateBatchJob(ctx *gin.Context) {
 	}
 
 	// Store the batch job in Redis with status 'pending'
-	err = bh.jobCache.SetJob(jobID, jobData)
+	err = bh.jobCache.SetJob(ctx, jobID, jobData)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to store batch job in Redis"})
 		return
 	}
 
 	// Run batch job in the background with Goroutines
-	go bh.batchDataProcessor.ProcessBatchJob(jobID, startTime, endTime)
+	// The job outlives the request, it runs until it ends, is canceled through CancelBatchJob or the server shuts down.
+	// The gin context itself is reused once the handler returns, so it must not reach the goroutine.
+	jobCtx, cancel := context.WithCancel(bh.jobsCtx)
+	bh.mu.Lock()
+	bh.cancels[jobID] = cancel
+	bh.mu.Unlock()
+
+	bh.running.Add(1)
+	go func() {
+		defer bh.running.Done()
+		defer bh.forgetJob(jobID)
+		bh.batchDataProcessor.ProcessBatchJob(jobCtx, jobID, startTime, endTime)
+	}()
 
 	ctx.JSON(http.StatusCreated, job)
 }
 
+// CancelBatchJob godoc
+// @Summary Cancel a running batch job
+// @Description Abort a batch job running on this server. The transactions it fetched so far are still stored, and its status becomes canceled.
+// @Tags Batch Jobs
+// @Produce  json
+// @Param id path string true "Batch Job ID (UUID)"
+// @Success 202 {object} MessageResponse "Cancellation requested"
+// @Failure 400 {object} ErrorResponse "Invalid Batch Job ID"
+// @Failure 404 {object} ErrorResponse "Batch job not running on this server"
+// @Router /batch-jobs/{id} [delete]
+func (bh *BatchJobHandler) CancelBatchJob(ctx *gin.Context) {
+	jobID := ctx.Param("id")
+	if !utils.IsValidUUID(jobID) {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid batch job ID format"})
+		return
+	}
+
+	bh.mu.Lock()
+	cancel, ok := bh.cancels[jobID]
+	bh.mu.Unlock()
+	if !ok {
+		ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Batch job not running on this server"})
+		return
+	}
+
+	cancel()
+	ctx.JSON(http.StatusAccepted, MessageResponse{Message: "Batch job cancellation requested"})
+}
+
+// Wait blocks until the running batch jobs have ended, which their cancellation on shutdown hastens
+func (bh *BatchJobHandler) Wait() {
+	bh.running.Wait()
+}
+
+// forgetJob releases the context of an ended job
+func (bh *BatchJobHandler) forgetJob(jobID string) {
+	bh.mu.Lock()
+	defer bh.mu.Unlock()
+	if cancel, ok := bh.cancels[jobID]; ok {
+		cancel()
+		delete(bh.cancels, jobID)
+	}
+}
+
 // GetBatchJob godoc
 // @Summary Get a specific batch job by ID
 // @Description Retrieve the status and details of a specific batch job using its unique ID.
@@ -130,7 +195,7 @@ func (bh *BatchJobHandler) GetBatchJob(ctx *gin.Context) {
 	}
 
 	// Retrieve job data from Redis
-	jobData, err := bh.jobCache.GetJob(jobID)
+	jobData, err := bh.jobCache.GetJob(ctx, jobID)
 	if err != nil {
 		if err == cache.ErrJobNotFound {
 			ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Batch job not found"})
@@ -161,7 +226,7 @@ func (bh *BatchJobHandler) GetBatchJob(ctx *gin.Context) {
 // @Router /batch-jobs [get]
 func (bh *BatchJobHandler) ListBatchJobs(ctx *gin.Context) {
 	// Fetch all batch jobs from Redis
-	allJobs, err := bh.jobCache.GetAllJobs()
+	allJobs, err := bh.jobCache.GetAllJobs(ctx)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to retrieve batch jobs"})
 		return
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -15,7 +16,6 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/winQe/uniswap-fee-tracker/internal/cache"
 	"github.com/winQe/uniswap-fee-tracker/internal/mocks"
-	"github.com/winQe/uniswap-fee-tracker/internal/utils"
 )
 
 func TestCreateBatchJob_Success(t *testing.T) {
@@ -29,7 +29,7 @@ func TestCreateBatchJob_Success(t *testing.T) {
 	mockTxManager := new(mocks.MockTransactionManager)
 
 	// Initialize BatchJobHandler with mocks
-	handler := NewBatchJobHandler(mockTxDbQuery, mockJobsStore, mockTxManager, mockBatchDataProcessor)
+	handler := NewBatchJobHandler(context.Background(), mockTxDbQuery, mockJobsStore, mockTxManager, mockBatchDataProcessor)
 
 	// Create a test router and register the handler
 	router := gin.Default()
@@ -44,7 +44,7 @@ func TestCreateBatchJob_Success(t *testing.T) {
 	expectedResult := ""
 
 	// Set up mock expectations
-	mockJobsStore.On("SetJob", mock.AnythingOfType("string"), mock.MatchedBy(func(data []byte) bool {
+	mockJobsStore.On("SetJob", mock.Anything, mock.AnythingOfType("string"), mock.MatchedBy(func(data []byte) bool {
 		var job cache.BatchJob
 		err := json.Unmarshal(data, &job)
 		if err != nil {
@@ -57,7 +57,12 @@ func TestCreateBatchJob_Success(t *testing.T) {
 			job.Result == expectedResult
 	})).Return(nil)
 
-	mockBatchDataProcessor.On("ProcessBatchJob", mock.AnythingOfType("string"), startTime, endTime).Return(nil)
+	// The job runs on its own context, never on the gin context reused after the handler returns
+	notGinContext := mock.MatchedBy(func(jobCtx context.Context) bool {
+		_, isGin := jobCtx.(*gin.Context)
+		return !isGin
+	})
+	mockBatchDataProcessor.On("ProcessBatchJob", notGinContext, mock.AnythingOfType("string"), startTime, endTime).Return(nil)
 
 	// Create a test HTTP request with query parameters
 	req, err := http.NewRequest("POST", "/batch-jobs", nil)
@@ -101,7 +106,7 @@ func TestCreateBatchJob_Success(t *testing.T) {
 	assert.NoError(t, err, "Job ID should be a valid UUID")
 
 	// Assert that SetJob was called with the correct parameters
-	mockJobsStore.AssertCalled(t, "SetJob", mock.AnythingOfType("string"), mock.MatchedBy(func(data []byte) bool {
+	mockJobsStore.AssertCalled(t, "SetJob", mock.Anything, mock.AnythingOfType("string"), mock.MatchedBy(func(data []byte) bool {
 		var job cache.BatchJob
 		err := json.Unmarshal(data, &job)
 		if err != nil {
@@ -114,7 +119,7 @@ func TestCreateBatchJob_Success(t *testing.T) {
 	}))
 
 	// Assert that ProcessBatchJob was called
-	mockBatchDataProcessor.AssertCalled(t, "ProcessBatchJob", mock.AnythingOfType("string"), startTime, endTime)
+	mockBatchDataProcessor.AssertCalled(t, "ProcessBatchJob", mock.Anything, mock.AnythingOfType("string"), startTime, endTime)
 }
 
 func TestCreateBatchJob_SetJobFailure(t *testing.T) {
@@ -128,7 +133,7 @@ func TestCreateBatchJob_SetJobFailure(t *testing.T) {
 	mockTxManager := new(mocks.MockTransactionManager)
 
 	// Initialize BatchJobHandler with mocks
-	handler := NewBatchJobHandler(mockTxDbQuery, mockJobsStore, mockTxManager, mockBatchDataProcessor)
+	handler := NewBatchJobHandler(context.Background(), mockTxDbQuery, mockJobsStore, mockTxManager, mockBatchDataProcessor)
 
 	// Create a test router and register the handler
 	router := gin.Default()
@@ -139,7 +144,7 @@ func TestCreateBatchJob_SetJobFailure(t *testing.T) {
 	endTime := time.Now().Unix()
 
 	// Set up mock expectations for SetJob to return an error
-	mockJobsStore.On("SetJob", mock.AnythingOfType("string"), mock.MatchedBy(func(data []byte) bool {
+	mockJobsStore.On("SetJob", mock.Anything, mock.AnythingOfType("string"), mock.MatchedBy(func(data []byte) bool {
 		var job cache.BatchJob
 		err := json.Unmarshal(data, &job)
 		if err != nil {
@@ -178,7 +183,7 @@ func TestCreateBatchJob_SetJobFailure(t *testing.T) {
 	assert.Equal(t, "Failed to store batch job in Redis", responseBody.Error)
 
 	// Assert that SetJob was called with the correct parameters
-	mockJobsStore.AssertCalled(t, "SetJob", mock.AnythingOfType("string"), mock.MatchedBy(func(data []byte) bool {
+	mockJobsStore.AssertCalled(t, "SetJob", mock.Anything, mock.AnythingOfType("string"), mock.MatchedBy(func(data []byte) bool {
 		var job cache.BatchJob
 		err := json.Unmarshal(data, &job)
 		if err != nil {
@@ -191,5 +196,76 @@ func TestCreateBatchJob_SetJobFailure(t *testing.T) {
 	}))
 
 	// Assert that ProcessBatchJob was not called
-	mockBatchDataProcessor.AssertNotCalled(t, "ProcessBatchJob", mock.Anything, mock.Anything, mock.Anything)
+	mockBatchDataProcessor.AssertNotCalled(t, "ProcessBatchJob", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+// blockingBatchDataProcessor runs every job until its context is canceled
+type blockingBatchDataProcessor struct {
+	started chan struct{}
+	ended   chan error
+}
+
+func (p *blockingBatchDataProcessor) ProcessBatchJob(ctx context.Context, jobID string, startTime, endTime int64) error {
+	p.started <- struct{}{}
+	<-ctx.Done()
+	p.ended <- ctx.Err()
+	return ctx.Err()
+}
+
+func TestCancelBatchJob(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockJobsStore := new(mocks.MockJobsStore)
+	mockJobsStore.On("SetJob", mock.Anything, mock.AnythingOfType("string"), mock.Anything).Return(nil)
+	processor := &blockingBatchDataProcessor{started: make(chan struct{}, 2), ended: make(chan error, 2)}
+
+	serverCtx, shutdown := context.WithCancel(context.Background())
+	defer shutdown()
+	handler := NewBatchJobHandler(serverCtx, new(mocks.MockQuerier), mockJobsStore, new(mocks.MockTransactionManager), processor)
+
+	router := gin.Default()
+	router.POST("/batch-jobs", handler.CreateBatchJob)
+	router.DELETE("/batch-jobs/:id", handler.CancelBatchJob)
+
+	createJob := func() string {
+		req, err := http.NewRequest("POST", "/batch-jobs?start_time=1727790000&end_time=1727793600", nil)
+		assert.NoError(t, err)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusCreated, resp.Code)
+
+		var job cache.BatchJob
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &job))
+		<-processor.started
+		return job.ID
+	}
+	cancelJob := func(jobID string) int {
+		req, err := http.NewRequest("DELETE", "/batch-jobs/"+jobID, nil)
+		assert.NoError(t, err)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp.Code
+	}
+
+	// A running job is canceled by its ID
+	canceledJob := createJob()
+	runningJob := createJob()
+	assert.Equal(t, http.StatusAccepted, cancelJob(canceledJob))
+	select {
+	case err := <-processor.ended:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("The canceled job is still running")
+	}
+
+	// Unknown IDs are not running on this server
+	assert.Equal(t, http.StatusNotFound, cancelJob(uuid.New().String()))
+	assert.Equal(t, http.StatusBadRequest, cancelJob("not-a-uuid"))
+
+	// The other jobs are canceled on shutdown
+	shutdown()
+	handler.Wait()
+	assert.ErrorIs(t, <-processor.ended, context.Canceled)
+	assert.Equal(t, http.StatusNotFound, cancelJob(runningJob))
+	assert.Equal(t, http.StatusNotFound, cancelJob(canceledJob))
 }
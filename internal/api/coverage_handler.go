@@ -0,0 +1,158 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	db "github.com/winQe/uniswap-fee-tracker/internal/db/sqlc"
+	"github.com/winQe/uniswap-fee-tracker/internal/domain"
+)
+
+// ChainCoverageResponse represents the JSON structure of the ingestion coverage of a chain.
+// swagger:model
+type ChainCoverageResponse struct {
+	// The ID of the chain
+	ChainID int64 `json:"chain_id"`
+	// The last block processed by the live recorder, null before its first run
+	CheckpointBlock *int64 `json:"checkpoint_block"`
+	// When the checkpoint last moved (Unix epoch time in seconds), null before the first run
+	CheckpointUpdatedAt *int64 `json:"checkpoint_updated_at"`
+	// The most recent confirmed block of the chain, null when it could not be fetched
+	ConfirmedHead *uint64 `json:"confirmed_head"`
+	// The block ranges ingested, in order
+	Ranges []BlockRangeResponse `json:"ranges"`
+	// The block ranges with no ingestion coverage, between the first ingested block and the confirmed head
+	Gaps []BlockRangeResponse `json:"gaps"`
+}
+
+// BlockRangeResponse represents an inclusive range of blocks.
+// swagger:model
+type BlockRangeResponse struct {
+	// The first block of the range
+	StartBlock int64 `json:"start_block"`
+	// The last block of the range
+	EndBlock int64 `json:"end_block"`
+}
+
+// CoverageHandler reports which blocks the live recorder ingested
+type CoverageHandler struct {
+	txDbQuery db.Querier
+	txManager domain.TransactionManagerInterface // Fetches the confirmed heads
+}
+
+// NewCoverageHandler initializes a new CoverageHandler with the given dependencies.
+func NewCoverageHandler(txDbQuery db.Querier, txManager domain.TransactionManagerInterface) *CoverageHandler {
+	return &CoverageHandler{
+		txDbQuery: txDbQuery,
+		txManager: txManager,
+	}
+}
+
+// GetCoverage godoc
+// @Summary Get the ingestion coverage
+// @Description Report the ingested block ranges of every chain and the ranges with no coverage, up to the confirmed head.
+// @Tags coverage
+// @Accept  json
+// @Produce  json
+// @Param chain_id query int false "Only report this chain"
+// @Success 200 {array} ChainCoverageResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /coverage [get]
+func (ch *CoverageHandler) GetCoverage(ctx *gin.Context) {
+	chainFilter, ok := parseChainFilter(ctx)
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid chain ID"})
+		return
+	}
+
+	chainIDs := ch.txManager.ChainIDs()
+	if chainFilter.Valid {
+		if !slices.Contains(chainIDs, chainFilter.Int64) {
+			ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Chain not configured"})
+			return
+		}
+		chainIDs = []int64{chainFilter.Int64}
+	}
+
+	response := make([]ChainCoverageResponse, 0, len(chainIDs))
+	for _, chainID := range chainIDs {
+		coverage, err := ch.chainCoverage(ctx, chainID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Internal server error"})
+			log.Printf("error getting coverage of chain %d: %v", chainID, err)
+			return
+		}
+		response = append(response, coverage)
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// chainCoverage builds the coverage of a chain from its checkpoint, its ingested ranges and its confirmed head
+func (ch *CoverageHandler) chainCoverage(ctx *gin.Context, chainID int64) (ChainCoverageResponse, error) {
+	coverage := ChainCoverageResponse{ChainID: chainID}
+
+	checkpoint, err := ch.txDbQuery.GetIngestionCheckpoint(ctx, chainID)
+	switch {
+	case err == nil:
+		updatedAt := checkpoint.UpdatedAt.Unix()
+		coverage.CheckpointBlock = &checkpoint.LastBlockNumber
+		coverage.CheckpointUpdatedAt = &updatedAt
+	case !errors.Is(err, pgx.ErrNoRows):
+		return coverage, err
+	}
+
+	ranges, err := ch.txDbQuery.ListIngestedRanges(ctx, chainID)
+	if err != nil {
+		return coverage, err
+	}
+
+	// The gaps still reflect the stored ranges when the head can't be fetched
+	if head, err := ch.txManager.GetSafeBlockNumber(ctx, chainID); err == nil {
+		coverage.ConfirmedHead = &head
+	} else {
+		log.Printf("error getting the confirmed head of chain %d: %v", chainID, err)
+	}
+
+	coverage.Ranges, coverage.Gaps = coverageGaps(ranges, coverage.ConfirmedHead)
+	return coverage, nil
+}
+
+// coverageGaps merges the ingested ranges, sorted by start block, and returns them with the gaps between them.
+// The span after the last range up to the head is a gap too, nothing before the first range is reported.
+func coverageGaps(ranges []db.IngestedRanges, head *uint64) ([]BlockRangeResponse, []BlockRangeResponse) {
+	merged := []BlockRangeResponse{}
+	gaps := []BlockRangeResponse{}
+
+	for _, r := range ranges {
+		if r.EndBlock < r.StartBlock {
+			continue
+		}
+
+		last := len(merged) - 1
+		switch {
+		case last >= 0 && r.StartBlock <= merged[last].EndBlock+1:
+			merged[last].EndBlock = max(merged[last].EndBlock, r.EndBlock)
+		default:
+			if last >= 0 {
+				gaps = append(gaps, BlockRangeResponse{StartBlock: merged[last].EndBlock + 1, EndBlock: r.StartBlock - 1})
+			}
+			merged = append(merged, BlockRangeResponse{StartBlock: r.StartBlock, EndBlock: r.EndBlock})
+		}
+	}
+
+	if head != nil && len(merged) > 0 {
+		end := merged[len(merged)-1].EndBlock
+		if int64(*head) > end {
+			gaps = append(gaps, BlockRangeResponse{StartBlock: end + 1, EndBlock: int64(*head)})
+		}
+	}
+
+	return merged, gaps
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	db "github.com/winQe/uniswap-fee-tracker/internal/db/sqlc"
+	"github.com/winQe/uniswap-fee-tracker/internal/mocks"
+)
+
+func TestGetCoverage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockQuerier := new(mocks.MockQuerier)
+	mockTxManager := new(mocks.MockTransactionManager)
+
+	mockTxManager.On("ChainIDs").Return([]int64{1, 42161})
+
+	// Mainnet was down between blocks 2001 and 2499, and lags 100 blocks behind its confirmed head
+	mockQuerier.On("GetIngestionCheckpoint", mock.Anything, int64(1)).
+		Return(db.IngestionCheckpoints{ChainID: 1, LastBlockNumber: 3000, UpdatedAt: time.Unix(1727790000, 0)}, nil)
+	mockQuerier.On("ListIngestedRanges", mock.Anything, int64(1)).Return([]db.IngestedRanges{
+		{ChainID: 1, StartBlock: 1001, EndBlock: 2000},
+		{ChainID: 1, StartBlock: 2500, EndBlock: 2800},
+		{ChainID: 1, StartBlock: 2801, EndBlock: 3000},
+	}, nil)
+	mockTxManager.On("GetSafeBlockNumber", mock.Anything, int64(1)).Return(uint64(3100), nil)
+
+	// Arbitrum never ran and its head is unavailable
+	mockQuerier.On("GetIngestionCheckpoint", mock.Anything, int64(42161)).Return(db.IngestionCheckpoints{}, pgx.ErrNoRows)
+	mockQuerier.On("ListIngestedRanges", mock.Anything, int64(42161)).Return([]db.IngestedRanges{}, nil)
+	mockTxManager.On("GetSafeBlockNumber", mock.Anything, int64(42161)).Return(uint64(0), errors.New("rate limited"))
+
+	handler := NewCoverageHandler(mockQuerier, mockTxManager)
+
+	router := gin.Default()
+	router.GET("/coverage", handler.GetCoverage)
+
+	req, _ := http.NewRequest("GET", "/coverage", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `[
+		{
+			"chain_id": 1,
+			"checkpoint_block": 3000,
+			"checkpoint_updated_at": 1727790000,
+			"confirmed_head": 3100,
+			"ranges": [{"start_block": 1001, "end_block": 2000}, {"start_block": 2500, "end_block": 3000}],
+			"gaps": [{"start_block": 2001, "end_block": 2499}, {"start_block": 3001, "end_block": 3100}]
+		},
+		{
+			"chain_id": 42161,
+			"checkpoint_block": null,
+			"checkpoint_updated_at": null,
+			"confirmed_head": null,
+			"ranges": [],
+			"gaps": []
+		}
+	]`, resp.Body.String())
+}
+
+func TestGetCoverage_UnknownChain(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockTxManager := new(mocks.MockTransactionManager)
+	mockTxManager.On("ChainIDs").Return([]int64{1})
+
+	handler := NewCoverageHandler(new(mocks.MockQuerier), mockTxManager)
+
+	router := gin.Default()
+	router.GET("/coverage", handler.GetCoverage)
+
+	req, _ := http.NewRequest("GET", "/coverage?chain_id=10", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	assert.JSONEq(t, `{"error": "Chain not configured"}`, resp.Body.String())
+}
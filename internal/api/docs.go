@@ -23,3 +23,9 @@ package api
 type ErrorResponse struct {
 	Error string `json:"error"`
 }
+
+// MessageResponse represents the structure of responses acknowledging a request.
+// swagger:model
+type MessageResponse struct {
+	Message string `json:"message"`
+}
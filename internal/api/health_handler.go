@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/winQe/uniswap-fee-tracker/internal/client"
+)
+
+// UpstreamHealthResponse represents the JSON structure of the circuit breaker of an upstream.
+// swagger:model
+type UpstreamHealthResponse struct {
+	// The name of the breaker, e.g. etherscan:1 or binance
+	Name string `json:"name"`
+	// The state of the breaker: closed, open or half_open
+	State string `json:"state"`
+	// The number of requests that failed in a row
+	ConsecutiveFailures int `json:"consecutive_failures"`
+	// The last failure, without the request URL, empty once a request succeeds
+	LastError string `json:"last_error,omitempty"`
+	// When the breaker opened (Unix epoch time in seconds), null while closed
+	OpenedAt *int64 `json:"opened_at"`
+	// When the breaker lets probes through (Unix epoch time in seconds), null while closed
+	RetryAt *int64 `json:"retry_at"`
+}
+
+// HealthHandler reports the health of the upstream APIs
+type HealthHandler struct {
+	breakerStates func() []client.BreakerState
+}
+
+// NewHealthHandler initializes a new HealthHandler reading the circuit breakers from breakerStates.
+func NewHealthHandler(breakerStates func() []client.BreakerState) *HealthHandler {
+	return &HealthHandler{
+		breakerStates: breakerStates,
+	}
+}
+
+// GetUpstreamHealth godoc
+// @Summary Get the health of the upstream APIs
+// @Description Report the circuit breaker state of every upstream called by the API server. Requests to an open upstream fail fast and batch jobs pause until it closes. Breakers are kept per process, those of the live recorder are not reported.
+// @Tags health
+// @Produce  json
+// @Success 200 {array} UpstreamHealthResponse
+// @Router /health/upstreams [get]
+func (hh *HealthHandler) GetUpstreamHealth(ctx *gin.Context) {
+	states := hh.breakerStates()
+
+	response := make([]UpstreamHealthResponse, 0, len(states))
+	for _, state := range states {
+		health := UpstreamHealthResponse{
+			Name:                state.Name,
+			State:               state.State,
+			ConsecutiveFailures: state.ConsecutiveFailures,
+			LastError:           state.LastError,
+		}
+		if !state.OpenedAt.IsZero() {
+			openedAt := state.OpenedAt.Unix()
+			health.OpenedAt = &openedAt
+		}
+		if !state.RetryAt.IsZero() {
+			retryAt := state.RetryAt.Unix()
+			health.RetryAt = &retryAt
+		}
+		response = append(response, health)
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/winQe/uniswap-fee-tracker/internal/client"
+	"github.com/winQe/uniswap-fee-tracker/internal/utils"
+)
+
+func TestGetUpstreamHealth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	handler := NewHealthHandler(func() []client.BreakerState {
+		return []client.BreakerState{
+			{Name: "binance", State: client.BreakerClosed},
+			{
+				Name:                "etherscan:1",
+				State:               client.BreakerOpen,
+				ConsecutiveFailures: 5,
+				LastError:           "etherscan returned 502",
+				OpenedAt:            time.Unix(1727790000, 0),
+				RetryAt:             time.Unix(1727790030, 0),
+			},
+		}
+	})
+
+	router := gin.Default()
+	router.GET("/health/upstreams", handler.GetUpstreamHealth)
+
+	req, _ := http.NewRequest("GET", "/health/upstreams", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `[
+		{"name": "binance", "state": "closed", "consecutive_failures": 0, "opened_at": null, "retry_at": null},
+		{
+			"name": "etherscan:1",
+			"state": "open",
+			"consecutive_failures": 5,
+			"last_error": "etherscan returned 502",
+			"opened_at": 1727790000,
+			"retry_at": 1727790030
+		}
+	]`, resp.Body.String())
+}
+
+func TestGetUpstreamHealth_RedactsAPIKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Explorer failing every request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	etherscan := client.NewEtherscanClient(utils.ChainConfig{
+		ChainID:           990001,
+		ExplorerURL:       server.URL + "/api",
+		APIKey:            "SECRETAPIKEY",
+		RequestsPerSecond: 1000,
+		RequestsPerDay:    1000,
+	}, nil, client.RetryPolicy{MaxAttempts: 1}, client.DefaultBreakerConfig)
+	_, err := etherscan.GetBlockNumber(context.Background())
+	assert.ErrorContains(t, err, "SECRETAPIKEY")
+
+	router := gin.Default()
+	router.GET("/health/upstreams", NewHealthHandler(client.BreakerStates).GetUpstreamHealth)
+
+	req, _ := http.NewRequest("GET", "/health/upstreams", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	// The failure is reported without the request URL and its key
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"last_error":"etherscan error (HTTP 502): "`)
+	assert.NotContains(t, resp.Body.String(), "SECRETAPIKEY")
+}
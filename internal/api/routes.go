@@ -7,7 +7,7 @@ import (
 	docs "github.com/winQe/uniswap-fee-tracker/docs"
 )
 
-func RegisterRoutes(rg *gin.RouterGroup, transactionHandler *TransactionHandler, batchJobHandler *BatchJobHandler) {
+func RegisterRoutes(rg *gin.RouterGroup, transactionHandler *TransactionHandler, batchJobHandler *BatchJobHandler, coverageHandler *CoverageHandler, healthHandler *HealthHandler, statsHandler *StatsHandler) {
 	docs.SwaggerInfo.BasePath = "/api/v1"
 	// Register transactions handlers
 	rg.GET("/transactions/:hash", transactionHandler.getTransactionHash)
@@ -17,8 +17,18 @@ func RegisterRoutes(rg *gin.RouterGroup, transactionHandler *TransactionHandler,
 	// Register batch jobs handler
 	rg.POST("/batch-jobs", batchJobHandler.CreateBatchJob)
 	rg.GET("/batch-jobs/:id", batchJobHandler.GetBatchJob)
+	rg.DELETE("/batch-jobs/:id", batchJobHandler.CancelBatchJob)
 	rg.GET("/batch-jobs", batchJobHandler.ListBatchJobs)
 
+	// Register ingestion coverage handler
+	rg.GET("/coverage", coverageHandler.GetCoverage)
+
+	// Register upstream health handler
+	rg.GET("/health/upstreams", healthHandler.GetUpstreamHealth)
+
+	// Register fee statistics handler
+	rg.GET("/stats/fees", statsHandler.GetFeeStats)
+
 	// Register Swagger route
 	rg.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 }
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/winQe/uniswap-fee-tracker/internal/db/sqlc"
+	"github.com/winQe/uniswap-fee-tracker/internal/utils"
+)
+
+// maxStatsBuckets bounds the buckets of a chain returned by a single request
+const maxStatsBuckets = 10000
+
+// FeeStatsResponse represents the JSON structure of the fee statistics of a chain over a bucket.
+// Amounts are exact, returned as decimal strings.
+// swagger:model
+type FeeStatsResponse struct {
+	// The ID of the chain
+	ChainID int64 `json:"chain_id"`
+	// The start of the bucket (Unix epoch time in seconds), buckets are aligned on UTC
+	BucketStart int64 `json:"bucket_start"`
+	// The number of transactions of the bucket
+	TxCount int64 `json:"tx_count"`
+	// The total gas used by the transactions of the bucket
+	GasUsedSum string `json:"gas_used_sum"`
+	// The statistics of the transaction fees in Ether
+	FeeEth *FeeSummaryResponse `json:"fee_eth"`
+	// The statistics of the transaction fees in USDT, null when no transaction of the bucket was priced
+	FeeUsdt *FeeSummaryResponse `json:"fee_usdt"`
+}
+
+// FeeSummaryResponse represents the distribution of the transaction fees of a bucket.
+// swagger:model
+type FeeSummaryResponse struct {
+	// The lowest fee
+	Min string `json:"min"`
+	// The average fee
+	Avg string `json:"avg"`
+	// The highest fee
+	Max string `json:"max"`
+	// The median fee
+	P50 string `json:"p50"`
+	// The 95th percentile of the fees
+	P95 string `json:"p95"`
+}
+
+// StatsHandler serves the fee statistics precomputed per bucket
+type StatsHandler struct {
+	txDbQuery db.Querier
+}
+
+// NewStatsHandler initializes a new StatsHandler with the given dependencies.
+func NewStatsHandler(txDbQuery db.Querier) *StatsHandler {
+	return &StatsHandler{
+		txDbQuery: txDbQuery,
+	}
+}
+
+// GetFeeStats godoc
+// @Summary Get fee statistics
+// @Description Retrieve the transaction count, gas used and fee distribution of every bucket of the interval between the start and end Unix epoch timestamps, read from the fee rollups. Buckets without transactions are omitted.
+// @Tags stats
+// @Accept  json
+// @Produce  json
+// @Param interval query string false "Size of the buckets" Enums(1m, 1h, 1d) default(1h)
+// @Param start query string true "Start timestamp in Unix epoch seconds, the bucket holding it is included"
+// @Param end query string true "End timestamp in Unix epoch seconds"
+// @Param chain_id query int false "Only return the statistics of this chain"
+// @Success 200 {array} FeeStatsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /stats/fees [get]
+func (sh *StatsHandler) GetFeeStats(ctx *gin.Context) {
+	bucketSize := ctx.DefaultQuery("interval", db.BucketHour)
+	bucketDuration, ok := db.BucketDuration(bucketSize)
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid interval. Use 1m, 1h or 1d."})
+		return
+	}
+
+	startStr := ctx.Query("start")
+	endStr := ctx.Query("end")
+	if startStr == "" || endStr == "" {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Start and end timestamps are required"})
+		return
+	}
+	startUnix, err := utils.ParseUnixTime(startStr)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid start timestamp. Use Unix time in seconds."})
+		return
+	}
+	endUnix, err := utils.ParseUnixTime(endStr)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid end timestamp. Use Unix time in seconds."})
+		return
+	}
+
+	// Buckets are aligned on UTC, the one holding the start is included
+	startTime := time.Unix(startUnix, 0).UTC().Truncate(bucketDuration)
+	endTime := time.Unix(endUnix, 0).UTC()
+	if endTime.Before(startTime) {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "End timestamp must be after start timestamp"})
+		return
+	}
+	if endTime.Sub(startTime)/bucketDuration >= maxStatsBuckets {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Too many buckets, use a larger interval or a shorter range"})
+		return
+	}
+
+	chainID, ok := parseChainFilter(ctx)
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid chain ID"})
+		return
+	}
+
+	rollups, err := sh.txDbQuery.ListFeeRollups(ctx, db.ListFeeRollupsParams{
+		BucketSize: bucketSize,
+		StartTime:  startTime,
+		EndTime:    endTime,
+		ChainID:    chainID,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Internal server error"})
+		log.Printf("error listing fee rollups %v", err)
+		return
+	}
+
+	response := make([]FeeStatsResponse, 0, len(rollups))
+	for _, rollup := range rollups {
+		response = append(response, FeeStatsResponse{
+			ChainID:     rollup.ChainID,
+			BucketStart: rollup.BucketStart.Unix(),
+			TxCount:     rollup.TxCount,
+			GasUsedSum:  utils.NumericToString(rollup.GasUsedSum),
+			FeeEth:      toFeeSummary(rollup.FeeEthMin, rollup.FeeEthAvg, rollup.FeeEthMax, rollup.FeeEthP50, rollup.FeeEthP95),
+			FeeUsdt:     toFeeSummary(rollup.FeeUsdtMin, rollup.FeeUsdtAvg, rollup.FeeUsdtMax, rollup.FeeUsdtP50, rollup.FeeUsdtP95),
+		})
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// toFeeSummary converts the fee statistics of a bucket to a response, nil when no fee was known
+func toFeeSummary(lowest, avg, highest, p50, p95 pgtype.Numeric) *FeeSummaryResponse {
+	if !lowest.Valid {
+		return nil
+	}
+	return &FeeSummaryResponse{
+		Min: utils.NumericToString(lowest),
+		Avg: utils.NumericToString(avg),
+		Max: utils.NumericToString(highest),
+		P50: utils.NumericToString(p50),
+		P95: utils.NumericToString(p95),
+	}
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	db "github.com/winQe/uniswap-fee-tracker/internal/db/sqlc"
+	"github.com/winQe/uniswap-fee-tracker/internal/mocks"
+)
+
+func TestGetFeeStats(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockQuerier := new(mocks.MockQuerier)
+	// 13:40:00 is read from the start of its hour, 2024-10-01 13:00:00 UTC
+	mockQuerier.On("ListFeeRollups", mock.Anything, db.ListFeeRollupsParams{
+		BucketSize: db.BucketHour,
+		StartTime:  time.Unix(1727787600, 0).UTC(),
+		EndTime:    time.Unix(1727794800, 0).UTC(),
+		ChainID:    pgtype.Int8{Int64: 1, Valid: true},
+	}).Return([]db.FeeRollups{
+		{
+			BucketSize:  db.BucketHour,
+			ChainID:     1,
+			BucketStart: time.Unix(1727787600, 0),
+			TxCount:     3,
+			GasUsedSum:  pgtype.Numeric{Int: big.NewInt(450000), Valid: true},
+			FeeEthMin:   decimalNumeric("0.001206538"),
+			FeeEthAvg:   decimalNumeric("0.001830014333333333"),
+			FeeEthMax:   decimalNumeric("0.002214276"),
+			FeeEthP50:   decimalNumeric("0.002069229"),
+			FeeEthP95:   decimalNumeric("0.002214276"),
+		},
+		{
+			BucketSize:  db.BucketHour,
+			ChainID:     1,
+			BucketStart: time.Unix(1727791200, 0),
+			TxCount:     1,
+			GasUsedSum:  pgtype.Numeric{Int: big.NewInt(21000), Valid: true},
+			FeeEthMin:   decimalNumeric("0.000021"),
+			FeeEthAvg:   decimalNumeric("0.000021"),
+			FeeEthMax:   decimalNumeric("0.000021"),
+			FeeEthP50:   decimalNumeric("0.000021"),
+			FeeEthP95:   decimalNumeric("0.000021"),
+			FeeUsdtMin:  decimalNumeric("0.042"),
+			FeeUsdtAvg:  decimalNumeric("0.042"),
+			FeeUsdtMax:  decimalNumeric("0.042"),
+			FeeUsdtP50:  decimalNumeric("0.042"),
+			FeeUsdtP95:  decimalNumeric("0.042"),
+		},
+	}, nil)
+
+	handler := NewStatsHandler(mockQuerier)
+	router := gin.Default()
+	router.GET("/stats/fees", handler.GetFeeStats)
+
+	req, _ := http.NewRequest("GET", "/stats/fees?interval=1h&start=1727790000&end=1727794800&chain_id=1", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	// Buckets without a priced transaction have no USDT statistics
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `[
+		{
+			"chain_id": 1,
+			"bucket_start": 1727787600,
+			"tx_count": 3,
+			"gas_used_sum": "450000",
+			"fee_eth": {"min": "0.001206538", "avg": "0.001830014333333333", "max": "0.002214276", "p50": "0.002069229", "p95": "0.002214276"},
+			"fee_usdt": null
+		},
+		{
+			"chain_id": 1,
+			"bucket_start": 1727791200,
+			"tx_count": 1,
+			"gas_used_sum": "21000",
+			"fee_eth": {"min": "0.000021", "avg": "0.000021", "max": "0.000021", "p50": "0.000021", "p95": "0.000021"},
+			"fee_usdt": {"min": "0.042", "avg": "0.042", "max": "0.042", "p50": "0.042", "p95": "0.042"}
+		}
+	]`, resp.Body.String())
+	mockQuerier.AssertExpectations(t)
+}
+
+func TestGetFeeStats_InvalidRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	handler := NewStatsHandler(new(mocks.MockQuerier))
+	router := gin.Default()
+	router.GET("/stats/fees", handler.GetFeeStats)
+
+	tests := []struct {
+		query string
+		error string
+	}{
+		{"interval=1w&start=1727790000&end=1727794800", "Invalid interval. Use 1m, 1h or 1d."},
+		{"interval=1h&start=1727790000", "Start and end timestamps are required"},
+		{"interval=1h&start=yesterday&end=1727794800", "Invalid start timestamp. Use Unix time in seconds."},
+		{"interval=1h&start=1727794800&end=1727790000", "End timestamp must be after start timestamp"},
+		// A year of minutes
+		{"interval=1m&start=1696254000&end=1727790000", "Too many buckets, use a larger interval or a shorter range"},
+		{"start=1727790000&end=1727794800&chain_id=mainnet", "Invalid chain ID"},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest("GET", "/stats/fees?"+test.query, nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusBadRequest, resp.Code, test.query)
+		assert.JSONEq(t, `{"error": "`+test.error+`"}`, resp.Body.String(), test.query)
+	}
+}
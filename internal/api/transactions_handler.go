@@ -1,15 +1,26 @@
 package api
 
 import (
-	"database/sql"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/winQe/uniswap-fee-tracker/internal/cache"
+	"github.com/winQe/uniswap-fee-tracker/internal/client"
 	db "github.com/winQe/uniswap-fee-tracker/internal/db/sqlc"
+	"github.com/winQe/uniswap-fee-tracker/internal/domain"
+	"github.com/winQe/uniswap-fee-tracker/internal/service"
+	"github.com/winQe/uniswap-fee-tracker/internal/types"
 	"github.com/winQe/uniswap-fee-tracker/internal/utils"
+	"golang.org/x/sync/singleflight"
 )
 
 // TransactionResponse represents the JSON structure of a transaction in the API response.
@@ -23,31 +34,123 @@ type TransactionResponse struct {
 	Timestamp int64 `json:"timestamp"`
 	// The amount of gas used by the transaction
 	GasUsed int64 `json:"gas_used"`
-	// The gas price in Wei
-	GasPriceWei int64 `json:"gas_price_wei"`
+	// The gas price in Wei, as an integer of any size
+	GasPriceWei json.Number `json:"gas_price_wei" swaggertype:"integer"`
+	// The transaction fee in Wei, as a decimal string
+	TransactionFeeWei string `json:"transaction_fee_wei"`
 	// The transaction fee in Ether
 	TransactionFeeEth float64 `json:"transaction_fee_eth"`
+	// The exact transaction fee in Ether, as a decimal string
+	TransactionFeeEthDecimal string `json:"transaction_fee_eth_decimal"`
 	// The transaction fee in USDT
 	TransactionFeeUsdt float64 `json:"transaction_fee_usdt"`
+	// The exact transaction fee in USDT, as a decimal string
+	TransactionFeeUsdtDecimal string `json:"transaction_fee_usdt_decimal"`
 	// The Ether to USDT price at the time of the transaction
 	EthUsdtPrice float64 `json:"eth_usdt_price"`
+	// The exact Ether to USDT price, as a decimal string
+	EthUsdtPriceDecimal string `json:"eth_usdt_price_decimal"`
+	// The sources of the Ether to USDT price, e.g. binance or binance,pool:<address> when aggregated
+	PriceSource string `json:"price_source"`
+	// The relative spread between the aggregated price sources
+	PriceSpread float64 `json:"price_spread"`
+	// Whether the price sources disagree beyond the divergence threshold
+	PriceSuspect bool `json:"price_suspect"`
+	// The tracked pool the transaction was recorded for
+	PoolAddress string `json:"pool_address"`
+	// The ID of the chain the transaction was executed on
+	ChainID int64 `json:"chain_id"`
+	// The split of the fee between the burned base fee and the priority tip, null when the base fee is unknown
+	FeeBreakdown *FeeBreakdownResponse `json:"fee_breakdown"`
+	// The Uniswap V3 swaps executed by the transaction
+	Swaps []SwapResponse `json:"swaps"`
+	// Where a transaction looked up by hash came from, store when already recorded or live when fetched on demand
+	Origin string `json:"origin,omitempty" enums:"store,live"`
+}
+
+// Origins of a transaction looked up by hash
+const (
+	OriginStore = "store"
+	OriginLive  = "live"
+)
+
+// FeeBreakdownResponse represents the JSON structure of the EIP-1559 fee breakdown of a transaction.
+// Amounts are exact, returned as decimal strings.
+// swagger:model
+type FeeBreakdownResponse struct {
+	// The base fee per gas of the block in Wei
+	BaseFeePerGasWei string `json:"base_fee_per_gas_wei"`
+	// The maximum fee per gas declared by the transaction in Wei, omitted for legacy transactions
+	MaxFeePerGasWei string `json:"max_fee_per_gas_wei,omitempty"`
+	// The maximum priority fee per gas declared by the transaction in Wei, omitted for legacy transactions
+	MaxPriorityFeePerGasWei string `json:"max_priority_fee_per_gas_wei,omitempty"`
+	// The tip per gas actually paid above the base fee in Wei
+	EffectiveTipPerGasWei string `json:"effective_tip_per_gas_wei"`
+	// The base fee burned in Ether
+	BurnedFeeEth string `json:"burned_fee_eth"`
+	// The base fee burned in USDT
+	BurnedFeeUsdt string `json:"burned_fee_usdt"`
+	// The priority tip paid to the block builder in Ether
+	TipFeeEth string `json:"tip_fee_eth"`
+	// The priority tip paid to the block builder in USDT
+	TipFeeUsdt string `json:"tip_fee_usdt"`
+}
+
+// SwapResponse represents the JSON structure of a decoded Uniswap V3 Swap event.
+// Token amounts and pool state are 256-bit integers, returned as decimal strings.
+// swagger:model
+type SwapResponse struct {
+	// The index of the Swap log within its block
+	LogIndex int32 `json:"log_index"`
+	// The pool that emitted the event
+	PoolAddress string `json:"pool_address"`
+	// The address that initiated the swap
+	Sender string `json:"sender"`
+	// The address that received the output
+	Recipient string `json:"recipient"`
+	// The signed token0 delta of the pool
+	Amount0 string `json:"amount0"`
+	// The signed token1 delta of the pool
+	Amount1 string `json:"amount1"`
+	// The pool price after the swap as a Q64.96 square root
+	SqrtPriceX96 string `json:"sqrt_price_x96"`
+	// The in-range liquidity of the pool after the swap
+	Liquidity string `json:"liquidity"`
+	// The pool tick after the swap
+	Tick int32 `json:"tick"`
 }
 
 // TransactionHandler handles transaction related CRUD logic
 type TransactionHandler struct {
 	txDbQuery db.Querier
+	txWriter  db.BulkWriter                      // Stores the fetched transactions
+	txManager domain.TransactionManagerInterface // Fetches the transactions missing from the DB
+	missCache cache.TxMissStore                  // Transactions recently not found upstream
+	lookups   singleflight.Group                 // Shares the upstream lookup of a hash between concurrent requests
 }
 
+// Reasons recorded for the transactions not found upstream
+const (
+	missNotFound = "not_found"
+	missNotPool  = "not_pool"
+)
+
+// fetchTimeout bounds an upstream lookup, which outlives the request that started it when others wait for it
+const fetchTimeout = 30 * time.Second
+
 // NewTransactionHandler initializes a new TransactionHandler with the given dependencies.
-func NewTransactionHandler(txDbQuery db.Querier) *TransactionHandler {
+func NewTransactionHandler(txDbQuery db.Querier, txWriter db.BulkWriter, txManager domain.TransactionManagerInterface, missCache cache.TxMissStore) *TransactionHandler {
 	return &TransactionHandler{
 		txDbQuery: txDbQuery,
+		txWriter:  txWriter,
+		txManager: txManager,
+		missCache: missCache,
 	}
 }
 
 // getTransactionHash godoc
 // @Summary Get transaction by hash
-// @Description Retrieve a specific transaction using its hash.
+// @Description Retrieve a specific transaction using its hash. Transactions not recorded yet are fetched, priced and stored on demand.
 // @Tags transactions
 // @Accept  json
 // @Produce  json
@@ -56,6 +159,7 @@ func NewTransactionHandler(txDbQuery db.Querier) *TransactionHandler {
 // @Failure 400 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
+// @Failure 502 {object} ErrorResponse
 // @Router /transactions/{hash} [get]
 func (th *TransactionHandler) getTransactionHash(ctx *gin.Context) {
 	txHash := ctx.Param("hash")
@@ -67,31 +171,119 @@ func (th *TransactionHandler) getTransactionHash(ctx *gin.Context) {
 	}
 
 	// Fetch transaction from the database
+	origin := OriginStore
 	transaction, err := th.txDbQuery.GetTransactionByHash(ctx, txHash)
-	if err != nil {
-		if err == sql.ErrNoRows {
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Not recorded yet, fetch it from the chain unless it was recently missing there
+		origin = OriginLive
+		transaction, err = th.lookupTransaction(ctx.Request.Context(), txHash)
+
+		var notFoundErr *client.TransactionNotFoundError
+		var notPoolErr *client.NotPoolTransactionError
+		switch {
+		case errors.As(err, &notFoundErr):
 			ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Transaction not found"})
 			return
+		case errors.As(err, &notPoolErr):
+			ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Transaction did not touch any tracked pool"})
+			return
+		case errors.Is(err, errFetchTransaction):
+			ctx.JSON(http.StatusBadGateway, ErrorResponse{Error: "Failed to fetch the transaction"})
+			log.Printf("error fetching transaction %s: %v", txHash, err)
+			return
 		}
-
+	}
+	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Internal server error"})
+		log.Printf("error getting transaction %s: %v", txHash, err)
 		return
 	}
 
-	response := TransactionResponse{
-		TransactionHash:    transaction.TransactionHash,
-		BlockNumber:        transaction.BlockNumber,
-		Timestamp:          transaction.Timestamp.Unix(),
-		GasUsed:            transaction.GasUsed,
-		GasPriceWei:        transaction.GasPriceWei,
-		TransactionFeeEth:  float64(transaction.TransactionFeeEth.Float64),
-		TransactionFeeUsdt: float64(transaction.TransactionFeeUsdt.Float64),
-		EthUsdtPrice:       float64(transaction.EthUsdtPrice.Float64),
+	swaps, err := th.txDbQuery.GetSwapsByTransactionHash(ctx, transaction.TransactionHash)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Internal server error"})
+		log.Printf("error getting swaps of %s: %v", txHash, err)
+		return
 	}
 
+	response := toTransactionResponse(transaction, swaps)
+	response.Origin = origin
 	ctx.JSON(http.StatusOK, response)
 }
 
+// errFetchTransaction marks failures to fetch or price a transaction, as opposed to failures to store it
+var errFetchTransaction = errors.New("error fetching transaction")
+
+// lookupTransaction returns a transaction missing from the DB, fetched upstream once for all the concurrent requests.
+// Transactions recently missing upstream are answered from the miss cache, with the error of their first lookup.
+func (th *TransactionHandler) lookupTransaction(ctx context.Context, txHash string) (db.Transactions, error) {
+	reason, err := th.missCache.GetMiss(ctx, txHash)
+	switch {
+	case err == nil:
+		return db.Transactions{}, missError(txHash, reason)
+	case !errors.Is(err, cache.ErrMissNotFound):
+		log.Printf("error reading the miss cache for transaction %s: %v", txHash, err)
+	}
+
+	result := th.lookups.DoChan(txHash, func() (interface{}, error) {
+		fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), fetchTimeout)
+		defer cancel()
+
+		transaction, err := th.fetchTransaction(fetchCtx, txHash)
+
+		var notFoundErr *client.TransactionNotFoundError
+		var notPoolErr *client.NotPoolTransactionError
+		reason := ""
+		switch {
+		case errors.As(err, &notFoundErr):
+			reason = missNotFound
+		case errors.As(err, &notPoolErr):
+			reason = missNotPool
+		}
+		if reason != "" {
+			if err := th.missCache.StoreMiss(fetchCtx, txHash, reason); err != nil {
+				log.Printf("error caching the miss of transaction %s: %v", txHash, err)
+			}
+		}
+
+		return transaction, err
+	})
+
+	select {
+	case <-ctx.Done():
+		return db.Transactions{}, ctx.Err()
+	case res := <-result:
+		if res.Err != nil {
+			return db.Transactions{}, res.Err
+		}
+		return res.Val.(db.Transactions), nil
+	}
+}
+
+// missError rebuilds the error of a transaction recorded as missing upstream
+func missError(txHash string, reason string) error {
+	if reason == missNotPool {
+		return &client.NotPoolTransactionError{Hash: txHash}
+	}
+	return &client.TransactionNotFoundError{Hash: txHash}
+}
+
+// fetchTransaction fetches and prices a transaction missing from the DB, then stores it.
+// It is upserted, as concurrent lookups or the recorder may store it in the meantime, and read back
+// so that it is returned exactly as stored.
+func (th *TransactionHandler) fetchTransaction(ctx context.Context, txHash string) (db.Transactions, error) {
+	tx, err := th.txManager.GetTransaction(ctx, txHash)
+	if err != nil {
+		return db.Transactions{}, fmt.Errorf("%w: %w", errFetchTransaction, err)
+	}
+
+	if _, err := service.StoreTransactions(ctx, th.txWriter, []types.TxWithPrice{*tx}); err != nil {
+		return db.Transactions{}, fmt.Errorf("error storing transaction: %v", err)
+	}
+
+	return th.txDbQuery.GetTransactionByHash(ctx, tx.Hash)
+}
+
 // getLatestTransactions godoc
 // @Summary Get latest transactions
 // @Description Retrieve the latest transactions with an optional limit.
@@ -99,7 +291,10 @@ func (th *TransactionHandler) getTransactionHash(ctx *gin.Context) {
 // @Accept  json
 // @Produce  json
 // @Param limit query int false "Number of transactions to retrieve" default(10)
+// @Param pool query string false "Only return transactions of this pool address"
+// @Param chain_id query int false "Only return transactions of this chain"
 // @Success 200 {array} TransactionResponse
+// @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /transactions/latest [get]
 func (th *TransactionHandler) getLatestTransactions(ctx *gin.Context) {
@@ -114,26 +309,35 @@ func (th *TransactionHandler) getLatestTransactions(ctx *gin.Context) {
 		}
 	}
 
-	transactions, err := th.txDbQuery.GetLatestTransactions(ctx, limit)
+	pool, ok := parsePoolFilter(ctx)
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid pool address"})
+		return
+	}
+
+	chainID, ok := parseChainFilter(ctx)
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid chain ID"})
+		return
+	}
+
+	params := db.GetLatestTransactionsParams{
+		PoolAddress: pool,
+		ChainID:     chainID,
+		Limit:       limit,
+	}
+	transactions, err := th.txDbQuery.GetLatestTransactions(ctx, params)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Internal server error"})
 		log.Printf("error getting latest txs %v", err)
 		return
 	}
 
-	var response []TransactionResponse
-	for _, tx := range transactions {
-		txResp := TransactionResponse{
-			TransactionHash:    tx.TransactionHash,
-			BlockNumber:        tx.BlockNumber,
-			Timestamp:          tx.Timestamp.Unix(),
-			GasUsed:            tx.GasUsed,
-			GasPriceWei:        tx.GasPriceWei,
-			TransactionFeeEth:  float64(tx.TransactionFeeEth.Float64),
-			TransactionFeeUsdt: float64(tx.TransactionFeeUsdt.Float64),
-			EthUsdtPrice:       float64(tx.EthUsdtPrice.Float64),
-		}
-		response = append(response, txResp)
+	response, err := th.toTransactionResponses(ctx, transactions)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Internal server error"})
+		log.Printf("error getting swaps %v", err)
+		return
 	}
 
 	ctx.JSON(http.StatusOK, response)
@@ -147,6 +351,8 @@ func (th *TransactionHandler) getLatestTransactions(ctx *gin.Context) {
 // @Produce  json
 // @Param start query string true "Start timestamp in Unix epoch seconds"
 // @Param end query string true "End timestamp in Unix epoch seconds"
+// @Param pool query string false "Only return transactions of this pool address"
+// @Param chain_id query int false "Only return transactions of this chain"
 // @Success 200 {array} TransactionResponse "List of transactions"
 // @Failure 400 {object} ErrorResponse "Bad Request"
 // @Failure 500 {object} ErrorResponse "Internal Server Error"
@@ -180,9 +386,23 @@ func (th *TransactionHandler) getTransactionByTimestamp(ctx *gin.Context) {
 		return
 	}
 
+	pool, ok := parsePoolFilter(ctx)
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid pool address"})
+		return
+	}
+
+	chainID, ok := parseChainFilter(ctx)
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid chain ID"})
+		return
+	}
+
 	params := db.GetTransactionsByTimeRangeParams{
-		Timestamp:   startTime,
-		Timestamp_2: endTime,
+		StartTime:   startTime,
+		EndTime:     endTime,
+		PoolAddress: pool,
+		ChainID:     chainID,
 	}
 	transactions, err := th.txDbQuery.GetTransactionsByTimeRange(ctx, params)
 	if err != nil {
@@ -190,20 +410,126 @@ func (th *TransactionHandler) getTransactionByTimestamp(ctx *gin.Context) {
 		return
 	}
 
+	response, err := th.toTransactionResponses(ctx, transactions)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Internal server error"})
+		log.Printf("error getting swaps %v", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// parsePoolFilter reads the optional pool query parameter. An absent pool matches every pool.
+func parsePoolFilter(ctx *gin.Context) (pgtype.Text, bool) {
+	poolStr, exists := ctx.GetQuery("pool")
+	if !exists {
+		return pgtype.Text{}, true
+	}
+
+	poolAddress := utils.SanitizeAddress(poolStr)
+	if poolAddress == "" {
+		return pgtype.Text{}, false
+	}
+	return pgtype.Text{String: poolAddress, Valid: true}, true
+}
+
+// parseChainFilter reads the optional chain_id query parameter. An absent chain ID matches every chain.
+func parseChainFilter(ctx *gin.Context) (pgtype.Int8, bool) {
+	chainStr, exists := ctx.GetQuery("chain_id")
+	if !exists {
+		return pgtype.Int8{}, true
+	}
+
+	chainID, err := strconv.ParseInt(chainStr, 10, 64)
+	if err != nil || chainID <= 0 {
+		return pgtype.Int8{}, false
+	}
+	return pgtype.Int8{Int64: chainID, Valid: true}, true
+}
+
+// toTransactionResponses converts the transactions to responses, attaching their swaps with a single query
+func (th *TransactionHandler) toTransactionResponses(ctx *gin.Context, transactions []db.Transactions) ([]TransactionResponse, error) {
+	if len(transactions) == 0 {
+		return nil, nil
+	}
+
+	hashes := make([]string, 0, len(transactions))
+	for _, tx := range transactions {
+		hashes = append(hashes, tx.TransactionHash)
+	}
+
+	swaps, err := th.txDbQuery.GetSwapsByTransactionHashes(ctx, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	swapsByHash := make(map[string][]db.Swaps)
+	for _, swap := range swaps {
+		swapsByHash[swap.TransactionHash] = append(swapsByHash[swap.TransactionHash], swap)
+	}
+
 	var response []TransactionResponse
 	for _, tx := range transactions {
-		txResp := TransactionResponse{
-			TransactionHash:    tx.TransactionHash,
-			BlockNumber:        tx.BlockNumber,
-			Timestamp:          tx.Timestamp.Unix(),
-			GasUsed:            tx.GasUsed,
-			GasPriceWei:        tx.GasPriceWei,
-			TransactionFeeEth:  float64(tx.TransactionFeeEth.Float64),
-			TransactionFeeUsdt: float64(tx.TransactionFeeUsdt.Float64),
-			EthUsdtPrice:       float64(tx.EthUsdtPrice.Float64),
-		}
-		response = append(response, txResp)
+		response = append(response, toTransactionResponse(tx, swapsByHash[tx.TransactionHash]))
+	}
+	return response, nil
+}
+
+// toTransactionResponse converts a stored transaction and its swaps to the API representation
+func toTransactionResponse(tx db.Transactions, swaps []db.Swaps) TransactionResponse {
+	swapResponses := make([]SwapResponse, 0, len(swaps))
+	for _, swap := range swaps {
+		swapResponses = append(swapResponses, SwapResponse{
+			LogIndex:     swap.LogIndex,
+			PoolAddress:  swap.PoolAddress,
+			Sender:       swap.Sender,
+			Recipient:    swap.Recipient,
+			Amount0:      utils.NumericToString(swap.Amount0),
+			Amount1:      utils.NumericToString(swap.Amount1),
+			SqrtPriceX96: utils.NumericToString(swap.SqrtPriceX96),
+			Liquidity:    utils.NumericToString(swap.Liquidity),
+			Tick:         swap.Tick,
+		})
 	}
 
-	ctx.JSON(http.StatusOK, response)
+	return TransactionResponse{
+		TransactionHash:           tx.TransactionHash,
+		BlockNumber:               tx.BlockNumber,
+		Timestamp:                 tx.Timestamp.Unix(),
+		GasUsed:                   tx.GasUsed,
+		GasPriceWei:               json.Number(utils.NumericToString(tx.GasPriceWei)),
+		TransactionFeeWei:         utils.NumericToString(tx.TransactionFeeWei),
+		TransactionFeeEth:         utils.NumericToFloat64(tx.TransactionFeeEth),
+		TransactionFeeEthDecimal:  utils.NumericToString(tx.TransactionFeeEth),
+		TransactionFeeUsdt:        utils.NumericToFloat64(tx.TransactionFeeUsdt),
+		TransactionFeeUsdtDecimal: utils.NumericToString(tx.TransactionFeeUsdt),
+		EthUsdtPrice:              utils.NumericToFloat64(tx.EthUsdtPrice),
+		EthUsdtPriceDecimal:       utils.NumericToString(tx.EthUsdtPrice),
+		PriceSource:               tx.PriceSource.String,
+		PriceSpread:               tx.PriceSpread.Float64,
+		PriceSuspect:              tx.PriceSuspect,
+		PoolAddress:               tx.PoolAddress.String,
+		ChainID:                   tx.ChainID,
+		FeeBreakdown:              toFeeBreakdownResponse(tx),
+		Swaps:                     swapResponses,
+	}
+}
+
+// toFeeBreakdownResponse converts the stored fee breakdown of a transaction, nil when it has none
+func toFeeBreakdownResponse(tx db.Transactions) *FeeBreakdownResponse {
+	if !tx.BaseFeePerGasWei.Valid {
+		return nil
+	}
+
+	return &FeeBreakdownResponse{
+		BaseFeePerGasWei:        utils.NumericToString(tx.BaseFeePerGasWei),
+		MaxFeePerGasWei:         utils.NumericToString(tx.MaxFeePerGasWei),
+		MaxPriorityFeePerGasWei: utils.NumericToString(tx.MaxPriorityFeePerGasWei),
+		EffectiveTipPerGasWei:   utils.NumericToString(tx.EffectiveTipPerGasWei),
+		BurnedFeeEth:            utils.NumericToString(tx.BurnedFeeEth),
+		BurnedFeeUsdt:           utils.NumericToString(tx.BurnedFeeUsdt),
+		TipFeeEth:               utils.NumericToString(tx.TipFeeEth),
+		TipFeeUsdt:              utils.NumericToString(tx.TipFeeUsdt),
+	}
 }
@@ -1,18 +1,28 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/winQe/uniswap-fee-tracker/internal/cache"
+	"github.com/winQe/uniswap-fee-tracker/internal/client"
 	db "github.com/winQe/uniswap-fee-tracker/internal/db/sqlc"
 	"github.com/winQe/uniswap-fee-tracker/internal/mocks"
+	"github.com/winQe/uniswap-fee-tracker/internal/types"
+	"github.com/winQe/uniswap-fee-tracker/internal/utils"
 )
 
 // TestGetTransactionHash tests the retrieval of a transaction by its hash.
@@ -25,21 +35,51 @@ func TestGetTransactionHash(t *testing.T) {
 
 	// Sample transaction data
 	sampleTx := db.Transactions{
-		TransactionHash:    "0xabcdef1234567890abcdef1234567890abcdef1234567890abcdef1234567890",
-		BlockNumber:        123456,
-		Timestamp:          time.Unix(1617181723, 0).UTC(),
-		GasUsed:            21000,
-		GasPriceWei:        1000000000,
-		TransactionFeeEth:  pgtype.Float8{Float64: 0.021, Valid: true},
-		TransactionFeeUsdt: pgtype.Float8{Float64: 42.0, Valid: true},
-		EthUsdtPrice:       pgtype.Float8{Float64: 2000.0, Valid: true},
+		TransactionHash:         "0xabcdef1234567890abcdef1234567890abcdef1234567890abcdef1234567890",
+		BlockNumber:             123456,
+		Timestamp:               time.Unix(1617181723, 0).UTC(),
+		GasUsed:                 21000,
+		GasPriceWei:             pgtype.Numeric{Int: big.NewInt(1000000000), Valid: true},
+		TransactionFeeEth:       decimalNumeric("0.021"),
+		TransactionFeeUsdt:      decimalNumeric("42"),
+		EthUsdtPrice:            decimalNumeric("2000"),
+		TransactionFeeWei:       pgtype.Numeric{Int: big.NewInt(21000000000000000), Valid: true},
+		PoolAddress:             pgtype.Text{String: "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640", Valid: true},
+		ChainID:                 1,
+		PriceSource:             pgtype.Text{String: "binance", Valid: true},
+		BaseFeePerGasWei:        pgtype.Numeric{Int: big.NewInt(800000000), Valid: true},
+		MaxFeePerGasWei:         pgtype.Numeric{Int: big.NewInt(2000000000), Valid: true},
+		MaxPriorityFeePerGasWei: pgtype.Numeric{Int: big.NewInt(200000000), Valid: true},
+		EffectiveTipPerGasWei:   pgtype.Numeric{Int: big.NewInt(200000000), Valid: true},
+		BurnedFeeEth:            decimalNumeric("0.0000168"),
+		BurnedFeeUsdt:           decimalNumeric("0.0336"),
+		TipFeeEth:               decimalNumeric("0.0000042"),
+		TipFeeUsdt:              decimalNumeric("0.0084"),
+	}
+
+	amount1, _ := new(big.Int).SetString("-467118523758354530", 10)
+	sqrtPriceX96, _ := new(big.Int).SetString("1564081234567890123456789012345678", 10)
+	sampleSwaps := []db.Swaps{
+		{
+			TransactionHash: sampleTx.TransactionHash,
+			LogIndex:        158,
+			PoolAddress:     "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640",
+			Sender:          "0x8449e4198a021e8a2a5537c0508430b8febf8efc",
+			Recipient:       "0x3fc91a3afd70395cd496c647d5a6cc9d4b2b7fad",
+			Amount0:         pgtype.Numeric{Int: big.NewInt(1200000000), Valid: true},
+			Amount1:         pgtype.Numeric{Int: amount1, Valid: true},
+			SqrtPriceX96:    pgtype.Numeric{Int: sqrtPriceX96, Valid: true},
+			Liquidity:       pgtype.Numeric{Int: big.NewInt(12813487219), Valid: true},
+			Tick:            197817,
+		},
 	}
 
 	// Set up expectations
 	mockQuerier.On("GetTransactionByHash", mock.Anything, sampleTx.TransactionHash).Return(sampleTx, nil)
+	mockQuerier.On("GetSwapsByTransactionHash", mock.Anything, sampleTx.TransactionHash).Return(sampleSwaps, nil)
 
 	// Initialize TransactionHandler
-	handler := NewTransactionHandler(mockQuerier)
+	handler := NewTransactionHandler(mockQuerier, new(mocks.MockBulkWriter), new(mocks.MockTransactionManager), new(mocks.MockTxMissStore))
 
 	// Set up Gin router
 	router := gin.Default()
@@ -60,9 +100,42 @@ func TestGetTransactionHash(t *testing.T) {
 		"timestamp": 1617181723,
 		"gas_used": 21000,
 		"gas_price_wei": 1000000000,
+		"transaction_fee_wei": "21000000000000000",
 		"transaction_fee_eth": 0.021,
+		"transaction_fee_eth_decimal": "0.021",
 		"transaction_fee_usdt": 42,
-		"eth_usdt_price": 2000
+		"transaction_fee_usdt_decimal": "42",
+		"eth_usdt_price": 2000,
+		"eth_usdt_price_decimal": "2000",
+		"pool_address": "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640",
+		"chain_id": 1,
+		"price_source": "binance",
+		"price_spread": 0.0,
+		"price_suspect": false,
+		"fee_breakdown": {
+			"base_fee_per_gas_wei": "800000000",
+			"max_fee_per_gas_wei": "2000000000",
+			"max_priority_fee_per_gas_wei": "200000000",
+			"effective_tip_per_gas_wei": "200000000",
+			"burned_fee_eth": "0.0000168",
+			"burned_fee_usdt": "0.0336",
+			"tip_fee_eth": "0.0000042",
+			"tip_fee_usdt": "0.0084"
+		},
+		"origin": "store",
+		"swaps": [
+			{
+				"log_index": 158,
+				"pool_address": "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640",
+				"sender": "0x8449e4198a021e8a2a5537c0508430b8febf8efc",
+				"recipient": "0x3fc91a3afd70395cd496c647d5a6cc9d4b2b7fad",
+				"amount0": "1200000000",
+				"amount1": "-467118523758354530",
+				"sqrt_price_x96": "1564081234567890123456789012345678",
+				"liquidity": "12813487219",
+				"tick": 197817
+			}
+		]
 	}`
 	assert.JSONEq(t, expectedBody, resp.Body.String())
 
@@ -70,6 +143,197 @@ func TestGetTransactionHash(t *testing.T) {
 	mockQuerier.AssertExpectations(t)
 }
 
+// TestGetTransactionHash_FetchThrough tests that a transaction missing from the database is fetched, stored and returned.
+func TestGetTransactionHash_FetchThrough(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	txHash := "0x8a4ed869c6b0ba8ed9543ec13f634a8105523eed2848a699c0b2150ae694bfc8"
+	storedTx := db.Transactions{
+		TransactionHash:    txHash,
+		BlockNumber:        20871328,
+		Timestamp:          time.Unix(1727793947, 0).UTC(),
+		GasUsed:            21000,
+		GasPriceWei:        pgtype.Numeric{Int: big.NewInt(1000000000), Valid: true},
+		TransactionFeeEth:  decimalNumeric("0.000021"),
+		TransactionFeeUsdt: decimalNumeric("0.042"),
+		EthUsdtPrice:       decimalNumeric("2000"),
+		TransactionFeeWei:  pgtype.Numeric{Int: big.NewInt(21000000000000), Valid: true},
+		PoolAddress:        pgtype.Text{String: "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640", Valid: true},
+		ChainID:            1,
+		PriceSource:        pgtype.Text{String: "binance", Valid: true},
+	}
+
+	// The first lookup misses, the second one reads the stored transaction back
+	mockQuerier := new(mocks.MockQuerier)
+	mockQuerier.On("GetTransactionByHash", mock.Anything, txHash).Return(db.Transactions{}, pgx.ErrNoRows).Once()
+	mockQuerier.On("GetTransactionByHash", mock.Anything, txHash).Return(storedTx, nil).Once()
+	mockQuerier.On("GetSwapsByTransactionHash", mock.Anything, txHash).Return([]db.Swaps{}, nil)
+
+	// The transaction is upserted, in case a concurrent lookup or the recorder stored it in the meantime
+	mockWriter := new(mocks.MockBulkWriter)
+	mockWriter.On("UpsertTransactions", mock.Anything, mock.MatchedBy(func(rows []db.TransactionRow) bool {
+		return len(rows) == 1 && rows[0].Transaction.TransactionHash == txHash && rows[0].Transaction.ChainID == 1
+	})).Return(db.UpsertResult{Inserted: 1}, nil)
+
+	mockTxManager := new(mocks.MockTransactionManager)
+	mockTxManager.On("GetTransaction", mock.Anything, txHash).Return(&types.TxWithPrice{
+		TransactionData: types.TransactionData{
+			ChainID: 1, BlockNumber: 20871328, Hash: txHash, GasUsed: 21000, GasPriceWei: big.NewInt(1000000000),
+			Timestamp: time.Unix(1727793947, 0), PoolAddress: "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640",
+		},
+		ETHUSDTPrice:       big.NewRat(2000, 1),
+		PriceSource:        "binance",
+		TransactionFeeWei:  big.NewInt(21000000000000),
+		TransactionFeeETH:  big.NewRat(21, 1000000),
+		TransactionFeeUSDT: big.NewRat(42, 1000),
+	}, nil)
+
+	mockMissStore := new(mocks.MockTxMissStore)
+	mockMissStore.On("GetMiss", mock.Anything, txHash).Return("", cache.ErrMissNotFound)
+
+	handler := NewTransactionHandler(mockQuerier, mockWriter, mockTxManager, mockMissStore)
+	router := gin.Default()
+	router.GET("/transactions/:hash", handler.getTransactionHash)
+
+	// Mixed case hashes are looked up lowercase
+	req, _ := http.NewRequest("GET", "/transactions/0x8A4ED869c6b0ba8ed9543ec13f634a8105523eed2848a699c0b2150ae694bfc8", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var body TransactionResponse
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+	assert.Equal(t, OriginLive, body.Origin)
+	assert.Equal(t, txHash, body.TransactionHash)
+	assert.Equal(t, "0.042", body.TransactionFeeUsdtDecimal)
+
+	mockQuerier.AssertExpectations(t)
+	mockWriter.AssertExpectations(t)
+	mockTxManager.AssertExpectations(t)
+}
+
+// TestGetTransactionHash_FetchThroughErrors tests the responses of failed on demand fetches.
+func TestGetTransactionHash_FetchThroughErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	txHash := "0x8a4ed869c6b0ba8ed9543ec13f634a8105523eed2848a699c0b2150ae694bfc8"
+
+	testCases := []struct {
+		name         string
+		err          error
+		expectedCode int
+		expectedBody string
+		missReason   string // Recorded in the miss cache, empty when the failure may not last
+	}{
+		{"unknown transaction", &client.TransactionNotFoundError{Hash: txHash}, http.StatusNotFound, `{"error": "Transaction not found"}`, "not_found"},
+		{"not a pool transaction", &client.NotPoolTransactionError{Hash: txHash}, http.StatusNotFound, `{"error": "Transaction did not touch any tracked pool"}`, "not_pool"},
+		{"upstream failure", errors.New("Etherscan server error: NOTOK"), http.StatusBadGateway, `{"error": "Failed to fetch the transaction"}`, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockQuerier := new(mocks.MockQuerier)
+			mockQuerier.On("GetTransactionByHash", mock.Anything, txHash).Return(db.Transactions{}, pgx.ErrNoRows)
+
+			// The domain wraps the client errors
+			mockTxManager := new(mocks.MockTransactionManager)
+			mockTxManager.On("GetTransaction", mock.Anything, txHash).Return((*types.TxWithPrice)(nil), fmt.Errorf("failed to get transaction by hash from API client: %w", tc.err))
+
+			mockMissStore := new(mocks.MockTxMissStore)
+			mockMissStore.On("GetMiss", mock.Anything, txHash).Return("", cache.ErrMissNotFound)
+			if tc.missReason != "" {
+				mockMissStore.On("StoreMiss", mock.Anything, txHash, tc.missReason).Return(nil)
+			}
+
+			handler := NewTransactionHandler(mockQuerier, new(mocks.MockBulkWriter), mockTxManager, mockMissStore)
+			router := gin.Default()
+			router.GET("/transactions/:hash", handler.getTransactionHash)
+
+			req, _ := http.NewRequest("GET", "/transactions/"+txHash, nil)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tc.expectedCode, resp.Code)
+			assert.JSONEq(t, tc.expectedBody, resp.Body.String())
+			mockMissStore.AssertExpectations(t)
+			if tc.missReason == "" {
+				mockMissStore.AssertNotCalled(t, "StoreMiss", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+// TestGetTransactionHash_CachedMiss tests transactions recently missing upstream are not looked up again.
+func TestGetTransactionHash_CachedMiss(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	txHash := "0x8a4ed869c6b0ba8ed9543ec13f634a8105523eed2848a699c0b2150ae694bfc8"
+
+	mockQuerier := new(mocks.MockQuerier)
+	mockQuerier.On("GetTransactionByHash", mock.Anything, txHash).Return(db.Transactions{}, pgx.ErrNoRows)
+	mockMissStore := new(mocks.MockTxMissStore)
+	mockMissStore.On("GetMiss", mock.Anything, txHash).Return("not_pool", nil)
+	mockTxManager := new(mocks.MockTransactionManager)
+
+	handler := NewTransactionHandler(mockQuerier, new(mocks.MockBulkWriter), mockTxManager, mockMissStore)
+	router := gin.Default()
+	router.GET("/transactions/:hash", handler.getTransactionHash)
+
+	req, _ := http.NewRequest("GET", "/transactions/"+txHash, nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	assert.JSONEq(t, `{"error": "Transaction did not touch any tracked pool"}`, resp.Body.String())
+	mockTxManager.AssertNotCalled(t, "GetTransaction", mock.Anything, mock.Anything)
+}
+
+// TestGetTransactionHash_ConcurrentLookups tests concurrent requests for a missing transaction share one upstream lookup.
+func TestGetTransactionHash_ConcurrentLookups(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	txHash := "0x8a4ed869c6b0ba8ed9543ec13f634a8105523eed2848a699c0b2150ae694bfc8"
+	const requests = 5
+
+	// Every request reads the miss cache before the lookup is let through
+	var waiting sync.WaitGroup
+	waiting.Add(requests)
+	release := make(chan struct{})
+
+	mockQuerier := new(mocks.MockQuerier)
+	mockQuerier.On("GetTransactionByHash", mock.Anything, txHash).Return(db.Transactions{}, pgx.ErrNoRows)
+	mockMissStore := new(mocks.MockTxMissStore)
+	mockMissStore.On("GetMiss", mock.Anything, txHash).Run(func(mock.Arguments) { waiting.Done() }).Return("", cache.ErrMissNotFound)
+	mockMissStore.On("StoreMiss", mock.Anything, txHash, "not_found").Return(nil).Once()
+	mockTxManager := new(mocks.MockTransactionManager)
+	mockTxManager.On("GetTransaction", mock.Anything, txHash).Run(func(mock.Arguments) { <-release }).
+		Return((*types.TxWithPrice)(nil), &client.TransactionNotFoundError{Hash: txHash}).Once()
+
+	handler := NewTransactionHandler(mockQuerier, new(mocks.MockBulkWriter), mockTxManager, mockMissStore)
+	router := gin.Default()
+	router.GET("/transactions/:hash", handler.getTransactionHash)
+
+	codes := make(chan int, requests)
+	for i := 0; i < requests; i++ {
+		go func() {
+			req, _ := http.NewRequest("GET", "/transactions/"+txHash, nil)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+			codes <- resp.Code
+		}()
+	}
+
+	waiting.Wait()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	for i := 0; i < requests; i++ {
+		assert.Equal(t, http.StatusNotFound, <-codes)
+	}
+	mockTxManager.AssertNumberOfCalls(t, "GetTransaction", 1)
+	mockMissStore.AssertExpectations(t)
+}
+
 // TestGetLatestTransactions_DefaultLimit tests the retrieval of the latest transactions with the default limit.
 func TestGetLatestTransactions_DefaultLimit(t *testing.T) {
 	// Initialize Gin in test mode
@@ -85,29 +349,41 @@ func TestGetLatestTransactions_DefaultLimit(t *testing.T) {
 			BlockNumber:        123456,
 			Timestamp:          time.Unix(1617181723, 0).UTC(),
 			GasUsed:            21000,
-			GasPriceWei:        1000000000,
-			TransactionFeeEth:  pgtype.Float8{Float64: 0.021, Valid: true},
-			TransactionFeeUsdt: pgtype.Float8{Float64: 42.0, Valid: true},
-			EthUsdtPrice:       pgtype.Float8{Float64: 2000.0, Valid: true},
+			GasPriceWei:        pgtype.Numeric{Int: big.NewInt(1000000000), Valid: true},
+			TransactionFeeEth:  decimalNumeric("0.021"),
+			TransactionFeeUsdt: decimalNumeric("42"),
+			EthUsdtPrice:       decimalNumeric("2000"),
+			TransactionFeeWei:  pgtype.Numeric{Int: big.NewInt(21000000000000000), Valid: true},
+			PoolAddress:        pgtype.Text{String: "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640", Valid: true},
+			ChainID:            1,
+			PriceSource:        pgtype.Text{String: "binance", Valid: true},
 		},
 		{
 			TransactionHash:    "0xhash2",
 			BlockNumber:        123457,
 			Timestamp:          time.Unix(1617181730, 0).UTC(),
 			GasUsed:            22000,
-			GasPriceWei:        1100000000,
-			TransactionFeeEth:  pgtype.Float8{Float64: 0.022, Valid: true},
-			TransactionFeeUsdt: pgtype.Float8{Float64: 44.0, Valid: true},
-			EthUsdtPrice:       pgtype.Float8{Float64: 2000.0, Valid: true},
+			GasPriceWei:        pgtype.Numeric{Int: big.NewInt(1100000000), Valid: true},
+			TransactionFeeEth:  decimalNumeric("0.022"),
+			TransactionFeeUsdt: decimalNumeric("44"),
+			EthUsdtPrice:       decimalNumeric("2000"),
+			TransactionFeeWei:  pgtype.Numeric{Int: big.NewInt(22000000000000000), Valid: true},
+			PoolAddress:        pgtype.Text{String: "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640", Valid: true},
+			ChainID:            1,
+			PriceSource:        pgtype.Text{String: "binance", Valid: true},
 		},
 		// Add more transactions as needed
 	}
 
 	// Set up expectations
-	mockQuerier.On("GetLatestTransactions", mock.Anything, int32(10)).Return(sampleTxs, nil)
+	params := db.GetLatestTransactionsParams{
+		Limit: 10,
+	}
+	mockQuerier.On("GetLatestTransactions", mock.Anything, params).Return(sampleTxs, nil)
+	mockQuerier.On("GetSwapsByTransactionHashes", mock.Anything, []string{"0xhash1", "0xhash2"}).Return([]db.Swaps{}, nil)
 
 	// Initialize TransactionHandler
-	handler := NewTransactionHandler(mockQuerier)
+	handler := NewTransactionHandler(mockQuerier, new(mocks.MockBulkWriter), new(mocks.MockTransactionManager), new(mocks.MockTxMissStore))
 
 	// Set up Gin router
 	router := gin.Default()
@@ -129,9 +405,20 @@ func TestGetLatestTransactions_DefaultLimit(t *testing.T) {
 			"timestamp": 1617181723,
 			"gas_used": 21000,
 			"gas_price_wei": 1000000000,
+			"transaction_fee_wei": "21000000000000000",
 			"transaction_fee_eth": 0.021,
+			"transaction_fee_eth_decimal": "0.021",
 			"transaction_fee_usdt": 42,
-			"eth_usdt_price": 2000
+			"transaction_fee_usdt_decimal": "42",
+			"eth_usdt_price": 2000,
+			"eth_usdt_price_decimal": "2000",
+			"pool_address": "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640",
+			"chain_id": 1,
+			"price_source": "binance",
+			"price_spread": 0.0,
+			"price_suspect": false,
+			"fee_breakdown": null,
+			"swaps": []
 		},
 		{
 			"transaction_hash": "0xhash2",
@@ -139,9 +426,20 @@ func TestGetLatestTransactions_DefaultLimit(t *testing.T) {
 			"timestamp": 1617181730,
 			"gas_used": 22000,
 			"gas_price_wei": 1100000000,
+			"transaction_fee_wei": "22000000000000000",
 			"transaction_fee_eth": 0.022,
+			"transaction_fee_eth_decimal": "0.022",
 			"transaction_fee_usdt": 44,
-			"eth_usdt_price": 2000
+			"transaction_fee_usdt_decimal": "44",
+			"eth_usdt_price": 2000,
+			"eth_usdt_price_decimal": "2000",
+			"pool_address": "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640",
+			"chain_id": 1,
+			"price_source": "binance",
+			"price_spread": 0.0,
+			"price_suspect": false,
+			"fee_breakdown": null,
+			"swaps": []
 		}
 	]`
 	assert.JSONEq(t, expectedBody, resp.Body.String())
@@ -165,20 +463,28 @@ func TestGetTransactionByTimestamp(t *testing.T) {
 			BlockNumber:        123458,
 			Timestamp:          time.Unix(1617181740, 0).UTC(), // 2021-03-31T12:09:00Z
 			GasUsed:            21000,
-			GasPriceWei:        1000000000,
-			TransactionFeeEth:  pgtype.Float8{Float64: 0.021, Valid: true},
-			TransactionFeeUsdt: pgtype.Float8{Float64: 420.0, Valid: true},
-			EthUsdtPrice:       pgtype.Float8{Float64: 20000.0, Valid: true},
+			GasPriceWei:        pgtype.Numeric{Int: big.NewInt(1000000000), Valid: true},
+			TransactionFeeEth:  decimalNumeric("0.021"),
+			TransactionFeeUsdt: decimalNumeric("420"),
+			EthUsdtPrice:       decimalNumeric("20000"),
+			TransactionFeeWei:  pgtype.Numeric{Int: big.NewInt(21000000000000000), Valid: true},
+			PoolAddress:        pgtype.Text{String: "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640", Valid: true},
+			ChainID:            1,
+			PriceSource:        pgtype.Text{String: "binance", Valid: true},
 		},
 		{
 			TransactionHash:    "0xhash4",
 			BlockNumber:        123459,
 			Timestamp:          time.Unix(1617181750, 0).UTC(), // 2021-03-31T12:09:10Z
 			GasUsed:            22000,
-			GasPriceWei:        1100000000,
-			TransactionFeeEth:  pgtype.Float8{Float64: 0.0242, Valid: true},
-			TransactionFeeUsdt: pgtype.Float8{Float64: 484.0, Valid: true},
-			EthUsdtPrice:       pgtype.Float8{Float64: 20000.0, Valid: true},
+			GasPriceWei:        pgtype.Numeric{Int: big.NewInt(1100000000), Valid: true},
+			TransactionFeeEth:  decimalNumeric("0.0242"),
+			TransactionFeeUsdt: decimalNumeric("484"),
+			EthUsdtPrice:       decimalNumeric("20000"),
+			TransactionFeeWei:  pgtype.Numeric{Int: big.NewInt(24200000000000000), Valid: true},
+			PoolAddress:        pgtype.Text{String: "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640", Valid: true},
+			ChainID:            1,
+			PriceSource:        pgtype.Text{String: "binance", Valid: true},
 		},
 	}
 
@@ -188,22 +494,26 @@ func TestGetTransactionByTimestamp(t *testing.T) {
 
 	// Set up expectations for GetTransactionsByTimeRange
 	params := db.GetTransactionsByTimeRangeParams{
-		Timestamp:   time.Unix(startUnix, 0),
-		Timestamp_2: time.Unix(endUnix, 0),
+		StartTime:   time.Unix(startUnix, 0),
+		EndTime:     time.Unix(endUnix, 0),
+		PoolAddress: pgtype.Text{String: "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640", Valid: true},
+		ChainID:     pgtype.Int8{Int64: 1, Valid: true},
 	}
 	mockQuerier.On("GetTransactionsByTimeRange", mock.Anything, params).Return(sampleTxs, nil)
+	mockQuerier.On("GetSwapsByTransactionHashes", mock.Anything, []string{"0xhash3", "0xhash4"}).Return([]db.Swaps{}, nil)
 
 	// Initialize TransactionHandler with the mock Querier
-	handler := NewTransactionHandler(mockQuerier)
+	handler := NewTransactionHandler(mockQuerier, new(mocks.MockBulkWriter), new(mocks.MockTransactionManager), new(mocks.MockTxMissStore))
 
 	// Set up Gin router and register the route
 	router := gin.Default()
 	router.GET("/transactions", handler.getTransactionByTimestamp)
 
 	// Create a test request with 'start' and 'end' query parameters in RFC3339 format
+	// The pool filter is matched case insensitively
 	startTimeStr := strconv.FormatInt(startUnix, 10)
 	endTimeStr := strconv.FormatInt(endUnix, 10)
-	reqURL := "/transactions?start=" + startTimeStr + "&end=" + endTimeStr
+	reqURL := "/transactions?start=" + startTimeStr + "&end=" + endTimeStr + "&pool=0x88e6A0c2dDD26FEEb64F039a2c41296FcB3f5640&chain_id=1"
 	req, _ := http.NewRequest("GET", reqURL, nil)
 	resp := httptest.NewRecorder()
 
@@ -221,9 +531,20 @@ func TestGetTransactionByTimestamp(t *testing.T) {
 			"timestamp": 1617181740,
 			"gas_used": 21000,
 			"gas_price_wei": 1000000000,
+			"transaction_fee_wei": "21000000000000000",
 			"transaction_fee_eth": 0.021,
+			"transaction_fee_eth_decimal": "0.021",
 			"transaction_fee_usdt": 420.0,
-			"eth_usdt_price": 20000.0
+			"transaction_fee_usdt_decimal": "420",
+			"eth_usdt_price": 20000.0,
+			"eth_usdt_price_decimal": "20000",
+			"pool_address": "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640",
+			"chain_id": 1,
+			"price_source": "binance",
+			"price_spread": 0.0,
+			"price_suspect": false,
+			"fee_breakdown": null,
+			"swaps": []
 		},
 		{
 			"transaction_hash": "0xhash4",
@@ -231,9 +552,20 @@ func TestGetTransactionByTimestamp(t *testing.T) {
 			"timestamp": 1617181750,
 			"gas_used": 22000,
 			"gas_price_wei": 1100000000,
+			"transaction_fee_wei": "24200000000000000",
 			"transaction_fee_eth": 0.0242,
+			"transaction_fee_eth_decimal": "0.0242",
 			"transaction_fee_usdt": 484.0,
-			"eth_usdt_price": 20000.0
+			"transaction_fee_usdt_decimal": "484",
+			"eth_usdt_price": 20000.0,
+			"eth_usdt_price_decimal": "20000",
+			"pool_address": "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640",
+			"chain_id": 1,
+			"price_source": "binance",
+			"price_spread": 0.0,
+			"price_suspect": false,
+			"fee_breakdown": null,
+			"swaps": []
 		}
 	]`
 
@@ -243,3 +575,33 @@ func TestGetTransactionByTimestamp(t *testing.T) {
 	// Assert that all expectations were met
 	mockQuerier.AssertExpectations(t)
 }
+
+// TestGetLatestTransactions_InvalidPool tests that a malformed pool filter is rejected.
+func TestGetLatestTransactions_InvalidPool(t *testing.T) {
+	// Initialize Gin in test mode
+	gin.SetMode(gin.TestMode)
+
+	// The database must not be queried
+	mockQuerier := new(mocks.MockQuerier)
+
+	handler := NewTransactionHandler(mockQuerier, new(mocks.MockBulkWriter), new(mocks.MockTransactionManager), new(mocks.MockTxMissStore))
+
+	router := gin.Default()
+	router.GET("/transactions/latest", handler.getLatestTransactions)
+
+	req, _ := http.NewRequest("GET", "/transactions/latest?pool=0x1234", nil)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.JSONEq(t, `{"error": "Invalid pool address"}`, resp.Body.String())
+
+	mockQuerier.AssertExpectations(t)
+}
+
+// decimalNumeric converts a decimal string to a NUMERIC column value
+func decimalNumeric(value string) pgtype.Numeric {
+	rat, _ := utils.ParseDecimal(value)
+	return utils.RatToNumeric(rat, 38)
+}
@@ -1,16 +1,28 @@
 package cache
 
-import "time"
+import (
+	"context"
+	"time"
+
+	"github.com/winQe/uniswap-fee-tracker/internal/client"
+)
 
 // RateStore defines the interface for interacting with the rate cache.
-// It allows storing and retrieving rate values based on timestamps.
+// It allows storing and retrieving rate values, along with their source, based on timestamps.
 type RateStore interface {
-	StoreRate(timestamp time.Time, price float64) error
-	GetRate(timestamp time.Time) (float64, error)
+	StoreRate(ctx context.Context, timestamp time.Time, price client.KlineData) error
+	StoreRates(ctx context.Context, prices []client.PricePoint) error
+	GetRate(ctx context.Context, timestamp time.Time) (*client.KlineData, error)
+}
+
+// TxMissStore remembers why the transactions looked up upstream were not returned, so they are not looked up again.
+type TxMissStore interface {
+	StoreMiss(ctx context.Context, txHash string, reason string) error
+	GetMiss(ctx context.Context, txHash string) (string, error)
 }
 
 type JobsStore interface {
-	SetJob(jobID string, jobData []byte) error
-	GetJob(jobID string) ([]byte, error)
-	GetAllJobs() ([][]byte, error)
+	SetJob(ctx context.Context, jobID string, jobData []byte) error
+	GetJob(ctx context.Context, jobID string) ([]byte, error)
+	GetAllJobs(ctx context.Context) ([][]byte, error)
 }
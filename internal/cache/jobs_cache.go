@@ -1,10 +1,13 @@
 package cache
 
 import (
+	"context"
 	"errors"
 	"time"
 
 	"github.com/redis/go-redis/v9"
+	db "github.com/winQe/uniswap-fee-tracker/internal/db/sqlc"
+	"github.com/winQe/uniswap-fee-tracker/internal/types"
 )
 
 // BatchJob represents a batch job for historical data recording.
@@ -13,7 +16,7 @@ type BatchJob struct {
 	// Unique identifier for the batch job
 	ID string `json:"id"`
 
-	// Current status of the job (e.g., pending, completed, failed)
+	// Current status of the job (e.g., pending, running, paused, completed, failed, canceled)
 	Status string `json:"status"`
 
 	// Start time for the batch job (Unix epoch seconds)
@@ -30,6 +33,13 @@ type BatchJob struct {
 
 	// Result of the batch job
 	Result string `json:"result"`
+
+	// Block ranges fetched per chain and pool. Ranges holding more results than the explorer pages through
+	// are marked split and followed by their two halves.
+	Progress []types.RangeProgress `json:"progress,omitempty"`
+
+	// Transactions written by the job, inserted, updated or skipped when already stored unchanged
+	Stored *db.UpsertResult `json:"stored,omitempty"`
 }
 
 // JobsCache keeps track of all pending batch jobs within the TTL
@@ -53,15 +63,15 @@ func NewJobCache(addr, password string) *JobsCache {
 }
 
 // SetJob stores a batch job in Redis with the given job ID.
-func (jb *JobsCache) SetJob(jobID string, jobData []byte) error {
+func (jb *JobsCache) SetJob(ctx context.Context, jobID string, jobData []byte) error {
 	key := "batch_job:" + jobID
-	return jb.client.Set(jb.ctx, key, jobData, jb.expiryTime).Err()
+	return jb.client.Set(ctx, key, jobData, jb.expiryTime).Err()
 }
 
 // GetJob retrieves a batch job from Redis by job ID.
-func (jb *JobsCache) GetJob(jobID string) ([]byte, error) {
+func (jb *JobsCache) GetJob(ctx context.Context, jobID string) ([]byte, error) {
 	key := "batch_job:" + jobID
-	jobData, err := jb.client.Get(jb.ctx, key).Bytes()
+	jobData, err := jb.client.Get(ctx, key).Bytes()
 	if err != nil {
 		if err == redis.Nil {
 			return nil, ErrJobNotFound
@@ -72,11 +82,11 @@ func (jb *JobsCache) GetJob(jobID string) ([]byte, error) {
 }
 
 // GetAllJobs retrieves all batch jobs from Redis.
-func (jb *JobsCache) GetAllJobs() ([][]byte, error) {
+func (jb *JobsCache) GetAllJobs(ctx context.Context) ([][]byte, error) {
 	var jobs [][]byte
-	iter := jb.client.Scan(jb.ctx, 0, "batch_job:*", 0).Iterator()
-	for iter.Next(jb.ctx) {
-		jobData, err := jb.client.Get(jb.ctx, iter.Val()).Bytes()
+	iter := jb.client.Scan(ctx, 0, "batch_job:*", 0).Iterator()
+	for iter.Next(ctx) {
+		jobData, err := jb.client.Get(ctx, iter.Val()).Bytes()
 		if err != nil {
 			continue // Skip if unable to get job data
 		}
@@ -0,0 +1,39 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/winQe/uniswap-fee-tracker/internal/client"
+)
+
+// KeyUsageCache counts the daily requests of every explorer API key, implementing client.KeyUsageStore
+type KeyUsageCache struct {
+	*RedisCache
+	keyPrefix  string
+	expiryTime time.Duration
+}
+
+const keyUsageDB = 3
+
+// NewKeyUsageCache creates a new KeyUsageCache instance.
+func NewKeyUsageCache(addr, password string) client.KeyUsageStore {
+	return &KeyUsageCache{
+		RedisCache: NewRedisCache(addr, password, keyUsageDB),
+		keyPrefix:  "key_usage",
+		expiryTime: 48 * time.Hour, // Counts outlive their UTC day in every time zone
+	}
+}
+
+// IncrementKeyUsage counts a request of the key on the day and returns the count of the day.
+func (kc *KeyUsageCache) IncrementKeyUsage(ctx context.Context, keyID string, day string) (int64, error) {
+	key := kc.keyPrefix + ":" + day + ":" + keyID
+
+	pipe := kc.client.TxPipeline()
+	count := pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, kc.expiryTime)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return count.Val(), nil
+}
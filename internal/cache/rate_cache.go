@@ -1,45 +1,110 @@
 package cache
 
 import (
+	"context"
 	"fmt"
+	"math"
 	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
+	"github.com/winQe/uniswap-fee-tracker/internal/client"
+	"github.com/winQe/uniswap-fee-tracker/internal/utils"
 )
 
 // RateCache implements the RateStore interface.
+// Prices covering a span, like klines, are kept apart from the ones read at a point in time
+// and only returned for the timestamps within their span.
 type RateCache struct {
 	*RedisCache
 	sortedSetKey string
+	spansKey     string
 	ttl          time.Duration
+	lookupWindow time.Duration
+}
+
+// cachedRate is the sorted set member of a stored rate.
+// The timestamp keeps members unique when two timestamps share the same price.
+type cachedRate struct {
+	Timestamp int64   `json:"timestamp"`
+	Until     int64   `json:"until,omitempty"` // End of the span covered by the price in milliseconds, exclusive
+	Price     float64 `json:"price"`
+	Decimal   string  `json:"decimal"`
+	Source    string  `json:"source"`
+	Spread    float64 `json:"spread"`
+	Suspect   bool    `json:"suspect"`
 }
 
 const rateDB = 0
 
 // NewRateCache creates a new RateCache instance.
-func NewRateCache(addr, password string) RateStore {
+// GetRate reuses a stored rate within lookupWindow of the requested timestamp, a stored kline within its span.
+func NewRateCache(addr, password string, lookupWindow time.Duration) RateStore {
 	return &RateCache{
 		RedisCache:   NewRedisCache(addr, password, rateDB),
-		sortedSetKey: "rate_cache",    // key for redis sorted set
-		ttl:          5 * time.Minute, // price expires after 5 minutes
+		sortedSetKey: "rate_cache",       // key for redis sorted set
+		spansKey:     "rate_cache_spans", // key for the sorted set of prices covering a span
+		ttl:          15 * time.Minute,   // prices expire after 15 minutes, outliving the 10 minute timeout of batch jobs that prefetch them
+		lookupWindow: lookupWindow,
 	}
 }
 
 // StoreRate stores the current price with the given timestamp.
-func (rc *RateCache) StoreRate(timestamp time.Time, price float64) error {
-	ts := timestamp.Unix()
-	_, err := rc.client.ZAdd(rc.ctx, rc.sortedSetKey, redis.Z{
-		Score:  float64(ts),
-		Member: price,
-	}).Result()
+func (rc *RateCache) StoreRate(ctx context.Context, timestamp time.Time, price client.KlineData) error {
+	return rc.StoreRates(ctx, []client.PricePoint{{Timestamp: timestamp, KlineData: price}})
+}
+
+// StoreRates stores a batch of prices with their timestamps, the prices covering a span with their span.
+func (rc *RateCache) StoreRates(ctx context.Context, prices []client.PricePoint) error {
+	var points, spans []redis.Z
+	for _, price := range prices {
+		rate := cachedRate{
+			Timestamp: price.Timestamp.Unix(),
+			Price:     price.ClosePrice,
+			Decimal:   price.ClosePriceDecimal,
+			Source:    price.Source,
+			Spread:    price.Spread,
+			Suspect:   price.Suspect,
+		}
+		score := float64(rate.Timestamp)
+		if !price.Until.IsZero() {
+			// Spans are scored by their start in milliseconds, klines start and end on a millisecond
+			rate.Until = price.Until.UnixMilli()
+			score = float64(price.Timestamp.UnixMilli())
+		}
+
+		member, err := utils.SerializeToJSON(rate)
+		if err != nil {
+			return fmt.Errorf("error serializing rate: %w", err)
+		}
+
+		if price.Until.IsZero() {
+			points = append(points, redis.Z{Score: score, Member: member})
+		} else {
+			spans = append(spans, redis.Z{Score: score, Member: member})
+		}
+	}
+
+	if err := rc.storeMembers(ctx, rc.sortedSetKey, points); err != nil {
+		return err
+	}
+	return rc.storeMembers(ctx, rc.spansKey, spans)
+}
+
+// storeMembers adds the members to the sorted set in a single request
+func (rc *RateCache) storeMembers(ctx context.Context, key string, members []redis.Z) error {
+	if len(members) == 0 {
+		return nil
+	}
+
+	_, err := rc.client.ZAdd(ctx, key, members...).Result()
 	if err != nil {
 		return fmt.Errorf("error adding rate to sorted set: %w", err)
 	}
 
 	// Set the TTL for the sorted set key
 	// Reset the TTL every time a new rate is stored to keep the key alive as long as data is being added
-	err = rc.client.Expire(rc.ctx, rc.sortedSetKey, rc.ttl).Err()
+	err = rc.client.Expire(ctx, key, rc.ttl).Err()
 	if err != nil {
 		return fmt.Errorf("error setting expiration on sorted set: %w", err)
 	}
@@ -47,33 +112,87 @@ func (rc *RateCache) StoreRate(timestamp time.Time, price float64) error {
 	return nil
 }
 
-// GetRate retrieves the price within a 5-minute range of the given timestamp.
-func (rc *RateCache) GetRate(timestamp time.Time) (float64, error) {
+// GetRate retrieves the price whose span holds the given timestamp, or else the price stored closest to it
+// within the lookup window.
+func (rc *RateCache) GetRate(ctx context.Context, timestamp time.Time) (*client.KlineData, error) {
+	rate, err := rc.getSpanRate(ctx, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	if rate == nil {
+		if rate, err = rc.getPointRate(ctx, timestamp); err != nil {
+			return nil, err
+		}
+	}
+
+	return &client.KlineData{
+		ClosePrice:        rate.Price,
+		ClosePriceDecimal: rate.Decimal,
+		Source:            rate.Source,
+		Spread:            rate.Spread,
+		Suspect:           rate.Suspect,
+	}, nil
+}
+
+// getSpanRate returns the price of the last span starting at or before the timestamp, nil when it ended before it
+func (rc *RateCache) getSpanRate(ctx context.Context, timestamp time.Time) (*cachedRate, error) {
+	ms := timestamp.UnixMilli()
+
+	members, err := rc.client.ZRevRangeByScore(ctx, rc.spansKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(ms, 10),
+		Count: 1,
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error querying sorted set: %w", err)
+	}
+	if len(members) == 0 {
+		return nil, nil
+	}
+
+	var rate cachedRate
+	if err := utils.DeserializeFromJSON([]byte(members[0]), &rate); err != nil {
+		return nil, fmt.Errorf("error parsing rate: %w", err)
+	}
+	if ms >= rate.Until {
+		return nil, nil
+	}
+	return &rate, nil
+}
+
+// getPointRate returns the price stored closest to the timestamp within the lookup window
+func (rc *RateCache) getPointRate(ctx context.Context, timestamp time.Time) (*cachedRate, error) {
 	ts := timestamp.Unix()
+	window := int64(rc.lookupWindow.Seconds())
 
-	// Scores to find prices within one minute range of the provided timestamp
-	minScore := float64(ts - 60*5)
-	maxScore := float64(ts + 60*5)
+	// Scores to find prices within the lookup window of the provided timestamp
+	minScore := float64(ts - window)
+	maxScore := float64(ts + window)
 
 	// Retrieve members with their scores within the specified score range
-	zRange, err := rc.client.ZRangeByScoreWithScores(rc.ctx, rc.sortedSetKey, &redis.ZRangeBy{
+	zRange, err := rc.client.ZRangeByScoreWithScores(ctx, rc.sortedSetKey, &redis.ZRangeBy{
 		Min: fmt.Sprintf("%f", minScore),
 		Max: fmt.Sprintf("%f", maxScore),
 	}).Result()
 	if err != nil {
-		return 0, fmt.Errorf("error querying sorted set with scores: %w", err)
+		return nil, fmt.Errorf("error querying sorted set with scores: %w", err)
 	}
 
 	if len(zRange) == 0 {
-		return 0, fmt.Errorf("no rate found within 5-minute range of timestamp %v", timestamp)
+		return nil, fmt.Errorf("no rate found within %v of timestamp %v", rc.lookupWindow, timestamp)
 	}
 
-	// Return the first rate found within the range
-	firstMember := zRange[0].Member.(string)
-	price, err := strconv.ParseFloat(firstMember, 64)
-	if err != nil {
-		return 0, fmt.Errorf("error parsing price: %w", err)
+	// Return the rate stored closest to the timestamp
+	closest := zRange[0]
+	for _, z := range zRange[1:] {
+		if math.Abs(z.Score-float64(ts)) < math.Abs(closest.Score-float64(ts)) {
+			closest = z
+		}
 	}
 
-	return price, nil
+	var rate cachedRate
+	if err := utils.DeserializeFromJSON([]byte(closest.Member.(string)), &rate); err != nil {
+		return nil, fmt.Errorf("error parsing rate: %w", err)
+	}
+	return &rate, nil
 }
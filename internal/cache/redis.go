@@ -10,7 +10,6 @@ import (
 // RedisCache abstracts Redis client
 type RedisCache struct {
 	client *redis.Client
-	ctx    context.Context
 }
 
 // NewRedisCache creates a new Redis client instance. Can be called for different DBs
@@ -29,7 +28,6 @@ func NewRedisCache(addr, password string, db int) *RedisCache {
 
 	return &RedisCache{
 		client: rdb,
-		ctx:    context.Background(),
 	}
 }
 
@@ -0,0 +1,47 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TxMissCache keeps the transaction hashes missing upstream, or not touching a tracked pool, within the TTL
+type TxMissCache struct {
+	*RedisCache
+	keyPrefix  string
+	expiryTime time.Duration
+}
+
+// ErrMissNotFound is returned when a transaction hash was not recorded as missing.
+var ErrMissNotFound = errors.New("miss not found")
+
+const txMissDB = 4
+
+// NewTxMissCache creates a new TxMissCache instance.
+func NewTxMissCache(addr, password string) TxMissStore {
+	return &TxMissCache{
+		RedisCache: NewRedisCache(addr, password, txMissDB),
+		keyPrefix:  "tx_miss",
+		expiryTime: time.Minute, // Pending transactions are looked up again once they had time to be mined
+	}
+}
+
+// StoreMiss records why the transaction was not returned.
+func (mc *TxMissCache) StoreMiss(ctx context.Context, txHash string, reason string) error {
+	return mc.client.Set(ctx, mc.keyPrefix+":"+txHash, reason, mc.expiryTime).Err()
+}
+
+// GetMiss returns why the transaction was not returned, ErrMissNotFound when it was not recorded as missing.
+func (mc *TxMissCache) GetMiss(ctx context.Context, txHash string) (string, error) {
+	reason, err := mc.client.Get(ctx, mc.keyPrefix+":"+txHash).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return "", ErrMissNotFound
+		}
+		return "", err
+	}
+	return reason, nil
+}
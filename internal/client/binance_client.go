@@ -2,19 +2,69 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/adshao/go-binance/v2"
+	"github.com/adshao/go-binance/v2/common"
 	"golang.org/x/time/rate"
 )
 
+// PriceSourceBinance is the source of prices read from Binance klines
+const PriceSourceBinance = "binance"
+
+// Defaults of the Binance market prices are read from
+const (
+	DefaultKlineSymbol   = "ETHUSDT"
+	DefaultKlineInterval = "15m"
+)
+
+// klineIntervals maps the Binance kline intervals to their duration.
+// The 1M interval is left out, months have no fixed duration.
+var klineIntervals = map[string]time.Duration{
+	"1s":  time.Second,
+	"1m":  time.Minute,
+	"3m":  3 * time.Minute,
+	"5m":  5 * time.Minute,
+	"15m": 15 * time.Minute,
+	"30m": 30 * time.Minute,
+	"1h":  time.Hour,
+	"2h":  2 * time.Hour,
+	"4h":  4 * time.Hour,
+	"6h":  6 * time.Hour,
+	"8h":  8 * time.Hour,
+	"12h": 12 * time.Hour,
+	"1d":  24 * time.Hour,
+	"3d":  3 * 24 * time.Hour,
+	"1w":  7 * 24 * time.Hour,
+}
+
+// maxRangeKlineInterval is the coarsest candle interval of range fetches, finer intervals are kept as configured
+const maxRangeKlineInterval = "1m"
+
+// maxKlinesPerRequest is the largest number of klines Binance returns per request
+const maxKlinesPerRequest = 1000
+
+// KlineConfig selects the Binance market KlineClient reads prices from
+type KlineConfig struct {
+	Testnet  bool
+	BaseURL  string // Overrides the mainnet or testnet endpoint, e.g. to point at a local stand-in
+	Symbol   string
+	Interval string        // Binance kline interval, from 1s up to 1w
+	Retry    RetryPolicy   // Retries of failed requests, DefaultRetryPolicy when unset
+	Breaker  BreakerConfig // Circuit breaker registered as binance, DefaultBreakerConfig when unset
+}
+
 // KlineClient is the client for interacting with Binance Kline API using go-binance.
 type KlineClient struct {
 	binanceClient *binance.Client
-	rateLimiter   *rate.Limiter
+	symbol        string
+	interval      string
+	resolution    time.Duration
+	rangeInterval string
 }
 
 // NewKlineClient initializes a new KlineClient with rate limits.
@@ -23,79 +73,157 @@ type KlineClient struct {
 //
 // https://github.com/binance/binance-spot-api-docs/blob/master/rest-api.md#limits
 // https://developers.binance.com/docs/binance-spot-api-docs/web-socket-api#ip-limits
-func NewKlineClient() *KlineClient {
+func NewKlineClient(config KlineConfig) (*KlineClient, error) {
+	if config.Symbol == "" {
+		config.Symbol = DefaultKlineSymbol
+	}
+	if config.Interval == "" {
+		config.Interval = DefaultKlineInterval
+	}
+
+	resolution, ok := klineIntervals[config.Interval]
+	if !ok {
+		return nil, fmt.Errorf("unsupported kline interval %q", config.Interval)
+	}
+
 	// Initialize the Binance client. No API key is required for public endpoints.
-	binance.UseTestnet = true
 	binanceClient := binance.NewClient("", "")
-
-	// Set up a rate limiter: 50 requests per second with a burst of 30.
+	switch {
+	case config.BaseURL != "":
+		binanceClient.BaseURL = config.BaseURL
+	case config.Testnet:
+		binanceClient.BaseURL = binance.BaseAPITestnetURL
+	default:
+		binanceClient.BaseURL = binance.BaseAPIMainURL
+	}
+	// Set up a rate limiter: 50 requests per second with a burst of 30, waited on by every attempt of the retrying transport.
 	rateLimiter := rate.NewLimiter(50, 30)
+	binanceClient.HTTPClient = newRetryingHTTPClient(PriceSourceBinance, config.Retry, NewCircuitBreaker(PriceSourceBinance, config.Breaker), rateLimiter)
+
+	// Range fetches use 1 minute klines unless the interval is finer
+	rangeInterval := config.Interval
+	if resolution > klineIntervals[maxRangeKlineInterval] {
+		rangeInterval = maxRangeKlineInterval
+	}
 
 	return &KlineClient{
 		binanceClient: binanceClient,
-		rateLimiter:   rateLimiter,
-	}
+		symbol:        config.Symbol,
+		interval:      config.Interval,
+		resolution:    resolution,
+		rangeInterval: rangeInterval,
+	}, nil
 }
 
-// GetETHUSDT fetches the latest ETH/USDT conversion rate for the given timestamp (1 min interval).
+// UseTransport sends the requests of the client through transport instead of the network, e.g. a Cassette.
+// Rate limits, retries and the circuit breaker still apply.
+func (k *KlineClient) UseTransport(transport http.RoundTripper) {
+	setBaseTransport(k.binanceClient.HTTPClient, transport)
+}
+
+// GetETHUSDT fetches the ETH/USDT conversion rate of the kline containing the given timestamp.
 // It queries the Binance Kline API.
-func (k *KlineClient) GetETHUSDT(timestamp time.Time) (*KlineData, error) {
+func (k *KlineClient) GetETHUSDT(ctx context.Context, timestamp time.Time) (*KlineData, error) {
 	if timestamp.IsZero() {
 		return nil, fmt.Errorf("timestamp is invalid")
 	}
-	// Respect the rate limit
-	if err := k.rateLimiter.Wait(context.Background()); err != nil {
-		return nil, fmt.Errorf("rate limiter error: %v", err)
+	// Failed requests are retried by the transport, which also waits on the rate limit before every attempt
+	klines, err := k.binanceClient.NewKlinesService().
+		Symbol(k.symbol).
+		Interval(k.interval).
+		EndTime(timestamp.UnixMilli()).
+		Limit(1). // Fetch only the latest kline
+		Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching klines: %w", binanceError(err))
+	}
+	if len(klines) == 0 {
+		return nil, fmt.Errorf("no kline data returned at %v: %w", timestamp, &NotFoundError{Source: PriceSourceBinance, Resource: "kline"})
 	}
 
-	// Define maximum number of retries
-	maxRetries := 3
-	var klines []*binance.Kline
-	var err error
-
-	// Retry loop
-	for attempts := 0; attempts < maxRetries; attempts++ {
-		log.Printf("Attempt %d of fetching klines data from Binance API\n", attempts+1)
-		// Respect the rate limit
-		if err := k.rateLimiter.Wait(context.Background()); err != nil {
-			return nil, fmt.Errorf("rate limiter error: %v", err)
-		}
+	// Extract the close price from the first kline
+	closePriceStr := klines[0].Close
+	closePrice, err := strconv.ParseFloat(closePriceStr, 64)
+	if err != nil {
+		return nil, &DecodeError{Source: PriceSourceBinance, Err: fmt.Errorf("error converting close price to float64: %w", err)}
+	}
+
+	// Return the structured KlineData
+	return &KlineData{
+		ClosePrice:        closePrice,
+		ClosePriceDecimal: closePriceStr,
+		Source:            PriceSourceBinance,
+	}, nil
+}
 
-		// Prepare the Kline request
-		klinesService := k.binanceClient.NewKlinesService()
-		klinesService.Symbol("ETHUSDT").
-			Interval("15m").
-			EndTime(timestamp.UnixMilli()).
-			Limit(1) // Fetch only the latest kline
+// Resolution is the kline interval, every price covers a whole candle
+func (k *KlineClient) Resolution() time.Duration {
+	return k.resolution
+}
 
-		klines, err = klinesService.Do(context.Background())
+// GetETHUSDTRange fetches every ETH/USDT kline between startTime and endTime, up to 1000 klines per request.
+// Klines are 1 minute long, or the configured interval when it is finer. Each price covers its kline, from its open time until its close time.
+func (k *KlineClient) GetETHUSDTRange(ctx context.Context, startTime time.Time, endTime time.Time) ([]PricePoint, error) {
+	if startTime.IsZero() || endTime.Before(startTime) {
+		return nil, fmt.Errorf("time range is invalid")
+	}
+
+	step := klineIntervals[k.rangeInterval]
+
+	var points []PricePoint
+	from := startTime.Truncate(step)
+	for !from.After(endTime) {
+		klines, err := k.binanceClient.NewKlinesService().
+			Symbol(k.symbol).
+			Interval(k.rangeInterval).
+			StartTime(from.UnixMilli()).
+			EndTime(endTime.UnixMilli()).
+			Limit(maxKlinesPerRequest).
+			Do(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("error fetching klines: %v", err)
+			return nil, fmt.Errorf("error fetching klines: %w", binanceError(err))
 		}
 
-		// If klines data is returned, break the loop
-		if len(klines) > 0 {
+		// No more klines in the range
+		if len(klines) == 0 {
 			break
 		}
 
-		// If no data, wait for a second before retrying
-		time.Sleep(1 * time.Second)
-	}
+		for _, kline := range klines {
+			closePrice, err := strconv.ParseFloat(kline.Close, 64)
+			if err != nil {
+				return nil, &DecodeError{Source: PriceSourceBinance, Err: fmt.Errorf("error converting close price to float64: %w", err)}
+			}
+			points = append(points, PricePoint{
+				Timestamp: time.UnixMilli(kline.OpenTime),
+				Until:     time.UnixMilli(kline.CloseTime + 1), // The close time is the last millisecond of the kline
+				KlineData: KlineData{
+					ClosePrice:        closePrice,
+					ClosePriceDecimal: kline.Close,
+					Source:            PriceSourceBinance,
+				},
+			})
+		}
 
-	// Check if klines are still empty after retries
-	if len(klines) == 0 {
-		log.Printf("timstamp: %d", timestamp.UnixMilli())
-		return nil, fmt.Errorf("no kline data returned after %d attempts", maxRetries)
+		// Continue after the last kline returned
+		from = time.UnixMilli(klines[len(klines)-1].OpenTime).Add(step)
 	}
-	// Extract the close price from the first kline
-	closePriceStr := klines[0].Close
-	closePrice, err := strconv.ParseFloat(closePriceStr, 64)
-	if err != nil {
-		return nil, fmt.Errorf("error converting close price to float64: %v", err)
+
+	return points, nil
+}
+
+// binanceError classifies the errors of the go-binance client.
+// The retrying transport already types transport failures, error responses of the API are upstream errors
+// and responses go-binance fails to parse are decode errors.
+func binanceError(err error) error {
+	var circuitOpenErr *CircuitOpenError
+	if isUpstreamFailure(err) || errors.As(err, &circuitOpenErr) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
 	}
 
-	// Return the structured KlineData
-	return &KlineData{
-		ClosePrice: closePrice,
-	}, nil
+	var apiErr *common.APIError
+	if errors.As(err, &apiErr) {
+		return &UpstreamError{Source: PriceSourceBinance, Message: apiErr.Error()}
+	}
+	return &DecodeError{Source: PriceSourceBinance, Err: err}
 }
@@ -0,0 +1,164 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adshao/go-binance/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// initializeKlineClient sets up the KlineClient against a local stand-in of the Binance API without rate limits.
+func initializeKlineClient(t *testing.T, server *httptest.Server, interval string) *KlineClient {
+	client, err := NewKlineClient(KlineConfig{BaseURL: server.URL, Interval: interval})
+	assert.NoError(t, err)
+
+	client.binanceClient.HTTPClient = server.Client()
+	return client
+}
+
+func TestNewKlineClient(t *testing.T) {
+	// Mainnet ETHUSDT 15m candles by default
+	client, err := NewKlineClient(KlineConfig{})
+	assert.NoError(t, err)
+	assert.Equal(t, binance.BaseAPIMainURL, client.binanceClient.BaseURL)
+	assert.Equal(t, "ETHUSDT", client.symbol)
+	assert.Equal(t, 15*time.Minute, client.Resolution())
+
+	// Every attempt of the retrying transport waits on the rate limit
+	transport, ok := client.binanceClient.HTTPClient.Transport.(*retryTransport)
+	if assert.True(t, ok) {
+		assert.Len(t, transport.limiters, 1)
+	}
+
+	client, err = NewKlineClient(KlineConfig{Testnet: true, Symbol: "ETHUSDC", Interval: "1s"})
+	assert.NoError(t, err)
+	assert.Equal(t, binance.BaseAPITestnetURL, client.binanceClient.BaseURL)
+	assert.Equal(t, "ETHUSDC", client.symbol)
+	assert.Equal(t, time.Second, client.Resolution())
+	assert.Equal(t, "1s", client.rangeInterval)
+
+	_, err = NewKlineClient(KlineConfig{Interval: "7m"})
+	assert.Error(t, err)
+}
+
+func TestGetETHUSDT(t *testing.T) {
+	timestamp := time.Unix(1727790030, 0)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		assert.Equal(t, "ETHUSDT", query.Get("symbol"))
+		assert.Equal(t, "1m", query.Get("interval"))
+		assert.Equal(t, strconv.FormatInt(timestamp.UnixMilli(), 10), query.Get("endTime"))
+		assert.Equal(t, "1", query.Get("limit"))
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `[[1727790000000,"0","0","0","2612.34","0",1727790059999,"0",1,"0","0","0"]]`)
+	}))
+	defer server.Close()
+
+	client := initializeKlineClient(t, server, "1m")
+
+	kline, err := client.GetETHUSDT(context.Background(), timestamp)
+	assert.NoError(t, err, "Expected no error from GetETHUSDT")
+	assert.Equal(t, 2612.34, kline.ClosePrice)
+	assert.Equal(t, PriceSourceBinance, kline.Source)
+}
+
+func TestGetETHUSDT_NoKline(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `[]`)
+	}))
+	defer server.Close()
+
+	client := initializeKlineClient(t, server, "1m")
+
+	// A successful empty answer is not retried
+	_, err := client.GetETHUSDT(context.Background(), time.Unix(1727790030, 0))
+	var notFoundErr *NotFoundError
+	assert.ErrorAs(t, err, &notFoundErr)
+	assert.Equal(t, 1, requests)
+}
+
+func TestGetETHUSDTRange(t *testing.T) {
+	startTime := time.Unix(1727790000, 0)        // 13:40:00 UTC
+	endTime := startTime.Add(2500 * time.Minute) // 2501 klines, 3 requests
+
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "/api/v3/klines", r.URL.Path)
+
+		query := r.URL.Query()
+		assert.Equal(t, "ETHUSDT", query.Get("symbol"))
+		assert.Equal(t, "1m", query.Get("interval"))
+		assert.Equal(t, "1000", query.Get("limit"))
+
+		from, _ := strconv.ParseInt(query.Get("startTime"), 10, 64)
+		to, _ := strconv.ParseInt(query.Get("endTime"), 10, 64)
+		assert.Equal(t, endTime.UnixMilli(), to)
+
+		// One kline per minute, closing at 2000 plus the minutes since startTime
+		var klines []string
+		for openTime := from; openTime <= to && len(klines) < 1000; openTime += time.Minute.Milliseconds() {
+			minutes := (openTime - startTime.UnixMilli()) / time.Minute.Milliseconds()
+			klines = append(klines, fmt.Sprintf(`[%d,"0","0","0","%d.5","0",%d,"0",1,"0","0","0"]`, openTime, 2000+minutes, openTime+59999))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, "[%s]", strings.Join(klines, ","))
+	}))
+	defer server.Close()
+
+	// 15 minute candles still prefetch 1 minute klines
+	client := initializeKlineClient(t, server, "15m")
+
+	points, err := client.GetETHUSDTRange(context.Background(), startTime, endTime)
+	assert.NoError(t, err, "Expected no error from GetETHUSDTRange")
+	assert.Equal(t, 3, requests)
+	assert.Len(t, points, 2501)
+
+	// Every kline is returned once, in order, covering the minute from its open time
+	for i, point := range points {
+		assert.Equal(t, startTime.Add(time.Duration(i)*time.Minute), point.Timestamp)
+		assert.Equal(t, startTime.Add(time.Duration(i+1)*time.Minute), point.Until)
+		assert.Equal(t, float64(2000+i)+0.5, point.ClosePrice)
+		assert.Equal(t, PriceSourceBinance, point.Source)
+	}
+}
+
+// TestKlineClient_Replay reads prices from the Binance responses of the binance_klines cassette, rate limited once.
+// Record it again with CASSETTE_MODE=record.
+func TestKlineClient_Replay(t *testing.T) {
+	cassette := loadCassette(t, "binance_klines")
+
+	client, err := NewKlineClient(KlineConfig{Retry: fastRetryPolicy})
+	assert.NoError(t, err)
+	client.UseTransport(cassette)
+
+	// The 15 minute candle opened at 13:30 UTC, answered once the rate limited attempt is retried
+	kline, err := client.GetETHUSDT(context.Background(), time.Unix(1727790030, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, 2611.45, kline.ClosePrice)
+	assert.Equal(t, "2611.45000000", kline.ClosePriceDecimal)
+
+	// 1 minute klines of 13:40 to 13:42 UTC
+	startTime := time.Unix(1727790000, 0)
+	points, err := client.GetETHUSDTRange(context.Background(), startTime, startTime.Add(2*time.Minute))
+	assert.NoError(t, err)
+	if assert.Len(t, points, 3) {
+		assert.Equal(t, startTime, points[0].Timestamp)
+		assert.Equal(t, "2612.34000000", points[0].ClosePriceDecimal)
+		assert.Equal(t, "2613.10000000", points[1].ClosePriceDecimal)
+		assert.Equal(t, "2611.87000000", points[2].ClosePriceDecimal)
+	}
+}
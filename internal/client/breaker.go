@@ -0,0 +1,238 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/winQe/uniswap-fee-tracker/internal/utils"
+)
+
+// States of a circuit breaker
+const (
+	BreakerClosed   = "closed"    // Requests go through
+	BreakerOpen     = "open"      // Requests fail fast until the open duration elapses
+	BreakerHalfOpen = "half_open" // A few probes go through, their outcome closes or reopens the breaker
+)
+
+// halfOpenRetryDelay is how long callers turned away while probes are in flight wait before trying again
+const halfOpenRetryDelay = time.Second
+
+// BreakerConfig controls when a circuit breaker trips and how long it stays open
+type BreakerConfig struct {
+	FailureThreshold int           // Consecutive failed requests tripping the breaker
+	OpenDuration     time.Duration // Time requests fail fast before the breaker is probed
+	HalfOpenProbes   int           // Concurrent probes let through while half open
+}
+
+// DefaultBreakerConfig trips after 5 failed requests in a row and probes the upstream every 30 seconds
+var DefaultBreakerConfig = BreakerConfig{
+	FailureThreshold: 5,
+	OpenDuration:     30 * time.Second,
+	HalfOpenProbes:   1,
+}
+
+// NewBreakerConfig reads the circuit breaker config of the BREAKER_* config, unset fields fall back to DefaultBreakerConfig
+func NewBreakerConfig(config utils.Config) BreakerConfig {
+	return BreakerConfig{
+		FailureThreshold: config.BreakerFailureThreshold,
+		OpenDuration:     config.BreakerOpenDuration,
+	}.withDefaults()
+}
+
+// withDefaults fills the unset fields of the config from DefaultBreakerConfig
+func (c BreakerConfig) withDefaults() BreakerConfig {
+	if c.FailureThreshold <= 0 {
+		c.FailureThreshold = DefaultBreakerConfig.FailureThreshold
+	}
+	if c.OpenDuration <= 0 {
+		c.OpenDuration = DefaultBreakerConfig.OpenDuration
+	}
+	if c.HalfOpenProbes <= 0 {
+		c.HalfOpenProbes = DefaultBreakerConfig.HalfOpenProbes
+	}
+	return c
+}
+
+// BreakerState is a snapshot of a circuit breaker
+type BreakerState struct {
+	Name                string
+	State               string
+	ConsecutiveFailures int
+	LastError           string
+	OpenedAt            time.Time // Zero while closed
+	RetryAt             time.Time // When an open breaker lets probes through, zero while closed
+}
+
+// CircuitBreaker stops sending requests to an upstream failing repeatedly.
+// Once FailureThreshold requests failed in a row it opens and fails every request fast with a CircuitOpenError.
+// After OpenDuration it lets a few probes through, closing again on success and reopening on failure.
+type CircuitBreaker struct {
+	name   string
+	config BreakerConfig
+	now    func() time.Time
+
+	mu        sync.Mutex
+	state     string
+	failures  int
+	lastError string
+	openedAt  time.Time
+	retryAt   time.Time
+	probes    int // Probes in flight while half open
+}
+
+// breakers holds every circuit breaker by name, so that their states can be read without a reference to the clients
+var breakers = struct {
+	sync.Mutex
+	byName map[string]*CircuitBreaker
+}{byName: make(map[string]*CircuitBreaker)}
+
+// NewCircuitBreaker initializes a closed breaker and registers it under the name, replacing any breaker of the same name
+func NewCircuitBreaker(name string, config BreakerConfig) *CircuitBreaker {
+	breaker := &CircuitBreaker{
+		name:   name,
+		config: config.withDefaults(),
+		now:    time.Now,
+		state:  BreakerClosed,
+	}
+
+	breakers.Lock()
+	defer breakers.Unlock()
+	breakers.byName[name] = breaker
+	return breaker
+}
+
+// BreakerStates returns the state of every circuit breaker registered by the process, sorted by name
+func BreakerStates() []BreakerState {
+	breakers.Lock()
+	registered := make([]*CircuitBreaker, 0, len(breakers.byName))
+	for _, breaker := range breakers.byName {
+		registered = append(registered, breaker)
+	}
+	breakers.Unlock()
+
+	states := make([]BreakerState, 0, len(registered))
+	for _, breaker := range registered {
+		states = append(states, breaker.State())
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+	return states
+}
+
+// State returns a snapshot of the breaker
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state := BreakerState{
+		Name:                b.name,
+		State:               b.state,
+		ConsecutiveFailures: b.failures,
+		LastError:           b.lastError,
+	}
+	if b.state != BreakerClosed {
+		state.OpenedAt = b.openedAt
+		state.RetryAt = b.retryAt
+	}
+	return state
+}
+
+// allow reports whether a request may be sent, failing with a CircuitOpenError while the breaker is open.
+// An open breaker past its open duration turns half open and lets the request through as a probe.
+func (b *CircuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	if b.state == BreakerOpen && !now.Before(b.retryAt) {
+		b.state = BreakerHalfOpen
+		b.probes = 0
+		log.Printf("Circuit breaker %s is half open, probing.", b.name)
+	}
+
+	switch b.state {
+	case BreakerOpen:
+		return &CircuitOpenError{Name: b.name, RetryAt: b.retryAt}
+	case BreakerHalfOpen:
+		if b.probes >= b.config.HalfOpenProbes {
+			return &CircuitOpenError{Name: b.name, RetryAt: now.Add(halfOpenRetryDelay)}
+		}
+		b.probes++
+	}
+	return nil
+}
+
+// record counts the outcome of a request let through by allow.
+// Only upstream failures count, requests abandoned by their caller just free their probe.
+func (b *CircuitBreaker) record(ctx context.Context, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerHalfOpen && b.probes > 0 {
+		b.probes--
+	}
+	if err != nil && (ctx.Err() != nil || !isUpstreamFailure(err)) {
+		return
+	}
+
+	if err == nil {
+		if b.state != BreakerClosed {
+			log.Printf("Circuit breaker %s is closed again.", b.name)
+		}
+		b.state = BreakerClosed
+		b.failures = 0
+		b.lastError = ""
+		return
+	}
+
+	b.failures++
+	b.lastError = failureReason(err)
+	if b.state == BreakerOpen {
+		// A request sent before the breaker opened
+		return
+	}
+	if b.state == BreakerHalfOpen || b.failures >= b.config.FailureThreshold {
+		now := b.now()
+		if b.state == BreakerClosed {
+			b.openedAt = now
+		}
+		b.state = BreakerOpen
+		b.retryAt = now.Add(b.config.OpenDuration)
+		log.Printf("Circuit breaker %s is open until %v after %d failures: %s", b.name, b.retryAt.Format(time.RFC3339), b.failures, b.lastError)
+	}
+}
+
+// isUpstreamFailure reports whether the error means the upstream is unavailable, e.g. 5xx responses,
+// timeouts or persistent rate limits, as opposed to requests the upstream answered
+func isUpstreamFailure(err error) bool {
+	var rateLimitedErr *RateLimitedError
+	var upstreamErr *UpstreamError
+	return errors.As(err, &rateLimitedErr) || errors.As(err, &upstreamErr)
+}
+
+// failureReason describes an upstream failure without the request URL, whose query can carry API keys.
+// http.Client wraps the failures of the transport in a url.Error quoting the URL, only the typed error is kept.
+func failureReason(err error) string {
+	var rateLimitedErr *RateLimitedError
+	if errors.As(err, &rateLimitedErr) {
+		return rateLimitedErr.Error()
+	}
+	var upstreamErr *UpstreamError
+	if !errors.As(err, &upstreamErr) {
+		return "unknown failure"
+	}
+	if upstreamErr.Err == nil {
+		return upstreamErr.Error()
+	}
+
+	cause := upstreamErr.Err
+	var urlErr *url.Error
+	if errors.As(cause, &urlErr) {
+		cause = urlErr.Err
+	}
+	return (&UpstreamError{Source: upstreamErr.Source, Err: cause}).Error()
+}
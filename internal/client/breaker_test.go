@@ -0,0 +1,103 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Unix(1727790000, 0)
+	breaker := NewCircuitBreaker("test:breaker", BreakerConfig{FailureThreshold: 3, OpenDuration: 30 * time.Second})
+	breaker.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	upstreamErr := &UpstreamError{Source: "test", StatusCode: http.StatusBadGateway}
+
+	// Answered requests and failures below the threshold leave it closed
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, breaker.allow())
+		breaker.record(ctx, upstreamErr)
+	}
+	assert.NoError(t, breaker.allow())
+	breaker.record(ctx, &NotFoundError{Source: "test", Resource: "transactions"})
+	assert.Equal(t, BreakerClosed, breaker.State().State)
+
+	// Abandoned requests don't count
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.NoError(t, breaker.allow())
+	breaker.record(canceled, &UpstreamError{Source: "test", Err: context.Canceled})
+	assert.Equal(t, 2, breaker.State().ConsecutiveFailures)
+
+	// The third failure in a row trips it
+	assert.NoError(t, breaker.allow())
+	breaker.record(ctx, upstreamErr)
+	state := breaker.State()
+	assert.Equal(t, BreakerOpen, state.State)
+	assert.Equal(t, now, state.OpenedAt)
+	assert.Equal(t, now.Add(30*time.Second), state.RetryAt)
+
+	var circuitOpenErr *CircuitOpenError
+	assert.ErrorAs(t, breaker.allow(), &circuitOpenErr)
+	assert.Equal(t, now.Add(30*time.Second), circuitOpenErr.RetryAt)
+
+	// Once the open duration elapsed a single probe goes through, a failed probe reopens it
+	now = now.Add(30 * time.Second)
+	assert.NoError(t, breaker.allow())
+	assert.Equal(t, BreakerHalfOpen, breaker.State().State)
+	assert.ErrorAs(t, breaker.allow(), &circuitOpenErr)
+	breaker.record(ctx, upstreamErr)
+	assert.Equal(t, BreakerOpen, breaker.State().State)
+	assert.Equal(t, now.Add(30*time.Second), breaker.State().RetryAt)
+
+	// A successful probe closes it
+	now = now.Add(30 * time.Second)
+	assert.NoError(t, breaker.allow())
+	breaker.record(ctx, nil)
+	assert.Equal(t, BreakerState{Name: "test:breaker", State: BreakerClosed}, breaker.State())
+
+	assert.Contains(t, BreakerStates(), breaker.State())
+}
+
+func TestFailureReason(t *testing.T) {
+	// http.Client quotes the URL of failed requests, API key included
+	wrapped := func(err error) error {
+		return fmt.Errorf("error making GET request: %w", &url.Error{Op: "Get", URL: "https://api.etherscan.io/api?apikey=SECRET", Err: err})
+	}
+
+	assert.Equal(t, "etherscan error (HTTP 502): bad gateway", failureReason(wrapped(&UpstreamError{Source: "etherscan", StatusCode: 502, Message: "bad gateway"})))
+	assert.Equal(t, "etherscan rate limited: 429 Too Many Requests", failureReason(wrapped(&RateLimitedError{Source: "etherscan", Reason: "429 Too Many Requests"})))
+	assert.Equal(t, "etherscan request failed: connection refused",
+		failureReason(&UpstreamError{Source: "etherscan", Err: &url.Error{Op: "Get", URL: "https://api.etherscan.io/api?apikey=SECRET", Err: errors.New("connection refused")}}))
+	assert.Equal(t, "unknown failure", failureReason(wrapped(errors.New("boom"))))
+}
+
+func TestRetryTransport_Breaker(t *testing.T) {
+	server, requests := createFlakyServer([]int{500, 500, 500, 500, 500, 500}, "ok")
+	defer server.Close()
+
+	breaker := NewCircuitBreaker("test:transport", BreakerConfig{FailureThreshold: 2, OpenDuration: time.Hour})
+	client := newRetryingHTTPClient("test", fastRetryPolicy, breaker)
+
+	// Two requests exhausting their retries trip the breaker
+	for i := 0; i < 2; i++ {
+		_, err := client.Get(server.URL)
+		var upstreamErr *UpstreamError
+		assert.ErrorAs(t, err, &upstreamErr)
+	}
+	assert.Equal(t, int32(6), requests.Load())
+
+	// Requests then fail fast
+	_, err := client.Get(server.URL)
+	var circuitOpenErr *CircuitOpenError
+	assert.True(t, errors.As(err, &circuitOpenErr))
+	assert.Equal(t, "test:transport", circuitOpenErr.Name)
+	assert.Equal(t, int32(6), requests.Load())
+}
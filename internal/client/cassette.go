@@ -0,0 +1,271 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// CassetteMode selects whether a Cassette records or replays responses
+type CassetteMode string
+
+// Modes of a Cassette
+const (
+	CassetteReplay CassetteMode = "replay" // Requests are answered from the fixture, unmatched requests fail
+	CassetteRecord CassetteMode = "record" // Requests are sent upstream and their responses saved to the fixture
+)
+
+// cassetteModeEnv selects the mode of the cassettes of the tests, replay unless set to record
+const cassetteModeEnv = "CASSETTE_MODE"
+
+// redactedParams are the query parameters replaced in recorded URLs, so that fixtures never hold secrets
+// and replay matches whatever key the client was configured with
+var redactedParams = []string{"apikey"}
+
+// Cassette is an http.RoundTripper recording responses to a fixture file and replaying them.
+// Replayed requests are matched exactly on their method, URL and body, every recorded response is replayed once
+// in the order it was recorded. Plug it under the retries of a client with RateLimitedClient.UseTransport
+// or KlineClient.UseTransport.
+type Cassette struct {
+	path string
+	mode CassetteMode
+	base http.RoundTripper // Sends the requests being recorded
+
+	mu           sync.Mutex
+	interactions []CassetteInteraction
+	used         []bool // Interactions already replayed
+}
+
+// CassetteInteraction is a recorded request along with its response
+type CassetteInteraction struct {
+	Request  CassetteRequest  `json:"request"`
+	Response CassetteResponse `json:"response"`
+}
+
+// CassetteRequest is a recorded request, its URL with the query sorted and secrets redacted
+type CassetteRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+// CassetteResponse is a recorded response. JSON bodies are kept as JSON so that fixtures stay readable.
+type CassetteResponse struct {
+	StatusCode int             `json:"status_code"`
+	Header     http.Header     `json:"header,omitempty"`
+	JSON       json.RawMessage `json:"json,omitempty"`
+	Body       string          `json:"body,omitempty"` // Bodies that aren't JSON
+}
+
+// cassetteFile is the layout of a fixture file
+type cassetteFile struct {
+	Interactions []CassetteInteraction `json:"interactions"`
+}
+
+// CassetteMissError is returned when a replayed request was not recorded.
+// It is not retried, the fixture has to be recorded again.
+type CassetteMissError struct {
+	Path   string
+	Method string
+	URL    string
+}
+
+func (e *CassetteMissError) Error() string {
+	return fmt.Sprintf("cassette %s has no response left for %s %s", e.Path, e.Method, e.URL)
+}
+
+// NewCassette initializes a cassette of the fixture at path.
+// Replay loads the fixture, record starts empty and overwrites it on Save.
+func NewCassette(path string, mode CassetteMode) (*Cassette, error) {
+	cassette := &Cassette{
+		path: path,
+		mode: mode,
+		base: http.DefaultTransport,
+	}
+
+	switch mode {
+	case CassetteRecord:
+		return cassette, nil
+	case CassetteReplay:
+		interactions, err := ReadCassette(path)
+		if err != nil {
+			return nil, err
+		}
+		cassette.interactions = interactions
+		cassette.used = make([]bool, len(interactions))
+		return cassette, nil
+	default:
+		return nil, fmt.Errorf("unknown cassette mode %q", mode)
+	}
+}
+
+// ReadCassette reads the interactions recorded in the fixture at path
+func ReadCassette(path string) ([]CassetteInteraction, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading cassette: %v", err)
+	}
+	var file cassetteFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("error parsing cassette %s: %v", path, err)
+	}
+	return file.Interactions, nil
+}
+
+// CassetteModeFromEnv returns the cassette mode set by the CASSETTE_MODE environment variable, replay by default.
+// Run the tests with CASSETTE_MODE=record and network access to record their fixtures again.
+func CassetteModeFromEnv() CassetteMode {
+	if CassetteMode(os.Getenv(cassetteModeEnv)) == CassetteRecord {
+		return CassetteRecord
+	}
+	return CassetteReplay
+}
+
+// Mode returns whether the cassette records or replays
+func (c *Cassette) Mode() CassetteMode {
+	return c.mode
+}
+
+// RoundTrip answers the request from the fixture, or sends it upstream and records the response
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	recorded := CassetteRequest{
+		Method: req.Method,
+		URL:    RedactURL(req.URL),
+		Body:   string(body),
+	}
+
+	if c.mode == CassetteRecord {
+		return c.record(req, recorded)
+	}
+	return c.replay(req, recorded)
+}
+
+// replay answers the request with the first response recorded for it that was not replayed yet
+func (c *Cassette) replay(req *http.Request, recorded CassetteRequest) (*http.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, interaction := range c.interactions {
+		if c.used[i] || interaction.Request != recorded {
+			continue
+		}
+		c.used[i] = true
+		return interaction.Response.toHTTP(req), nil
+	}
+	return nil, &CassetteMissError{Path: c.path, Method: recorded.Method, URL: recorded.URL}
+}
+
+// record sends the request upstream and records its response
+func (c *Cassette) record(req *http.Request, recorded CassetteRequest) (*http.Response, error) {
+	resp, err := c.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	header := resp.Header.Clone()
+	header.Del("Set-Cookie")
+	header.Del("Content-Length")
+	response := CassetteResponse{StatusCode: resp.StatusCode, Header: header}
+	if len(body) > 0 && json.Valid(body) {
+		response.JSON = json.RawMessage(body)
+	} else {
+		response.Body = string(body)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interactions = append(c.interactions, CassetteInteraction{Request: recorded, Response: response})
+	return resp, nil
+}
+
+// Save writes the recorded interactions to the fixture, a no-op while replaying
+func (c *Cassette) Save() error {
+	if c.mode != CassetteRecord {
+		return nil
+	}
+
+	// URLs stay readable without escaping their ampersands
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+
+	c.mu.Lock()
+	err := encoder.Encode(cassetteFile{Interactions: c.interactions})
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("error serializing cassette: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return fmt.Errorf("error creating cassette directory: %v", err)
+	}
+	return os.WriteFile(c.path, data.Bytes(), 0o644)
+}
+
+// toHTTP builds the response to the replayed request
+func (r CassetteResponse) toHTTP(req *http.Request) *http.Response {
+	body := []byte(r.Body)
+	if len(r.JSON) > 0 {
+		body = r.JSON
+	}
+	header := r.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// readRequestBody reads the body of the request and restores it, so that it can still be sent
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("error reading request body: %v", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// RedactURL returns the URL as recorded by a cassette, with its query sorted and the values of secret parameters replaced
+func RedactURL(u *url.URL) string {
+	redacted := *u
+	query := redacted.Query()
+	for _, param := range redactedParams {
+		if query.Has(param) {
+			query.Set(param, "REDACTED")
+		}
+	}
+	redacted.RawQuery = query.Encode()
+	return redacted.String()
+}
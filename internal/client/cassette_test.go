@@ -0,0 +1,134 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// loadCassette opens the fixture testdata/cassettes/<name>.json in the mode of CASSETTE_MODE, saving it once the test is over
+func loadCassette(t *testing.T, name string) *Cassette {
+	cassette, err := NewCassette(filepath.Join("testdata", "cassettes", name+".json"), CassetteModeFromEnv())
+	if err != nil {
+		t.Fatalf("error loading cassette: %v", err)
+	}
+	t.Cleanup(func() {
+		assert.NoError(t, cassette.Save())
+	})
+	return cassette
+}
+
+// readBody reads the whole response body
+func readBody(t *testing.T, resp *http.Response) string {
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	return string(body)
+}
+
+func TestCassette_RecordAndReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			body, _ := io.ReadAll(r.Body)
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, `{"echo":`+string(body)+`}`)
+			return
+		}
+		io.WriteString(w, "block "+r.URL.Query().Get("block"))
+	}))
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	// Record against the server
+	recorder, err := NewCassette(path, CassetteRecord)
+	assert.NoError(t, err)
+	client := NewRateLimitedClient("test", fastRetryPolicy)
+	client.UseTransport(recorder)
+
+	resp, err := client.get(context.Background(), server.URL+"/api?block=1&apikey=secret")
+	assert.NoError(t, err)
+	assert.Equal(t, "block 1", readBody(t, resp))
+	resp, err = client.post(context.Background(), server.URL+"/rpc", "application/json", strings.NewReader(`{"id":1}`))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"echo":{"id":1}}`, readBody(t, resp))
+	assert.NoError(t, recorder.Save())
+	server.Close()
+
+	// Keys are redacted and JSON bodies kept as JSON
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "secret")
+	assert.Contains(t, string(data), "apikey=REDACTED&block=1")
+	assert.Contains(t, string(data), `"echo": {`)
+
+	// Replay without the server, whatever the key
+	player, err := NewCassette(path, CassetteReplay)
+	assert.NoError(t, err)
+	client = NewRateLimitedClient("test", fastRetryPolicy)
+	client.UseTransport(player)
+
+	resp, err = client.get(context.Background(), server.URL+"/api?apikey=other&block=1")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "block 1", readBody(t, resp))
+	resp, err = client.post(context.Background(), server.URL+"/rpc", "application/json", strings.NewReader(`{"id":1}`))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"echo":{"id":1}}`, readBody(t, resp))
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	// Every response is replayed once, and only to the exact request
+	var missErr *CassetteMissError
+	_, err = client.get(context.Background(), server.URL+"/api?block=1&apikey=other")
+	assert.ErrorAs(t, err, &missErr)
+	_, err = client.post(context.Background(), server.URL+"/rpc", "application/json", strings.NewReader(`{"id":2}`))
+	assert.ErrorAs(t, err, &missErr)
+	var upstreamErr *UpstreamError
+	assert.False(t, errors.As(err, &upstreamErr), "misses are not retried as upstream failures")
+}
+
+func TestCassette_ReplaysInRecordedOrder(t *testing.T) {
+	server, requests := createFlakyServer([]int{http.StatusServiceUnavailable}, "ok")
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	recorder, err := NewCassette(path, CassetteRecord)
+	assert.NoError(t, err)
+	client := NewRateLimitedClient("test", fastRetryPolicy)
+	client.UseTransport(recorder)
+
+	resp, err := client.get(context.Background(), server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", readBody(t, resp))
+	assert.NoError(t, recorder.Save())
+	server.Close()
+	assert.Equal(t, int32(2), requests.Load())
+
+	// The failed attempt is replayed first and retried like it was upstream
+	player, err := NewCassette(path, CassetteReplay)
+	assert.NoError(t, err)
+	client = NewRateLimitedClient("test", fastRetryPolicy)
+	client.UseTransport(player)
+
+	resp, err = client.get(context.Background(), server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", readBody(t, resp))
+}
+
+func TestNewCassette(t *testing.T) {
+	_, err := NewCassette(filepath.Join(t.TempDir(), "missing.json"), CassetteReplay)
+	assert.Error(t, err)
+
+	_, err = NewCassette(filepath.Join(t.TempDir(), "cassette.json"), "rewind")
+	assert.Error(t, err)
+
+	t.Setenv(cassetteModeEnv, "record")
+	assert.Equal(t, CassetteRecord, CassetteModeFromEnv())
+	t.Setenv(cassetteModeEnv, "")
+	assert.Equal(t, CassetteReplay, CassetteModeFromEnv())
+}
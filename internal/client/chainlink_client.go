@@ -0,0 +1,273 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Function selectors of the Chainlink aggregator views read by ChainlinkClient
+const (
+	latestRoundDataSelector = "0xfeaf968c" // latestRoundData()
+	getRoundDataSelector    = "0x9a6fc8f5" // getRoundData(uint80)
+)
+
+// PriceSourceChainlink prefixes the source of prices read from a Chainlink aggregator
+const PriceSourceChainlink = "chainlink"
+
+// phaseOffset is the bit offset of the phase ID in the round IDs of an aggregator proxy.
+// A proxy round ID is phaseID << 64 | aggregatorRoundID, round IDs restart at 1 in every phase.
+const phaseOffset = 64
+
+// errRoundNotFound is returned when the aggregator has no data for a round ID
+var errRoundNotFound = errors.New("round not found")
+
+// ChainlinkClient reads the ETH/USD price from a Chainlink aggregator proxy.
+// The price at a timestamp is the answer of the last round updated at or before it.
+type ChainlinkClient struct {
+	*jsonRPCClient
+	aggregatorAddress string
+
+	// Answer decimals, resolved on first use
+	mu               sync.Mutex
+	decimalsResolved bool
+	decimals         int
+}
+
+// roundData holds the fields of a round returned by latestRoundData and getRoundData
+type roundData struct {
+	RoundID   *big.Int
+	Answer    *big.Int
+	UpdatedAt uint64
+}
+
+// NewChainlinkClient initializes a price client reading the aggregator proxy through the JSON-RPC node at rpcURL.
+func NewChainlinkClient(rpcURL string, aggregatorAddress string) *ChainlinkClient {
+	// 10 requests per second fits the free tier of most hosted node providers
+	secondLimiter := rate.NewLimiter(10, 10)
+
+	return &ChainlinkClient{
+		jsonRPCClient:     newJSONRPCClient(rpcURL, secondLimiter),
+		aggregatorAddress: strings.ToLower(aggregatorAddress),
+	}
+}
+
+// GetETHUSDT returns the answer in effect at the timestamp.
+// It binary searches the round IDs of the current phase, then of the previous phases for older timestamps.
+func (c *ChainlinkClient) GetETHUSDT(ctx context.Context, timestamp time.Time) (*KlineData, error) {
+	if timestamp.IsZero() {
+		return nil, fmt.Errorf("timestamp is invalid")
+	}
+
+	if err := c.resolveDecimals(ctx); err != nil {
+		return nil, err
+	}
+
+	latest, err := c.latestRoundData(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the latest round: %v", err)
+	}
+
+	target := uint64(timestamp.Unix())
+	if latest.UpdatedAt <= target {
+		return c.toKlineData(latest), nil
+	}
+
+	phaseID, lastRound := splitRoundID(latest.RoundID)
+	for ; phaseID > 0; phaseID-- {
+		// Only the last round of the current phase is known, older phases are probed
+		if lastRound == 0 {
+			lastRound, err = c.findLastRound(ctx, phaseID)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		round, err := c.findRoundAt(ctx, phaseID, lastRound, target)
+		if err != nil {
+			return nil, err
+		}
+		if round != nil {
+			return c.toKlineData(round), nil
+		}
+		lastRound = 0
+	}
+
+	return nil, fmt.Errorf("no Chainlink round at or before %v", timestamp)
+}
+
+// Resolution is zero, every round answer holds until the next round
+func (c *ChainlinkClient) Resolution() time.Duration {
+	return 0
+}
+
+// findRoundAt binary searches the rounds [1, lastRound] of the phase for the last one updated at or before target.
+// It returns nil when the first round of the phase is already after target.
+func (c *ChainlinkClient) findRoundAt(ctx context.Context, phaseID uint64, lastRound uint64, target uint64) (*roundData, error) {
+	var found *roundData
+	low, high := uint64(1), lastRound
+	for low <= high {
+		mid := low + (high-low)/2
+		round, err := c.getRoundData(ctx, phaseID, mid)
+		if err != nil {
+			return nil, fmt.Errorf("error reading round %d of phase %d: %v", mid, phaseID, err)
+		}
+
+		if round.UpdatedAt <= target {
+			found = round
+			low = mid + 1
+		} else {
+			high = mid - 1
+		}
+	}
+	return found, nil
+}
+
+// findLastRound finds the last round of a past phase by doubling the round ID until a round is missing,
+// then binary searching between the last existing and the first missing round.
+func (c *ChainlinkClient) findLastRound(ctx context.Context, phaseID uint64) (uint64, error) {
+	exists := func(aggregatorRound uint64) (bool, error) {
+		_, err := c.getRoundData(ctx, phaseID, aggregatorRound)
+		if errors.Is(err, errRoundNotFound) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("error reading round %d of phase %d: %v", aggregatorRound, phaseID, err)
+		}
+		return true, nil
+	}
+
+	found, err := exists(1)
+	if err != nil || !found {
+		return 0, err
+	}
+
+	low, high := uint64(1), uint64(2)
+	for {
+		found, err := exists(high)
+		if err != nil {
+			return 0, err
+		}
+		if !found {
+			break
+		}
+		low, high = high, high*2
+	}
+
+	// low exists and high is missing
+	for high-low > 1 {
+		mid := low + (high-low)/2
+		found, err := exists(mid)
+		if err != nil {
+			return 0, err
+		}
+		if found {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return low, nil
+}
+
+// latestRoundData reads the latest round of the aggregator
+func (c *ChainlinkClient) latestRoundData(ctx context.Context) (*roundData, error) {
+	output, err := c.ethCall(ctx, c.aggregatorAddress, latestRoundDataSelector, latestBlockTag)
+	if err != nil {
+		return nil, err
+	}
+	return decodeRoundData(output)
+}
+
+// getRoundData reads a round of the phase, errRoundNotFound when the aggregator has no data for it
+func (c *ChainlinkClient) getRoundData(ctx context.Context, phaseID uint64, aggregatorRound uint64) (*roundData, error) {
+	roundID := new(big.Int).Lsh(new(big.Int).SetUint64(phaseID), phaseOffset)
+	roundID.Or(roundID, new(big.Int).SetUint64(aggregatorRound))
+
+	data := getRoundDataSelector + fmt.Sprintf("%064x", roundID)
+	output, err := c.ethCall(ctx, c.aggregatorAddress, data, latestBlockTag)
+	if err != nil {
+		// Aggregators revert on rounds they have no data for, other failures of the node are not a missing round
+		if isExecutionReverted(err) {
+			return nil, errRoundNotFound
+		}
+		return nil, err
+	}
+
+	round, err := decodeRoundData(output)
+	if err != nil {
+		return nil, err
+	}
+	// Older aggregators return an empty round instead of reverting
+	if round.UpdatedAt == 0 {
+		return nil, errRoundNotFound
+	}
+	return round, nil
+}
+
+// resolveDecimals reads the decimals of the aggregator answer.
+// They never change, so they are only read until a read succeeds.
+func (c *ChainlinkClient) resolveDecimals(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.decimalsResolved {
+		return nil
+	}
+
+	output, err := c.ethCall(ctx, c.aggregatorAddress, decimalsSelector, latestBlockTag)
+	if err != nil {
+		return fmt.Errorf("error reading decimals of aggregator %s: %v", c.aggregatorAddress, err)
+	}
+	if len(output) < 32 {
+		return fmt.Errorf("unexpected decimals output length %d", len(output))
+	}
+
+	c.decimals = int(new(big.Int).SetBytes(output[0:32]).Int64())
+	c.decimalsResolved = true
+	return nil
+}
+
+// toKlineData scales the round answer by the aggregator decimals
+func (c *ChainlinkClient) toKlineData(round *roundData) *KlineData {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(c.decimals)), nil)
+	answer := new(big.Rat).SetFrac(round.Answer, scale)
+	price, _ := answer.Float64()
+
+	return &KlineData{
+		ClosePrice:        price,
+		ClosePriceDecimal: answer.FloatString(c.decimals),
+		Source:            PriceSourceChainlink + ":" + c.aggregatorAddress,
+	}
+}
+
+// decodeRoundData decodes the (roundId, answer, startedAt, updatedAt, answeredInRound) output of a round view
+func decodeRoundData(output []byte) (*roundData, error) {
+	if len(output) < 5*32 {
+		return nil, fmt.Errorf("unexpected round data output length %d", len(output))
+	}
+
+	updatedAt := new(big.Int).SetBytes(output[96:128])
+	if !updatedAt.IsUint64() {
+		return nil, fmt.Errorf("invalid round update time %s", updatedAt)
+	}
+
+	return &roundData{
+		RoundID:   new(big.Int).SetBytes(output[0:32]),
+		Answer:    decodeInt256(output[32:64]),
+		UpdatedAt: updatedAt.Uint64(),
+	}, nil
+}
+
+// splitRoundID splits a proxy round ID into its phase ID and aggregator round ID
+func splitRoundID(roundID *big.Int) (uint64, uint64) {
+	phaseID := new(big.Int).Rsh(roundID, phaseOffset).Uint64()
+	aggregatorRound := new(big.Int).And(roundID, new(big.Int).SetUint64(^uint64(0))).Uint64()
+	return phaseID, aggregatorRound
+}
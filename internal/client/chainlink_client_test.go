@@ -0,0 +1,149 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const ethUSDAggregator = "0x5f4ec3df9cbd43714fe2740f5e3616155c5b8419"
+
+// stubPhase is an aggregator phase whose round i is updated at start + i hours with an answer of base + i dollars
+type stubPhase struct {
+	rounds int
+	start  uint64
+	base   int64
+}
+
+// roundWords encodes the round data output of a round view
+func roundWords(phaseID uint64, round uint64, phase stubPhase) string {
+	roundID := new(big.Int).Lsh(new(big.Int).SetUint64(phaseID), phaseOffset)
+	roundID.Or(roundID, new(big.Int).SetUint64(round))
+	answer := big.NewInt((phase.base + int64(round)) * 1e8)
+	updatedAt := phase.start + round*3600
+
+	return fmt.Sprintf("0x%064x%064x%064x%064x%064x", roundID, answer, updatedAt, updatedAt, roundID)
+}
+
+// initializeChainlinkClient sets up a ChainlinkClient against a stub aggregator holding the given phases, the last one being current.
+func initializeChainlinkClient(t *testing.T, phases map[uint64]stubPhase, currentPhase uint64) (*ChainlinkClient, func()) {
+	stub := createRPCStub(t, map[string]rpcHandler{
+		"eth_call": func(params []json.RawMessage) interface{} {
+			var call map[string]string
+			json.Unmarshal(params[0], &call)
+			assert.Equal(t, ethUSDAggregator, call["to"])
+
+			switch {
+			case call["data"] == decimalsSelector:
+				return "0x" + word("8")
+			case call["data"] == latestRoundDataSelector:
+				phase := phases[currentPhase]
+				return roundWords(currentPhase, uint64(phase.rounds), phase)
+			case strings.HasPrefix(call["data"], getRoundDataSelector):
+				roundID, _ := new(big.Int).SetString(call["data"][len(getRoundDataSelector):], 16)
+				phaseID, round := splitRoundID(roundID)
+
+				phase, ok := phases[phaseID]
+				if !ok || round == 0 || round > uint64(phase.rounds) {
+					return &rpcError{Code: 3, Message: "execution reverted: No data present"}
+				}
+				return roundWords(phaseID, round, phase)
+			}
+			t.Errorf("unexpected eth_call %v", call)
+			return nil
+		},
+	})
+
+	client := &ChainlinkClient{
+		jsonRPCClient:     initializeJSONRPCClient(stub),
+		aggregatorAddress: ethUSDAggregator,
+	}
+	return client, stub.Close
+}
+
+func TestChainlinkGetETHUSDT(t *testing.T) {
+	phases := map[uint64]stubPhase{
+		1: {rounds: 5, start: 1727000000, base: 1000},
+		2: {rounds: 10, start: 1727100000, base: 2000},
+	}
+	client, closeStub := initializeChainlinkClient(t, phases, 2)
+	defer closeStub()
+
+	testCases := []struct {
+		name      string
+		timestamp time.Time
+		expected  float64
+	}{
+		{"after the latest round", time.Unix(1727100000+20*3600, 0), 2010},
+		{"at a round update", time.Unix(1727100000+4*3600, 0), 2004},
+		{"between two rounds", time.Unix(1727100000+7*3600+1800, 0), 2007},
+		{"before the current phase", time.Unix(1727000000+3*3600+60, 0), 1003},
+		{"between two phases", time.Unix(1727050000, 0), 1005},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			kline, err := client.GetETHUSDT(context.Background(), tc.timestamp)
+			assert.NoError(t, err, "Expected no error from GetETHUSDT")
+			assert.Equal(t, tc.expected, kline.ClosePrice)
+			assert.Equal(t, "chainlink:"+ethUSDAggregator, kline.Source)
+		})
+	}
+}
+
+func TestChainlinkGetETHUSDT_BeforeFirstRound(t *testing.T) {
+	phases := map[uint64]stubPhase{
+		1: {rounds: 5, start: 1727000000, base: 1000},
+	}
+	client, closeStub := initializeChainlinkClient(t, phases, 1)
+	defer closeStub()
+
+	_, err := client.GetETHUSDT(context.Background(), time.Unix(1726000000, 0))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no Chainlink round")
+}
+
+func TestChainlinkGetRoundData_Errors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      *rpcError
+		notFound bool
+	}{
+		{"reverted", &rpcError{Code: 3, Message: "execution reverted: No data present"}, true},
+		{"reverted without revert data", &rpcError{Code: -32000, Message: "execution reverted"}, true},
+		{"rate limited", &rpcError{Code: -32005, Message: "limit exceeded"}, false},
+		{"out of capacity", &rpcError{Code: -32000, Message: "request failed, provider is over capacity"}, false},
+		{"block not found", &rpcError{Code: -32000, Message: "header not found"}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stub := createRPCStub(t, map[string]rpcHandler{
+				"eth_call": func(params []json.RawMessage) interface{} {
+					return tc.err
+				},
+			})
+			defer stub.Close()
+
+			client := &ChainlinkClient{
+				jsonRPCClient:     initializeJSONRPCClient(stub),
+				aggregatorAddress: ethUSDAggregator,
+			}
+			_, err := client.getRoundData(context.Background(), 1, 1)
+
+			// Only reverts mean the round is missing, the other failures are passed through
+			assert.Error(t, err)
+			assert.Equal(t, tc.notFound, errors.Is(err, errRoundNotFound))
+			if !tc.notFound {
+				assert.ErrorContains(t, err, tc.err.Message)
+			}
+		})
+	}
+}
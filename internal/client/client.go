@@ -2,25 +2,125 @@
 package client
 
 import (
+	"context"
+	"fmt"
+	"math/big"
 	"time"
 
 	"github.com/winQe/uniswap-fee-tracker/internal/types"
+	"github.com/winQe/uniswap-fee-tracker/internal/utils"
 )
 
 // KlineData is the return type of PriceClient GetETHUSDT
 type KlineData struct {
-	ClosePrice float64
+	ClosePrice        float64
+	ClosePriceDecimal string  // ClosePrice as an exact decimal, fees are computed from it
+	Source            string  // Where the price comes from, e.g. binance, pool:<address> or chainlink:<address>
+	Spread            float64 // Relative spread between the sources the price was aggregated from, zero for a single source
+	Suspect           bool    // Whether the sources disagree beyond the divergence threshold
 }
 
 // PriceClient defines the interface for fetching price data. Mostly for dependency injection
 type PriceClient interface {
-	GetETHUSDT(timestamp time.Time) (*KlineData, error)
+	GetETHUSDT(ctx context.Context, timestamp time.Time) (*KlineData, error)
+	// Resolution is the time span a single price covers, zero when prices are exact to the block
+	Resolution() time.Duration
+}
+
+// ExactClosePrice returns the close price as an exact rational.
+// Prices without a decimal fall back to the shortest decimal of the float.
+func (k KlineData) ExactClosePrice() (*big.Rat, error) {
+	if k.ClosePriceDecimal != "" {
+		return utils.ParseDecimal(k.ClosePriceDecimal)
+	}
+	return utils.ParseDecimal(utils.FloatToDecimal(k.ClosePrice))
+}
+
+// PricePoint is a price at a point in time, returned by range fetches
+type PricePoint struct {
+	Timestamp time.Time
+	Until     time.Time // End of the span the price covers from Timestamp, exclusive. Zero when it only holds at Timestamp
+	KlineData
+}
+
+// RangePriceClient is implemented by price clients able to fetch every price of a time range in bulk
+type RangePriceClient interface {
+	PriceClient
+	GetETHUSDTRange(ctx context.Context, startTime time.Time, endTime time.Time) ([]PricePoint, error)
+}
+
+// BlockPriceClient is implemented by price clients reading the price from a chain, able to price a transaction at its block
+type BlockPriceClient interface {
+	PriceClient
+	// GetETHUSDTAtBlock returns the price at the end of the block of the chain, mined at timestamp
+	GetETHUSDTAtBlock(ctx context.Context, chainID int64, blockNumber uint64, timestamp time.Time) (*KlineData, error)
+	// ReadsBlocks reports whether the prices differ between blocks, rather than only depending on their timestamp
+	ReadsBlocks() bool
 }
 
 // TransactionClient defines the interface from fetching transactions data from the client
 type TransactionClient interface {
-	GetTransactionReceipt(hash string) (*types.TransactionData, error)
-	GetLatestTransaction() (*types.TransactionData, error)
-	ListTransactions(offset *int, startBlock *uint64, endBlock *uint64, page *int) ([]types.TransactionData, error)
-	GetBlockNumberByTimestamp(timestamp time.Time, before bool) (uint64, error)
+	GetTransactionReceipt(ctx context.Context, hash string) (*types.TransactionData, error)
+	GetLatestTransaction(ctx context.Context, poolAddress string) (*types.TransactionData, error)
+	ListTransactions(ctx context.Context, poolAddress string, offset *int, startBlock *uint64, endBlock *uint64, page *int) ([]types.TransactionData, error)
+	GetBlockNumberByTimestamp(ctx context.Context, timestamp time.Time, before bool) (uint64, error)
+	// GetBlockNumber returns the chain head, GetBlockHeader the hashes linking a block to its parent
+	GetBlockNumber(ctx context.Context) (uint64, error)
+	GetBlockHeader(ctx context.Context, blockNumber uint64) (*types.BlockHeader, error)
+}
+
+// NewPriceClient creates the PriceClient of the sources listed in the PRICE_SOURCE config.
+// Several sources are combined into a CompositePriceClient.
+func NewPriceClient(config utils.Config) (PriceClient, error) {
+	var clients []PriceClient
+	for _, source := range config.PriceSources {
+		priceClient, err := newSourcePriceClient(config, source)
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, priceClient)
+	}
+
+	if len(clients) == 1 {
+		return clients[0], nil
+	}
+	return NewCompositePriceClient(clients, config.PriceDivergenceThreshold)
+}
+
+// newSourcePriceClient creates the PriceClient of a single price source
+func newSourcePriceClient(config utils.Config, source string) (PriceClient, error) {
+	switch source {
+	case utils.PriceSourceBinance:
+		klineClient, err := NewKlineClient(KlineConfig{
+			Testnet:  config.BinanceTestnet,
+			BaseURL:  config.BinanceBaseURL,
+			Symbol:   config.BinanceSymbol,
+			Interval: config.BinanceInterval,
+			Retry:    NewRetryPolicy(config),
+			Breaker:  NewBreakerConfig(config),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return klineClient, nil
+	case utils.PriceSourcePool:
+		return NewPoolPriceClient(config.PriceRPCURL, config.PricePoolAddress, config.WETHAddress), nil
+	case utils.PriceSourceChainlink:
+		return NewChainlinkClient(config.PriceRPCURL, config.ChainlinkAggregatorAddress), nil
+	default:
+		return nil, fmt.Errorf("unknown price source %q", source)
+	}
+}
+
+// NewTransactionClient creates the TransactionClient of the chain selected by the TRANSACTION_CLIENT config.
+// keyUsage persists the daily usage of the explorer API keys.
+func NewTransactionClient(config utils.Config, chain utils.ChainConfig, keyUsage KeyUsageStore) (TransactionClient, error) {
+	switch config.TransactionClient {
+	case utils.TransactionClientEtherscan:
+		return NewEtherscanClient(chain, keyUsage, NewRetryPolicy(config), NewBreakerConfig(config)), nil
+	case utils.TransactionClientRPC:
+		return NewRPCClient(chain.RPCURL, chain.PoolAddresses()), nil
+	default:
+		return nil, fmt.Errorf("unknown transaction client %q", config.TransactionClient)
+	}
 }
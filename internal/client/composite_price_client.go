@@ -0,0 +1,176 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/winQe/uniswap-fee-tracker/internal/utils"
+)
+
+// CompositePriceClient queries several price sources and returns the median of the ones that answer.
+// A source failing is tolerated as long as another one answers, and prices whose sources disagree
+// by more than the divergence threshold are flagged as suspect.
+// It does not fetch ranges: prefetching the prices of a single source would serve them without the median.
+type CompositePriceClient struct {
+	clients             []PriceClient
+	divergenceThreshold float64 // Relative spread above which a price is suspect, 0.01 = 1%
+}
+
+// sourcePrice is the answer of a single source of the composite client
+type sourcePrice struct {
+	kline *KlineData
+	err   error
+}
+
+// NewCompositePriceClient combines the given price clients
+func NewCompositePriceClient(clients []PriceClient, divergenceThreshold float64) (*CompositePriceClient, error) {
+	if len(clients) == 0 {
+		return nil, fmt.Errorf("composite price client needs at least one source")
+	}
+	if divergenceThreshold <= 0 {
+		return nil, fmt.Errorf("divergence threshold must be positive")
+	}
+
+	return &CompositePriceClient{
+		clients:             clients,
+		divergenceThreshold: divergenceThreshold,
+	}, nil
+}
+
+// GetETHUSDT queries every source concurrently and returns the median of their prices.
+// Source lists the sources the median was taken from, in the configured order.
+func (c *CompositePriceClient) GetETHUSDT(ctx context.Context, timestamp time.Time) (*KlineData, error) {
+	return c.aggregate(timestamp, func(priceClient PriceClient) (*KlineData, error) {
+		return priceClient.GetETHUSDT(ctx, timestamp)
+	})
+}
+
+// GetETHUSDTAtBlock returns the median of the prices of the sources like GetETHUSDT,
+// the sources able to price the block reading it directly.
+func (c *CompositePriceClient) GetETHUSDTAtBlock(ctx context.Context, chainID int64, blockNumber uint64, timestamp time.Time) (*KlineData, error) {
+	return c.aggregate(timestamp, func(priceClient PriceClient) (*KlineData, error) {
+		if blockClient, ok := priceClient.(BlockPriceClient); ok {
+			return blockClient.GetETHUSDTAtBlock(ctx, chainID, blockNumber, timestamp)
+		}
+		return priceClient.GetETHUSDT(ctx, timestamp)
+	})
+}
+
+// ReadsBlocks reports whether any source prices blocks directly
+func (c *CompositePriceClient) ReadsBlocks() bool {
+	for _, priceClient := range c.clients {
+		if blockClient, ok := priceClient.(BlockPriceClient); ok && blockClient.ReadsBlocks() {
+			return true
+		}
+	}
+	return false
+}
+
+// aggregate fetches the price of every source concurrently through fetch and returns their median
+func (c *CompositePriceClient) aggregate(timestamp time.Time, fetch func(priceClient PriceClient) (*KlineData, error)) (*KlineData, error) {
+	answers := make([]sourcePrice, len(c.clients))
+
+	var wg sync.WaitGroup
+	for i, priceClient := range c.clients {
+		wg.Add(1)
+		go func(i int, priceClient PriceClient) {
+			defer wg.Done()
+			kline, err := fetch(priceClient)
+			answers[i] = sourcePrice{kline: kline, err: err}
+		}(i, priceClient)
+	}
+	wg.Wait()
+
+	var prices []float64
+	var exactPrices []*big.Rat
+	var sources []string
+	var errs []error
+	for _, answer := range answers {
+		if answer.err != nil {
+			errs = append(errs, answer.err)
+			continue
+		}
+		exactPrice, err := answer.kline.ExactClosePrice()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		prices = append(prices, answer.kline.ClosePrice)
+		exactPrices = append(exactPrices, exactPrice)
+		sources = append(sources, answer.kline.Source)
+	}
+
+	if len(prices) == 0 {
+		return nil, fmt.Errorf("every price source failed: %w", errors.Join(errs...))
+	}
+	if len(errs) > 0 {
+		// Log the failures and fall back to the remaining sources
+		log.Printf("%d of %d price sources failed at %v: %v", len(errs), len(c.clients), timestamp, errors.Join(errs...))
+	}
+
+	exactPrice := median(exactPrices)
+	price, _ := exactPrice.Float64()
+	spread := relativeSpread(prices, price)
+	suspect := spread > c.divergenceThreshold
+	if suspect {
+		log.Printf("Price sources diverge by %.4f at %v: %v from %v", spread, timestamp, prices, sources)
+	}
+
+	// The mean of the two middle prices has at most one more decimal than them
+	return &KlineData{
+		ClosePrice:        price,
+		ClosePriceDecimal: utils.RatToDecimal(exactPrice, poolPriceDecimals+1),
+		Source:            strings.Join(sources, ","),
+		Spread:            spread,
+		Suspect:           suspect,
+	}, nil
+}
+
+// Resolution is the finest resolution of the sources, so cached prices stay as precise as the most precise source.
+// Sources exact to the block are left out, they would otherwise disable the reuse of cached prices.
+func (c *CompositePriceClient) Resolution() time.Duration {
+	var resolution time.Duration
+	for _, priceClient := range c.clients {
+		if r := priceClient.Resolution(); r > 0 && (resolution == 0 || r < resolution) {
+			resolution = r
+		}
+	}
+	return resolution
+}
+
+// median returns the exact median of the prices, the mean of the two middle ones for an even count
+func median(prices []*big.Rat) *big.Rat {
+	sorted := append([]*big.Rat(nil), prices...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Cmp(sorted[j]) < 0
+	})
+
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		mean := new(big.Rat).Add(sorted[middle-1], sorted[middle])
+		return mean.Quo(mean, big.NewRat(2, 1))
+	}
+	return new(big.Rat).Set(sorted[middle])
+}
+
+// relativeSpread returns the spread between the highest and lowest prices relative to the reference price
+func relativeSpread(prices []float64, reference float64) float64 {
+	if reference == 0 {
+		return 0
+	}
+
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, price := range prices {
+		low = math.Min(low, price)
+		high = math.Max(high, price)
+	}
+	return (high - low) / reference
+}
@@ -0,0 +1,151 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// staticPriceClient answers every timestamp with the same price or error
+type staticPriceClient struct {
+	price      float64
+	source     string
+	err        error
+	resolution time.Duration
+}
+
+func (s *staticPriceClient) GetETHUSDT(ctx context.Context, timestamp time.Time) (*KlineData, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &KlineData{ClosePrice: s.price, Source: s.source}, nil
+}
+
+func (s *staticPriceClient) Resolution() time.Duration {
+	return s.resolution
+}
+
+// staticBlockPriceClient answers every block with the same price and records the blocks it was asked for
+type staticBlockPriceClient struct {
+	staticPriceClient
+	blocks []uint64
+}
+
+func (s *staticBlockPriceClient) GetETHUSDTAtBlock(ctx context.Context, chainID int64, blockNumber uint64, timestamp time.Time) (*KlineData, error) {
+	s.blocks = append(s.blocks, blockNumber)
+	return &KlineData{ClosePrice: s.price, Source: s.source}, nil
+}
+
+func (s *staticBlockPriceClient) ReadsBlocks() bool {
+	return true
+}
+
+func TestCompositeGetETHUSDT(t *testing.T) {
+	timestamp := time.Unix(1727790000, 0)
+	binance := &staticPriceClient{price: 2000, source: "binance", resolution: 15 * time.Minute}
+	pool := &staticPriceClient{price: 2004, source: "pool:0xpool"}
+	chainlink := &staticPriceClient{price: 2002, source: "chainlink:0xfeed"}
+	failing := &staticPriceClient{err: errors.New("no kline data returned")}
+	diverging := &staticPriceClient{price: 2100, source: "pool:0xthin"}
+
+	testCases := []struct {
+		name            string
+		clients         []PriceClient
+		expectedPrice   float64
+		expectedSource  string
+		expectedSpread  float64
+		expectedSuspect bool
+	}{
+		{
+			name:            "median of agreeing sources",
+			clients:         []PriceClient{binance, pool, chainlink},
+			expectedPrice:   2002,
+			expectedSource:  "binance,pool:0xpool,chainlink:0xfeed",
+			expectedSpread:  4.0 / 2002,
+			expectedSuspect: false,
+		},
+		{
+			name:            "failing source falls back to the others",
+			clients:         []PriceClient{failing, binance, pool},
+			expectedPrice:   2002,
+			expectedSource:  "binance,pool:0xpool",
+			expectedSpread:  4.0 / 2002,
+			expectedSuspect: false,
+		},
+		{
+			name:            "diverging sources are suspect",
+			clients:         []PriceClient{binance, diverging},
+			expectedPrice:   2050,
+			expectedSource:  "binance,pool:0xthin",
+			expectedSpread:  100.0 / 2050,
+			expectedSuspect: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			composite, err := NewCompositePriceClient(tc.clients, 0.01)
+			assert.NoError(t, err)
+
+			kline, err := composite.GetETHUSDT(context.Background(), timestamp)
+			assert.NoError(t, err, "Expected no error from GetETHUSDT")
+			assert.Equal(t, tc.expectedPrice, kline.ClosePrice)
+			assert.Equal(t, tc.expectedSource, kline.Source)
+			assert.InDelta(t, tc.expectedSpread, kline.Spread, 1e-12)
+			assert.Equal(t, tc.expectedSuspect, kline.Suspect)
+		})
+	}
+}
+
+func TestCompositeGetETHUSDTAtBlock(t *testing.T) {
+	binance := &staticPriceClient{price: 2000, source: "binance", resolution: 15 * time.Minute}
+	pool := &staticBlockPriceClient{staticPriceClient: staticPriceClient{price: 2004, source: "pool:0xpool", resolution: 12 * time.Second}}
+
+	composite, err := NewCompositePriceClient([]PriceClient{binance, pool}, 0.01)
+	assert.NoError(t, err)
+
+	// The pool prices the block, Binance the timestamp
+	kline, err := composite.GetETHUSDTAtBlock(context.Background(), 1, 20871331, time.Unix(1727790000, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, 2002.0, kline.ClosePrice)
+	assert.Equal(t, "binance,pool:0xpool", kline.Source)
+	assert.Equal(t, []uint64{20871331}, pool.blocks)
+	assert.True(t, composite.ReadsBlocks())
+
+	// Without a source reading the chain, blocks are priced by their timestamp
+	composite, err = NewCompositePriceClient([]PriceClient{binance, &staticPriceClient{price: 2002, source: "chainlink:0xfeed"}}, 0.01)
+	assert.NoError(t, err)
+	assert.False(t, composite.ReadsBlocks())
+}
+
+func TestCompositeGetETHUSDT_EverySourceFails(t *testing.T) {
+	composite, err := NewCompositePriceClient([]PriceClient{
+		&staticPriceClient{err: errors.New("binance down")},
+		&staticPriceClient{err: errors.New("node down")},
+	}, 0.01)
+	assert.NoError(t, err)
+
+	_, err = composite.GetETHUSDT(context.Background(), time.Unix(1727790000, 0))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "binance down")
+	assert.Contains(t, err.Error(), "node down")
+}
+
+func TestCompositeResolution(t *testing.T) {
+	composite, err := NewCompositePriceClient([]PriceClient{
+		&staticPriceClient{resolution: 15 * time.Minute},
+		&staticPriceClient{resolution: 0},
+		&staticPriceClient{resolution: time.Minute},
+	}, 0.01)
+	assert.NoError(t, err)
+
+	// The block exact source does not zero the window cached prices are reused in
+	assert.Equal(t, time.Minute, composite.Resolution())
+
+	exact, err := NewCompositePriceClient([]PriceClient{&staticPriceClient{resolution: 0}}, 0.01)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), exact.Resolution())
+}
@@ -0,0 +1,118 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// errNoResult is returned when a JSON-RPC call answers with a null result, e.g. for unknown hashes
+var errNoResult = errors.New("no result")
+
+// TransactionNotFoundError is returned when a looked up transaction is unknown to the chain
+type TransactionNotFoundError struct {
+	Hash string
+}
+
+func (e *TransactionNotFoundError) Error() string {
+	return fmt.Sprintf("transaction %s not found", e.Hash)
+}
+
+// Unwrap lets callers match unknown transactions as a NotFoundError too
+func (e *TransactionNotFoundError) Unwrap() error {
+	return &NotFoundError{Source: "chain", Resource: "transaction " + e.Hash}
+}
+
+// NotPoolTransactionError is returned when a looked up transaction never touched any of the tracked pools
+type NotPoolTransactionError struct {
+	Hash string
+}
+
+func (e *NotPoolTransactionError) Error() string {
+	return fmt.Sprintf("transaction %s did not touch any tracked pool", e.Hash)
+}
+
+// ResultWindow is the number of results Etherscan serves per query, pages beyond it are refused
+const ResultWindow = 10000
+
+// ResultWindowError is returned when a page lies beyond the result window of the API.
+// The results past the window can only be reached by narrowing the block range of the query.
+type ResultWindowError struct {
+	Page   int
+	Offset int
+}
+
+func (e *ResultWindowError) Error() string {
+	return fmt.Sprintf("page %d of %d results is beyond the %d results window", e.Page, e.Offset, ResultWindow)
+}
+
+// RateLimitedError is returned when the upstream keeps refusing requests over its rate limits
+type RateLimitedError struct {
+	Source     string
+	RetryAfter time.Duration // Delay the upstream asked to wait for, zero when unknown
+	Reason     string
+}
+
+func (e *RateLimitedError) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("%s rate limited: %s", e.Source, e.Reason)
+	}
+	return fmt.Sprintf("%s rate limited", e.Source)
+}
+
+// NotFoundError is returned when the upstream holds no data for the request
+type NotFoundError struct {
+	Source   string
+	Resource string // What was looked up, e.g. transactions or kline
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s: no %s found", e.Source, e.Resource)
+}
+
+// UpstreamError is returned when the upstream can't be reached, times out or answers with an error
+type UpstreamError struct {
+	Source     string
+	StatusCode int    // HTTP status of the response, zero when there was none
+	Message    string // Error reported by the upstream
+	Err        error  // Underlying transport error
+}
+
+func (e *UpstreamError) Error() string {
+	switch {
+	case e.Err != nil:
+		return fmt.Sprintf("%s request failed: %v", e.Source, e.Err)
+	case e.StatusCode != 0:
+		return fmt.Sprintf("%s error (HTTP %d): %s", e.Source, e.StatusCode, e.Message)
+	default:
+		return fmt.Sprintf("%s error: %s", e.Source, e.Message)
+	}
+}
+
+func (e *UpstreamError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeError is returned when a response of the upstream can't be decoded
+type DecodeError struct {
+	Source string
+	Err    error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("error decoding %s response: %v", e.Source, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// CircuitOpenError is returned without sending the request while the circuit breaker of the upstream is open
+type CircuitOpenError struct {
+	Name    string    // Name of the breaker, e.g. etherscan:1 or binance
+	RetryAt time.Time // When the breaker lets requests through again
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker %s is open until %s", e.Name, e.RetryAt.Format(time.RFC3339))
+}
@@ -1,39 +1,44 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"math/big"
+	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/winQe/uniswap-fee-tracker/internal/types"
 	"github.com/winQe/uniswap-fee-tracker/internal/utils"
-	"golang.org/x/time/rate"
 )
 
 // EtherscanClient is the client for interacting with the Etherscan API.
+// Requests are spread across the API keys of its key pool, which applies the rate limits of each key.
 type EtherscanClient struct {
 	*RateLimitedClient
-	baseURL     string
-	apiKey      string
-	poolAddress string
+	baseURL       string
+	keys          *KeyPool
+	breaker       *CircuitBreaker
+	poolAddresses []string
 }
 
-// receiptResponse represents API response for the transaction receipt.
-type receiptResponse struct {
-	ID     int            `json:"id"`
-	Result receiptDetails `json:"result"`
-}
-
-// receiptDetails holds the core details of a transaction.
+// receiptDetails holds the core details of a transaction receipt.
 type receiptDetails struct {
-	BlockNumber       string `json:"blockNumber"`
-	Hash              string `json:"transactionHash"`
-	GasUsed           string `json:"gasUsed"`
-	EffectiveGasPrice string `json:"effectiveGasPrice"`
+	BlockNumber       string       `json:"blockNumber"`
+	Hash              string       `json:"transactionHash"`
+	Status            string       `json:"status"` // 0x1 on success, absent before Byzantium
+	From              string       `json:"from"`
+	To                string       `json:"to"`
+	GasUsed           string       `json:"gasUsed"`
+	EffectiveGasPrice string       `json:"effectiveGasPrice"`
+	Logs              []logDetails `json:"logs"`
 }
 
 // tokenTxResponse represents the API response of tokenTx API call
@@ -55,6 +60,27 @@ type tokenTxDetails struct {
 	GasUsed      string `json:"gasUsed"`
 }
 
+// logsResponse represents the API response of getLogs API call
+type logsResponse struct {
+	Status  string          `json:"status"` // OK = 1
+	Message string          `json:"message"`
+	Result  json.RawMessage `json:"result"`
+}
+
+// proxyResponse represents the API response of the proxy module, a JSON-RPC response or a status error when the call is rejected
+type proxyResponse struct {
+	Status  string          `json:"status"` // Only set on errors, NOTOK = 0
+	Message string          `json:"message"`
+	Result  json.RawMessage `json:"result"`
+	Error   *rpcError       `json:"error"`
+}
+
+// sourceEtherscan names Etherscan in the errors of the client
+const sourceEtherscan = "etherscan"
+
+// logsPageSize is the maximum number of records the getLogs API returns per page
+const logsPageSize = 1000
+
 // blockNumberResponse represents the API response for getting block number by timestamp.
 type blockNumberResponse struct {
 	Status  string `json:"status"`  // "1" indicates success
@@ -62,102 +88,76 @@ type blockNumberResponse struct {
 	Result  string `json:"result"`  // Block number as a string
 }
 
-// NewEtherscanClient initializes Etherscan with Free Plan API Limits
-func NewEtherscanClient(apiKey string, poolAddress string) *EtherscanClient {
-	// 5 API calls per second
-	secondLimiter := rate.NewLimiter(5, 5) // 5 requests per second, burst of 5
-
-	// 100,000 API calls per day = ~1.15 requests per second
-	dailyLimiter := rate.NewLimiter(1.15, 1000) // 1.15 requests per second, burst of 1,000
-
+// NewEtherscanClient initializes a client for the Etherscan-family explorer of the chain.
+// The API limits of the chain apply to each of its keys, usage persists the daily count of every key.
+// The circuit breaker of the chain is registered as etherscan:<chain ID>.
+func NewEtherscanClient(chain utils.ChainConfig, usage KeyUsageStore, retry RetryPolicy, breaker BreakerConfig) *EtherscanClient {
 	return &EtherscanClient{
-		RateLimitedClient: NewRateLimitedClient(secondLimiter, dailyLimiter),
-		baseURL:           "https://api.etherscan.io/api",
-		apiKey:            apiKey,
-		poolAddress:       poolAddress,
+		// The key pool applies the limits, they differ per key
+		RateLimitedClient: NewRateLimitedClient(sourceEtherscan, retry),
+		baseURL:           chain.ExplorerURL,
+		keys:              NewKeyPool(chain.Keys(), chain.RequestsPerSecond, chain.RequestsPerDay, usage),
+		breaker:           NewCircuitBreaker(fmt.Sprintf("%s:%d", sourceEtherscan, chain.ChainID), breaker),
+		poolAddresses:     chain.PoolAddresses(),
 	}
 }
 
-// GetTransactionReceipt fetches the transaction receipt based on the txHash
-// TODO: Needs to verify whether transaction actually belongs in the WETH-USDT Pool
-// TODO: Timestamp from eth_getBlockByNumber
-func (e *EtherscanClient) GetTransactionReceipt(hash string) (*types.TransactionData, error) {
+// GetTransactionReceipt fetches the transaction receipt and its block based on the txHash.
+// Unknown transactions fail with a TransactionNotFoundError,
+// and transactions that did not touch any tracked pool with a NotPoolTransactionError.
+func (e *EtherscanClient) GetTransactionReceipt(ctx context.Context, hash string) (*types.TransactionData, error) {
 	params := url.Values{}
 
-	// https://docs.etherscan.io/api-endpoints/geth-parity-proxy#eth_gettransactionbyhash
-	params.Add("module", "proxy")
-	params.Add("action", "eth_getTransactionByHash")
+	// https://docs.etherscan.io/api-endpoints/geth-parity-proxy#eth_gettransactionreceipt
 	params.Add("txhash", hash)
-	params.Add("apikey", e.apiKey)
-
-	txURL := fmt.Sprintf("%s?%s", e.baseURL, params.Encode())
-	_, err := e.get(txURL)
-	if err != nil {
-		return nil, fmt.Errorf("error making GET request: %v", err)
-	}
 
-	resp, err := e.get(txURL)
-	if err != nil {
-		return nil, fmt.Errorf("error making GET request: %v", err)
-	}
-	defer resp.Body.Close()
-
-	// Parse the response
-	var receipt receiptResponse
-	err = json.NewDecoder(resp.Body).Decode(&receipt)
-	if err != nil {
-		return nil, fmt.Errorf("error parsing JSON: %v", err)
+	var receipt receiptDetails
+	if err := e.proxyCall(ctx, "eth_getTransactionReceipt", params, &receipt); err != nil {
+		if errors.Is(err, errNoResult) {
+			return nil, &TransactionNotFoundError{Hash: hash}
+		}
+		return nil, err
 	}
 
-	// Extract values
-	txHash := receipt.Result.Hash
-	blockNumber, err := hexutil.DecodeUint64(receipt.Result.BlockNumber)
+	blockNumber, err := hexutil.DecodeUint64(receipt.BlockNumber)
 	if err != nil {
 		return nil, fmt.Errorf("error converting block number: %v", err)
 	}
 
-	gasUsed, err := hexutil.DecodeUint64(receipt.Result.GasUsed)
+	// The block holds the timestamp and the base fee, the transaction the fee caps it declared
+	block, err := e.getBlock(ctx, blockNumber)
 	if err != nil {
-		return nil, fmt.Errorf("error converting gas used: %v", err)
+		return nil, fmt.Errorf("error fetching block %d: %v", blockNumber, err)
 	}
 
-	gasPriceWei, err := hexutil.DecodeBig(receipt.Result.EffectiveGasPrice)
+	tx, err := e.getTransaction(ctx, hash)
 	if err != nil {
-		return nil, fmt.Errorf("error converting gas price %v", err)
+		return nil, fmt.Errorf("error fetching transaction %s: %v", hash, err)
 	}
 
-	// Construct the TransactionData and return
-	txData := &types.TransactionData{
-		Hash:        txHash,
-		BlockNumber: blockNumber,
-		GasUsed:     gasUsed,
-		GasPriceWei: gasPriceWei,
-	}
-
-	return txData, nil
+	return convertReceiptToTransactionData(receipt, *block, tx, e.poolAddresses)
 }
 
-// GetLatestTransaction fetches the latest transaction from the Uniswap V3 ETH-USDC pool.
-func (e *EtherscanClient) GetLatestTransaction() (*types.TransactionData, error) {
+// GetLatestTransaction fetches the latest transaction from the given Uniswap V3 pool.
+func (e *EtherscanClient) GetLatestTransaction(ctx context.Context, poolAddress string) (*types.TransactionData, error) {
 	// Only the latest transaction
 	offset := 1
 	page := 1
-	transactions, err := e.ListTransactions(&offset, nil, nil, &page)
+	transactions, err := e.ListTransactions(ctx, poolAddress, &offset, nil, nil, &page)
 	if err != nil || len(transactions) == 0 {
 		return nil, fmt.Errorf("error fetching the latest transaction: %v", err)
 	}
 	return &transactions[0], nil
 }
 
-// listTransactions is a helper function to query transactions from the Uniswap V3 WETH-USDC pool based on optional parameters.
-func (e *EtherscanClient) ListTransactions(offset *int, startBlock *uint64, endBlock *uint64, page *int) ([]types.TransactionData, error) {
+// ListTransactions queries transactions from the given Uniswap V3 pool based on optional parameters.
+func (e *EtherscanClient) ListTransactions(ctx context.Context, poolAddress string, offset *int, startBlock *uint64, endBlock *uint64, page *int) ([]types.TransactionData, error) {
 	params := url.Values{}
 
 	// Required parameters
 	params.Add("module", "account")
 	params.Add("action", "tokentx")
-	params.Add("address", e.poolAddress)
-	params.Add("apikey", e.apiKey)
+	params.Add("address", poolAddress)
 	params.Add("sort", "desc")
 
 	// Optional parameters
@@ -177,29 +177,36 @@ func (e *EtherscanClient) ListTransactions(offset *int, startBlock *uint64, endB
 		params.Add("page", strconv.Itoa(*page))
 	}
 
-	txURL := fmt.Sprintf("%s?%s", e.baseURL, params.Encode())
-	resp, err := e.get(txURL)
+	body, err := e.request(ctx, params)
 	if err != nil {
-		return nil, fmt.Errorf("error making GET request: %v", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
 	// Decode the JSON response
 	var result tokenTxResponse
-	err = json.NewDecoder(resp.Body).Decode(&result)
+	err = json.Unmarshal(body, &result)
 	if err != nil {
-		return nil, fmt.Errorf("error parsing JSON response: %v", err)
+		return nil, &DecodeError{Source: sourceEtherscan, Err: err}
 	}
 
 	// Check for success in the API response (status == "1")
 	if result.Status != "1" {
-		return nil, fmt.Errorf("Etherscan server error: %s", result.Message)
+		// The reason of rejected queries is in the result, e.g. "Result window is too large, PageNo x Offset size must be less than or equal to 10000"
+		var reason string
+		json.Unmarshal(result.Result, &reason)
+		if strings.Contains(reason, "Result window is too large") && offset != nil && page != nil {
+			return nil, &ResultWindowError{Page: *page, Offset: *offset}
+		}
+		if result.Message == "No transactions found" {
+			return nil, &NotFoundError{Source: sourceEtherscan, Resource: "transactions"}
+		}
+		return nil, &UpstreamError{Source: sourceEtherscan, Message: result.Message}
 	}
 
 	// Unmarshal the Result into []tokenTxDetails
 	var transactionsDetails []tokenTxDetails
 	if err := json.Unmarshal(result.Result, &transactionsDetails); err != nil {
-		return nil, fmt.Errorf("error parsing transactions: %v", err)
+		return nil, &DecodeError{Source: sourceEtherscan, Err: fmt.Errorf("error parsing transactions: %w", err)}
 	}
 
 	// Convert to []TransactionData
@@ -208,9 +215,281 @@ func (e *EtherscanClient) ListTransactions(offset *int, startBlock *uint64, endB
 		return nil, fmt.Errorf("error converting response to TransactionData: %v", err)
 	}
 
+	if err := e.attachSwaps(ctx, poolAddress, transactions); err != nil {
+		return nil, fmt.Errorf("error attaching swaps: %v", err)
+	}
+
+	if err := e.attachFeeDetails(ctx, transactions); err != nil {
+		return nil, fmt.Errorf("error attaching fee details: %v", err)
+	}
+
 	return transactions, nil
 }
 
+// attachFeeDetails sets the EIP-1559 fee fields of the transactions from their blocks.
+// tokentx only reports the effective gas price, so every block is fetched once along with its transactions,
+// which hold their fee caps. The breakdown is best effort: the fee fields of the transactions of a block
+// that can't be fetched are left nil instead of failing the page.
+func (e *EtherscanClient) attachFeeDetails(ctx context.Context, transactions []types.TransactionData) error {
+	blocks := make(map[uint64]*fullBlockDetails)
+
+	for i := range transactions {
+		blockNumber := transactions[i].BlockNumber
+		block, ok := blocks[blockNumber]
+		if !ok {
+			var err error
+			block, err = e.getFullBlock(ctx, blockNumber)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil {
+				log.Printf("Error fetching block %d, the fee breakdown of its transactions is left empty: %v", blockNumber, err)
+			}
+			// Failed blocks are not fetched again for the other transactions of the page
+			blocks[blockNumber] = block
+		}
+		if block == nil {
+			continue
+		}
+
+		if err := applyFeeDetails(&transactions[i], block.BaseFeePerGas, block.findTransaction(transactions[i].Hash)); err != nil {
+			log.Printf("Error reading the fee breakdown of transaction %s, it is left empty: %v", transactions[i].Hash, err)
+		}
+	}
+
+	return nil
+}
+
+// getFullBlock fetches the block along with its transactions
+func (e *EtherscanClient) getFullBlock(ctx context.Context, blockNumber uint64) (*fullBlockDetails, error) {
+	params := url.Values{}
+
+	// https://docs.etherscan.io/api-endpoints/geth-parity-proxy#eth_getblockbynumber
+	params.Add("tag", hexutil.EncodeUint64(blockNumber))
+	params.Add("boolean", "true")
+
+	var block fullBlockDetails
+	if err := e.proxyCall(ctx, "eth_getBlockByNumber", params, &block); err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+// getBlock fetches the header of the block, its transactions are only listed by hash
+func (e *EtherscanClient) getBlock(ctx context.Context, blockNumber uint64) (*blockDetails, error) {
+	params := url.Values{}
+
+	// https://docs.etherscan.io/api-endpoints/geth-parity-proxy#eth_getblockbynumber
+	params.Add("tag", hexutil.EncodeUint64(blockNumber))
+	params.Add("boolean", "false")
+
+	var block blockDetails
+	if err := e.proxyCall(ctx, "eth_getBlockByNumber", params, &block); err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+// getTransaction fetches the transaction for the fee caps it declared
+func (e *EtherscanClient) getTransaction(ctx context.Context, hash string) (*transactionDetails, error) {
+	params := url.Values{}
+
+	// https://docs.etherscan.io/api-endpoints/geth-parity-proxy#eth_gettransactionbyhash
+	params.Add("txhash", hash)
+
+	var tx transactionDetails
+	if err := e.proxyCall(ctx, "eth_getTransactionByHash", params, &tx); err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+// proxyCall executes a JSON-RPC method through the proxy module and decodes its result into result
+func (e *EtherscanClient) proxyCall(ctx context.Context, action string, params url.Values, result interface{}) error {
+	params.Add("module", "proxy")
+	params.Add("action", action)
+
+	body, err := e.request(ctx, params)
+	if err != nil {
+		return err
+	}
+
+	var proxyResp proxyResponse
+	if err := json.Unmarshal(body, &proxyResp); err != nil {
+		return &DecodeError{Source: sourceEtherscan, Err: err}
+	}
+
+	if proxyResp.Error != nil {
+		return fmt.Errorf("%s failed: %w", action, proxyResp.Error)
+	}
+
+	// Rejected calls, e.g. rate limited ones, answer with a status and the reason as result
+	if proxyResp.Status == "0" {
+		return &UpstreamError{Source: sourceEtherscan, Message: fmt.Sprintf("%s - %s", proxyResp.Message, string(proxyResp.Result))}
+	}
+
+	if len(proxyResp.Result) == 0 || string(proxyResp.Result) == "null" {
+		return fmt.Errorf("%s returned %w", action, errNoResult)
+	}
+
+	if err := json.Unmarshal(proxyResp.Result, result); err != nil {
+		return &DecodeError{Source: sourceEtherscan, Err: fmt.Errorf("error parsing %s result: %w", action, err)}
+	}
+
+	return nil
+}
+
+// request sends the query through the circuit breaker of the explorer and returns the response body.
+// It fails fast with a CircuitOpenError while the explorer keeps failing.
+func (e *EtherscanClient) request(ctx context.Context, params url.Values) ([]byte, error) {
+	if err := e.breaker.allow(); err != nil {
+		return nil, err
+	}
+
+	body, err := e.send(ctx, params)
+	e.breaker.record(ctx, err)
+	return body, err
+}
+
+// send sends the query with a key of the pool and returns the response body.
+// Transient HTTP failures are retried by the transport. A key answered with a rate limit error is benched
+// for the retry backoff and the query sent again with the next key, up to the attempts of the retry policy.
+func (e *EtherscanClient) send(ctx context.Context, params url.Values) ([]byte, error) {
+	var rateLimitedErr *RateLimitedError
+	for attempt := 1; attempt <= e.retry.MaxAttempts; attempt++ {
+		key, err := e.keys.acquire(ctx)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			return nil, &RateLimitedError{Source: sourceEtherscan, Reason: err.Error()}
+		}
+		params.Set("apikey", key.key)
+
+		// Transient failures are retried with the same key, under its limiter
+		resp, err := e.get(withRetryLimiter(ctx, key.limiter), fmt.Sprintf("%s?%s", e.baseURL, params.Encode()))
+		if err != nil {
+			return nil, fmt.Errorf("error making GET request: %w", err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, &UpstreamError{Source: sourceEtherscan, Err: fmt.Errorf("error reading response: %w", err)}
+		}
+
+		reason, daily := rateLimitReason(resp.StatusCode, body)
+		if reason == "" {
+			return body, nil
+		}
+		e.keys.bench(key, daily, e.retry.backoff(attempt))
+		rateLimitedErr = &RateLimitedError{Source: sourceEtherscan, Reason: reason}
+	}
+	return nil, rateLimitedErr
+}
+
+// rateLimitReason returns why the response was rate limited, empty when it wasn't, and whether the daily quota is spent.
+// Etherscan rejects rate limited calls with a NOTOK status and the reason as result, e.g. "Max calls per sec rate limit reached (5/sec)".
+func rateLimitReason(statusCode int, body []byte) (string, bool) {
+	if statusCode == http.StatusTooManyRequests {
+		return http.StatusText(statusCode), false
+	}
+
+	var resp struct {
+		Status string          `json:"status"`
+		Result json.RawMessage `json:"result"`
+	}
+	if json.Unmarshal(body, &resp) != nil || resp.Status != "0" {
+		return "", false
+	}
+
+	var reason string
+	json.Unmarshal(resp.Result, &reason)
+	lower := strings.ToLower(reason)
+	if !strings.Contains(lower, "rate limit") {
+		return "", false
+	}
+	return reason, strings.Contains(lower, "daily")
+}
+
+// attachSwaps decodes the Swap events emitted in the block span of the transactions and attaches them by hash.
+// tokentx only reports token transfers, so the swap details come from the pool's event logs.
+func (e *EtherscanClient) attachSwaps(ctx context.Context, poolAddress string, transactions []types.TransactionData) error {
+	if len(transactions) == 0 {
+		return nil
+	}
+
+	fromBlock, toBlock := transactions[0].BlockNumber, transactions[0].BlockNumber
+	for _, tx := range transactions {
+		if tx.BlockNumber < fromBlock {
+			fromBlock = tx.BlockNumber
+		}
+		if tx.BlockNumber > toBlock {
+			toBlock = tx.BlockNumber
+		}
+	}
+
+	logs, err := e.getSwapLogs(ctx, poolAddress, fromBlock, toBlock)
+	if err != nil {
+		return err
+	}
+
+	swaps := groupSwapsByTransaction(logs, []string{poolAddress})
+	for i := range transactions {
+		transactions[i].PoolAddress = strings.ToLower(poolAddress)
+		transactions[i].Swaps = swaps[strings.ToLower(transactions[i].Hash)]
+	}
+
+	return nil
+}
+
+// getSwapLogs fetches the Swap events of the pool between fromBlock and toBlock (inclusive)
+func (e *EtherscanClient) getSwapLogs(ctx context.Context, poolAddress string, fromBlock uint64, toBlock uint64) ([]logDetails, error) {
+	var logs []logDetails
+
+	for page := 1; ; page++ {
+		params := url.Values{}
+
+		// https://docs.etherscan.io/api-endpoints/logs
+		params.Add("module", "logs")
+		params.Add("action", "getLogs")
+		params.Add("address", poolAddress)
+		params.Add("topic0", swapEventTopic)
+		params.Add("fromBlock", strconv.FormatUint(fromBlock, 10))
+		params.Add("toBlock", strconv.FormatUint(toBlock, 10))
+		params.Add("page", strconv.Itoa(page))
+		params.Add("offset", strconv.Itoa(logsPageSize))
+
+		body, err := e.request(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+
+		var result logsResponse
+		if err := json.Unmarshal(body, &result); err != nil {
+			return nil, &DecodeError{Source: sourceEtherscan, Err: err}
+		}
+
+		if result.Status != "1" {
+			if result.Message == "No records found" {
+				break
+			}
+			return nil, &UpstreamError{Source: sourceEtherscan, Message: result.Message}
+		}
+
+		var pageLogs []logDetails
+		if err := json.Unmarshal(result.Result, &pageLogs); err != nil {
+			return nil, &DecodeError{Source: sourceEtherscan, Err: fmt.Errorf("error parsing logs: %w", err)}
+		}
+		logs = append(logs, pageLogs...)
+
+		if len(pageLogs) < logsPageSize {
+			break
+		}
+	}
+
+	return logs, nil
+}
+
 // Adjust convertResponseToTransactionData to accept tokenTxDetails as a parameter
 func convertResponseToTransactionData(details []tokenTxDetails) ([]types.TransactionData, error) {
 	var transactions []types.TransactionData
@@ -253,9 +532,11 @@ func convertToTransactionData(details tokenTxDetails) (*types.TransactionData, e
 	}
 	txTime := time.Unix(unixTime, 0)
 
+	// tokentx only lists the transfers of successful transactions
 	txData := &types.TransactionData{
 		BlockNumber: blockNumber,
 		Hash:        details.Hash,
+		Status:      types.TxStatusSuccess,
 		GasUsed:     gasUsed,
 		GasPriceWei: gasPriceWei,
 		Timestamp:   txTime,
@@ -265,7 +546,7 @@ func convertToTransactionData(details tokenTxDetails) (*types.TransactionData, e
 }
 
 // GetBlockNumberByTimestamp fetches the block number closest(can be before of after) to the given timestamp.
-func (e *EtherscanClient) GetBlockNumberByTimestamp(timestamp time.Time, before bool) (uint64, error) {
+func (e *EtherscanClient) GetBlockNumberByTimestamp(ctx context.Context, timestamp time.Time, before bool) (uint64, error) {
 	closest := "after"
 	if before {
 		closest = "before"
@@ -277,31 +558,51 @@ func (e *EtherscanClient) GetBlockNumberByTimestamp(timestamp time.Time, before
 	params.Add("action", "getblocknobytime")
 	params.Add("timestamp", strconv.FormatInt(timestamp.Unix(), 10)) // base 10
 	params.Add("closest", closest)
-	params.Add("apikey", e.apiKey)
 
-	blockURL := fmt.Sprintf("%s?%s", e.baseURL, params.Encode())
-
-	resp, err := e.get(blockURL)
+	body, err := e.request(ctx, params)
 	if err != nil {
-		return 0, fmt.Errorf("error making GET request: %v", err)
+		return 0, err
 	}
-	defer resp.Body.Close()
 
 	// Decode the JSON response
 	var blockResp blockNumberResponse
-	if err := json.NewDecoder(resp.Body).Decode(&blockResp); err != nil {
-		return 0, fmt.Errorf("error parsing JSON response: %v", err)
+	if err := json.Unmarshal(body, &blockResp); err != nil {
+		return 0, &DecodeError{Source: sourceEtherscan, Err: err}
 	}
 
 	// Check if the API returned a successful status
 	if blockResp.Status != "1" {
-		return 0, fmt.Errorf("Etherscan API error: %s - %s", blockResp.Status, blockResp.Message)
+		return 0, &UpstreamError{Source: sourceEtherscan, Message: fmt.Sprintf("%s - %s", blockResp.Message, blockResp.Result)}
 	}
 
 	blockNumber, err := strconv.ParseUint(blockResp.Result, 10, 64)
 	if err != nil {
-		return 0, fmt.Errorf("error converting block number: %v", err)
+		return 0, &DecodeError{Source: sourceEtherscan, Err: fmt.Errorf("error converting block number: %w", err)}
 	}
 
 	return blockNumber, nil
 }
+
+// GetBlockNumber returns the number of the most recent block
+func (e *EtherscanClient) GetBlockNumber(ctx context.Context) (uint64, error) {
+	// https://docs.etherscan.io/api-endpoints/geth-parity-proxy#eth_blocknumber
+	var result string
+	if err := e.proxyCall(ctx, "eth_blockNumber", url.Values{}, &result); err != nil {
+		return 0, err
+	}
+
+	blockNumber, err := hexutil.DecodeUint64(result)
+	if err != nil {
+		return 0, fmt.Errorf("error converting block number: %v", err)
+	}
+	return blockNumber, nil
+}
+
+// GetBlockHeader fetches the hash and parent hash of the block
+func (e *EtherscanClient) GetBlockHeader(ctx context.Context, blockNumber uint64) (*types.BlockHeader, error) {
+	block, err := e.getBlock(ctx, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	return block.toBlockHeader(blockNumber)
+}
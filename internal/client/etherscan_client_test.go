@@ -1,6 +1,7 @@
 package client
 
 import (
+	"context"
 	"fmt"
 	"math/big"
 	"net/http"
@@ -10,13 +11,15 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/winQe/uniswap-fee-tracker/internal/types"
-	"golang.org/x/time/rate"
 )
 
 // mockServerConfig holds the configuration for the mock HTTP server.
 type mockServerConfig struct {
 	expectedParams map[string]string
 	responseBody   string
+	// responses answer by the value of the keyParam query parameter instead of responseBody, when set
+	keyParam  string
+	responses map[string]string
 }
 
 // createMockServer initializes a mock HTTP server based on the provided configuration.
@@ -36,34 +39,58 @@ func createMockServer(config mockServerConfig) *httptest.Server {
 	}))
 }
 
+// createRoutedMockServer initializes a mock HTTP server that picks the configuration by the "action" query parameter.
+func createRoutedMockServer(routes map[string]mockServerConfig) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		config, ok := routes[query.Get("action")]
+		if !ok {
+			http.Error(w, "unexpected action", http.StatusNotFound)
+			return
+		}
+
+		// Verify expected query parameters
+		for key, expectedValue := range config.expectedParams {
+			actualValue := query.Get(key)
+			assert.Equal(nil, expectedValue, actualValue, fmt.Sprintf("Parameter %s mismatch", key))
+		}
+
+		body := config.responseBody
+		if config.responses != nil {
+			var ok bool
+			if body, ok = config.responses[query.Get(config.keyParam)]; !ok {
+				http.Error(w, "unexpected "+config.keyParam, http.StatusNotFound)
+				return
+			}
+		}
+
+		// Respond with the specified JSON body
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintln(w, body)
+	}))
+}
+
 // initializeEtherscanClient sets up the EtherscanClient with a mock HTTP client.
-func initializeEtherscanClient(mockServer *httptest.Server, apiKey string, poolAddress string) *EtherscanClient {
-	rateLimiter := rate.NewLimiter(5, 5)
-	dailyLimiter := rate.NewLimiter(1.15, 1000)
-	rateLimitedClient := NewRateLimitedClient(rateLimiter, dailyLimiter)
+func initializeEtherscanClient(mockServer *httptest.Server, apiKey string, poolAddresses ...string) *EtherscanClient {
+	rateLimitedClient := NewRateLimitedClient(sourceEtherscan, DefaultRetryPolicy)
 
 	// Override the httpClient to use the mock server's client
 	rateLimitedClient.httpClient = mockServer.Client()
 
-	// Initialize EtherscanClient with the mock server's URL and mock RateLimitedClient
+	// Initialize EtherscanClient with the mock server's URL and a single key
 	return &EtherscanClient{
 		RateLimitedClient: rateLimitedClient,
 		baseURL:           mockServer.URL,
-		apiKey:            apiKey,
-		poolAddress:       poolAddress,
+		keys:              NewKeyPool([]string{apiKey}, 5, 100000, nil),
+		breaker:           NewCircuitBreaker("etherscan:test", DefaultBreakerConfig),
+		poolAddresses:     poolAddresses,
 	}
 }
 
-func TestGetTransactionReceipt(t *testing.T) {
-	expectedParams := map[string]string{
-		"module": "proxy",
-		"action": "eth_getTransactionByHash",
-		"txhash": "0x003c8127556d023655168023988401be7cc46570be7713d42e8a9558c2ab1ae6",
-		"apikey": "test-api-key",
-	}
-
-	// JSON response from actual API call with emitted logs
-	sampleJSON := `{
+// receiptJSON is the proxy response of a receipt whose only log is emitted by the given address
+func receiptJSON(logAddress string) string {
+	return fmt.Sprintf(`{
         "jsonrpc": "2.0",
         "id": 1,
         "result": {
@@ -72,26 +99,95 @@ func TestGetTransactionReceipt(t *testing.T) {
             "contractAddress": null,
             "cumulativeGasUsed": "0x1d9bc",
             "effectiveGasPrice": "0x16b86486ae",
-            "from": "0x3d9aae030b9661e3605b3acb5d0385ede221a0cc",
+            "from": "0x3D9AAE030B9661E3605B3ACB5D0385EDE221A0CC",
             "gasUsed": "0x1d9bc",
+            "logs": [
+                {
+                    "address": "%s",
+                    "topics": [
+                        "0xc42079f94a6350d7e6235f29174924f928cc2ac818eb64fed8004e115fbcca67",
+                        "0x00000000000000000000000068d3a973e7272eb388022a5c6518d9b2a2e66fbf",
+                        "0x0000000000000000000000003d9aae030b9661e3605b3acb5d0385ede221a0cc"
+                    ],
+                    "data": "0x0000000000000000000000000000000000000000000000000000000047868c00fffffffffffffffffffffffffffffffffffffffffffffffff984762d7360df9e0000000000000000000000000000000000004d1d7b09b14bd0ff10b93e38f34e000000000000000000000000000000000000000000000000b1d2a6bcb79cb5a500000000000000000000000000000000000000000000000000000000000304b9",
+                    "blockNumber": "0x13e5af1",
+                    "transactionHash": "0x003c8127556d023655168023988401be7cc46570be7713d42e8a9558c2ab1ae6",
+                    "logIndex": "0x4"
+                }
+            ],
             "status": "0x1",
             "to": "0x68d3a973e7272eb388022a5c6518d9b2a2e66fbf",
             "transactionHash": "0x003c8127556d023655168023988401be7cc46570be7713d42e8a9558c2ab1ae6",
             "transactionIndex": "0x0",
             "type": "0x2"
         }
-    }`
+    }`, logAddress)
+}
 
-	config := mockServerConfig{
-		expectedParams: expectedParams,
-		responseBody:   sampleJSON,
-	}
-	mockServer := createMockServer(config)
+// sampleReceiptBlockJSON is the proxy response of the header of the block of the receipt
+const sampleReceiptBlockJSON = `{
+    "jsonrpc": "2.0",
+    "id": 1,
+    "result": {
+        "number": "0x13e5af1",
+        "hash": "0x21ab72deeb4bb490bb3a6dc8ef46892e146a0c61b691354f5fa16c9dbf90b85f",
+        "timestamp": "0x66fb45a3",
+        "baseFeePerGas": "0x12a05f200",
+        "transactions": [
+            "0x003c8127556d023655168023988401be7cc46570be7713d42e8a9558c2ab1ae6"
+        ]
+    }
+}`
+
+// sampleReceiptTransactionJSON is the proxy response of the transaction of the receipt, with its fee caps
+const sampleReceiptTransactionJSON = `{
+    "jsonrpc": "2.0",
+    "id": 1,
+    "result": {
+        "hash": "0x003c8127556d023655168023988401be7cc46570be7713d42e8a9558c2ab1ae6",
+        "type": "0x2",
+        "gasPrice": "0x16b86486ae",
+        "maxFeePerGas": "0x174876e800",
+        "maxPriorityFeePerGas": "0x165a0bc000"
+    }
+}`
+
+func TestGetTransactionReceipt(t *testing.T) {
+	txHash := "0x003c8127556d023655168023988401be7cc46570be7713d42e8a9558c2ab1ae6"
+	poolAddress := "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640"
+
+	mockServer := createRoutedMockServer(map[string]mockServerConfig{
+		"eth_getTransactionReceipt": {
+			expectedParams: map[string]string{
+				"module": "proxy",
+				"txhash": txHash,
+				"apikey": "test-api-key",
+			},
+			responseBody: receiptJSON(poolAddress),
+		},
+		"eth_getBlockByNumber": {
+			expectedParams: map[string]string{
+				"module":  "proxy",
+				"tag":     "0x13e5af1",
+				"boolean": "false",
+				"apikey":  "test-api-key",
+			},
+			responseBody: sampleReceiptBlockJSON,
+		},
+		"eth_getTransactionByHash": {
+			expectedParams: map[string]string{
+				"module": "proxy",
+				"txhash": txHash,
+				"apikey": "test-api-key",
+			},
+			responseBody: sampleReceiptTransactionJSON,
+		},
+	})
 	defer mockServer.Close()
 
-	client := initializeEtherscanClient(mockServer, "test-api-key", "")
+	client := initializeEtherscanClient(mockServer, "test-api-key", poolAddress)
 
-	receipt, err := client.GetTransactionReceipt("0x003c8127556d023655168023988401be7cc46570be7713d42e8a9558c2ab1ae6")
+	receipt, err := client.GetTransactionReceipt(context.Background(), txHash)
 	assert.NoError(t, err, "Expected no error from GetTransactionReceipt")
 
 	// Define the expected ReceiptData
@@ -101,9 +197,76 @@ func TestGetTransactionReceipt(t *testing.T) {
 	expectedGasPriceWei.SetString("0x16b86486ae", 0) // 97582876334
 
 	assert.Equal(t, expectedBlockNumber, receipt.BlockNumber, "BlockNumber does not match")
-	assert.Equal(t, "0x003c8127556d023655168023988401be7cc46570be7713d42e8a9558c2ab1ae6", receipt.Hash, "Transaction hash does not match")
+	assert.Equal(t, txHash, receipt.Hash, "Transaction hash does not match")
 	assert.Equal(t, expectedGasUsed, receipt.GasUsed, "GasUsed does not match")
 	assert.Equal(t, expectedGasPriceWei, receipt.GasPriceWei, "GasPriceWei does not match")
+	assert.Equal(t, types.TxStatusSuccess, receipt.Status)
+	assert.Equal(t, "0x3d9aae030b9661e3605b3acb5d0385ede221a0cc", receipt.From)
+	assert.Equal(t, "0x68d3a973e7272eb388022a5c6518d9b2a2e66fbf", receipt.To)
+	assert.Equal(t, time.Unix(0x66fb45a3, 0), receipt.Timestamp, "Timestamp is the block time")
+	assert.Equal(t, poolAddress, receipt.PoolAddress)
+	assert.Equal(t, big.NewInt(5000000000), receipt.BaseFeePerGasWei)
+	assert.Equal(t, big.NewInt(100000000000), receipt.MaxFeePerGasWei)
+	assert.Equal(t, big.NewInt(96000000000), receipt.MaxPriorityFeePerGasWei)
+
+	assert.Len(t, receipt.Logs, 1)
+	assert.Equal(t, uint(4), receipt.Logs[0].LogIndex)
+	assert.Equal(t, poolAddress, receipt.Logs[0].Address)
+	assert.Len(t, receipt.Swaps, 1)
+	assert.Equal(t, big.NewInt(1200000000), receipt.Swaps[0].Amount0)
+}
+
+func TestGetTransactionReceipt_NotPoolTransaction(t *testing.T) {
+	txHash := "0x003c8127556d023655168023988401be7cc46570be7713d42e8a9558c2ab1ae6"
+
+	// The only log is emitted by another pool
+	mockServer := createRoutedMockServer(map[string]mockServerConfig{
+		"eth_getTransactionReceipt": {responseBody: receiptJSON("0x8ad599c3a0ff1de082011efddc58f1908eb6e6d8")},
+		"eth_getBlockByNumber":      {responseBody: sampleReceiptBlockJSON},
+		"eth_getTransactionByHash":  {responseBody: sampleReceiptTransactionJSON},
+	})
+	defer mockServer.Close()
+
+	client := initializeEtherscanClient(mockServer, "test-api-key", "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640")
+
+	_, err := client.GetTransactionReceipt(context.Background(), txHash)
+	var notPoolErr *NotPoolTransactionError
+	assert.ErrorAs(t, err, &notPoolErr)
+	assert.Equal(t, txHash, notPoolErr.Hash)
+}
+
+func TestGetBlockHeader(t *testing.T) {
+	mockServer := createRoutedMockServer(map[string]mockServerConfig{
+		"eth_blockNumber": {
+			expectedParams: map[string]string{"module": "proxy", "apikey": "test-api-key"},
+			responseBody:   `{"jsonrpc":"2.0","id":83,"result":"0x13eadce"}`,
+		},
+		"eth_getBlockByNumber": {
+			expectedParams: map[string]string{"module": "proxy", "boolean": "false"},
+			responseBody: `{"jsonrpc":"2.0","id":1,"result":{"number":"0x13eadc4","timestamp":"0x66fbb43f",` +
+				`"hash":"0x4B1F0C5E8A2D9A6E3C2B7F1D0E9A8B7C6D5E4F3A2B1C0D9E8F7A6B5C4D3E2F1A",` +
+				`"parentHash":"0x9a8b7c6d5e4f3a2b1c0d9e8f7a6b5c4d3e2f1a0b9c8d7e6f5a4b3c2d1e0f9a8b"}}`,
+		},
+	})
+	defer mockServer.Close()
+
+	client := initializeEtherscanClient(mockServer, "test-api-key")
+
+	head, err := client.GetBlockNumber(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(20884942), head)
+
+	header, err := client.GetBlockHeader(context.Background(), 20884932)
+	assert.NoError(t, err)
+	assert.Equal(t, &types.BlockHeader{
+		Number:     20884932,
+		Hash:       "0x4b1f0c5e8a2d9a6e3c2b7f1d0e9a8b7c6d5e4f3a2b1c0d9e8f7a6b5c4d3e2f1a",
+		ParentHash: "0x9a8b7c6d5e4f3a2b1c0d9e8f7a6b5c4d3e2f1a0b9c8d7e6f5a4b3c2d1e0f9a8b",
+	}, header)
+
+	// The node answering for another block is an error
+	_, err = client.GetBlockHeader(context.Background(), 20884933)
+	assert.Error(t, err)
 }
 
 func TestListTransactions(t *testing.T) {
@@ -201,20 +364,120 @@ func TestListTransactions(t *testing.T) {
         ]
     }`
 
-	config := mockServerConfig{
-		expectedParams: map[string]string{
-			"module":     "account",
-			"action":     "tokentx",
-			"address":    "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640",
-			"apikey":     "test-api-key",
-			"sort":       "desc",
-			"offset":     "10",
-			"startblock": "1000000",
-			"endblock":   "2000000",
-		},
-		responseBody: sampleListTransactionsJSON,
+	// Swap events of the pool emitted by both transactions, with Etherscan's bare "0x" for a zero log index
+	sampleSwapLogsJSON := `{
+        "status": "1",
+        "message": "OK",
+        "result": [
+            {
+                "address": "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640",
+                "topics": [
+                    "0xc42079f94a6350d7e6235f29174924f928cc2ac818eb64fed8004e115fbcca67",
+                    "0x00000000000000000000000068d3a973e7272eb388022a5c6518d9b2a2e66fbf",
+                    "0x00000000000000000000000068d3a973e7272eb388022a5c6518d9b2a2e66fbf"
+                ],
+                "data": "0xffffffffffffffffffffffffffffffffffffffffffffffffffffffef0b9a748a00000000000000000000000000000000000000000000000189d6c836b2608f6f0000000000000000000000000000000000004d1dac13e697ca7eea8843b9c000000000000000000000000000000000000000000000000000b1d2a6bcb79cb5a500000000000000000000000000000000000000000000000000000000000304ba",
+                "blockNumber": "0x13e78a3",
+                "timeStamp": "0x66fc1b3f",
+                "gasPrice": "0x8185ca3d7",
+                "gasUsed": "0x1d99a",
+                "logIndex": "0x",
+                "transactionHash": "0xf508343089e789298f09e941e7c76bc500809e3f203b17d4d5769e263fa4d3f1",
+                "transactionIndex": "0x3"
+            },
+            {
+                "address": "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640",
+                "topics": [
+                    "0xc42079f94a6350d7e6235f29174924f928cc2ac818eb64fed8004e115fbcca67",
+                    "0x0000000000000000000000008449e4198a021e8a2a5537c0508430b8febf8efc",
+                    "0x0000000000000000000000003fc91a3afd70395cd496c647d5a6cc9d4b2b7fad"
+                ],
+                "data": "0x0000000000000000000000000000000000000000000000000000000047868c00fffffffffffffffffffffffffffffffffffffffffffffffff984762d7360df9e0000000000000000000000000000000000004d1d7b09b14bd0ff10b93e38f34e000000000000000000000000000000000000000000000000b1d2a6bcb79cb5a500000000000000000000000000000000000000000000000000000000000304b9",
+                "blockNumber": "0x13e78a0",
+                "timeStamp": "0x66fc1b1b",
+                "gasPrice": "0x59bc3b52c",
+                "gasUsed": "0x537cd",
+                "logIndex": "0x9e",
+                "transactionHash": "0x8a4ed869c6b0ba8ed9543ec13f634a8105523eed2848a699c0b2150ae694bfc8",
+                "transactionIndex": "0x1b"
+            }
+        ]
+    }`
+
+	// Blocks along with their transactions, the first transaction is EIP-1559 and the second one legacy
+	sampleBlocksJSON := map[string]string{
+		"0x13e78a3": `{
+            "jsonrpc": "2.0",
+            "id": 1,
+            "result": {
+                "number": "0x13e78a3",
+                "hash": "0x45a90ab0d934a425dd3e222da2772b59f826d7fedb14b012b37ac49122e18f70",
+                "timestamp": "0x66fc1b3f",
+                "baseFeePerGas": "0x4a817c800",
+                "transactions": [
+                    {
+                        "hash": "0xf508343089e789298f09e941e7c76bc500809e3f203b17d4d5769e263fa4d3f1",
+                        "type": "0x2",
+                        "gasPrice": "0x8185ca3d7",
+                        "maxFeePerGas": "0xba43b7400",
+                        "maxPriorityFeePerGas": "0x37e11d600"
+                    }
+                ]
+            }
+        }`,
+		"0x13e78a0": `{
+            "jsonrpc": "2.0",
+            "id": 1,
+            "result": {
+                "number": "0x13e78a0",
+                "hash": "0x9c1f4e8a0b3d2c7e6f5a4b3c2d1e0f9a8b7c6d5e4f3a2b1c0d9e8f7a6b5c4d3e",
+                "timestamp": "0x66fc1b1b",
+                "baseFeePerGas": "0x4a817c800",
+                "transactions": [
+                    {
+                        "hash": "0x8a4ed869c6b0ba8ed9543ec13f634a8105523eed2848a699c0b2150ae694bfc8",
+                        "type": "0x0",
+                        "gasPrice": "0x59bc3b52c"
+                    }
+                ]
+            }
+        }`,
 	}
-	mockServer := createMockServer(config)
+
+	mockServer := createRoutedMockServer(map[string]mockServerConfig{
+		"tokentx": {
+			expectedParams: map[string]string{
+				"module":     "account",
+				"address":    "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640",
+				"apikey":     "test-api-key",
+				"sort":       "desc",
+				"offset":     "10",
+				"startblock": "1000000",
+				"endblock":   "2000000",
+			},
+			responseBody: sampleListTransactionsJSON,
+		},
+		"getLogs": {
+			expectedParams: map[string]string{
+				"module":    "logs",
+				"address":   "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640",
+				"topic0":    swapEventTopic,
+				"fromBlock": "20871328",
+				"toBlock":   "20871331",
+				"apikey":    "test-api-key",
+			},
+			responseBody: sampleSwapLogsJSON,
+		},
+		"eth_getBlockByNumber": {
+			expectedParams: map[string]string{
+				"module":  "proxy",
+				"boolean": "true",
+				"apikey":  "test-api-key",
+			},
+			keyParam:  "tag",
+			responses: sampleBlocksJSON,
+		},
+	})
 	defer mockServer.Close()
 
 	client := initializeEtherscanClient(mockServer, "test-api-key", "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640")
@@ -223,7 +486,7 @@ func TestListTransactions(t *testing.T) {
 	offset := 10
 	startBlock := uint64(1000000)
 	endBlock := uint64(2000000)
-	transactions, err := client.ListTransactions(&offset, &startBlock, &endBlock, nil)
+	transactions, err := client.ListTransactions(context.Background(), "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640", &offset, &startBlock, &endBlock, nil)
 	assert.NoError(t, err, "Expected no error from listTransactions")
 
 	// Parse the Unix timestamps from the JSON to time.Time
@@ -237,37 +500,167 @@ func TestListTransactions(t *testing.T) {
 	expectedGasPrice3, _ := new(big.Int).SetString("24087796268", 10)
 	expectedGasPrice4, _ := new(big.Int).SetString("24087796268", 10)
 
+	amount1, _ := new(big.Int).SetString("28379090239168221039", 10)
+	sqrtPrice1, _ := new(big.Int).SetString("1564096411674316473236788346470400", 10)
+	sqrtPrice2, _ := new(big.Int).SetString("1564081234567890123456789012345678", 10)
+	liquidity, _ := new(big.Int).SetString("12813487219287045541", 10)
+
+	swaps1 := []types.SwapEvent{
+		{
+			LogIndex:     0,
+			PoolAddress:  "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640",
+			Sender:       "0x68d3a973e7272eb388022a5c6518d9b2a2e66fbf",
+			Recipient:    "0x68d3a973e7272eb388022a5c6518d9b2a2e66fbf",
+			Amount0:      big.NewInt(-72819772278),
+			Amount1:      amount1,
+			SqrtPriceX96: sqrtPrice1,
+			Liquidity:    liquidity,
+			Tick:         197818,
+		},
+	}
+	swaps2 := []types.SwapEvent{
+		{
+			LogIndex:     158,
+			PoolAddress:  "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640",
+			Sender:       "0x8449e4198a021e8a2a5537c0508430b8febf8efc",
+			Recipient:    "0x3fc91a3afd70395cd496c647d5a6cc9d4b2b7fad",
+			Amount0:      big.NewInt(1200000000),
+			Amount1:      big.NewInt(-467118523758354530),
+			SqrtPriceX96: sqrtPrice2,
+			Liquidity:    liquidity,
+			Tick:         197817,
+		},
+	}
+
+	// Base fee of the block and fee caps of the EIP-1559 transaction
+	baseFee := big.NewInt(20000000000)
+	maxFee := big.NewInt(50000000000)
+	maxPriorityFee := big.NewInt(15000000000)
+
 	expectedTransactions := []types.TransactionData{
 		{
-			BlockNumber: 20871331,
-			Hash:        "0xf508343089e789298f09e941e7c76bc500809e3f203b17d4d5769e263fa4d3f1",
-			GasUsed:     121242,
-			GasPriceWei: expectedGasPrice1,
-			Timestamp:   timestamp1,
+			BlockNumber:             20871331,
+			Hash:                    "0xf508343089e789298f09e941e7c76bc500809e3f203b17d4d5769e263fa4d3f1",
+			Status:                  types.TxStatusSuccess,
+			GasUsed:                 121242,
+			GasPriceWei:             expectedGasPrice1,
+			Timestamp:               timestamp1,
+			PoolAddress:             "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640",
+			Swaps:                   swaps1,
+			BaseFeePerGasWei:        baseFee,
+			MaxFeePerGasWei:         maxFee,
+			MaxPriorityFeePerGasWei: maxPriorityFee,
 		},
 		{
-			BlockNumber: 20871331,
-			Hash:        "0xf508343089e789298f09e941e7c76bc500809e3f203b17d4d5769e263fa4d3f1",
-			GasUsed:     121242,
-			GasPriceWei: expectedGasPrice2,
-			Timestamp:   timestamp2,
+			BlockNumber:             20871331,
+			Hash:                    "0xf508343089e789298f09e941e7c76bc500809e3f203b17d4d5769e263fa4d3f1",
+			Status:                  types.TxStatusSuccess,
+			GasUsed:                 121242,
+			GasPriceWei:             expectedGasPrice2,
+			Timestamp:               timestamp2,
+			PoolAddress:             "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640",
+			Swaps:                   swaps1,
+			BaseFeePerGasWei:        baseFee,
+			MaxFeePerGasWei:         maxFee,
+			MaxPriorityFeePerGasWei: maxPriorityFee,
 		},
 		{
-			BlockNumber: 20871328,
-			Hash:        "0x8a4ed869c6b0ba8ed9543ec13f634a8105523eed2848a699c0b2150ae694bfc8",
-			GasUsed:     341965,
-			GasPriceWei: expectedGasPrice3,
-			Timestamp:   timestamp3,
+			BlockNumber:      20871328,
+			Hash:             "0x8a4ed869c6b0ba8ed9543ec13f634a8105523eed2848a699c0b2150ae694bfc8",
+			Status:           types.TxStatusSuccess,
+			GasUsed:          341965,
+			GasPriceWei:      expectedGasPrice3,
+			Timestamp:        timestamp3,
+			PoolAddress:      "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640",
+			Swaps:            swaps2,
+			BaseFeePerGasWei: baseFee,
 		},
 		{
-			BlockNumber: 20871328,
-			Hash:        "0x8a4ed869c6b0ba8ed9543ec13f634a8105523eed2848a699c0b2150ae694bfc8",
-			GasUsed:     341965,
-			GasPriceWei: expectedGasPrice4,
-			Timestamp:   timestamp4,
+			BlockNumber:      20871328,
+			Hash:             "0x8a4ed869c6b0ba8ed9543ec13f634a8105523eed2848a699c0b2150ae694bfc8",
+			Status:           types.TxStatusSuccess,
+			GasUsed:          341965,
+			GasPriceWei:      expectedGasPrice4,
+			Timestamp:        timestamp4,
+			PoolAddress:      "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640",
+			Swaps:            swaps2,
+			BaseFeePerGasWei: baseFee,
 		},
 	}
 
 	// Assertions to verify the correctness of the parsed data
 	assert.Equal(t, expectedTransactions, transactions, "Transaction data does not match expected values")
 }
+
+func TestAttachFeeDetails_BestEffort(t *testing.T) {
+	// The second block can't be fetched, which only leaves the fee breakdown of its transaction empty
+	mockServer := createRoutedMockServer(map[string]mockServerConfig{
+		"eth_getBlockByNumber": {
+			expectedParams: map[string]string{"module": "proxy", "boolean": "true"},
+			keyParam:       "tag",
+			responses: map[string]string{
+				"0x13e78a3": `{
+                    "jsonrpc": "2.0",
+                    "id": 1,
+                    "result": {
+                        "number": "0x13e78a3",
+                        "baseFeePerGas": "0x4a817c800",
+                        "transactions": [
+                            {
+                                "hash": "0xf508343089e789298f09e941e7c76bc500809e3f203b17d4d5769e263fa4d3f1",
+                                "type": "0x2",
+                                "gasPrice": "0x8185ca3d7",
+                                "maxFeePerGas": "0xba43b7400",
+                                "maxPriorityFeePerGas": "0x37e11d600"
+                            }
+                        ]
+                    }
+                }`,
+				"0x13e78a0": `{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"header not found"}}`,
+			},
+		},
+	})
+	defer mockServer.Close()
+
+	client := initializeEtherscanClient(mockServer, "test-api-key")
+
+	transactions := []types.TransactionData{
+		{BlockNumber: 20871331, Hash: "0xf508343089e789298f09e941e7c76bc500809e3f203b17d4d5769e263fa4d3f1"},
+		{BlockNumber: 20871328, Hash: "0x8a4ed869c6b0ba8ed9543ec13f634a8105523eed2848a699c0b2150ae694bfc8"},
+	}
+	err := client.attachFeeDetails(context.Background(), transactions)
+	assert.NoError(t, err)
+
+	assert.Equal(t, big.NewInt(20000000000), transactions[0].BaseFeePerGasWei)
+	assert.Equal(t, big.NewInt(50000000000), transactions[0].MaxFeePerGasWei)
+	assert.Equal(t, big.NewInt(15000000000), transactions[0].MaxPriorityFeePerGasWei)
+
+	assert.Nil(t, transactions[1].BaseFeePerGasWei)
+	assert.Nil(t, transactions[1].MaxFeePerGasWei)
+	assert.Nil(t, transactions[1].MaxPriorityFeePerGasWei)
+}
+
+func TestListTransactions_ResultWindow(t *testing.T) {
+	// Etherscan refuses pages past its first 10,000 results
+	mockServer := createMockServer(mockServerConfig{
+		expectedParams: map[string]string{
+			"action": "tokentx",
+			"offset": "100",
+			"page":   "101",
+		},
+		responseBody: `{"status":"0","message":"NOTOK","result":"Result window is too large, PageNo x Offset size must be less than or equal to 10000"}`,
+	})
+	defer mockServer.Close()
+
+	client := initializeEtherscanClient(mockServer, "test-api-key")
+
+	offset := 100
+	page := 101
+	startBlock := uint64(20871000)
+	endBlock := uint64(20881000)
+	_, err := client.ListTransactions(context.Background(), "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640", &offset, &startBlock, &endBlock, &page)
+
+	var windowErr *ResultWindowError
+	assert.ErrorAs(t, err, &windowErr)
+	assert.Equal(t, 101, windowErr.Page)
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/winQe/uniswap-fee-tracker/internal/types"
+)
+
+// transactionDetails holds the fee fields of a transaction returned by eth_getTransactionByHash or within a full block.
+// Legacy transactions only declare a gas price, EIP-1559 ones their fee caps.
+type transactionDetails struct {
+	Hash                 string `json:"hash"`
+	Type                 string `json:"type"`
+	GasPrice             string `json:"gasPrice"`
+	MaxFeePerGas         string `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas string `json:"maxPriorityFeePerGas"`
+}
+
+// fullBlockDetails is a block returned by eth_getBlockByNumber along with its transactions
+type fullBlockDetails struct {
+	blockDetails
+	Transactions []transactionDetails `json:"transactions"`
+}
+
+// applyFeeDetails sets the EIP-1559 fee fields of the transaction from the base fee of its block and the fee caps it declared.
+// Blocks before London have no base fee and legacy transactions no fee caps, their fields are left nil.
+func applyFeeDetails(txData *types.TransactionData, baseFeePerGas string, tx *transactionDetails) error {
+	baseFee, err := decodeOptionalBig(baseFeePerGas)
+	if err != nil {
+		return fmt.Errorf("error converting base fee: %v", err)
+	}
+	txData.BaseFeePerGasWei = baseFee
+
+	if tx == nil {
+		return nil
+	}
+
+	maxFee, err := decodeOptionalBig(tx.MaxFeePerGas)
+	if err != nil {
+		return fmt.Errorf("error converting max fee per gas: %v", err)
+	}
+	maxPriorityFee, err := decodeOptionalBig(tx.MaxPriorityFeePerGas)
+	if err != nil {
+		return fmt.Errorf("error converting max priority fee per gas: %v", err)
+	}
+
+	txData.MaxFeePerGasWei = maxFee
+	txData.MaxPriorityFeePerGasWei = maxPriorityFee
+	return nil
+}
+
+// findTransaction returns the transaction of the block with the given hash, nil when the block does not hold it
+func (b *fullBlockDetails) findTransaction(hash string) *transactionDetails {
+	for i := range b.Transactions {
+		if strings.EqualFold(b.Transactions[i].Hash, hash) {
+			return &b.Transactions[i]
+		}
+	}
+	return nil
+}
+
+// decodeOptionalBig decodes a hex quantity, nil when the field is absent
+func decodeOptionalBig(value string) (*big.Int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	return hexutil.DecodeBig(value)
+}
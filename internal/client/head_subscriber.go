@@ -0,0 +1,152 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/gorilla/websocket"
+)
+
+// Reconnection backoff of the newHeads subscription, doubling from the minimum up to the maximum
+const (
+	minSubscribeBackoff = time.Second
+	maxSubscribeBackoff = 2 * time.Minute
+)
+
+// headTimeout is how long a subscription may stay silent before it is considered stale and reconnected.
+// Ethereum produces a block every 12 seconds, so a minute without one means the connection is dead.
+const headTimeout = time.Minute
+
+// HeadSubscriber streams the new chain heads of an eth_subscribe newHeads subscription over a WebSocket JSON-RPC connection.
+// Dropped connections are reconnected with an exponential backoff.
+type HeadSubscriber struct {
+	wsURL       string
+	dialer      *websocket.Dialer
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+	headTimeout time.Duration
+	connected   atomic.Bool
+}
+
+// subscriptionMessage is either the response to eth_subscribe or a subscription notification
+type subscriptionMessage struct {
+	ID     uint64          `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+	Method string          `json:"method"` // eth_subscription for notifications
+	Params struct {
+		Subscription string       `json:"subscription"`
+		Result       blockDetails `json:"result"`
+	} `json:"params"`
+}
+
+// NewHeadSubscriber initializes a subscriber to the new heads of the node at wsURL
+func NewHeadSubscriber(wsURL string) *HeadSubscriber {
+	return &HeadSubscriber{
+		wsURL:       wsURL,
+		dialer:      websocket.DefaultDialer,
+		minBackoff:  minSubscribeBackoff,
+		maxBackoff:  maxSubscribeBackoff,
+		headTimeout: headTimeout,
+	}
+}
+
+// Connected reports whether the subscription is currently established
+func (s *HeadSubscriber) Connected() bool {
+	return s.connected.Load()
+}
+
+// Run subscribes to new heads and calls onHead with the number of every new head until the context is canceled.
+// A dropped or stale subscription is reconnected after a jittered backoff, reset once a subscription is established.
+func (s *HeadSubscriber) Run(ctx context.Context, onHead func(blockNumber uint64)) {
+	backoff := s.minBackoff
+	for {
+		established, err := s.subscribe(ctx, onHead)
+		s.connected.Store(false)
+		if ctx.Err() != nil {
+			return
+		}
+		if established {
+			backoff = s.minBackoff
+		}
+
+		// Full jitter keeps the recorders of several chains from reconnecting in lockstep
+		wait := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		log.Printf("newHeads subscription to %s dropped: %v, reconnecting in %v", s.wsURL, err, wait)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		backoff = min(backoff*2, s.maxBackoff)
+	}
+}
+
+// subscribe opens a connection, subscribes to new heads and forwards them until the connection fails.
+// It returns whether the subscription was established along with the error that ended it.
+func (s *HeadSubscriber) subscribe(ctx context.Context, onHead func(blockNumber uint64)) (bool, error) {
+	conn, _, err := s.dialer.DialContext(ctx, s.wsURL, nil)
+	if err != nil {
+		return false, fmt.Errorf("error connecting: %v", err)
+	}
+	defer conn.Close()
+
+	// Unblock the pending read on cancellation
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	err = conn.WriteJSON(rpcRequest{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  "eth_subscribe",
+		Params:  []interface{}{"newHeads"},
+	})
+	if err != nil {
+		return false, fmt.Errorf("error sending eth_subscribe: %v", err)
+	}
+
+	var subscriptionID string
+	for {
+		conn.SetReadDeadline(time.Now().Add(s.headTimeout))
+
+		var msg subscriptionMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			return subscriptionID != "", fmt.Errorf("error reading: %v", err)
+		}
+
+		if subscriptionID == "" {
+			if msg.Error != nil {
+				return false, fmt.Errorf("eth_subscribe failed: %w", msg.Error)
+			}
+			if msg.ID != 1 || json.Unmarshal(msg.Result, &subscriptionID) != nil || subscriptionID == "" {
+				return false, fmt.Errorf("unexpected eth_subscribe response")
+			}
+			s.connected.Store(true)
+			log.Printf("Subscribed to new heads of %s.", s.wsURL)
+			continue
+		}
+
+		if msg.Method != "eth_subscription" || msg.Params.Subscription != subscriptionID {
+			continue
+		}
+		blockNumber, err := hexutil.DecodeUint64(msg.Params.Result.Number)
+		if err != nil {
+			return true, fmt.Errorf("error converting head number: %v", err)
+		}
+		onHead(blockNumber)
+	}
+}
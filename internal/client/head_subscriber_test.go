@@ -0,0 +1,125 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+// createNewHeadsStub starts a WebSocket node stub. Every connection is answered by serve with its 1-based index.
+func createNewHeadsStub(t *testing.T, serve func(conn *websocket.Conn, connection int)) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	var connections atomic.Int32
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("error upgrading: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		var req rpcRequest
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+		assert.Equal(t, "eth_subscribe", req.Method)
+		assert.Equal(t, []interface{}{"newHeads"}, req.Params)
+
+		serve(conn, int(connections.Add(1)))
+	}))
+}
+
+// sendHead pushes a newHeads notification of the block
+func sendHead(conn *websocket.Conn, subscription string, blockNumber uint64) {
+	conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(
+		`{"jsonrpc":"2.0","method":"eth_subscription","params":{"subscription":"%s","result":{"number":"0x%x","hash":"0x%x"}}}`,
+		subscription, blockNumber, blockNumber)))
+}
+
+// initializeHeadSubscriber sets up a HeadSubscriber against the stub with short backoffs
+func initializeHeadSubscriber(stub *httptest.Server) *HeadSubscriber {
+	subscriber := NewHeadSubscriber("ws" + strings.TrimPrefix(stub.URL, "http"))
+	subscriber.minBackoff = 10 * time.Millisecond
+	subscriber.maxBackoff = 20 * time.Millisecond
+	return subscriber
+}
+
+func TestHeadSubscriberRun(t *testing.T) {
+	stub := createNewHeadsStub(t, func(conn *websocket.Conn, connection int) {
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","id":1,"result":"0xsub"}`))
+		if connection == 1 {
+			// The first connection drops after two heads
+			sendHead(conn, "0xsub", 100)
+			sendHead(conn, "0xsub", 101)
+			return
+		}
+		// Notifications of other subscriptions are ignored
+		sendHead(conn, "0xother", 999)
+		sendHead(conn, "0xsub", 102)
+		// Hold the connection until the client closes it
+		conn.ReadMessage()
+	})
+	defer stub.Close()
+
+	subscriber := initializeHeadSubscriber(stub)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	heads := make(chan uint64, 10)
+	stopped := make(chan struct{})
+	go func() {
+		subscriber.Run(ctx, func(blockNumber uint64) {
+			heads <- blockNumber
+		})
+		close(stopped)
+	}()
+
+	var received []uint64
+	for len(received) < 3 {
+		select {
+		case head := <-heads:
+			received = append(received, head)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for heads, received %v", received)
+		}
+	}
+	assert.Equal(t, []uint64{100, 101, 102}, received)
+	assert.True(t, subscriber.Connected())
+
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after cancellation")
+	}
+	assert.False(t, subscriber.Connected())
+}
+
+func TestHeadSubscriberRun_SubscriptionRejected(t *testing.T) {
+	var attempts atomic.Int32
+	stub := createNewHeadsStub(t, func(conn *websocket.Conn, connection int) {
+		attempts.Store(int32(connection))
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"the method eth_subscribe does not exist"}}`))
+	})
+	defer stub.Close()
+
+	subscriber := initializeHeadSubscriber(stub)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	subscriber.Run(ctx, func(blockNumber uint64) {
+		t.Errorf("unexpected head %d", blockNumber)
+	})
+
+	// Rejected subscriptions are retried with a backoff
+	assert.Greater(t, attempts.Load(), int32(1))
+	assert.False(t, subscriber.Connected())
+}
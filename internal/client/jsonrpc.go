@@ -0,0 +1,260 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/winQe/uniswap-fee-tracker/internal/types"
+	"golang.org/x/time/rate"
+)
+
+// jsonRPCClient is the base client for calling an Ethereum JSON-RPC node, shared by the node backed clients.
+type jsonRPCClient struct {
+	*RateLimitedClient
+	rpcURL    string
+	requestID atomic.Uint64
+}
+
+// rpcRequest represents a JSON-RPC 2.0 request.
+type rpcRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      uint64        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+// rpcResponse represents a JSON-RPC 2.0 response.
+type rpcResponse struct {
+	ID     uint64          `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+// rpcError is the error object of a failed JSON-RPC call.
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("JSON-RPC error %d: %s", e.Code, e.Message)
+}
+
+// rpcCodeExecutionReverted is the error code of calls reverted by the contract
+const rpcCodeExecutionReverted = 3
+
+// isExecutionReverted reports whether the call failed because the contract reverted, as opposed to the node failing it.
+// Some nodes only tell reverts apart by their message.
+func isExecutionReverted(err error) bool {
+	var rpcErr *rpcError
+	if !errors.As(err, &rpcErr) {
+		return false
+	}
+	return rpcErr.Code == rpcCodeExecutionReverted || strings.Contains(strings.ToLower(rpcErr.Message), "revert")
+}
+
+// blockDetails holds the header fields of a block returned by eth_getBlockByNumber.
+type blockDetails struct {
+	Number        string `json:"number"`
+	Hash          string `json:"hash"`
+	ParentHash    string `json:"parentHash"`
+	Timestamp     string `json:"timestamp"`
+	BaseFeePerGas string `json:"baseFeePerGas"` // Absent before London
+}
+
+// newJSONRPCClient initializes the base client for the JSON-RPC node at rpcURL
+func newJSONRPCClient(rpcURL string, rateLimits ...*rate.Limiter) *jsonRPCClient {
+	return &jsonRPCClient{
+		RateLimitedClient: NewRateLimitedClient("rpc", DefaultRetryPolicy, rateLimits...),
+		rpcURL:            rpcURL,
+	}
+}
+
+// call executes a JSON-RPC method and decodes its result into result.
+func (r *jsonRPCClient) call(ctx context.Context, method string, result interface{}, params ...interface{}) error {
+	if params == nil {
+		// Some nodes reject a null params field
+		params = []interface{}{}
+	}
+
+	reqBody, err := json.Marshal(rpcRequest{
+		JSONRPC: "2.0",
+		ID:      r.requestID.Add(1),
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return fmt.Errorf("error encoding %s request: %v", method, err)
+	}
+
+	resp, err := r.post(ctx, r.rpcURL, "application/json", bytes.NewReader(reqBody))
+	if err != nil {
+		return fmt.Errorf("error making POST request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var rpcResp rpcResponse
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return fmt.Errorf("error parsing JSON response: %v", err)
+	}
+
+	if rpcResp.Error != nil {
+		return fmt.Errorf("%s failed: %w", method, rpcResp.Error)
+	}
+
+	if len(rpcResp.Result) == 0 || string(rpcResp.Result) == "null" {
+		return fmt.Errorf("%s returned %w", method, errNoResult)
+	}
+
+	if err := json.Unmarshal(rpcResp.Result, result); err != nil {
+		return fmt.Errorf("error parsing %s result: %v", method, err)
+	}
+
+	return nil
+}
+
+// latestBlockTag selects the state of the chain head in calls taking a block parameter
+const latestBlockTag = "latest"
+
+// ethCall executes a read-only contract call against the state at the end of the block and returns the raw output.
+// blockTag is a hex block number or latestBlockTag.
+func (r *jsonRPCClient) ethCall(ctx context.Context, to string, data string, blockTag string) ([]byte, error) {
+	callObject := map[string]string{
+		"to":   to,
+		"data": data,
+	}
+
+	var result string
+	if err := r.call(ctx, "eth_call", &result, callObject, blockTag); err != nil {
+		return nil, err
+	}
+
+	output, err := hexutil.Decode(result)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding eth_call output: %v", err)
+	}
+	return output, nil
+}
+
+// GetBlockNumberByTimestamp fetches the block number closest(can be before of after) to the given timestamp.
+// It binary searches the chain on block timestamps.
+func (r *jsonRPCClient) GetBlockNumberByTimestamp(ctx context.Context, timestamp time.Time, before bool) (uint64, error) {
+	head, err := r.getBlockNumber(ctx)
+	if err != nil {
+		return 0, err
+	}
+	target := uint64(timestamp.Unix())
+
+	// Find the first block with a timestamp at or after the target
+	low, high := uint64(0), head
+	for low < high {
+		mid := low + (high-low)/2
+		blockTime, err := r.getBlockTime(ctx, mid)
+		if err != nil {
+			return 0, err
+		}
+		if blockTime < target {
+			low = mid + 1
+		} else {
+			high = mid
+		}
+	}
+
+	blockTime, err := r.getBlockTime(ctx, low)
+	if err != nil {
+		return 0, err
+	}
+
+	if before {
+		if blockTime > target {
+			if low == 0 {
+				return 0, fmt.Errorf("no block found before timestamp %d", target)
+			}
+			return low - 1, nil
+		}
+		return low, nil
+	}
+
+	if blockTime < target {
+		return 0, fmt.Errorf("no block found after timestamp %d", target)
+	}
+	return low, nil
+}
+
+// GetBlockNumber returns the number of the most recent block
+func (r *jsonRPCClient) GetBlockNumber(ctx context.Context) (uint64, error) {
+	return r.getBlockNumber(ctx)
+}
+
+// GetBlockHeader fetches the hash and parent hash of the block
+func (r *jsonRPCClient) GetBlockHeader(ctx context.Context, blockNumber uint64) (*types.BlockHeader, error) {
+	block, err := r.getBlock(ctx, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	return block.toBlockHeader(blockNumber)
+}
+
+// getBlockNumber returns the number of the most recent block
+func (r *jsonRPCClient) getBlockNumber(ctx context.Context) (uint64, error) {
+	var result string
+	if err := r.call(ctx, "eth_blockNumber", &result); err != nil {
+		return 0, err
+	}
+
+	blockNumber, err := hexutil.DecodeUint64(result)
+	if err != nil {
+		return 0, fmt.Errorf("error converting block number: %v", err)
+	}
+	return blockNumber, nil
+}
+
+// getBlock fetches the block header without its transactions
+func (r *jsonRPCClient) getBlock(ctx context.Context, blockNumber uint64) (*blockDetails, error) {
+	var block blockDetails
+	if err := r.call(ctx, "eth_getBlockByNumber", &block, hexutil.EncodeUint64(blockNumber), false); err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+// getBlockTime returns the unix timestamp of the block
+func (r *jsonRPCClient) getBlockTime(ctx context.Context, blockNumber uint64) (uint64, error) {
+	block, err := r.getBlock(ctx, blockNumber)
+	if err != nil {
+		return 0, err
+	}
+
+	blockTime, err := hexutil.DecodeUint64(block.Timestamp)
+	if err != nil {
+		return 0, fmt.Errorf("error converting timestamp: %v", err)
+	}
+	return blockTime, nil
+}
+
+// toBlockHeader converts the block to a header, checking the node answered for the requested block
+func (b *blockDetails) toBlockHeader(blockNumber uint64) (*types.BlockHeader, error) {
+	number, err := hexutil.DecodeUint64(b.Number)
+	if err != nil {
+		return nil, fmt.Errorf("error converting block number: %v", err)
+	}
+	if number != blockNumber {
+		return nil, fmt.Errorf("requested block %d but got block %d", blockNumber, number)
+	}
+	if b.Hash == "" || b.ParentHash == "" {
+		return nil, fmt.Errorf("block %d has no hash", blockNumber)
+	}
+
+	return &types.BlockHeader{
+		Number:     number,
+		Hash:       strings.ToLower(b.Hash),
+		ParentHash: strings.ToLower(b.ParentHash),
+	}, nil
+}
@@ -0,0 +1,204 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// KeyUsageStore persists the daily request count of every API key, so quotas survive restarts
+type KeyUsageStore interface {
+	// IncrementKeyUsage counts a request of the key on the day (UTC, 2006-01-02) and returns the count of the day
+	IncrementKeyUsage(ctx context.Context, keyID string, day string) (int64, error)
+}
+
+// KeyPool spreads requests across several API keys, each with its own per second limiter and daily quota.
+// Keys answered with rate limit errors are benched for a while, keys out of quota until the next UTC day.
+type KeyPool struct {
+	mu         sync.Mutex
+	keys       []*poolKey
+	next       int // Index the next selection starts at, so keys with the same delay take turns
+	dailyLimit int64
+	usage      KeyUsageStore // Nil keeps the daily counts in memory only
+	now        func() time.Time
+}
+
+// poolKey is an API key of the pool along with its limits
+type poolKey struct {
+	key          string
+	id           string // Short hash of the key, logged and stored instead of the key itself
+	limiter      *rate.Limiter
+	day          string // UTC day the used count applies to
+	used         int64
+	benchedUntil time.Time
+}
+
+// NewKeyPool initializes a pool of the given keys, requestsPerSecond and requestsPerDay apply to each key
+func NewKeyPool(keys []string, requestsPerSecond float64, requestsPerDay int, usage KeyUsageStore) *KeyPool {
+	pool := &KeyPool{
+		dailyLimit: int64(requestsPerDay),
+		usage:      usage,
+		now:        time.Now,
+	}
+	for _, key := range keys {
+		pool.keys = append(pool.keys, &poolKey{
+			key:     key,
+			id:      keyID(key),
+			limiter: rate.NewLimiter(rate.Limit(requestsPerSecond), int(math.Ceil(requestsPerSecond))),
+		})
+	}
+	return pool
+}
+
+// acquire waits for a key allowed to send a request and counts the request against its daily quota
+func (p *KeyPool) acquire(ctx context.Context) (*poolKey, error) {
+	if len(p.keys) == 0 {
+		return nil, fmt.Errorf("no API key configured")
+	}
+
+	for {
+		key, day, delay, err := p.reserve()
+		if err != nil {
+			return nil, err
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+		if key != nil && p.count(ctx, key, day) {
+			return key, nil
+		}
+	}
+}
+
+// reserve picks the key able to send a request the soonest and reserves its limiter.
+// Without any key in rotation, it returns nil along with the time until a benched key is back,
+// and fails when every key is out of daily quota.
+func (p *KeyPool) reserve() (*poolKey, string, time.Duration, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	day := now.UTC().Format(time.DateOnly)
+
+	var best *poolKey
+	var bestReservation *rate.Reservation
+	var bestDelay time.Duration
+	bestIndex := 0
+	var untilBack time.Duration
+	benched := false
+
+	for i := range p.keys {
+		index := (p.next + i) % len(p.keys)
+		key := p.keys[index]
+		if key.day != day {
+			key.day = day
+			key.used = 0
+		}
+
+		if now.Before(key.benchedUntil) {
+			if !benched || key.benchedUntil.Sub(now) < untilBack {
+				untilBack = key.benchedUntil.Sub(now)
+			}
+			benched = true
+			continue
+		}
+		if key.used >= p.dailyLimit {
+			continue
+		}
+
+		reservation := key.limiter.ReserveN(now, 1)
+		delay := reservation.DelayFrom(now)
+		if best != nil && delay >= bestDelay {
+			reservation.CancelAt(now)
+			continue
+		}
+		if bestReservation != nil {
+			bestReservation.CancelAt(now)
+		}
+		best, bestReservation, bestDelay, bestIndex = key, reservation, delay, index
+	}
+
+	if best == nil {
+		if !benched {
+			return nil, day, 0, fmt.Errorf("every API key reached its daily quota of %d requests until %v", p.dailyLimit, nextUTCDay(now))
+		}
+		return nil, day, untilBack, nil
+	}
+	p.next = bestIndex + 1
+	return best, day, bestDelay, nil
+}
+
+// count records a request of the key and reports whether it is within the daily quota.
+// The count of the usage store is authoritative, it includes the requests made before a restart.
+func (p *KeyPool) count(ctx context.Context, key *poolKey, day string) bool {
+	var stored int64
+	if p.usage != nil {
+		var err error
+		stored, err = p.usage.IncrementKeyUsage(ctx, key.id, day)
+		if err != nil {
+			log.Printf("Error counting the usage of API key %s, counting in memory: %v", key.id, err)
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key.day != day {
+		return true
+	}
+	key.used = max(key.used+1, stored)
+	if key.used > p.dailyLimit {
+		log.Printf("API key %s reached its daily quota of %d requests.", key.id, p.dailyLimit)
+		return false
+	}
+	return true
+}
+
+// bench takes the key out of rotation for the duration after a rate limit error,
+// or until the next UTC day when its daily quota is spent
+func (p *KeyPool) bench(key *poolKey, daily bool, duration time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if daily {
+		key.used = p.dailyLimit
+		log.Printf("API key %s is out of daily quota, benched until the next UTC day.", key.id)
+		return
+	}
+	key.benchedUntil = p.now().Add(duration)
+	log.Printf("API key %s is rate limited, benched for %v.", key.id, duration)
+}
+
+// keyID identifies the key without revealing it
+func keyID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:4])
+}
+
+// nextUTCDay returns the start of the UTC day after t, when daily quotas reset
+func nextUTCDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+}
+
+// sleep waits for the duration or until the context is canceled
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
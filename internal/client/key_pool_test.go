@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryKeyUsage is a KeyUsageStore counting in memory, starting from the given counts
+type memoryKeyUsage struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+func (m *memoryKeyUsage) IncrementKeyUsage(ctx context.Context, keyID string, day string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counts[day+":"+keyID]++
+	return m.counts[day+":"+keyID], nil
+}
+
+func TestKeyPoolAcquire(t *testing.T) {
+	t.Run("spreads requests across keys", func(t *testing.T) {
+		pool := NewKeyPool([]string{"key-a", "key-b"}, 1, 100000, nil)
+
+		first, err := pool.acquire(context.Background())
+		assert.NoError(t, err)
+		second, err := pool.acquire(context.Background())
+		assert.NoError(t, err)
+
+		// The second request does not wait on the limiter of the first key
+		assert.ElementsMatch(t, []string{"key-a", "key-b"}, []string{first.key, second.key})
+	})
+
+	t.Run("skips keys out of daily quota", func(t *testing.T) {
+		day := time.Now().UTC().Format(time.DateOnly)
+		// key-a spent its quota before a restart
+		usage := &memoryKeyUsage{counts: map[string]int64{day + ":" + keyID("key-a"): 10}}
+		pool := NewKeyPool([]string{"key-a", "key-b"}, 100, 10, usage)
+
+		for i := 0; i < 3; i++ {
+			key, err := pool.acquire(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, "key-b", key.key)
+		}
+		assert.Equal(t, int64(3), usage.counts[day+":"+keyID("key-b")])
+	})
+
+	t.Run("every key out of daily quota", func(t *testing.T) {
+		pool := NewKeyPool([]string{"key-a"}, 100, 1, nil)
+
+		_, err := pool.acquire(context.Background())
+		assert.NoError(t, err)
+		_, err = pool.acquire(context.Background())
+		assert.ErrorContains(t, err, "daily quota")
+	})
+
+	t.Run("benched key is out of rotation", func(t *testing.T) {
+		pool := NewKeyPool([]string{"key-a", "key-b"}, 100, 100000, nil)
+		pool.bench(pool.keys[0], false, time.Minute)
+
+		for i := 0; i < 3; i++ {
+			key, err := pool.acquire(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, "key-b", key.key)
+		}
+
+		// The key is back once its bench is over
+		now := time.Now()
+		pool.now = func() time.Time { return now.Add(time.Minute + time.Second) }
+		keys := map[string]bool{}
+		for i := 0; i < 2; i++ {
+			key, err := pool.acquire(context.Background())
+			assert.NoError(t, err)
+			keys[key.key] = true
+		}
+		assert.True(t, keys["key-a"])
+	})
+
+	t.Run("waits for a benched key", func(t *testing.T) {
+		pool := NewKeyPool([]string{"key-a"}, 100, 100000, nil)
+		pool.bench(pool.keys[0], false, time.Minute)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err := pool.acquire(ctx)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestEtherscanRequest_RotatesRateLimitedKey(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("apikey") == "key-a" {
+			fmt.Fprintln(w, `{"status":"0","message":"NOTOK","result":"Max calls per sec rate limit reached (5/sec)"}`)
+			return
+		}
+		fmt.Fprintln(w, `{"status":"1","message":"OK","result":"20884932"}`)
+	}))
+	defer mockServer.Close()
+
+	client := initializeEtherscanClient(mockServer, "key-a")
+	client.keys = NewKeyPool([]string{"key-a", "key-b"}, 100, 100000, nil)
+
+	for i := 0; i < 3; i++ {
+		blockNumber, err := client.GetBlockNumberByTimestamp(context.Background(), time.Unix(1727793983, 0), true)
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(20884932), blockNumber)
+	}
+
+	// The rate limited key is benched instead of being retried
+	assert.True(t, client.keys.keys[0].benchedUntil.After(time.Now()))
+	assert.Equal(t, int64(1), client.keys.keys[0].used)
+}
+
+func TestRateLimitReason(t *testing.T) {
+	reason, daily := rateLimitReason(http.StatusOK, []byte(`{"status":"0","message":"NOTOK","result":"Max daily rate limit reached. 100000 (100K) calls per day"}`))
+	assert.NotEmpty(t, reason)
+	assert.True(t, daily)
+
+	reason, _ = rateLimitReason(http.StatusOK, []byte(`{"status":"0","message":"No transactions found","result":[]}`))
+	assert.Empty(t, reason)
+
+	reason, daily = rateLimitReason(http.StatusTooManyRequests, nil)
+	assert.NotEmpty(t, reason)
+	assert.False(t, daily)
+}
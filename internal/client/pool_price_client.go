@@ -0,0 +1,230 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"golang.org/x/time/rate"
+)
+
+// Function selectors of the Uniswap V3 pool and ERC-20 views read by PoolPriceClient
+const (
+	slot0Selector    = "0x3850c7bd" // slot0()
+	token0Selector   = "0x0dfe1681" // token0()
+	token1Selector   = "0xd21220a7" // token1()
+	decimalsSelector = "0x313ce567" // decimals()
+)
+
+// PriceSourcePool prefixes the source of prices read from a Uniswap V3 pool
+const PriceSourcePool = "pool"
+
+// poolPriceDecimals is the number of decimals pool prices are rounded to, the exact price rarely has a finite decimal
+const poolPriceDecimals = 18
+
+// poolPriceResolution is the 12 second slot of Ethereum, the time between two blocks and so two pool prices
+const poolPriceResolution = 12 * time.Second
+
+// q96 is 2^96, the fixed point scale of sqrtPriceX96
+var q96 = new(big.Int).Lsh(big.NewInt(1), 96)
+
+// PoolPriceClient derives the ETH price from the state of a Uniswap V3 WETH-stablecoin pool.
+// The price is read from slot0 at the block of the transaction, or of the requested timestamp, so it is exact to that block.
+type PoolPriceClient struct {
+	*jsonRPCClient
+	poolAddress string
+	wethAddress string
+
+	// Chain and pool tokens, resolved on first use
+	mu             sync.Mutex
+	tokensResolved bool
+	chainID        int64
+	wethIsToken0   bool
+	decimals0      int
+	decimals1      int
+}
+
+// NewPoolPriceClient initializes a price client reading the pool through the JSON-RPC node at rpcURL.
+// wethAddress tells which side of the pool is ETH, the other side is taken as USD.
+func NewPoolPriceClient(rpcURL string, poolAddress string, wethAddress string) *PoolPriceClient {
+	// 10 requests per second fits the free tier of most hosted node providers
+	secondLimiter := rate.NewLimiter(10, 10)
+
+	return &PoolPriceClient{
+		jsonRPCClient: newJSONRPCClient(rpcURL, secondLimiter),
+		poolAddress:   strings.ToLower(poolAddress),
+		wethAddress:   strings.ToLower(wethAddress),
+	}
+}
+
+// GetETHUSDT returns the pool price at the last block mined at or before the timestamp.
+// The block is binary searched on the node, so transactions are priced with GetETHUSDTAtBlock instead.
+func (p *PoolPriceClient) GetETHUSDT(ctx context.Context, timestamp time.Time) (*KlineData, error) {
+	if timestamp.IsZero() {
+		return nil, fmt.Errorf("timestamp is invalid")
+	}
+
+	if err := p.resolveTokens(ctx); err != nil {
+		return nil, err
+	}
+
+	blockNumber, err := p.GetBlockNumberByTimestamp(ctx, timestamp, true)
+	if err != nil {
+		return nil, fmt.Errorf("error finding the block at %v: %v", timestamp, err)
+	}
+
+	return p.priceAtBlock(ctx, blockNumber)
+}
+
+// GetETHUSDTAtBlock returns the pool price at the end of the block, read directly when the pool is on the chain of the block.
+// Blocks of other chains are priced by their timestamp.
+func (p *PoolPriceClient) GetETHUSDTAtBlock(ctx context.Context, chainID int64, blockNumber uint64, timestamp time.Time) (*KlineData, error) {
+	if err := p.resolveTokens(ctx); err != nil {
+		return nil, err
+	}
+
+	if chainID != p.chainID {
+		return p.GetETHUSDT(ctx, timestamp)
+	}
+	return p.priceAtBlock(ctx, blockNumber)
+}
+
+// ReadsBlocks is true, the pool price can change with every block
+func (p *PoolPriceClient) ReadsBlocks() bool {
+	return true
+}
+
+// priceAtBlock reads the pool price at the end of the block, the tokens must be resolved
+func (p *PoolPriceClient) priceAtBlock(ctx context.Context, blockNumber uint64) (*KlineData, error) {
+	output, err := p.ethCall(ctx, p.poolAddress, slot0Selector, hexutil.EncodeUint64(blockNumber))
+	if err != nil {
+		return nil, fmt.Errorf("error reading slot0: %v", err)
+	}
+	if len(output) < 32 {
+		return nil, fmt.Errorf("unexpected slot0 output length %d", len(output))
+	}
+
+	// sqrtPriceX96 is the first field of slot0
+	sqrtPriceX96 := new(big.Int).SetBytes(output[0:32])
+	if sqrtPriceX96.Sign() == 0 {
+		return nil, fmt.Errorf("pool %s is not initialized at block %d", p.poolAddress, blockNumber)
+	}
+
+	price := sqrtPriceX96ToPrice(sqrtPriceX96, p.decimals0, p.decimals1)
+	if !p.wethIsToken0 {
+		price.Inv(price)
+	}
+
+	closePrice, _ := price.Float64()
+	return &KlineData{
+		ClosePrice:        closePrice,
+		ClosePriceDecimal: price.FloatString(poolPriceDecimals),
+		Source:            PriceSourcePool + ":" + p.poolAddress,
+	}, nil
+}
+
+// Resolution is the time between two blocks, every block has its own price
+func (p *PoolPriceClient) Resolution() time.Duration {
+	return poolPriceResolution
+}
+
+// resolveTokens reads the chain of the node, which pool token is WETH and the decimals of both tokens.
+// They never change, so they are only read until a read succeeds.
+func (p *PoolPriceClient) resolveTokens(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.tokensResolved {
+		return nil
+	}
+
+	var chainID string
+	if err := p.call(ctx, "eth_chainId", &chainID); err != nil {
+		return fmt.Errorf("error reading the chain ID: %v", err)
+	}
+	decodedChainID, err := hexutil.DecodeUint64(chainID)
+	if err != nil {
+		return fmt.Errorf("error converting chain ID: %v", err)
+	}
+	p.chainID = int64(decodedChainID)
+
+	token0, err := p.readAddress(ctx, p.poolAddress, token0Selector)
+	if err != nil {
+		return fmt.Errorf("error reading token0 of pool %s: %v", p.poolAddress, err)
+	}
+	token1, err := p.readAddress(ctx, p.poolAddress, token1Selector)
+	if err != nil {
+		return fmt.Errorf("error reading token1 of pool %s: %v", p.poolAddress, err)
+	}
+
+	switch p.wethAddress {
+	case token0:
+		p.wethIsToken0 = true
+	case token1:
+		p.wethIsToken0 = false
+	default:
+		return fmt.Errorf("pool %s does not hold WETH %s", p.poolAddress, p.wethAddress)
+	}
+
+	if p.decimals0, err = p.readDecimals(ctx, token0); err != nil {
+		return err
+	}
+	if p.decimals1, err = p.readDecimals(ctx, token1); err != nil {
+		return err
+	}
+
+	p.tokensResolved = true
+	return nil
+}
+
+// readAddress calls a view returning an address on the latest block
+func (p *PoolPriceClient) readAddress(ctx context.Context, to string, selector string) (string, error) {
+	output, err := p.ethCall(ctx, to, selector, latestBlockTag)
+	if err != nil {
+		return "", err
+	}
+	if len(output) < 32 {
+		return "", fmt.Errorf("unexpected output length %d", len(output))
+	}
+	return fmt.Sprintf("0x%x", output[12:32]), nil
+}
+
+// readDecimals reads the decimals of an ERC-20 token on the latest block
+func (p *PoolPriceClient) readDecimals(ctx context.Context, token string) (int, error) {
+	output, err := p.ethCall(ctx, token, decimalsSelector, latestBlockTag)
+	if err != nil {
+		return 0, fmt.Errorf("error reading decimals of %s: %v", token, err)
+	}
+	if len(output) < 32 {
+		return 0, fmt.Errorf("unexpected decimals output length %d", len(output))
+	}
+	return int(new(big.Int).SetBytes(output[0:32]).Int64()), nil
+}
+
+// sqrtPriceX96ToPrice converts a Q64.96 square root price to the exact price of token0 in token1, adjusted for decimals
+func sqrtPriceX96ToPrice(sqrtPriceX96 *big.Int, decimals0 int, decimals1 int) *big.Rat {
+	// price = (sqrtPriceX96 / 2^96)^2 * 10^(decimals0 - decimals1)
+	ratio := new(big.Rat).SetFrac(sqrtPriceX96, q96)
+	ratio.Mul(ratio, ratio)
+
+	scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(decimals0-decimals1))), nil))
+	if decimals0 >= decimals1 {
+		ratio.Mul(ratio, scale)
+	} else {
+		ratio.Quo(ratio, scale)
+	}
+
+	return ratio
+}
+
+// abs returns the absolute value of x
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	usdcAddress     = "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"
+	wethAddress     = "0xc02aaa39b223fe8d0a0e5c4f27ead9083c756cc2"
+	usdcWETHPool005 = "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640"
+)
+
+// word left pads a hex value to a 32 bytes ABI word
+func word(hexValue string) string {
+	return fmt.Sprintf("%064s", hexValue)
+}
+
+// createPoolStub initializes a stub mainnet node serving a USDC/WETH pool whose slot0 is read at each block through sqrtPriceAt.
+// blockReads, when set, counts the blocks read by the client.
+func createPoolStub(t *testing.T, sqrtPriceAt func(blockTag string) string, blockReads *atomic.Int32) func() (*PoolPriceClient, func()) {
+	stub := createRPCStub(t, map[string]rpcHandler{
+		"eth_call": func(params []json.RawMessage) interface{} {
+			var call map[string]string
+			json.Unmarshal(params[0], &call)
+			var blockTag string
+			json.Unmarshal(params[1], &blockTag)
+
+			switch {
+			case call["to"] == usdcWETHPool005 && call["data"] == token0Selector:
+				return "0x" + word(usdcAddress[2:])
+			case call["to"] == usdcWETHPool005 && call["data"] == token1Selector:
+				return "0x" + word(wethAddress[2:])
+			case call["to"] == usdcAddress && call["data"] == decimalsSelector:
+				return "0x" + word("6")
+			case call["to"] == wethAddress && call["data"] == decimalsSelector:
+				return "0x" + word("12")
+			case call["to"] == usdcWETHPool005 && call["data"] == slot0Selector:
+				// Only sqrtPriceX96 is read, the other slot0 fields are zero
+				return "0x" + word(sqrtPriceAt(blockTag)) + word("0")
+			}
+			t.Errorf("unexpected eth_call %v", call)
+			return nil
+		},
+		"eth_blockNumber": func(params []json.RawMessage) interface{} {
+			return "0x3e8" // 1000
+		},
+		"eth_chainId": func(params []json.RawMessage) interface{} {
+			return "0x1"
+		},
+		"eth_getBlockByNumber": func(params []json.RawMessage) interface{} {
+			if blockReads != nil {
+				blockReads.Add(1)
+			}
+			return stubBlock(params)
+		},
+	})
+
+	return func() (*PoolPriceClient, func()) {
+		return &PoolPriceClient{
+			jsonRPCClient: initializeJSONRPCClient(stub),
+			poolAddress:   usdcWETHPool005,
+			wethAddress:   wethAddress,
+		}, stub.Close
+	}
+}
+
+func TestPoolPriceGetETHUSDT(t *testing.T) {
+	// 20000 * 2^96 prices 1 USDC at 1/2500 WETH, so 1 ETH at 2500 USDC
+	newClient := createPoolStub(t, func(blockTag string) string {
+		assert.Equal(t, "0x1f4", blockTag) // block 500
+		return "4e20000000000000000000000000"
+	}, nil)
+	client, closeStub := newClient()
+	defer closeStub()
+
+	// A timestamp between block 500 and 501 reads the state of block 500
+	kline, err := client.GetETHUSDT(context.Background(), time.Unix(1727790000+500*12+5, 0))
+	assert.NoError(t, err, "Expected no error from GetETHUSDT")
+	assert.InDelta(t, 2500.0, kline.ClosePrice, 1e-9)
+	assert.Equal(t, "pool:"+usdcWETHPool005, kline.Source)
+	assert.Equal(t, 12*time.Second, client.Resolution())
+}
+
+func TestPoolPriceGetETHUSDTAtBlock(t *testing.T) {
+	var blockReads atomic.Int32
+	newClient := createPoolStub(t, func(blockTag string) string {
+		assert.Equal(t, "0x1f4", blockTag) // block 500
+		return "4e20000000000000000000000000"
+	}, &blockReads)
+	client, closeStub := newClient()
+	defer closeStub()
+
+	// A mainnet block is read directly, without searching it by its timestamp
+	kline, err := client.GetETHUSDTAtBlock(context.Background(), 1, 500, time.Unix(1727790000+500*12, 0))
+	assert.NoError(t, err)
+	assert.InDelta(t, 2500.0, kline.ClosePrice, 1e-9)
+	assert.Equal(t, int32(0), blockReads.Load())
+
+	// A block of another chain is priced by its timestamp
+	kline, err = client.GetETHUSDTAtBlock(context.Background(), 42161, 250000001, time.Unix(1727790000+500*12+5, 0))
+	assert.NoError(t, err)
+	assert.InDelta(t, 2500.0, kline.ClosePrice, 1e-9)
+	assert.Greater(t, blockReads.Load(), int32(0))
+}
+
+func TestPoolPriceUninitializedPool(t *testing.T) {
+	newClient := createPoolStub(t, func(blockTag string) string {
+		return "0"
+	}, nil)
+	client, closeStub := newClient()
+	defer closeStub()
+
+	_, err := client.GetETHUSDTAtBlock(context.Background(), 1, 500, time.Unix(1727790000+500*12, 0))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not initialized")
+}
+
+func TestSqrtPriceX96ToPrice(t *testing.T) {
+	// Equal decimals, sqrtPriceX96 of 2^96 is a price of 1
+	price, _ := sqrtPriceX96ToPrice(q96, 18, 18).Float64()
+	assert.Equal(t, 1.0, price)
+
+	// token0 with fewer decimals scales the raw price up
+	price, _ = sqrtPriceX96ToPrice(q96, 6, 18).Float64()
+	assert.InDelta(t, 1e-12, price, 1e-24)
+}
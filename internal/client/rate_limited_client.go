@@ -2,35 +2,57 @@ package client
 
 import (
 	"context"
+	"io"
 	"net/http"
 
 	"golang.org/x/time/rate"
 )
 
-// RateLimitedClient defines a base structure for a rate-limited API client
+// RateLimitedClient defines a base structure for a rate-limited API client.
+// Requests failing with transient errors are retried according to its retry policy,
+// every attempt waiting on the rate limiters.
 type RateLimitedClient struct {
-	httpClient   *http.Client
-	rateLimiters []*rate.Limiter // Allows multiple rate limiter (some API has both limits per day and per second)
+	httpClient *http.Client
+	retry      RetryPolicy
 }
 
-// NewRateLimitedClient initializes the base client with multiple rate limiters
-func NewRateLimitedClient(rateLimits ...*rate.Limiter) *RateLimitedClient {
+// NewRateLimitedClient initializes the base client of the source with its retry policy and multiple rate limiters
+// (some API has both limits per day and per second)
+func NewRateLimitedClient(source string, retry RetryPolicy, rateLimits ...*rate.Limiter) *RateLimitedClient {
+	retry = retry.withDefaults()
 	return &RateLimitedClient{
-		httpClient:   &http.Client{},
-		rateLimiters: rateLimits,
+		httpClient: newRetryingHTTPClient(source, retry, nil, rateLimits...),
+		retry:      retry,
 	}
 }
 
-// get sends a GET request with rate limits applied
-func (c *RateLimitedClient) get(url string) (*http.Response, error) {
-	ctx := context.Background()
+// UseTransport sends the requests of the client through transport instead of the network, e.g. a Cassette.
+// Rate limits and retries still apply.
+func (c *RateLimitedClient) UseTransport(transport http.RoundTripper) {
+	setBaseTransport(c.httpClient, transport)
+}
 
-	// Apply the rate limits
-	for _, limiter := range c.rateLimiters {
-		if err := limiter.Wait(ctx); err != nil {
-			return nil, err
-		}
+// get sends a GET request with rate limits applied, abandoned once the context is done.
+// Failures are a RateLimitedError or an UpstreamError once the retries are exhausted.
+func (c *RateLimitedClient) get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
 	}
+	return c.do(req)
+}
+
+// post sends a POST request with rate limits applied, abandoned once the context is done
+func (c *RateLimitedClient) post(ctx context.Context, url string, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	return c.do(req)
+}
 
-	return c.httpClient.Get(url)
+// do sends the request, each of its attempts once every rate limiter allows it
+func (c *RateLimitedClient) do(req *http.Request) (*http.Response, error) {
+	return c.httpClient.Do(req)
 }
@@ -1,6 +1,8 @@
 package client
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -17,11 +19,11 @@ func TestRateLimitedClientGet(t *testing.T) {
 	defer server.Close()
 
 	limiter := rate.NewLimiter(10, 1) // 10 requests per second, burst of 1
-	client := NewRateLimitedClient(limiter)
+	client := NewRateLimitedClient("test", DefaultRetryPolicy, limiter)
 
 	start := time.Now()
 	for i := 0; i < 3; i++ {
-		resp, err := client.get(server.URL)
+		resp, err := client.get(context.Background(), server.URL)
 		if err != nil {
 			t.Fatalf("Unexpected error: %v", err)
 		}
@@ -35,3 +37,31 @@ func TestRateLimitedClientGet(t *testing.T) {
 		t.Errorf("Requests were not rate-limited as expected")
 	}
 }
+
+func TestRateLimitedClientGet_Canceled(t *testing.T) {
+	// Mock server, hangs until the client gives up
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	client := NewRateLimitedClient("test", DefaultRetryPolicy, rate.NewLimiter(rate.Every(time.Hour), 1))
+
+	// In-flight requests are aborted
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := client.get(ctx, server.URL); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("Request was not aborted with its context")
+	}
+
+	// So are rate limiter waits, the limiter has no token left
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.get(ctx, server.URL); err == nil {
+		t.Errorf("Expected the canceled request to fail")
+	}
+}
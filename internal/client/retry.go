@@ -0,0 +1,248 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/winQe/uniswap-fee-tracker/internal/utils"
+	"golang.org/x/time/rate"
+)
+
+// RetryPolicy controls how requests failing with transient errors are retried.
+// Rate limited responses (429, or 418 once Binance bans the IP), 5xx responses, timeouts and network errors are retried.
+type RetryPolicy struct {
+	MaxAttempts int           // Attempts per request, including the first one
+	BaseDelay   time.Duration // Backoff before the first retry, doubling after every attempt
+	MaxDelay    time.Duration // Longest backoff, a longer Retry-After of the upstream is still honored
+	Timeout     time.Duration // Timeout of every attempt, reading the response included
+}
+
+// DefaultRetryPolicy retries a request 3 times, waiting about 0.5s, 1s then 2s in between
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    30 * time.Second,
+	Timeout:     30 * time.Second,
+}
+
+// NewRetryPolicy reads the retry policy of the HTTP_* config, unset fields fall back to DefaultRetryPolicy
+func NewRetryPolicy(config utils.Config) RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: config.HTTPMaxAttempts,
+		BaseDelay:   config.HTTPRetryBaseDelay,
+		MaxDelay:    config.HTTPRetryMaxDelay,
+		Timeout:     config.HTTPTimeout,
+	}.withDefaults()
+}
+
+// withDefaults fills the unset fields of the policy from DefaultRetryPolicy
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = DefaultRetryPolicy.MaxAttempts
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = DefaultRetryPolicy.BaseDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = DefaultRetryPolicy.MaxDelay
+	}
+	if p.Timeout <= 0 {
+		p.Timeout = DefaultRetryPolicy.Timeout
+	}
+	return p
+}
+
+// backoff returns the delay before the retry following the given attempt, starting at 1.
+// Half of the delay is jittered so that clients failing together don't retry in lockstep.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.MaxDelay
+	if attempt < 32 && p.BaseDelay<<(attempt-1) < p.MaxDelay {
+		delay = p.BaseDelay << (attempt - 1)
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// retryTransport is an http.RoundTripper retrying the requests failing with transient errors.
+// Responses are read within the attempt timeout and handed back buffered.
+type retryTransport struct {
+	base     http.RoundTripper
+	policy   RetryPolicy
+	source   string          // Upstream named in errors, e.g. etherscan or binance
+	breaker  *CircuitBreaker // Nil sends every request
+	limiters []*rate.Limiter // Waited on before every attempt, retries included
+}
+
+// retryLimiterKey is the context key of the limiter the retries of a request wait on
+type retryLimiterKey struct{}
+
+// withRetryLimiter makes the retries of the requests sent with the context wait on the limiter,
+// e.g. the limiter of the API key their first attempt was reserved with
+func withRetryLimiter(ctx context.Context, limiter *rate.Limiter) context.Context {
+	return context.WithValue(ctx, retryLimiterKey{}, limiter)
+}
+
+// newRetryingHTTPClient initializes an HTTP client retrying the requests to the source with the policy.
+// A non-nil breaker fails requests fast while the source keeps failing, every attempt waits on the rate limiters.
+func newRetryingHTTPClient(source string, policy RetryPolicy, breaker *CircuitBreaker, rateLimits ...*rate.Limiter) *http.Client {
+	return &http.Client{
+		Transport: &retryTransport{
+			base:     http.DefaultTransport,
+			policy:   policy.withDefaults(),
+			source:   source,
+			breaker:  breaker,
+			limiters: rateLimits,
+		},
+	}
+}
+
+// setBaseTransport sends the requests of the HTTP client through base, under its retries when it retries requests
+func setBaseTransport(httpClient *http.Client, base http.RoundTripper) {
+	if transport, ok := httpClient.Transport.(*retryTransport); ok {
+		transport.base = base
+		return
+	}
+	httpClient.Transport = base
+}
+
+// RoundTrip sends the request once the circuit breaker allows it, and records the outcome of its retries
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.breaker == nil {
+		return t.roundTrip(req)
+	}
+	if err := t.breaker.allow(); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.roundTrip(req)
+	t.breaker.record(req.Context(), err)
+	return resp, err
+}
+
+// roundTrip sends the request, retrying it with an exponential backoff while it fails with a transient error.
+// A request whose body can't be replayed is only sent once.
+func (t *retryTransport) roundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		if err := t.wait(req.Context(), attempt); err != nil {
+			return nil, err
+		}
+		resp, err := t.attempt(req, attempt)
+		if err == nil {
+			return resp, nil
+		}
+
+		retryAfter, retryable := retryDelay(err)
+		replayable := req.Body == nil || req.GetBody != nil
+		if !retryable || !replayable || attempt >= t.policy.MaxAttempts || req.Context().Err() != nil {
+			return nil, err
+		}
+
+		delay := max(t.policy.backoff(attempt), retryAfter)
+		log.Printf("Attempt %d of %s %s failed: %v, retrying in %v\n", attempt, req.Method, req.URL.Path, err, delay)
+		if sleepErr := sleep(req.Context(), delay); sleepErr != nil {
+			return nil, err
+		}
+	}
+}
+
+// wait blocks until the rate limiters allow the attempt or the context is done.
+// Retries also wait on the limiter of the request, its first attempt being limited by the caller.
+func (t *retryTransport) wait(ctx context.Context, attempt int) error {
+	limiters := t.limiters
+	if limiter, ok := ctx.Value(retryLimiterKey{}).(*rate.Limiter); ok && attempt > 1 {
+		limiters = append(slices.Clip(limiters), limiter)
+	}
+
+	for _, limiter := range limiters {
+		if err := limiter.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attempt sends the request once within the attempt timeout and classifies the failures
+func (t *retryTransport) attempt(req *http.Request, attempt int) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), t.policy.Timeout)
+	defer cancel()
+
+	attemptReq := req.Clone(ctx)
+	if attempt > 1 && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		attemptReq.Body = body
+	}
+
+	resp, err := t.base.RoundTrip(attemptReq)
+	var missErr *CassetteMissError
+	if errors.As(err, &missErr) {
+		// Nothing to retry, the request was never recorded
+		return nil, err
+	}
+	if err != nil {
+		return nil, &UpstreamError{Source: t.source, Err: err}
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, &UpstreamError{Source: t.source, Err: fmt.Errorf("error reading response: %w", err)}
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusTeapot:
+		return nil, &RateLimitedError{
+			Source:     t.source,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+			Reason:     resp.Status,
+		}
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return nil, &UpstreamError{Source: t.source, StatusCode: resp.StatusCode, Message: truncate(string(body), 200)}
+	}
+	return resp, nil
+}
+
+// retryDelay reports whether the error is transient, along with the delay the upstream asked to wait for
+func retryDelay(err error) (time.Duration, bool) {
+	var rateLimitedErr *RateLimitedError
+	if errors.As(err, &rateLimitedErr) {
+		return rateLimitedErr.RetryAfter, true
+	}
+	var upstreamErr *UpstreamError
+	if errors.As(err, &upstreamErr) {
+		return 0, true
+	}
+	return 0, false
+}
+
+// parseRetryAfter parses a Retry-After header, either a number of seconds or an HTTP date, zero when absent or invalid
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+// truncate shortens the text to at most n bytes, for error messages quoting a response
+func truncate(text string, n int) string {
+	if len(text) <= n {
+		return text
+	}
+	return text[:n] + "..."
+}
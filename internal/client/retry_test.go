@@ -0,0 +1,198 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+// fastRetryPolicy retries without slowing the tests down
+var fastRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond, Timeout: time.Second}
+
+// createFlakyServer answers with the given statuses in turn, then with OK and the body
+func createFlakyServer(statuses []int, body string) (*httptest.Server, *atomic.Int32) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := int(requests.Add(1))
+		if request <= len(statuses) {
+			w.WriteHeader(statuses[request-1])
+			return
+		}
+		fmt.Fprint(w, body)
+	}))
+	return server, &requests
+}
+
+func TestRetryTransport(t *testing.T) {
+	t.Run("retries transient failures", func(t *testing.T) {
+		server, requests := createFlakyServer([]int{http.StatusBadGateway, http.StatusTooManyRequests}, "ok")
+		defer server.Close()
+
+		client := newRetryingHTTPClient("test", fastRetryPolicy, nil)
+		resp, err := client.Get(server.URL)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, int32(3), requests.Load())
+	})
+
+	t.Run("typed error once the attempts are exhausted", func(t *testing.T) {
+		server, requests := createFlakyServer([]int{500, 500, 500, 500}, "ok")
+		defer server.Close()
+
+		client := newRetryingHTTPClient("test", fastRetryPolicy, nil)
+		_, err := client.Get(server.URL)
+
+		var upstreamErr *UpstreamError
+		assert.ErrorAs(t, err, &upstreamErr)
+		assert.Equal(t, http.StatusInternalServerError, upstreamErr.StatusCode)
+		assert.Equal(t, int32(3), requests.Load())
+	})
+
+	t.Run("client errors are not retried", func(t *testing.T) {
+		server, requests := createFlakyServer([]int{http.StatusNotFound}, "ok")
+		defer server.Close()
+
+		client := newRetryingHTTPClient("test", fastRetryPolicy, nil)
+		resp, err := client.Get(server.URL)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		assert.Equal(t, int32(1), requests.Load())
+	})
+
+	t.Run("honors Retry-After", func(t *testing.T) {
+		var requests atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if requests.Add(1) == 1 {
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			fmt.Fprint(w, "ok")
+		}))
+		defer server.Close()
+
+		client := newRetryingHTTPClient("test", fastRetryPolicy, nil)
+		start := time.Now()
+		_, err := client.Get(server.URL)
+		assert.NoError(t, err)
+		assert.GreaterOrEqual(t, time.Since(start), time.Second)
+	})
+
+	t.Run("attempts time out", func(t *testing.T) {
+		var requests atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if requests.Add(1) == 1 {
+				time.Sleep(200 * time.Millisecond)
+			}
+			fmt.Fprint(w, "ok")
+		}))
+		defer server.Close()
+
+		policy := fastRetryPolicy
+		policy.Timeout = 50 * time.Millisecond
+		client := newRetryingHTTPClient("test", policy, nil)
+		resp, err := client.Get(server.URL)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, int32(2), requests.Load())
+	})
+
+	t.Run("retries wait on the rate limiters", func(t *testing.T) {
+		server, requests := createFlakyServer([]int{503, 503}, "ok")
+		defer server.Close()
+
+		// 10 requests per second, the first attempt takes the only token
+		client := newRetryingHTTPClient("test", fastRetryPolicy, nil, rate.NewLimiter(10, 1))
+		start := time.Now()
+		resp, err := client.Get(server.URL)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, int32(3), requests.Load())
+		assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+	})
+
+	t.Run("retries wait on the limiter of the request", func(t *testing.T) {
+		server, requests := createFlakyServer([]int{503, 503}, "ok")
+		defer server.Close()
+
+		// The token was taken by the caller for the first attempt, e.g. when acquiring an API key
+		keyLimiter := rate.NewLimiter(10, 1)
+		keyLimiter.Allow()
+		req, _ := http.NewRequestWithContext(withRetryLimiter(context.Background(), keyLimiter), http.MethodGet, server.URL, nil)
+		start := time.Now()
+		resp, err := newRetryingHTTPClient("test", fastRetryPolicy, nil).Do(req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, int32(3), requests.Load())
+		assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+	})
+
+	t.Run("canceled requests are not retried", func(t *testing.T) {
+		server, requests := createFlakyServer([]int{503, 503, 503}, "ok")
+		defer server.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		_, err := newRetryingHTTPClient("test", fastRetryPolicy, nil).Do(req)
+		assert.True(t, errors.Is(err, context.Canceled))
+		assert.LessOrEqual(t, requests.Load(), int32(1))
+	})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, 5*time.Second, parseRetryAfter("5", now))
+	assert.Equal(t, 30*time.Second, parseRetryAfter("Tue, 01 Oct 2024 12:00:30 GMT", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("soon", now))
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	for attempt, ceiling := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 5: time.Second, 40: time.Second} {
+		delay := policy.backoff(attempt)
+		assert.GreaterOrEqual(t, delay, ceiling/2)
+		assert.LessOrEqual(t, delay, ceiling)
+	}
+}
+
+func TestGetETHUSDT_Retries(t *testing.T) {
+	server, requests := createFlakyServer([]int{http.StatusServiceUnavailable},
+		`[[1727790000000,"0","0","0","2612.34","0",1727790059999,"0",1,"0","0","0"]]`)
+	defer server.Close()
+
+	client, err := NewKlineClient(KlineConfig{BaseURL: server.URL, Interval: "1m", Retry: fastRetryPolicy})
+	assert.NoError(t, err)
+
+	kline, err := client.GetETHUSDT(context.Background(), time.Unix(1727790030, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, "2612.34", kline.ClosePriceDecimal)
+	assert.Equal(t, int32(2), requests.Load())
+}
+
+func TestGetETHUSDT_UpstreamError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"code":-1121,"msg":"Invalid symbol."}`)
+	}))
+	defer server.Close()
+
+	client, err := NewKlineClient(KlineConfig{BaseURL: server.URL, Retry: fastRetryPolicy})
+	assert.NoError(t, err)
+
+	_, err = client.GetETHUSDT(context.Background(), time.Unix(1727790030, 0))
+	var upstreamErr *UpstreamError
+	assert.ErrorAs(t, err, &upstreamErr)
+	assert.Contains(t, upstreamErr.Message, "Invalid symbol")
+}
@@ -0,0 +1,422 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/winQe/uniswap-fee-tracker/internal/types"
+	"golang.org/x/time/rate"
+)
+
+// maxLogBlockRange caps the block span of a single eth_getLogs call, most node providers reject wider ranges.
+const maxLogBlockRange = 2000
+
+// latestTxLookback is the number of blocks scanned per step when searching for the latest pool transaction.
+const latestTxLookback = 100
+
+// maxLatestTxLookups bounds how far back GetLatestTransaction searches before giving up.
+const maxLatestTxLookups = 50
+
+// maxCachedLogRanges bounds the block ranges whose Swap events are kept for the following pages of ListTransactions
+const maxCachedLogRanges = 16
+
+// RPCClient is the client for interacting with an Ethereum JSON-RPC node.
+// It finds pool activity through the Swap event logs of the pool instead of an indexer.
+type RPCClient struct {
+	*jsonRPCClient
+	poolAddresses []string
+
+	// Swap events of the last ranges listed, newest first, so every page of a range shares a single scan
+	logsMu    sync.Mutex
+	logRanges map[logRange]*rangeLogs
+	logOrder  []logRange
+}
+
+// logRange identifies the Swap events of a pool over a block range
+type logRange struct {
+	poolAddress string
+	fromBlock   uint64
+	toBlock     uint64
+}
+
+// rangeLogs are the Swap events of a range, fetched once by the first page asking for them
+type rangeLogs struct {
+	once sync.Once
+	logs []logDetails
+	err  error
+}
+
+// logFilter is the filter object of eth_getLogs.
+type logFilter struct {
+	FromBlock string   `json:"fromBlock"`
+	ToBlock   string   `json:"toBlock"`
+	Address   string   `json:"address"`
+	Topics    []string `json:"topics"`
+}
+
+// NewRPCClient initializes a client for the JSON-RPC node at rpcURL tracking the given pools.
+func NewRPCClient(rpcURL string, poolAddresses []string) *RPCClient {
+	// 10 requests per second fits the free tier of most hosted node providers
+	secondLimiter := rate.NewLimiter(10, 10)
+
+	return &RPCClient{
+		jsonRPCClient: newJSONRPCClient(rpcURL, secondLimiter),
+		poolAddresses: poolAddresses,
+	}
+}
+
+// GetTransactionReceipt fetches the transaction receipt and its block timestamp based on the txHash.
+// Unknown transactions fail with a TransactionNotFoundError,
+// and transactions that did not touch any tracked pool with a NotPoolTransactionError.
+func (r *RPCClient) GetTransactionReceipt(ctx context.Context, hash string) (*types.TransactionData, error) {
+	var receipt receiptDetails
+	if err := r.call(ctx, "eth_getTransactionReceipt", &receipt, hash); err != nil {
+		if errors.Is(err, errNoResult) {
+			return nil, &TransactionNotFoundError{Hash: hash}
+		}
+		return nil, err
+	}
+
+	blockNumber, err := hexutil.DecodeUint64(receipt.BlockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("error converting block number: %v", err)
+	}
+
+	block, err := r.getBlock(ctx, blockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching block %d: %v", blockNumber, err)
+	}
+
+	tx, err := r.getTransaction(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertReceiptToTransactionData(receipt, *block, tx, r.poolAddresses)
+}
+
+// GetLatestTransaction fetches the latest transaction from the given pool.
+// It walks back from the chain head until it finds a block range with a Swap event.
+func (r *RPCClient) GetLatestTransaction(ctx context.Context, poolAddress string) (*types.TransactionData, error) {
+	head, err := r.getBlockNumber(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	toBlock := head
+	for i := 0; i < maxLatestTxLookups; i++ {
+		fromBlock := uint64(0)
+		if toBlock >= latestTxLookback {
+			fromBlock = toBlock - latestTxLookback + 1
+		}
+
+		logs, err := r.getSwapLogs(ctx, poolAddress, fromBlock, toBlock)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching the latest transaction: %v", err)
+		}
+
+		if len(logs) > 0 {
+			sortLogsDesc(logs)
+			txData, err := r.GetTransactionReceipt(ctx, logs[0].TransactionHash)
+			if err != nil {
+				return nil, err
+			}
+			txData.PoolAddress = strings.ToLower(poolAddress)
+			return txData, nil
+		}
+
+		if fromBlock == 0 {
+			break
+		}
+		toBlock = fromBlock - 1
+	}
+
+	return nil, fmt.Errorf("error fetching the latest transaction: no swap found in the last %d blocks", maxLatestTxLookups*latestTxLookback)
+}
+
+// ListTransactions queries the Swap events of the given pool based on optional parameters, newest first.
+// offset and page paginate the results the same way Etherscan does, the events of the range are only scanned for its first page.
+// startBlock is required, scanning the node from genesis would take hundreds of thousands of calls.
+func (r *RPCClient) ListTransactions(ctx context.Context, poolAddress string, offset *int, startBlock *uint64, endBlock *uint64, page *int) ([]types.TransactionData, error) {
+	if startBlock == nil {
+		return nil, fmt.Errorf("a start block is required to list the transactions of pool %s", poolAddress)
+	}
+	fromBlock := *startBlock
+
+	var toBlock uint64
+	if endBlock != nil {
+		toBlock = *endBlock
+	} else {
+		head, err := r.getBlockNumber(ctx)
+		if err != nil {
+			return nil, err
+		}
+		toBlock = head
+	}
+
+	logs, err := r.getRangeLogs(ctx, poolAddress, fromBlock, toBlock)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply the pagination
+	if offset != nil && *offset > 0 {
+		pageNumber := 1
+		if page != nil && *page > 0 {
+			pageNumber = *page
+		}
+
+		start := (pageNumber - 1) * *offset
+		if start >= len(logs) {
+			return []types.TransactionData{}, nil
+		}
+		end := start + *offset
+		if end > len(logs) {
+			end = len(logs)
+		}
+		logs = logs[start:end]
+	}
+
+	// Receipts and blocks are shared between logs of the same transaction or block
+	receipts := make(map[string]*receiptDetails)
+	txs := make(map[string]*transactionDetails)
+	blocks := make(map[uint64]*blockDetails)
+
+	var transactions []types.TransactionData
+	for _, log := range logs {
+		txData, err := r.resolveLog(ctx, log, receipts, txs, blocks)
+		if err != nil {
+			// Log the error and skip the transaction
+			fmt.Printf("Error converting transaction data: %v\n", err)
+			continue
+		}
+		txData.PoolAddress = strings.ToLower(poolAddress)
+		transactions = append(transactions, *txData)
+	}
+
+	return transactions, nil
+}
+
+// resolveLog fetches the receipt, transaction and block of a log to build its TransactionData
+func (r *RPCClient) resolveLog(ctx context.Context, log logDetails, receipts map[string]*receiptDetails, txs map[string]*transactionDetails, blocks map[uint64]*blockDetails) (*types.TransactionData, error) {
+	receipt, ok := receipts[log.TransactionHash]
+	if !ok {
+		receipt = &receiptDetails{}
+		if err := r.call(ctx, "eth_getTransactionReceipt", receipt, log.TransactionHash); err != nil {
+			return nil, err
+		}
+		receipts[log.TransactionHash] = receipt
+	}
+
+	tx, ok := txs[log.TransactionHash]
+	if !ok {
+		var err error
+		tx, err = r.getTransaction(ctx, log.TransactionHash)
+		if err != nil {
+			return nil, err
+		}
+		txs[log.TransactionHash] = tx
+	}
+
+	blockNumber, err := hexutil.DecodeUint64(log.BlockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("error converting block number: %v", err)
+	}
+
+	block, ok := blocks[blockNumber]
+	if !ok {
+		block, err = r.getBlock(ctx, blockNumber)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching block %d: %v", blockNumber, err)
+		}
+		blocks[blockNumber] = block
+	}
+
+	return convertReceiptToTransactionData(*receipt, *block, tx, r.poolAddresses)
+}
+
+// getTransaction fetches the transaction for the fee caps it declared
+func (r *RPCClient) getTransaction(ctx context.Context, hash string) (*transactionDetails, error) {
+	var tx transactionDetails
+	if err := r.call(ctx, "eth_getTransactionByHash", &tx, hash); err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+// getRangeLogs returns the Swap events of the pool in [fromBlock, toBlock], newest first.
+// They are fetched once and kept for the other pages of the range, a failed fetch is retried by the next page.
+func (r *RPCClient) getRangeLogs(ctx context.Context, poolAddress string, fromBlock uint64, toBlock uint64) ([]logDetails, error) {
+	key := logRange{poolAddress: strings.ToLower(poolAddress), fromBlock: fromBlock, toBlock: toBlock}
+
+	r.logsMu.Lock()
+	entry, ok := r.logRanges[key]
+	if !ok {
+		if r.logRanges == nil {
+			r.logRanges = make(map[logRange]*rangeLogs)
+		}
+		entry = &rangeLogs{}
+		r.logRanges[key] = entry
+		r.logOrder = append(r.logOrder, key)
+		// Forget the oldest range
+		if len(r.logOrder) > maxCachedLogRanges {
+			delete(r.logRanges, r.logOrder[0])
+			r.logOrder = r.logOrder[1:]
+		}
+	}
+	r.logsMu.Unlock()
+
+	entry.once.Do(func() {
+		entry.logs, entry.err = r.getSwapLogs(ctx, poolAddress, fromBlock, toBlock)
+		sortLogsDesc(entry.logs)
+	})
+
+	if entry.err != nil {
+		r.logsMu.Lock()
+		if r.logRanges[key] == entry {
+			delete(r.logRanges, key)
+			r.logOrder = slices.DeleteFunc(r.logOrder, func(k logRange) bool { return k == key })
+		}
+		r.logsMu.Unlock()
+		return nil, entry.err
+	}
+	return entry.logs, nil
+}
+
+// getSwapLogs fetches the Swap events of the pool in [fromBlock, toBlock], split into ranges the node accepts
+func (r *RPCClient) getSwapLogs(ctx context.Context, poolAddress string, fromBlock uint64, toBlock uint64) ([]logDetails, error) {
+	var logs []logDetails
+	for start := fromBlock; start <= toBlock; start += maxLogBlockRange {
+		end := start + maxLogBlockRange - 1
+		if end > toBlock {
+			end = toBlock
+		}
+
+		filter := logFilter{
+			FromBlock: hexutil.EncodeUint64(start),
+			ToBlock:   hexutil.EncodeUint64(end),
+			Address:   poolAddress,
+			Topics:    []string{swapEventTopic},
+		}
+
+		var chunk []logDetails
+		if err := r.call(ctx, "eth_getLogs", &chunk, filter); err != nil {
+			return nil, err
+		}
+		logs = append(logs, chunk...)
+	}
+	return logs, nil
+}
+
+// sortLogsDesc orders logs from the newest to the oldest
+func sortLogsDesc(logs []logDetails) {
+	sort.SliceStable(logs, func(i, j int) bool {
+		blockI, _ := hexutil.DecodeUint64(logs[i].BlockNumber)
+		blockJ, _ := hexutil.DecodeUint64(logs[j].BlockNumber)
+		if blockI != blockJ {
+			return blockI > blockJ
+		}
+		indexI, _ := hexutil.DecodeUint64(logs[i].LogIndex)
+		indexJ, _ := hexutil.DecodeUint64(logs[j].LogIndex)
+		return indexI > indexJ
+	})
+}
+
+// convertReceiptToTransactionData converts a receipt, its transaction and its block to TransactionData, decoding the Swap events of the tracked pools.
+// The transaction is tagged with the pool of its first decoded swap, or of its first log when it touched a pool without swapping.
+// Transactions without any log of a tracked pool are rejected with a NotPoolTransactionError.
+func convertReceiptToTransactionData(receipt receiptDetails, block blockDetails, tx *transactionDetails, poolAddresses []string) (*types.TransactionData, error) {
+	blockNumber, err := hexutil.DecodeUint64(receipt.BlockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("error converting block number: %v", err)
+	}
+
+	gasUsed, err := hexutil.DecodeUint64(receipt.GasUsed)
+	if err != nil {
+		return nil, fmt.Errorf("error converting gas used: %v", err)
+	}
+
+	gasPriceWei, err := hexutil.DecodeBig(receipt.EffectiveGasPrice)
+	if err != nil {
+		return nil, fmt.Errorf("error converting gas price %v", err)
+	}
+
+	blockTime, err := hexutil.DecodeUint64(block.Timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("error converting timestamp: %v", err)
+	}
+
+	// Receipts before Byzantium carry a state root instead of a status.
+	// Failed transactions emit no logs, so one that touched a tracked pool succeeded.
+	status := types.TxStatusSuccess
+	if receipt.Status != "" {
+		status, err = hexutil.DecodeUint64(receipt.Status)
+		if err != nil {
+			return nil, fmt.Errorf("error converting status: %v", err)
+		}
+	}
+
+	logs, poolAddress, err := convertLogs(receipt.Logs, poolAddresses)
+	if err != nil {
+		return nil, err
+	}
+
+	swaps := decodeSwapLogs(receipt.Logs, poolAddresses)
+	if len(swaps) > 0 {
+		poolAddress = swaps[0].PoolAddress
+	}
+	if poolAddress == "" {
+		return nil, &NotPoolTransactionError{Hash: receipt.Hash}
+	}
+
+	txData := &types.TransactionData{
+		BlockNumber: blockNumber,
+		Hash:        receipt.Hash,
+		Status:      status,
+		From:        strings.ToLower(receipt.From),
+		To:          strings.ToLower(receipt.To),
+		GasUsed:     gasUsed,
+		GasPriceWei: gasPriceWei,
+		Timestamp:   time.Unix(int64(blockTime), 0),
+		PoolAddress: poolAddress,
+		Swaps:       swaps,
+		Logs:        logs,
+	}
+
+	if err := applyFeeDetails(txData, block.BaseFeePerGas, tx); err != nil {
+		return nil, err
+	}
+	return txData, nil
+}
+
+// convertLogs converts the logs of a receipt, returning the first tracked pool among their emitters
+func convertLogs(logs []logDetails, poolAddresses []string) ([]types.LogEvent, string, error) {
+	var events []types.LogEvent
+	var poolAddress string
+	for _, log := range logs {
+		logIndex, err := decodeHexUint64(log.LogIndex)
+		if err != nil {
+			return nil, "", fmt.Errorf("error converting log index: %v", err)
+		}
+
+		address := strings.ToLower(log.Address)
+		if poolAddress == "" && isTrackedPool(address, poolAddresses) {
+			poolAddress = address
+		}
+
+		events = append(events, types.LogEvent{
+			LogIndex: uint(logIndex),
+			Address:  address,
+			Topics:   log.Topics,
+			Data:     log.Data,
+		})
+	}
+	return events, poolAddress, nil
+}
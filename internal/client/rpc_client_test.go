@@ -0,0 +1,325 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/winQe/uniswap-fee-tracker/internal/types"
+	"golang.org/x/time/rate"
+)
+
+// rpcHandler answers a single JSON-RPC method given its raw params.
+// Returning an *rpcError answers with a JSON-RPC error instead of a result.
+type rpcHandler func(params []json.RawMessage) interface{}
+
+// createRPCStub initializes a local JSON-RPC node that dispatches calls to the given handlers.
+func createRPCStub(t *testing.T, handlers map[string]rpcHandler) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     uint64            `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("invalid JSON-RPC request: %v", err)
+			return
+		}
+
+		handler, ok := handlers[req.Method]
+		if !ok {
+			t.Errorf("unexpected JSON-RPC method %s", req.Method)
+			return
+		}
+
+		response := map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      req.ID,
+		}
+		result := handler(req.Params)
+		if rpcErr, ok := result.(*rpcError); ok {
+			response["error"] = rpcErr
+		} else {
+			response["result"] = result
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}))
+}
+
+// initializeJSONRPCClient sets up the base JSON-RPC client against the stub node without rate limits.
+func initializeJSONRPCClient(stub *httptest.Server) *jsonRPCClient {
+	rpcClient := newJSONRPCClient(stub.URL, rate.NewLimiter(rate.Inf, 1))
+	rpcClient.httpClient = stub.Client()
+	return rpcClient
+}
+
+// initializeRPCClient sets up the RPCClient against the stub node.
+func initializeRPCClient(stub *httptest.Server, poolAddresses ...string) *RPCClient {
+	return &RPCClient{
+		jsonRPCClient: initializeJSONRPCClient(stub),
+		poolAddresses: poolAddresses,
+	}
+}
+
+// stubBlock returns a block header whose timestamp grows 12 seconds per block from 1727790000, with a base fee of 0.5 gwei.
+func stubBlock(params []json.RawMessage) interface{} {
+	var number string
+	json.Unmarshal(params[0], &number)
+	blockNumber, _ := hexutil.DecodeUint64(number)
+
+	return map[string]string{
+		"number":        number,
+		"hash":          hexutil.EncodeUint64(blockNumber + 0xabc),
+		"parentHash":    hexutil.EncodeUint64(blockNumber - 1 + 0xabc),
+		"timestamp":     hexutil.EncodeUint64(1727790000 + blockNumber*12),
+		"baseFeePerGas": "0x1dcd6500",
+	}
+}
+
+func TestRPCGetTransactionReceipt(t *testing.T) {
+	txHash := "0x003c8127556d023655168023988401be7cc46570be7713d42e8a9558c2ab1ae6"
+
+	stub := createRPCStub(t, map[string]rpcHandler{
+		"eth_getTransactionReceipt": func(params []json.RawMessage) interface{} {
+			var hash string
+			json.Unmarshal(params[0], &hash)
+			assert.Equal(t, txHash, hash)
+
+			return map[string]interface{}{
+				"blockNumber":       "0x10",
+				"transactionHash":   txHash,
+				"gasUsed":           "0x1d9bc",
+				"effectiveGasPrice": "0x16b86486ae",
+				"status":            "0x1",
+				"logs": []map[string]interface{}{
+					// WETH Transfer, not a swap
+					{
+						"address":         "0xc02aaa39b223fe8d0a0e5c4f27ead9083c756cc2",
+						"topics":          []string{"0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"},
+						"data":            "0x",
+						"transactionHash": txHash,
+						"logIndex":        "0x3",
+					},
+					// Swap of the tracked pool
+					{
+						"address": "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640",
+						"topics": []string{
+							swapEventTopic,
+							"0x00000000000000000000000068d3a973e7272eb388022a5c6518d9b2a2e66fbf",
+							"0x0000000000000000000000003d9aae030b9661e3605b3acb5d0385ede221a0cc",
+						},
+						"data":            "0x0000000000000000000000000000000000000000000000000000000047868c00fffffffffffffffffffffffffffffffffffffffffffffffff984762d7360df9e0000000000000000000000000000000000004d1d7b09b14bd0ff10b93e38f34e000000000000000000000000000000000000000000000000b1d2a6bcb79cb5a500000000000000000000000000000000000000000000000000000000000304b9",
+						"transactionHash": txHash,
+						"logIndex":        "0x4",
+					},
+				},
+			}
+		},
+		"eth_getTransactionByHash": func(params []json.RawMessage) interface{} {
+			return map[string]string{
+				"hash":                 txHash,
+				"type":                 "0x2",
+				"gasPrice":             "0x16b86486ae",
+				"maxFeePerGas":         "0x1bf08eb000",
+				"maxPriorityFeePerGas": "0x77359400",
+			}
+		},
+		"eth_getBlockByNumber": stubBlock,
+	})
+	defer stub.Close()
+
+	client := initializeRPCClient(stub, "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640")
+
+	receipt, err := client.GetTransactionReceipt(context.Background(), txHash)
+	assert.NoError(t, err, "Expected no error from GetTransactionReceipt")
+
+	expectedGasPriceWei, _ := new(big.Int).SetString("97582876334", 10)
+	assert.Equal(t, uint64(16), receipt.BlockNumber)
+	assert.Equal(t, txHash, receipt.Hash)
+	assert.Equal(t, uint64(121276), receipt.GasUsed)
+	assert.Equal(t, expectedGasPriceWei, receipt.GasPriceWei)
+	assert.Equal(t, time.Unix(1727790000+16*12, 0), receipt.Timestamp)
+	assert.Equal(t, "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640", receipt.PoolAddress)
+	assert.Equal(t, types.TxStatusSuccess, receipt.Status)
+	assert.Len(t, receipt.Logs, 2)
+
+	// Fee caps of the transaction and base fee of its block
+	assert.Equal(t, big.NewInt(500000000), receipt.BaseFeePerGasWei)
+	assert.Equal(t, big.NewInt(120000000000), receipt.MaxFeePerGasWei)
+	assert.Equal(t, big.NewInt(2000000000), receipt.MaxPriorityFeePerGasWei)
+
+	// Only the Swap of the tracked pool is decoded
+	assert.Len(t, receipt.Swaps, 1)
+	swap := receipt.Swaps[0]
+	assert.Equal(t, uint(4), swap.LogIndex)
+	assert.Equal(t, "0x68d3a973e7272eb388022a5c6518d9b2a2e66fbf", swap.Sender)
+	assert.Equal(t, "0x3d9aae030b9661e3605b3acb5d0385ede221a0cc", swap.Recipient)
+	assert.Equal(t, big.NewInt(1200000000), swap.Amount0)
+	assert.Equal(t, big.NewInt(-467118523758354530), swap.Amount1)
+	assert.Equal(t, "1564081234567890123456789012345678", swap.SqrtPriceX96.String())
+	assert.Equal(t, "12813487219287045541", swap.Liquidity.String())
+	assert.Equal(t, int32(197817), swap.Tick)
+}
+
+func TestRPCListTransactions(t *testing.T) {
+	poolAddress := "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640"
+
+	logs := []map[string]interface{}{
+		{"address": poolAddress, "topics": []string{swapEventTopic}, "blockNumber": "0x64", "transactionHash": "0xaa", "logIndex": "0x1"},
+		{"address": poolAddress, "topics": []string{swapEventTopic}, "blockNumber": "0x65", "transactionHash": "0xbb", "logIndex": "0x0"},
+		{"address": poolAddress, "topics": []string{swapEventTopic}, "blockNumber": "0x65", "transactionHash": "0xcc", "logIndex": "0x3"},
+	}
+
+	var logScans atomic.Int32
+	stub := createRPCStub(t, map[string]rpcHandler{
+		"eth_getLogs": func(params []json.RawMessage) interface{} {
+			logScans.Add(1)
+			var filter logFilter
+			json.Unmarshal(params[0], &filter)
+			assert.Equal(t, "0x64", filter.FromBlock)
+			assert.Equal(t, "0x65", filter.ToBlock)
+			assert.Equal(t, poolAddress, filter.Address)
+			assert.Equal(t, []string{swapEventTopic}, filter.Topics)
+			return logs
+		},
+		"eth_getTransactionReceipt": func(params []json.RawMessage) interface{} {
+			var hash string
+			json.Unmarshal(params[0], &hash)
+
+			blockNumber := "0x65"
+			if hash == "0xaa" {
+				blockNumber = "0x64"
+			}
+			return map[string]interface{}{
+				"blockNumber":       blockNumber,
+				"transactionHash":   hash,
+				"gasUsed":           "0x5208",
+				"effectiveGasPrice": "0x3b9aca00",
+				"status":            "0x1",
+				// The Swap data is irrelevant here, a log of the pool marks the transaction as a pool one
+				"logs": []map[string]interface{}{
+					{"address": poolAddress, "topics": []string{}, "data": "0x", "transactionHash": hash, "logIndex": "0x0"},
+				},
+			}
+		},
+		"eth_getTransactionByHash": func(params []json.RawMessage) interface{} {
+			var hash string
+			json.Unmarshal(params[0], &hash)
+
+			// Legacy transactions only declare a gas price
+			return map[string]string{"hash": hash, "type": "0x0", "gasPrice": "0x3b9aca00"}
+		},
+		"eth_getBlockByNumber": stubBlock,
+	})
+	defer stub.Close()
+
+	client := initializeRPCClient(stub, poolAddress)
+
+	offset := 2
+	startBlock := uint64(100)
+	endBlock := uint64(101)
+
+	// First page holds the two newest swaps
+	page := 1
+	transactions, err := client.ListTransactions(context.Background(), poolAddress, &offset, &startBlock, &endBlock, &page)
+	assert.NoError(t, err, "Expected no error from ListTransactions")
+	assert.Len(t, transactions, 2)
+	assert.Equal(t, "0xcc", transactions[0].Hash)
+	assert.Equal(t, "0xbb", transactions[1].Hash)
+	assert.Equal(t, uint64(101), transactions[0].BlockNumber)
+	assert.Equal(t, uint64(21000), transactions[0].GasUsed)
+	assert.Equal(t, big.NewInt(1000000000), transactions[0].GasPriceWei)
+	assert.Equal(t, time.Unix(1727790000+101*12, 0), transactions[0].Timestamp)
+	assert.Equal(t, poolAddress, transactions[0].PoolAddress)
+	assert.Equal(t, big.NewInt(500000000), transactions[0].BaseFeePerGasWei)
+	assert.Nil(t, transactions[0].MaxFeePerGasWei)
+	assert.Nil(t, transactions[0].MaxPriorityFeePerGasWei)
+
+	// Second page holds the remaining swap
+	page = 2
+	transactions, err = client.ListTransactions(context.Background(), poolAddress, &offset, &startBlock, &endBlock, &page)
+	assert.NoError(t, err)
+	assert.Len(t, transactions, 1)
+	assert.Equal(t, "0xaa", transactions[0].Hash)
+	assert.Equal(t, uint64(100), transactions[0].BlockNumber)
+
+	// Pages past the end are empty
+	page = 3
+	transactions, err = client.ListTransactions(context.Background(), poolAddress, &offset, &startBlock, &endBlock, &page)
+	assert.NoError(t, err)
+	assert.Empty(t, transactions)
+
+	// The range was only scanned for its first page
+	assert.Equal(t, int32(1), logScans.Load())
+
+	// Listing from genesis is refused
+	_, err = client.ListTransactions(context.Background(), poolAddress, &offset, nil, &endBlock, &page)
+	assert.ErrorContains(t, err, "start block is required")
+}
+
+func TestRPCGetBlockNumberByTimestamp(t *testing.T) {
+	stub := createRPCStub(t, map[string]rpcHandler{
+		"eth_blockNumber": func(params []json.RawMessage) interface{} {
+			return "0x3e8" // 1000
+		},
+		"eth_getBlockByNumber": stubBlock,
+	})
+	defer stub.Close()
+
+	client := initializeRPCClient(stub)
+
+	// Block 500 is mined exactly at this timestamp
+	exact := time.Unix(1727790000+500*12, 0)
+	blockNumber, err := client.GetBlockNumberByTimestamp(context.Background(), exact, true)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(500), blockNumber)
+
+	blockNumber, err = client.GetBlockNumberByTimestamp(context.Background(), exact, false)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(500), blockNumber)
+
+	// Between block 500 and 501
+	between := exact.Add(5 * time.Second)
+	blockNumber, err = client.GetBlockNumberByTimestamp(context.Background(), between, true)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(500), blockNumber)
+
+	blockNumber, err = client.GetBlockNumberByTimestamp(context.Background(), between, false)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(501), blockNumber)
+
+	// After the chain head there is no block to return
+	_, err = client.GetBlockNumberByTimestamp(context.Background(), time.Unix(1727790000+2000*12, 0), false)
+	assert.Error(t, err)
+}
+
+func TestRPCGetBlockHeader(t *testing.T) {
+	stub := createRPCStub(t, map[string]rpcHandler{
+		"eth_blockNumber": func(params []json.RawMessage) interface{} {
+			return "0x3e8" // 1000
+		},
+		"eth_getBlockByNumber": stubBlock,
+	})
+	defer stub.Close()
+
+	client := initializeRPCClient(stub)
+
+	head, err := client.GetBlockNumber(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1000), head)
+
+	header, err := client.GetBlockHeader(context.Background(), 500)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(500), header.Number)
+	assert.Equal(t, hexutil.EncodeUint64(500+0xabc), header.Hash)
+	assert.Equal(t, hexutil.EncodeUint64(499+0xabc), header.ParentHash)
+}
@@ -0,0 +1,124 @@
+package client
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/winQe/uniswap-fee-tracker/internal/types"
+)
+
+// swapEventTopic is keccak256("Swap(address,address,int256,int256,uint160,uint128,int24)"),
+// the topic emitted by a Uniswap V3 pool on every swap.
+const swapEventTopic = "0xc42079f94a6350d7e6235f29174924f928cc2ac818eb64fed8004e115fbcca67"
+
+// swapEventDataLength is the size of the non-indexed Swap fields (amount0, amount1, sqrtPriceX96, liquidity, tick), one word each
+const swapEventDataLength = 5 * 32
+
+// logDetails holds the core details of an event log.
+type logDetails struct {
+	Address         string   `json:"address"`
+	Topics          []string `json:"topics"`
+	Data            string   `json:"data"`
+	BlockNumber     string   `json:"blockNumber"`
+	TransactionHash string   `json:"transactionHash"`
+	LogIndex        string   `json:"logIndex"`
+}
+
+// decodeSwapLog decodes a Uniswap V3 Swap event log
+func decodeSwapLog(log logDetails) (*types.SwapEvent, error) {
+	if len(log.Topics) != 3 || !strings.EqualFold(log.Topics[0], swapEventTopic) {
+		return nil, fmt.Errorf("log is not a Swap event")
+	}
+
+	data, err := hexutil.Decode(log.Data)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding Swap data: %v", err)
+	}
+	if len(data) != swapEventDataLength {
+		return nil, fmt.Errorf("unexpected Swap data length %d", len(data))
+	}
+
+	logIndex, err := decodeHexUint64(log.LogIndex)
+	if err != nil {
+		return nil, fmt.Errorf("error converting log index: %v", err)
+	}
+
+	return &types.SwapEvent{
+		LogIndex:     uint(logIndex),
+		PoolAddress:  strings.ToLower(log.Address),
+		Sender:       topicToAddress(log.Topics[1]),
+		Recipient:    topicToAddress(log.Topics[2]),
+		Amount0:      decodeInt256(data[0:32]),
+		Amount1:      decodeInt256(data[32:64]),
+		SqrtPriceX96: new(big.Int).SetBytes(data[64:96]),
+		Liquidity:    new(big.Int).SetBytes(data[96:128]),
+		Tick:         int32(decodeInt256(data[128:160]).Int64()),
+	}, nil
+}
+
+// decodeSwapLogs decodes the Swap events emitted by the tracked pools, skipping every other log
+func decodeSwapLogs(logs []logDetails, poolAddresses []string) []types.SwapEvent {
+	var swaps []types.SwapEvent
+	for _, log := range logs {
+		if !isTrackedPool(log.Address, poolAddresses) || len(log.Topics) == 0 || !strings.EqualFold(log.Topics[0], swapEventTopic) {
+			continue
+		}
+
+		swap, err := decodeSwapLog(log)
+		if err != nil {
+			// Log the error and skip the swap
+			fmt.Printf("Error decoding swap in transaction %s: %v\n", log.TransactionHash, err)
+			continue
+		}
+		swaps = append(swaps, *swap)
+	}
+	return swaps
+}
+
+// groupSwapsByTransaction decodes the Swap events of the tracked pools and indexes them by lowercase transaction hash
+func groupSwapsByTransaction(logs []logDetails, poolAddresses []string) map[string][]types.SwapEvent {
+	swaps := make(map[string][]types.SwapEvent)
+	for _, log := range logs {
+		hash := strings.ToLower(log.TransactionHash)
+		swaps[hash] = append(swaps[hash], decodeSwapLogs([]logDetails{log}, poolAddresses)...)
+	}
+	return swaps
+}
+
+// isTrackedPool reports whether the address is one of the tracked pools
+func isTrackedPool(address string, poolAddresses []string) bool {
+	for _, poolAddress := range poolAddresses {
+		if strings.EqualFold(address, poolAddress) {
+			return true
+		}
+	}
+	return false
+}
+
+// topicToAddress extracts the address from a 32-byte indexed topic
+func topicToAddress(topic string) string {
+	topic = strings.TrimPrefix(strings.ToLower(topic), "0x")
+	if len(topic) < 40 {
+		return "0x" + topic
+	}
+	return "0x" + topic[len(topic)-40:]
+}
+
+// decodeInt256 decodes a big-endian two's complement 256-bit word
+func decodeInt256(word []byte) *big.Int {
+	value := new(big.Int).SetBytes(word)
+	if len(word) > 0 && word[0]&0x80 != 0 {
+		value.Sub(value, new(big.Int).Lsh(big.NewInt(1), uint(len(word)*8)))
+	}
+	return value
+}
+
+// decodeHexUint64 decodes a hex quantity, treating the bare "0x" Etherscan returns for zero as 0
+func decodeHexUint64(value string) (uint64, error) {
+	if value == "0x" {
+		return 0, nil
+	}
+	return hexutil.DecodeUint64(value)
+}
@@ -0,0 +1,13 @@
+CREATE TABLE swaps (
+    transaction_hash TEXT NOT NULL REFERENCES transactions (transaction_hash) ON DELETE CASCADE,
+    log_index        INTEGER NOT NULL,
+    pool_address     TEXT NOT NULL,
+    sender           TEXT NOT NULL,
+    recipient        TEXT NOT NULL,
+    amount0          NUMERIC(78, 0) NOT NULL, -- Signed token0 delta of the pool
+    amount1          NUMERIC(78, 0) NOT NULL, -- Signed token1 delta of the pool
+    sqrt_price_x96   NUMERIC(78, 0) NOT NULL, -- Pool price after the swap as Q64.96
+    liquidity        NUMERIC(78, 0) NOT NULL,
+    tick             INTEGER NOT NULL,
+    PRIMARY KEY (transaction_hash, log_index)
+);
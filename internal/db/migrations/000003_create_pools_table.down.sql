@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_transactions_pool_address_timestamp;
+ALTER TABLE transactions DROP COLUMN IF EXISTS pool_address;
+DROP TABLE IF EXISTS pools;
@@ -0,0 +1,11 @@
+CREATE TABLE pools (
+    address  TEXT PRIMARY KEY,
+    chain_id BIGINT NOT NULL,
+    token0   TEXT NOT NULL,
+    token1   TEXT NOT NULL,
+    fee_tier INTEGER NOT NULL -- In hundredths of a bip, 500 = 0.05%
+);
+
+ALTER TABLE transactions ADD COLUMN pool_address TEXT REFERENCES pools (address);
+
+CREATE INDEX idx_transactions_pool_address_timestamp ON transactions (pool_address, timestamp);
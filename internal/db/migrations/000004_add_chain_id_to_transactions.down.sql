@@ -0,0 +1,8 @@
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_chain_id_pool_address_fkey;
+ALTER TABLE pools DROP CONSTRAINT IF EXISTS pools_pkey;
+ALTER TABLE pools ADD PRIMARY KEY (address);
+ALTER TABLE transactions ADD CONSTRAINT transactions_pool_address_fkey
+    FOREIGN KEY (pool_address) REFERENCES pools (address);
+
+DROP INDEX IF EXISTS idx_transactions_chain_id_timestamp;
+ALTER TABLE transactions DROP COLUMN IF EXISTS chain_id;
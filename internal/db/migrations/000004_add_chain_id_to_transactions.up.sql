@@ -0,0 +1,12 @@
+-- Transactions recorded before multi-chain support all come from Ethereum mainnet
+ALTER TABLE transactions ADD COLUMN chain_id BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE transactions ALTER COLUMN chain_id DROP DEFAULT;
+
+CREATE INDEX idx_transactions_chain_id_timestamp ON transactions (chain_id, timestamp);
+
+-- Pools deployed with CREATE2 share their address across chains, so a pool is keyed by its chain and address
+ALTER TABLE transactions DROP CONSTRAINT transactions_pool_address_fkey;
+ALTER TABLE pools DROP CONSTRAINT pools_pkey;
+ALTER TABLE pools ADD PRIMARY KEY (chain_id, address);
+ALTER TABLE transactions ADD CONSTRAINT transactions_chain_id_pool_address_fkey
+    FOREIGN KEY (chain_id, pool_address) REFERENCES pools (chain_id, address);
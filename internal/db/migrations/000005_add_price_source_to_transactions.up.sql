@@ -0,0 +1,2 @@
+-- Source of eth_usdt_price, e.g. binance or pool:<address>. Empty for rows recorded before it was tracked.
+ALTER TABLE transactions ADD COLUMN price_source TEXT;
@@ -0,0 +1,2 @@
+ALTER TABLE transactions DROP COLUMN IF EXISTS price_suspect;
+ALTER TABLE transactions DROP COLUMN IF EXISTS price_spread;
@@ -0,0 +1,3 @@
+-- Relative spread between the aggregated price sources and whether they disagree beyond the divergence threshold
+ALTER TABLE transactions ADD COLUMN price_spread DOUBLE PRECISION;
+ALTER TABLE transactions ADD COLUMN price_suspect BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,7 @@
+ALTER TABLE transactions DROP COLUMN IF EXISTS transaction_fee_wei;
+
+ALTER TABLE transactions
+    ALTER COLUMN gas_price_wei TYPE BIGINT,
+    ALTER COLUMN transaction_fee_eth TYPE DOUBLE PRECISION,
+    ALTER COLUMN transaction_fee_usdt TYPE DOUBLE PRECISION,
+    ALTER COLUMN eth_usdt_price TYPE DOUBLE PRECISION;
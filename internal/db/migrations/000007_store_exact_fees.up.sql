@@ -0,0 +1,15 @@
+-- Wei amounts can exceed BIGINT and fees need exact decimals, so both move to NUMERIC
+ALTER TABLE transactions
+    ALTER COLUMN gas_price_wei TYPE NUMERIC(78, 0),
+    ALTER COLUMN transaction_fee_eth TYPE NUMERIC,
+    ALTER COLUMN transaction_fee_usdt TYPE NUMERIC,
+    ALTER COLUMN eth_usdt_price TYPE NUMERIC;
+
+ALTER TABLE transactions ADD COLUMN transaction_fee_wei NUMERIC(78, 0);
+
+-- Recompute the fees of existing rows exactly from their wei amounts
+UPDATE transactions SET transaction_fee_wei = gas_used * gas_price_wei;
+UPDATE transactions SET transaction_fee_eth = transaction_fee_wei * 0.000000000000000001;
+UPDATE transactions SET transaction_fee_usdt = transaction_fee_eth * eth_usdt_price;
+
+ALTER TABLE transactions ALTER COLUMN transaction_fee_wei SET NOT NULL;
@@ -0,0 +1,8 @@
+ALTER TABLE transactions DROP COLUMN IF EXISTS tip_fee_usdt;
+ALTER TABLE transactions DROP COLUMN IF EXISTS tip_fee_eth;
+ALTER TABLE transactions DROP COLUMN IF EXISTS burned_fee_usdt;
+ALTER TABLE transactions DROP COLUMN IF EXISTS burned_fee_eth;
+ALTER TABLE transactions DROP COLUMN IF EXISTS effective_tip_per_gas_wei;
+ALTER TABLE transactions DROP COLUMN IF EXISTS max_priority_fee_per_gas_wei;
+ALTER TABLE transactions DROP COLUMN IF EXISTS max_fee_per_gas_wei;
+ALTER TABLE transactions DROP COLUMN IF EXISTS base_fee_per_gas_wei;
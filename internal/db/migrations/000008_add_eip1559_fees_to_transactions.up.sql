@@ -0,0 +1,9 @@
+-- EIP-1559 fee breakdown, NULL before London or when the source does not report it
+ALTER TABLE transactions ADD COLUMN base_fee_per_gas_wei NUMERIC(78, 0);
+ALTER TABLE transactions ADD COLUMN max_fee_per_gas_wei NUMERIC(78, 0);
+ALTER TABLE transactions ADD COLUMN max_priority_fee_per_gas_wei NUMERIC(78, 0);
+ALTER TABLE transactions ADD COLUMN effective_tip_per_gas_wei NUMERIC(78, 0);
+ALTER TABLE transactions ADD COLUMN burned_fee_eth NUMERIC;
+ALTER TABLE transactions ADD COLUMN burned_fee_usdt NUMERIC;
+ALTER TABLE transactions ADD COLUMN tip_fee_eth NUMERIC;
+ALTER TABLE transactions ADD COLUMN tip_fee_usdt NUMERIC;
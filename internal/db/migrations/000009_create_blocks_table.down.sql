@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_transactions_chain_id_block_number;
+DROP TABLE IF EXISTS blocks;
@@ -0,0 +1,10 @@
+-- Headers of the blocks the live recorder ingested up to, checked against the chain to detect reorgs
+CREATE TABLE blocks (
+    chain_id     BIGINT NOT NULL,
+    block_number BIGINT NOT NULL,
+    block_hash   TEXT NOT NULL,
+    parent_hash  TEXT NOT NULL,
+    PRIMARY KEY (chain_id, block_number)
+);
+
+CREATE INDEX idx_transactions_chain_id_block_number ON transactions (chain_id, block_number);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS ingested_ranges;
+DROP TABLE IF EXISTS ingestion_checkpoints;
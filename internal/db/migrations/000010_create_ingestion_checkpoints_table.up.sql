@@ -0,0 +1,14 @@
+-- Last block the live recorder processed per chain, it resumes from there on restart
+CREATE TABLE ingestion_checkpoints (
+    chain_id          BIGINT PRIMARY KEY,
+    last_block_number BIGINT NOT NULL,
+    updated_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Block ranges ingested per chain, contiguous ranges are merged
+CREATE TABLE ingested_ranges (
+    chain_id    BIGINT NOT NULL,
+    start_block BIGINT NOT NULL,
+    end_block   BIGINT NOT NULL,
+    PRIMARY KEY (chain_id, start_block)
+);
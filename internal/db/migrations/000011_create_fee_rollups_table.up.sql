@@ -0,0 +1,20 @@
+-- Fee statistics of the transactions of a chain per minute, hour and day bucket, recomputed as transactions are written
+CREATE TABLE fee_rollups (
+    bucket_size  TEXT NOT NULL, -- 1m, 1h or 1d
+    chain_id     BIGINT NOT NULL,
+    bucket_start TIMESTAMPTZ NOT NULL, -- Buckets are aligned on UTC
+    tx_count     BIGINT NOT NULL,
+    gas_used_sum NUMERIC NOT NULL,
+    fee_eth_min  NUMERIC,
+    fee_eth_avg  NUMERIC,
+    fee_eth_max  NUMERIC,
+    fee_eth_p50  NUMERIC,
+    fee_eth_p95  NUMERIC,
+    fee_usdt_min NUMERIC, -- USDT statistics are NULL when no transaction of the bucket was priced
+    fee_usdt_avg NUMERIC,
+    fee_usdt_max NUMERIC,
+    fee_usdt_p50 NUMERIC,
+    fee_usdt_p95 NUMERIC,
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (bucket_size, chain_id, bucket_start)
+);
@@ -0,0 +1,25 @@
+-- name: UpsertBlock :exec
+INSERT INTO blocks (
+    chain_id,
+    block_number,
+    block_hash,
+    parent_hash
+) VALUES (
+    $1, $2, $3, $4
+)
+ON CONFLICT (chain_id, block_number) DO UPDATE SET
+    block_hash = EXCLUDED.block_hash,
+    parent_hash = EXCLUDED.parent_hash;
+
+-- name: ListRecentBlocks :many
+SELECT *
+FROM blocks
+WHERE chain_id = $1
+  AND block_number <= $2
+ORDER BY block_number DESC
+LIMIT $3;
+
+-- name: DeleteBlocksFrom :exec
+DELETE FROM blocks
+WHERE chain_id = $1
+  AND block_number >= $2;
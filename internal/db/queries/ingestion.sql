@@ -0,0 +1,49 @@
+-- name: GetIngestionCheckpoint :one
+SELECT *
+FROM ingestion_checkpoints
+WHERE chain_id = $1;
+
+-- name: UpsertIngestionCheckpoint :exec
+INSERT INTO ingestion_checkpoints (
+    chain_id,
+    last_block_number
+) VALUES (
+    $1, $2
+)
+ON CONFLICT (chain_id) DO UPDATE SET
+    last_block_number = EXCLUDED.last_block_number,
+    updated_at = NOW();
+
+-- name: ExtendIngestedRange :execrows
+UPDATE ingested_ranges
+SET end_block = sqlc.arg(new_end_block)
+WHERE chain_id = sqlc.arg(chain_id)
+  AND end_block = sqlc.arg(end_block);
+
+-- name: InsertIngestedRange :exec
+INSERT INTO ingested_ranges (
+    chain_id,
+    start_block,
+    end_block
+) VALUES (
+    $1, $2, $3
+)
+ON CONFLICT (chain_id, start_block) DO UPDATE SET
+    end_block = GREATEST(ingested_ranges.end_block, EXCLUDED.end_block);
+
+-- name: ListIngestedRanges :many
+SELECT *
+FROM ingested_ranges
+WHERE chain_id = $1
+ORDER BY start_block;
+
+-- name: DeleteIngestedRangesFrom :exec
+DELETE FROM ingested_ranges
+WHERE chain_id = $1
+  AND start_block >= $2;
+
+-- name: TruncateIngestedRanges :exec
+UPDATE ingested_ranges
+SET end_block = sqlc.arg(from_block)::bigint - 1
+WHERE chain_id = sqlc.arg(chain_id)
+  AND end_block >= sqlc.arg(from_block);
@@ -0,0 +1,19 @@
+-- name: UpsertPool :exec
+INSERT INTO pools (
+    address,
+    chain_id,
+    token0,
+    token1,
+    fee_tier
+) VALUES (
+    $1, $2, $3, $4, $5
+)
+ON CONFLICT (chain_id, address) DO UPDATE SET
+    token0 = EXCLUDED.token0,
+    token1 = EXCLUDED.token1,
+    fee_tier = EXCLUDED.fee_tier;
+
+-- name: ListPools :many
+SELECT *
+FROM pools
+ORDER BY chain_id, address;
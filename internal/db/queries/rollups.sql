@@ -0,0 +1,98 @@
+-- name: RefreshFeeRollups :execrows
+-- Recomputes the buckets of a chain between the buckets of start_time and end_time from its transactions,
+-- deleting the buckets left without transactions
+WITH bounds AS (
+    SELECT
+        date_bin(sqlc.arg(bucket_size)::text::interval, sqlc.arg(start_time)::timestamptz, TIMESTAMPTZ '2000-01-01 00:00:00+00') AS first_bucket,
+        date_bin(sqlc.arg(bucket_size)::text::interval, sqlc.arg(end_time)::timestamptz, TIMESTAMPTZ '2000-01-01 00:00:00+00') AS last_bucket
+),
+computed AS (
+    SELECT
+        date_bin(sqlc.arg(bucket_size)::text::interval, t.timestamp, TIMESTAMPTZ '2000-01-01 00:00:00+00') AS bucket_start,
+        COUNT(*) AS tx_count,
+        SUM(t.gas_used)::numeric AS gas_used_sum,
+        MIN(t.transaction_fee_eth) AS fee_eth_min,
+        trim_scale(ROUND(AVG(t.transaction_fee_eth), 38)) AS fee_eth_avg,
+        MAX(t.transaction_fee_eth) AS fee_eth_max,
+        percentile_disc(0.5) WITHIN GROUP (ORDER BY t.transaction_fee_eth) AS fee_eth_p50,
+        percentile_disc(0.95) WITHIN GROUP (ORDER BY t.transaction_fee_eth) AS fee_eth_p95,
+        MIN(t.transaction_fee_usdt) AS fee_usdt_min,
+        trim_scale(ROUND(AVG(t.transaction_fee_usdt), 38)) AS fee_usdt_avg,
+        MAX(t.transaction_fee_usdt) AS fee_usdt_max,
+        percentile_disc(0.5) WITHIN GROUP (ORDER BY t.transaction_fee_usdt) AS fee_usdt_p50,
+        percentile_disc(0.95) WITHIN GROUP (ORDER BY t.transaction_fee_usdt) AS fee_usdt_p95
+    FROM transactions t, bounds b
+    WHERE t.chain_id = sqlc.arg(chain_id)
+      AND t.timestamp >= b.first_bucket
+      AND t.timestamp < b.last_bucket + sqlc.arg(bucket_size)::text::interval
+    GROUP BY 1
+),
+removed AS (
+    DELETE FROM fee_rollups r
+    USING bounds b
+    WHERE r.bucket_size = sqlc.arg(bucket_size)
+      AND r.chain_id = sqlc.arg(chain_id)
+      AND r.bucket_start BETWEEN b.first_bucket AND b.last_bucket
+      AND r.bucket_start NOT IN (SELECT bucket_start FROM computed)
+)
+INSERT INTO fee_rollups (
+    bucket_size,
+    chain_id,
+    bucket_start,
+    tx_count,
+    gas_used_sum,
+    fee_eth_min,
+    fee_eth_avg,
+    fee_eth_max,
+    fee_eth_p50,
+    fee_eth_p95,
+    fee_usdt_min,
+    fee_usdt_avg,
+    fee_usdt_max,
+    fee_usdt_p50,
+    fee_usdt_p95
+)
+SELECT
+    sqlc.arg(bucket_size),
+    sqlc.arg(chain_id),
+    bucket_start,
+    tx_count,
+    gas_used_sum,
+    fee_eth_min,
+    fee_eth_avg,
+    fee_eth_max,
+    fee_eth_p50,
+    fee_eth_p95,
+    fee_usdt_min,
+    fee_usdt_avg,
+    fee_usdt_max,
+    fee_usdt_p50,
+    fee_usdt_p95
+FROM computed
+ON CONFLICT (bucket_size, chain_id, bucket_start) DO UPDATE SET
+    tx_count = EXCLUDED.tx_count,
+    gas_used_sum = EXCLUDED.gas_used_sum,
+    fee_eth_min = EXCLUDED.fee_eth_min,
+    fee_eth_avg = EXCLUDED.fee_eth_avg,
+    fee_eth_max = EXCLUDED.fee_eth_max,
+    fee_eth_p50 = EXCLUDED.fee_eth_p50,
+    fee_eth_p95 = EXCLUDED.fee_eth_p95,
+    fee_usdt_min = EXCLUDED.fee_usdt_min,
+    fee_usdt_avg = EXCLUDED.fee_usdt_avg,
+    fee_usdt_max = EXCLUDED.fee_usdt_max,
+    fee_usdt_p50 = EXCLUDED.fee_usdt_p50,
+    fee_usdt_p95 = EXCLUDED.fee_usdt_p95,
+    updated_at = NOW();
+
+-- name: LockFeeRollups :exec
+-- Serializes the refreshes of the fee rollups of a chain until the end of the DB transaction,
+-- so concurrent writers compute their buckets from each other's committed transactions
+SELECT pg_advisory_xact_lock(sqlc.arg(chain_id)::bigint);
+
+-- name: ListFeeRollups :many
+SELECT *
+FROM fee_rollups
+WHERE bucket_size = sqlc.arg(bucket_size)
+  AND bucket_start BETWEEN sqlc.arg(start_time) AND sqlc.arg(end_time)
+  AND (sqlc.narg('chain_id')::bigint IS NULL OR chain_id = sqlc.narg('chain_id'))
+ORDER BY chain_id, bucket_start;
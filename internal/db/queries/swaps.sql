@@ -0,0 +1,27 @@
+-- name: InsertSwap :exec
+INSERT INTO swaps (
+    transaction_hash,
+    log_index,
+    pool_address,
+    sender,
+    recipient,
+    amount0,
+    amount1,
+    sqrt_price_x96,
+    liquidity,
+    tick
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+);
+
+-- name: GetSwapsByTransactionHash :many
+SELECT *
+FROM swaps
+WHERE transaction_hash = $1
+ORDER BY log_index;
+
+-- name: GetSwapsByTransactionHashes :many
+SELECT *
+FROM swaps
+WHERE transaction_hash = ANY(@transaction_hashes::text[])
+ORDER BY transaction_hash, log_index;
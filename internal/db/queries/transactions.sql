@@ -7,9 +7,23 @@ INSERT INTO transactions (
     gas_price_wei,
     transaction_fee_eth,
     transaction_fee_usdt,
-    eth_usdt_price
+    eth_usdt_price,
+    pool_address,
+    chain_id,
+    price_source,
+    price_spread,
+    price_suspect,
+    transaction_fee_wei,
+    base_fee_per_gas_wei,
+    max_fee_per_gas_wei,
+    max_priority_fee_per_gas_wei,
+    effective_tip_per_gas_wei,
+    burned_fee_eth,
+    burned_fee_usdt,
+    tip_fee_eth,
+    tip_fee_usdt
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22
 );
 
 -- name: GetTransactionByHash :one
@@ -21,7 +35,21 @@ SELECT
     gas_price_wei,
     transaction_fee_eth,
     transaction_fee_usdt,
-    eth_usdt_price
+    eth_usdt_price,
+    pool_address,
+    chain_id,
+    price_source,
+    price_spread,
+    price_suspect,
+    transaction_fee_wei,
+    base_fee_per_gas_wei,
+    max_fee_per_gas_wei,
+    max_priority_fee_per_gas_wei,
+    effective_tip_per_gas_wei,
+    burned_fee_eth,
+    burned_fee_usdt,
+    tip_fee_eth,
+    tip_fee_usdt
 FROM transactions
 WHERE transaction_hash = $1;
 
@@ -34,7 +62,21 @@ SELECT
     gas_price_wei,
     transaction_fee_eth,
     transaction_fee_usdt,
-    eth_usdt_price
+    eth_usdt_price,
+    pool_address,
+    chain_id,
+    price_source,
+    price_spread,
+    price_suspect,
+    transaction_fee_wei,
+    base_fee_per_gas_wei,
+    max_fee_per_gas_wei,
+    max_priority_fee_per_gas_wei,
+    effective_tip_per_gas_wei,
+    burned_fee_eth,
+    burned_fee_usdt,
+    tip_fee_eth,
+    tip_fee_usdt
 FROM transactions
 WHERE block_number = $1
 ORDER BY timestamp DESC;
@@ -48,13 +90,44 @@ SELECT
     gas_price_wei,
     transaction_fee_eth,
     transaction_fee_usdt,
-    eth_usdt_price
+    eth_usdt_price,
+    pool_address,
+    chain_id,
+    price_source,
+    price_spread,
+    price_suspect,
+    transaction_fee_wei,
+    base_fee_per_gas_wei,
+    max_fee_per_gas_wei,
+    max_priority_fee_per_gas_wei,
+    effective_tip_per_gas_wei,
+    burned_fee_eth,
+    burned_fee_usdt,
+    tip_fee_eth,
+    tip_fee_usdt
 FROM transactions
-WHERE timestamp BETWEEN $1 AND $2
+WHERE timestamp BETWEEN @start_time AND @end_time
+  AND (sqlc.narg('pool_address')::text IS NULL OR pool_address = sqlc.narg('pool_address'))
+  AND (sqlc.narg('chain_id')::bigint IS NULL OR chain_id = sqlc.narg('chain_id'))
 ORDER BY timestamp DESC;
 
 -- name: GetLatestTransactions :many
 SELECT *
 FROM transactions
+WHERE (sqlc.narg('pool_address')::text IS NULL OR pool_address = sqlc.narg('pool_address'))
+  AND (sqlc.narg('chain_id')::bigint IS NULL OR chain_id = sqlc.narg('chain_id'))
 ORDER BY timestamp DESC
-LIMIT $1;
+LIMIT sqlc.arg('limit');
+
+-- name: DeleteTransactionsFromBlock :execrows
+DELETE FROM transactions
+WHERE chain_id = $1
+  AND block_number >= $2;
+
+-- name: GetFirstTransactionTimeFromBlock :one
+SELECT timestamp
+FROM transactions
+WHERE chain_id = $1
+  AND block_number >= $2
+ORDER BY block_number
+LIMIT 1;
@@ -1,10 +1,89 @@
+CREATE TABLE pools (
+    address  TEXT NOT NULL,
+    chain_id BIGINT NOT NULL,
+    token0   TEXT NOT NULL,
+    token1   TEXT NOT NULL,
+    fee_tier INTEGER NOT NULL, -- In hundredths of a bip, 500 = 0.05%
+    PRIMARY KEY (chain_id, address) -- CREATE2 pools share their address across chains
+);
+
 CREATE TABLE transactions (
-    transaction_hash     TEXT PRIMARY KEY,
-    block_number         BIGINT NOT NULL,
-    timestamp            TIMESTAMPTZ NOT NULL,
-    gas_used             BIGINT NOT NULL,
-    gas_price_wei        BIGINT NOT NULL,
-    transaction_fee_eth  DOUBLE PRECISION, -- Calculated as gas_used * gas_price_wei / 1e18
-    transaction_fee_usdt DOUBLE PRECISION, -- Calculated as transaction_fee_eth * eth_usdt_price
-    eth_usdt_price       DOUBLE PRECISION  -- ETH/USDT price at transaction time
+    transaction_hash             TEXT PRIMARY KEY,
+    block_number                 BIGINT NOT NULL,
+    timestamp                    TIMESTAMPTZ NOT NULL,
+    gas_used                     BIGINT NOT NULL,
+    gas_price_wei                NUMERIC(78, 0) NOT NULL,
+    transaction_fee_eth          NUMERIC, -- Calculated as transaction_fee_wei / 1e18
+    transaction_fee_usdt         NUMERIC, -- Calculated as transaction_fee_eth * eth_usdt_price
+    eth_usdt_price               NUMERIC, -- ETH/USDT price at transaction time
+    pool_address                 TEXT,
+    chain_id                     BIGINT NOT NULL,
+    price_source                 TEXT, -- Sources of eth_usdt_price, e.g. binance or binance,pool:<address> when aggregated
+    price_spread                 DOUBLE PRECISION, -- Relative spread between the aggregated price sources
+    price_suspect                BOOLEAN NOT NULL DEFAULT FALSE, -- Whether the price sources disagree beyond the divergence threshold
+    transaction_fee_wei          NUMERIC(78, 0) NOT NULL, -- Calculated as gas_used * gas_price_wei
+    base_fee_per_gas_wei         NUMERIC(78, 0), -- Base fee of the block, NULL before London
+    max_fee_per_gas_wei          NUMERIC(78, 0), -- Declared by EIP-1559 transactions
+    max_priority_fee_per_gas_wei NUMERIC(78, 0), -- Declared by EIP-1559 transactions
+    effective_tip_per_gas_wei    NUMERIC(78, 0), -- Calculated as gas_price_wei - base_fee_per_gas_wei
+    burned_fee_eth               NUMERIC, -- Calculated as gas_used * base_fee_per_gas_wei / 1e18
+    burned_fee_usdt              NUMERIC, -- Calculated as burned_fee_eth * eth_usdt_price
+    tip_fee_eth                  NUMERIC, -- Calculated as gas_used * effective_tip_per_gas_wei / 1e18
+    tip_fee_usdt                 NUMERIC, -- Calculated as tip_fee_eth * eth_usdt_price
+    FOREIGN KEY (chain_id, pool_address) REFERENCES pools (chain_id, address)
+);
+
+CREATE TABLE swaps (
+    transaction_hash TEXT NOT NULL REFERENCES transactions (transaction_hash) ON DELETE CASCADE,
+    log_index        INTEGER NOT NULL,
+    pool_address     TEXT NOT NULL,
+    sender           TEXT NOT NULL,
+    recipient        TEXT NOT NULL,
+    amount0          NUMERIC(78, 0) NOT NULL, -- Signed token0 delta of the pool
+    amount1          NUMERIC(78, 0) NOT NULL, -- Signed token1 delta of the pool
+    sqrt_price_x96   NUMERIC(78, 0) NOT NULL, -- Pool price after the swap as Q64.96
+    liquidity        NUMERIC(78, 0) NOT NULL,
+    tick             INTEGER NOT NULL,
+    PRIMARY KEY (transaction_hash, log_index)
+);
+
+CREATE TABLE blocks (
+    chain_id     BIGINT NOT NULL,
+    block_number BIGINT NOT NULL,
+    block_hash   TEXT NOT NULL,
+    parent_hash  TEXT NOT NULL,
+    PRIMARY KEY (chain_id, block_number)
+);
+
+CREATE TABLE ingestion_checkpoints (
+    chain_id          BIGINT PRIMARY KEY,
+    last_block_number BIGINT NOT NULL,
+    updated_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE ingested_ranges (
+    chain_id    BIGINT NOT NULL,
+    start_block BIGINT NOT NULL,
+    end_block   BIGINT NOT NULL,
+    PRIMARY KEY (chain_id, start_block)
+);
+
+CREATE TABLE fee_rollups (
+    bucket_size  TEXT NOT NULL, -- 1m, 1h or 1d
+    chain_id     BIGINT NOT NULL,
+    bucket_start TIMESTAMPTZ NOT NULL, -- Buckets are aligned on UTC
+    tx_count     BIGINT NOT NULL,
+    gas_used_sum NUMERIC NOT NULL,
+    fee_eth_min  NUMERIC,
+    fee_eth_avg  NUMERIC,
+    fee_eth_max  NUMERIC,
+    fee_eth_p50  NUMERIC,
+    fee_eth_p95  NUMERIC,
+    fee_usdt_min NUMERIC, -- NULL when no transaction of the bucket was priced
+    fee_usdt_avg NUMERIC,
+    fee_usdt_max NUMERIC,
+    fee_usdt_p50 NUMERIC,
+    fee_usdt_p95 NUMERIC,
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (bucket_size, chain_id, bucket_start)
 );
@@ -0,0 +1,318 @@
+package db
+
+// Bulk writes are written by hand, sqlc does not generate COPY into temporary staging tables.
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// DefaultBulkChunkSize is the number of transactions written per DB transaction
+const DefaultBulkChunkSize = 1000
+
+// TxBeginner starts DB transactions, e.g. a *pgxpool.Pool
+type TxBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// BulkWriter writes transactions in bulk. Writes are idempotent, so overlapping ranges can be written again.
+type BulkWriter interface {
+	UpsertTransactions(ctx context.Context, rows []TransactionRow) (UpsertResult, error)
+}
+
+// TransactionRow is a transaction to write along with its swaps
+type TransactionRow struct {
+	Transaction InsertTransactionParams
+	Swaps       []InsertSwapParams
+}
+
+// UpsertResult counts the transactions of a bulk write
+type UpsertResult struct {
+	Inserted int `json:"inserted"`
+	Updated  int `json:"updated"`
+	Skipped  int `json:"skipped"` // Already stored unchanged, or repeated in the write
+}
+
+// Add sums the counts of both results
+func (r UpsertResult) Add(other UpsertResult) UpsertResult {
+	return UpsertResult{
+		Inserted: r.Inserted + other.Inserted,
+		Updated:  r.Updated + other.Updated,
+		Skipped:  r.Skipped + other.Skipped,
+	}
+}
+
+// BulkStore writes transactions through COPY into temporary staging tables, upserted into the tables from there.
+// Every chunk is written in its own DB transaction, along with its swaps and the fee rollups of its changed transactions.
+type BulkStore struct {
+	db        TxBeginner
+	chunkSize int
+}
+
+var _ BulkWriter = (*BulkStore)(nil)
+
+// NewBulkStore initializes a BulkStore writing DefaultBulkChunkSize transactions per DB transaction
+func NewBulkStore(db TxBeginner) *BulkStore {
+	return &BulkStore{db: db, chunkSize: DefaultBulkChunkSize}
+}
+
+// Columns written by the bulk upserts, in the order of the copied rows
+var (
+	transactionColumns = []string{
+		"transaction_hash",
+		"block_number",
+		"timestamp",
+		"gas_used",
+		"gas_price_wei",
+		"transaction_fee_eth",
+		"transaction_fee_usdt",
+		"eth_usdt_price",
+		"pool_address",
+		"chain_id",
+		"price_source",
+		"price_spread",
+		"price_suspect",
+		"transaction_fee_wei",
+		"base_fee_per_gas_wei",
+		"max_fee_per_gas_wei",
+		"max_priority_fee_per_gas_wei",
+		"effective_tip_per_gas_wei",
+		"burned_fee_eth",
+		"burned_fee_usdt",
+		"tip_fee_eth",
+		"tip_fee_usdt",
+	}
+	swapColumns = []string{
+		"transaction_hash",
+		"log_index",
+		"pool_address",
+		"sender",
+		"recipient",
+		"amount0",
+		"amount1",
+		"sqrt_price_x96",
+		"liquidity",
+		"tick",
+	}
+)
+
+// Staging tables live until the DB transaction of the chunk ends
+const (
+	createTransactionsStaging = `CREATE TEMP TABLE transactions_staging (LIKE transactions INCLUDING DEFAULTS) ON COMMIT DROP`
+	createSwapsStaging        = `CREATE TEMP TABLE swaps_staging (LIKE swaps INCLUDING DEFAULTS) ON COMMIT DROP`
+)
+
+// Upserts of the staged rows. Rows identical to the stored ones are left untouched and not returned,
+// inserted rows are told apart from updated ones by their xmax, only set on updated rows.
+// The chain and time of the returned rows bound the fee rollups to refresh.
+var (
+	upsertStagedTransactions = upsertStaged("transactions", "transactions_staging", transactionColumns, []string{"transaction_hash"}) + `
+RETURNING (xmax = 0) AS inserted, chain_id, timestamp`
+	upsertStagedSwaps = upsertStaged("swaps", "swaps_staging", swapColumns, []string{"transaction_hash", "log_index"})
+)
+
+// deleteStaleSwaps deletes the stored swaps of the staged transactions that are no longer staged,
+// the staged swaps of a transaction being all of its decoded swaps
+const deleteStaleSwaps = `DELETE FROM swaps s
+USING transactions_staging t
+WHERE s.transaction_hash = t.transaction_hash
+  AND NOT EXISTS (
+    SELECT 1 FROM swaps_staging ss
+    WHERE ss.transaction_hash = s.transaction_hash AND ss.log_index = s.log_index
+  )`
+
+// upsertStaged builds the upsert of the staged rows into the table, updating the rows of the same key that changed
+func upsertStaged(table string, staging string, columns []string, key []string) string {
+	var updated, stored, excluded []string
+	for _, column := range columns[len(key):] {
+		updated = append(updated, fmt.Sprintf("%s = EXCLUDED.%s", column, column))
+		stored = append(stored, table+"."+column)
+		excluded = append(excluded, "EXCLUDED."+column)
+	}
+
+	list := strings.Join(columns, ", ")
+	return fmt.Sprintf(`INSERT INTO %s (%s)
+SELECT DISTINCT ON (%s) %s FROM %s
+ON CONFLICT (%s) DO UPDATE SET %s
+WHERE (%s) IS DISTINCT FROM (%s)`,
+		table, list,
+		strings.Join(key, ", "), list, staging,
+		strings.Join(key, ", "), strings.Join(updated, ", "),
+		strings.Join(stored, ", "), strings.Join(excluded, ", "))
+}
+
+// UpsertTransactions writes the transactions and their swaps chunk by chunk. New transactions are inserted,
+// stored ones updated when they changed and skipped otherwise. A failed chunk is rolled back, the chunks
+// before it stay written and are counted in the result.
+func (s *BulkStore) UpsertTransactions(ctx context.Context, rows []TransactionRow) (UpsertResult, error) {
+	// A transaction repeated in the write is written once, the last one wins
+	var result UpsertResult
+	unique := make([]TransactionRow, 0, len(rows))
+	indexes := make(map[string]int, len(rows))
+	for _, row := range rows {
+		if i, ok := indexes[row.Transaction.TransactionHash]; ok {
+			unique[i] = row
+			result.Skipped++
+			continue
+		}
+		indexes[row.Transaction.TransactionHash] = len(unique)
+		unique = append(unique, row)
+	}
+
+	for start := 0; start < len(unique); start += s.chunkSize {
+		end := min(start+s.chunkSize, len(unique))
+		chunk, err := s.upsertChunk(ctx, unique[start:end])
+		if err != nil {
+			return result, fmt.Errorf("error writing transactions %d to %d: %w", start, end-1, err)
+		}
+		result = result.Add(chunk)
+	}
+	return result, nil
+}
+
+// upsertChunk stages the transactions and their swaps, then upserts them in a single DB transaction
+func (s *BulkStore) upsertChunk(ctx context.Context, rows []TransactionRow) (UpsertResult, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return UpsertResult{}, fmt.Errorf("error starting DB transaction: %w", err)
+	}
+	// No-op once committed
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, createTransactionsStaging); err != nil {
+		return UpsertResult{}, fmt.Errorf("error creating transactions staging table: %w", err)
+	}
+	if _, err := tx.Exec(ctx, createSwapsStaging); err != nil {
+		return UpsertResult{}, fmt.Errorf("error creating swaps staging table: %w", err)
+	}
+
+	var swaps []InsertSwapParams
+	for _, row := range rows {
+		swaps = append(swaps, row.Swaps...)
+	}
+
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"transactions_staging"}, transactionColumns, pgx.CopyFromSlice(len(rows), func(i int) ([]interface{}, error) {
+		t := rows[i].Transaction
+		return []interface{}{
+			t.TransactionHash,
+			t.BlockNumber,
+			t.Timestamp,
+			t.GasUsed,
+			t.GasPriceWei,
+			t.TransactionFeeEth,
+			t.TransactionFeeUsdt,
+			t.EthUsdtPrice,
+			t.PoolAddress,
+			t.ChainID,
+			t.PriceSource,
+			t.PriceSpread,
+			t.PriceSuspect,
+			t.TransactionFeeWei,
+			t.BaseFeePerGasWei,
+			t.MaxFeePerGasWei,
+			t.MaxPriorityFeePerGasWei,
+			t.EffectiveTipPerGasWei,
+			t.BurnedFeeEth,
+			t.BurnedFeeUsdt,
+			t.TipFeeEth,
+			t.TipFeeUsdt,
+		}, nil
+	}))
+	if err != nil {
+		return UpsertResult{}, fmt.Errorf("error copying transactions: %w", err)
+	}
+
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"swaps_staging"}, swapColumns, pgx.CopyFromSlice(len(swaps), func(i int) ([]interface{}, error) {
+		swap := swaps[i]
+		return []interface{}{
+			swap.TransactionHash,
+			swap.LogIndex,
+			swap.PoolAddress,
+			swap.Sender,
+			swap.Recipient,
+			swap.Amount0,
+			swap.Amount1,
+			swap.SqrtPriceX96,
+			swap.Liquidity,
+			swap.Tick,
+		}, nil
+	}))
+	if err != nil {
+		return UpsertResult{}, fmt.Errorf("error copying swaps: %w", err)
+	}
+
+	var result UpsertResult
+	changed := make(map[int64][]time.Time) // Timestamps of the changed transactions per chain
+	upserted, err := tx.Query(ctx, upsertStagedTransactions)
+	if err != nil {
+		return UpsertResult{}, fmt.Errorf("error upserting transactions: %w", err)
+	}
+	for upserted.Next() {
+		var inserted bool
+		var chainID int64
+		var timestamp time.Time
+		if err := upserted.Scan(&inserted, &chainID, &timestamp); err != nil {
+			upserted.Close()
+			return UpsertResult{}, fmt.Errorf("error reading upserted transactions: %w", err)
+		}
+		if inserted {
+			result.Inserted++
+		} else {
+			result.Updated++
+		}
+		changed[chainID] = append(changed[chainID], timestamp)
+	}
+	upserted.Close()
+	if err := upserted.Err(); err != nil {
+		return UpsertResult{}, fmt.Errorf("error upserting transactions: %w", err)
+	}
+	result.Skipped = len(rows) - result.Inserted - result.Updated
+
+	// Swaps reference their transactions, they are upserted once the transactions are.
+	// A transaction written again with fewer swaps drops the others.
+	if _, err := tx.Exec(ctx, deleteStaleSwaps); err != nil {
+		return UpsertResult{}, fmt.Errorf("error deleting stale swaps: %w", err)
+	}
+	if _, err := tx.Exec(ctx, upsertStagedSwaps); err != nil {
+		return UpsertResult{}, fmt.Errorf("error upserting swaps: %w", err)
+	}
+
+	// The buckets of the changed transactions are committed along with them. Writers refreshing the buckets
+	// of a chain take turns, locking the chains in order, so each computes them from the rows the others committed.
+	chainIDs := slices.Sorted(maps.Keys(changed))
+	for _, chainID := range chainIDs {
+		if err := New(tx).LockFeeRollups(ctx, chainID); err != nil {
+			return UpsertResult{}, fmt.Errorf("error locking fee rollups of chain %d: %w", chainID, err)
+		}
+		if err := RefreshFeeRollupsAt(ctx, New(tx), chainID, changed[chainID]); err != nil {
+			return UpsertResult{}, fmt.Errorf("error refreshing fee rollups of chain %d: %w", chainID, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return UpsertResult{}, fmt.Errorf("error committing DB transaction: %w", err)
+	}
+	return result, nil
+}
+
+// timeSpan is the time covered by a run of fee rollup buckets
+type timeSpan struct {
+	start time.Time
+	end   time.Time
+}
+
+// extend widens the span to include the timestamp
+func (s *timeSpan) extend(timestamp time.Time) {
+	if timestamp.Before(s.start) {
+		s.start = timestamp
+	}
+	if timestamp.After(s.end) {
+		s.end = timestamp
+	}
+}
@@ -0,0 +1,208 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeTx records the rows copied in a DB transaction, every staged transaction is upserted as inserted
+type fakeTx struct {
+	pgx.Tx
+	copied     map[string]int
+	staged     [][]interface{}           // Copied transactions
+	executed   []string                  // Statements executed, fee rollup refreshes aside
+	refreshed  []RefreshFeeRollupsParams // Fee rollups refreshed
+	queryErr   error
+	committed  bool
+	rolledBack bool
+}
+
+func (tx *fakeTx) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
+	if sql == refreshFeeRollups {
+		tx.refreshed = append(tx.refreshed, RefreshFeeRollupsParams{
+			BucketSize: arguments[0].(string),
+			StartTime:  arguments[1].(time.Time),
+			EndTime:    arguments[2].(time.Time),
+			ChainID:    arguments[3].(int64),
+		})
+	} else {
+		tx.executed = append(tx.executed, sql)
+	}
+	return pgconn.CommandTag{}, nil
+}
+
+func (tx *fakeTx) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	var n int64
+	for rowSrc.Next() {
+		values, err := rowSrc.Values()
+		if err != nil {
+			return n, err
+		}
+		if tableName.Sanitize() == `"transactions_staging"` {
+			tx.staged = append(tx.staged, values)
+		}
+		n++
+	}
+	tx.copied[tableName.Sanitize()] += int(n)
+	return n, nil
+}
+
+func (tx *fakeTx) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	if tx.queryErr != nil {
+		return nil, tx.queryErr
+	}
+	return &insertedRows{remaining: tx.staged}, nil
+}
+
+func (tx *fakeTx) Commit(ctx context.Context) error {
+	tx.committed = true
+	return nil
+}
+
+func (tx *fakeTx) Rollback(ctx context.Context) error {
+	if !tx.committed {
+		tx.rolledBack = true
+	}
+	return nil
+}
+
+// insertedRows returns the remaining transactions as inserted
+type insertedRows struct {
+	pgx.Rows
+	remaining [][]interface{}
+	current   []interface{}
+}
+
+func (r *insertedRows) Next() bool {
+	if len(r.remaining) == 0 {
+		return false
+	}
+	r.current, r.remaining = r.remaining[0], r.remaining[1:]
+	return true
+}
+
+func (r *insertedRows) Scan(dest ...interface{}) error {
+	*dest[0].(*bool) = true
+	*dest[1].(*int64) = r.current[9].(int64)
+	*dest[2].(*time.Time) = r.current[2].(time.Time)
+	return nil
+}
+
+func (r *insertedRows) Close()     {}
+func (r *insertedRows) Err() error { return nil }
+
+// fakeDB starts fake DB transactions, failing the query of the transaction numbered failAt
+type fakeDB struct {
+	txs    []*fakeTx
+	failAt int
+}
+
+func (d *fakeDB) Begin(ctx context.Context) (pgx.Tx, error) {
+	tx := &fakeTx{copied: make(map[string]int)}
+	d.txs = append(d.txs, tx)
+	if len(d.txs) == d.failAt {
+		tx.queryErr = errors.New("deadlock detected")
+	}
+	return tx, nil
+}
+
+// transactionRow returns a transaction of mainnet at the timestamp with the swaps
+func transactionRow(hash string, timestamp int64, swaps int) TransactionRow {
+	row := TransactionRow{Transaction: InsertTransactionParams{TransactionHash: hash, ChainID: 1, Timestamp: time.Unix(timestamp, 0)}}
+	for i := 0; i < swaps; i++ {
+		row.Swaps = append(row.Swaps, InsertSwapParams{TransactionHash: hash, LogIndex: int32(i)})
+	}
+	return row
+}
+
+func TestBulkStore_UpsertTransactions(t *testing.T) {
+	fake := &fakeDB{}
+	store := &BulkStore{db: fake, chunkSize: 2}
+
+	rows := []TransactionRow{
+		transactionRow("0x1", 1727790060, 1),
+		transactionRow("0x2", 1727790000, 2),
+		transactionRow("0x1", 1727790060, 1),
+		transactionRow("0x3", 1727793600, 1),
+	}
+	result, err := store.UpsertTransactions(context.Background(), rows)
+
+	// The repeated transaction is skipped, the others written in chunks of 2
+	assert.NoError(t, err)
+	assert.Equal(t, UpsertResult{Inserted: 3, Skipped: 1}, result)
+	assert.Len(t, fake.txs, 2)
+	assert.Equal(t, map[string]int{`"transactions_staging"`: 2, `"swaps_staging"`: 3}, fake.txs[0].copied)
+	assert.Equal(t, map[string]int{`"transactions_staging"`: 1, `"swaps_staging"`: 1}, fake.txs[1].copied)
+	for _, tx := range fake.txs {
+		assert.True(t, tx.committed)
+		assert.False(t, tx.rolledBack)
+		// Stale swaps are deleted before the staged ones are upserted, the fee rollups of the chain are locked before their refresh
+		assert.Equal(t, []string{createTransactionsStaging, createSwapsStaging, deleteStaleSwaps, upsertStagedSwaps, lockFeeRollups}, tx.executed)
+	}
+
+	// The buckets of every size holding the changed transactions of each chunk are refreshed
+	assert.Equal(t, []RefreshFeeRollupsParams{
+		{BucketSize: BucketMinute, StartTime: time.Unix(1727790000, 0), EndTime: time.Unix(1727790060, 0), ChainID: 1},
+		{BucketSize: BucketHour, StartTime: time.Unix(1727787600, 0), EndTime: time.Unix(1727787600, 0), ChainID: 1},
+		{BucketSize: BucketDay, StartTime: time.Unix(1727740800, 0), EndTime: time.Unix(1727740800, 0), ChainID: 1},
+	}, fake.txs[0].refreshed)
+	assert.Equal(t, time.Unix(1727793600, 0), fake.txs[1].refreshed[0].StartTime)
+
+	result, err = store.UpsertTransactions(context.Background(), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, UpsertResult{}, result)
+	assert.Len(t, fake.txs, 2)
+}
+
+func TestBulkStore_UpsertTransactions_FailedChunk(t *testing.T) {
+	fake := &fakeDB{failAt: 2}
+	store := &BulkStore{db: fake, chunkSize: 2}
+
+	var rows []TransactionRow
+	for i, hash := range []string{"0x1", "0x2", "0x3", "0x4", "0x5"} {
+		rows = append(rows, transactionRow(hash, 1727790000+int64(i)*12, 1))
+	}
+	result, err := store.UpsertTransactions(context.Background(), rows)
+
+	// The first chunk stays written, the failed one is rolled back and the rest not written
+	assert.ErrorContains(t, err, "error writing transactions 2 to 3")
+	assert.Equal(t, UpsertResult{Inserted: 2}, result)
+	assert.Len(t, fake.txs, 2)
+	assert.True(t, fake.txs[0].committed)
+	assert.False(t, fake.txs[1].committed)
+	assert.True(t, fake.txs[1].rolledBack)
+	assert.Empty(t, fake.txs[1].refreshed)
+}
+
+func TestBucketRuns(t *testing.T) {
+	// 2024-10-01 13:40:10, 13:41:50, 13:40:30 and 2024-10-03 06:00:00 UTC
+	timestamps := []time.Time{time.Unix(1727790010, 0), time.Unix(1727790110, 0), time.Unix(1727790030, 0), time.Unix(1727935200, 0)}
+
+	// Consecutive buckets are refreshed together, the days without changed transactions are skipped
+	assert.Equal(t, []timeSpan{
+		{start: time.Unix(1727790000, 0), end: time.Unix(1727790060, 0)},
+		{start: time.Unix(1727935200, 0), end: time.Unix(1727935200, 0)},
+	}, bucketRuns(timestamps, time.Minute))
+	assert.Equal(t, []timeSpan{
+		{start: time.Unix(1727740800, 0), end: time.Unix(1727740800, 0)},
+		{start: time.Unix(1727913600, 0), end: time.Unix(1727913600, 0)},
+	}, bucketRuns(timestamps, 24*time.Hour))
+	assert.Empty(t, bucketRuns(nil, time.Hour))
+}
+
+func TestUpsertStaged(t *testing.T) {
+	sql := upsertStaged("swaps", "swaps_staging", []string{"transaction_hash", "log_index", "sender", "tick"}, []string{"transaction_hash", "log_index"})
+
+	assert.True(t, strings.HasPrefix(sql, "INSERT INTO swaps (transaction_hash, log_index, sender, tick)\n"))
+	assert.Contains(t, sql, "SELECT DISTINCT ON (transaction_hash, log_index) transaction_hash, log_index, sender, tick FROM swaps_staging")
+	assert.Contains(t, sql, "ON CONFLICT (transaction_hash, log_index) DO UPDATE SET sender = EXCLUDED.sender, tick = EXCLUDED.tick")
+	// Unchanged rows are not rewritten
+	assert.Contains(t, sql, "WHERE (swaps.sender, swaps.tick) IS DISTINCT FROM (EXCLUDED.sender, EXCLUDED.tick)")
+}
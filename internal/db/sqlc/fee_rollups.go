@@ -0,0 +1,90 @@
+package db
+
+// Written by hand, the generated RefreshFeeRollups recomputes the buckets of a single size.
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Bucket sizes of the fee rollups, read by Postgres as intervals
+const (
+	BucketMinute = "1m"
+	BucketHour   = "1h"
+	BucketDay    = "1d"
+)
+
+// FeeRollupBucketSizes are the bucket sizes maintained, from the finest
+var FeeRollupBucketSizes = []string{BucketMinute, BucketHour, BucketDay}
+
+// BucketDuration returns the duration of the bucket size. Buckets are aligned on UTC.
+func BucketDuration(bucketSize string) (time.Duration, bool) {
+	switch bucketSize {
+	case BucketMinute:
+		return time.Minute, true
+	case BucketHour:
+		return time.Hour, true
+	case BucketDay:
+		return 24 * time.Hour, true
+	}
+	return 0, false
+}
+
+// RefreshFeeRollupsBetween recomputes the fee rollups of the chain of every bucket size,
+// from the bucket holding start to the bucket holding end
+func RefreshFeeRollupsBetween(ctx context.Context, q Querier, chainID int64, start time.Time, end time.Time) error {
+	for _, bucketSize := range FeeRollupBucketSizes {
+		_, err := q.RefreshFeeRollups(ctx, RefreshFeeRollupsParams{
+			BucketSize: bucketSize,
+			StartTime:  start,
+			EndTime:    end,
+			ChainID:    chainID,
+		})
+		if err != nil {
+			return fmt.Errorf("error refreshing the %s fee rollups: %w", bucketSize, err)
+		}
+	}
+	return nil
+}
+
+// RefreshFeeRollupsAt recomputes the fee rollups of the chain of every bucket size holding one of the timestamps.
+// Runs of consecutive buckets are refreshed together, the buckets between them are left untouched.
+func RefreshFeeRollupsAt(ctx context.Context, q Querier, chainID int64, timestamps []time.Time) error {
+	for _, bucketSize := range FeeRollupBucketSizes {
+		bucketDuration, _ := BucketDuration(bucketSize)
+		for _, run := range bucketRuns(timestamps, bucketDuration) {
+			_, err := q.RefreshFeeRollups(ctx, RefreshFeeRollupsParams{
+				BucketSize: bucketSize,
+				StartTime:  run.start,
+				EndTime:    run.end,
+				ChainID:    chainID,
+			})
+			if err != nil {
+				return fmt.Errorf("error refreshing the %s fee rollups: %w", bucketSize, err)
+			}
+		}
+	}
+	return nil
+}
+
+// bucketRuns returns the runs of consecutive buckets holding the timestamps, spanning from the start of their first bucket
+// to the start of their last one
+func bucketRuns(timestamps []time.Time, bucketDuration time.Duration) []timeSpan {
+	starts := make([]time.Time, 0, len(timestamps))
+	for _, timestamp := range timestamps {
+		starts = append(starts, timestamp.Truncate(bucketDuration))
+	}
+	slices.SortFunc(starts, func(a, b time.Time) int { return a.Compare(b) })
+
+	var runs []timeSpan
+	for _, start := range starts {
+		if len(runs) > 0 && !start.After(runs[len(runs)-1].end.Add(bucketDuration)) {
+			runs[len(runs)-1].extend(start)
+			continue
+		}
+		runs = append(runs, timeSpan{start: start, end: start})
+	}
+	return runs
+}
@@ -10,13 +10,86 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type Blocks struct {
+	ChainID     int64  `json:"chain_id"`
+	BlockNumber int64  `json:"block_number"`
+	BlockHash   string `json:"block_hash"`
+	ParentHash  string `json:"parent_hash"`
+}
+
+type FeeRollups struct {
+	BucketSize  string         `json:"bucket_size"`
+	ChainID     int64          `json:"chain_id"`
+	BucketStart time.Time      `json:"bucket_start"`
+	TxCount     int64          `json:"tx_count"`
+	GasUsedSum  pgtype.Numeric `json:"gas_used_sum"`
+	FeeEthMin   pgtype.Numeric `json:"fee_eth_min"`
+	FeeEthAvg   pgtype.Numeric `json:"fee_eth_avg"`
+	FeeEthMax   pgtype.Numeric `json:"fee_eth_max"`
+	FeeEthP50   pgtype.Numeric `json:"fee_eth_p50"`
+	FeeEthP95   pgtype.Numeric `json:"fee_eth_p95"`
+	FeeUsdtMin  pgtype.Numeric `json:"fee_usdt_min"`
+	FeeUsdtAvg  pgtype.Numeric `json:"fee_usdt_avg"`
+	FeeUsdtMax  pgtype.Numeric `json:"fee_usdt_max"`
+	FeeUsdtP50  pgtype.Numeric `json:"fee_usdt_p50"`
+	FeeUsdtP95  pgtype.Numeric `json:"fee_usdt_p95"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+type IngestedRanges struct {
+	ChainID    int64 `json:"chain_id"`
+	StartBlock int64 `json:"start_block"`
+	EndBlock   int64 `json:"end_block"`
+}
+
+type IngestionCheckpoints struct {
+	ChainID         int64     `json:"chain_id"`
+	LastBlockNumber int64     `json:"last_block_number"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+type Pools struct {
+	Address string `json:"address"`
+	ChainID int64  `json:"chain_id"`
+	Token0  string `json:"token0"`
+	Token1  string `json:"token1"`
+	FeeTier int32  `json:"fee_tier"`
+}
+
+type Swaps struct {
+	TransactionHash string         `json:"transaction_hash"`
+	LogIndex        int32          `json:"log_index"`
+	PoolAddress     string         `json:"pool_address"`
+	Sender          string         `json:"sender"`
+	Recipient       string         `json:"recipient"`
+	Amount0         pgtype.Numeric `json:"amount0"`
+	Amount1         pgtype.Numeric `json:"amount1"`
+	SqrtPriceX96    pgtype.Numeric `json:"sqrt_price_x96"`
+	Liquidity       pgtype.Numeric `json:"liquidity"`
+	Tick            int32          `json:"tick"`
+}
+
 type Transactions struct {
-	TransactionHash    string        `json:"transaction_hash"`
-	BlockNumber        int64         `json:"block_number"`
-	Timestamp          time.Time     `json:"timestamp"`
-	GasUsed            int64         `json:"gas_used"`
-	GasPriceWei        int64         `json:"gas_price_wei"`
-	TransactionFeeEth  pgtype.Float8 `json:"transaction_fee_eth"`
-	TransactionFeeUsdt pgtype.Float8 `json:"transaction_fee_usdt"`
-	EthUsdtPrice       pgtype.Float8 `json:"eth_usdt_price"`
+	TransactionHash         string         `json:"transaction_hash"`
+	BlockNumber             int64          `json:"block_number"`
+	Timestamp               time.Time      `json:"timestamp"`
+	GasUsed                 int64          `json:"gas_used"`
+	GasPriceWei             pgtype.Numeric `json:"gas_price_wei"`
+	TransactionFeeEth       pgtype.Numeric `json:"transaction_fee_eth"`
+	TransactionFeeUsdt      pgtype.Numeric `json:"transaction_fee_usdt"`
+	EthUsdtPrice            pgtype.Numeric `json:"eth_usdt_price"`
+	PoolAddress             pgtype.Text    `json:"pool_address"`
+	ChainID                 int64          `json:"chain_id"`
+	PriceSource             pgtype.Text    `json:"price_source"`
+	PriceSpread             pgtype.Float8  `json:"price_spread"`
+	PriceSuspect            bool           `json:"price_suspect"`
+	TransactionFeeWei       pgtype.Numeric `json:"transaction_fee_wei"`
+	BaseFeePerGasWei        pgtype.Numeric `json:"base_fee_per_gas_wei"`
+	MaxFeePerGasWei         pgtype.Numeric `json:"max_fee_per_gas_wei"`
+	MaxPriorityFeePerGasWei pgtype.Numeric `json:"max_priority_fee_per_gas_wei"`
+	EffectiveTipPerGasWei   pgtype.Numeric `json:"effective_tip_per_gas_wei"`
+	BurnedFeeEth            pgtype.Numeric `json:"burned_fee_eth"`
+	BurnedFeeUsdt           pgtype.Numeric `json:"burned_fee_usdt"`
+	TipFeeEth               pgtype.Numeric `json:"tip_fee_eth"`
+	TipFeeUsdt              pgtype.Numeric `json:"tip_fee_usdt"`
 }
@@ -6,14 +6,39 @@ package db
 
 import (
 	"context"
+	"time"
 )
 
 type Querier interface {
-	GetLatestTransactions(ctx context.Context, limit int32) ([]Transactions, error)
+	DeleteBlocksFrom(ctx context.Context, arg DeleteBlocksFromParams) error
+	DeleteIngestedRangesFrom(ctx context.Context, arg DeleteIngestedRangesFromParams) error
+	DeleteTransactionsFromBlock(ctx context.Context, arg DeleteTransactionsFromBlockParams) (int64, error)
+	ExtendIngestedRange(ctx context.Context, arg ExtendIngestedRangeParams) (int64, error)
+	GetFirstTransactionTimeFromBlock(ctx context.Context, arg GetFirstTransactionTimeFromBlockParams) (time.Time, error)
+	GetIngestionCheckpoint(ctx context.Context, chainID int64) (IngestionCheckpoints, error)
+	GetLatestTransactions(ctx context.Context, arg GetLatestTransactionsParams) ([]Transactions, error)
+	GetSwapsByTransactionHash(ctx context.Context, transactionHash string) ([]Swaps, error)
+	GetSwapsByTransactionHashes(ctx context.Context, transactionHashes []string) ([]Swaps, error)
 	GetTransactionByHash(ctx context.Context, transactionHash string) (Transactions, error)
 	GetTransactionsByBlockNumber(ctx context.Context, blockNumber int64) ([]Transactions, error)
 	GetTransactionsByTimeRange(ctx context.Context, arg GetTransactionsByTimeRangeParams) ([]Transactions, error)
+	InsertIngestedRange(ctx context.Context, arg InsertIngestedRangeParams) error
+	InsertSwap(ctx context.Context, arg InsertSwapParams) error
 	InsertTransaction(ctx context.Context, arg InsertTransactionParams) error
+	ListFeeRollups(ctx context.Context, arg ListFeeRollupsParams) ([]FeeRollups, error)
+	ListIngestedRanges(ctx context.Context, chainID int64) ([]IngestedRanges, error)
+	ListPools(ctx context.Context) ([]Pools, error)
+	ListRecentBlocks(ctx context.Context, arg ListRecentBlocksParams) ([]Blocks, error)
+	// Serializes the refreshes of the fee rollups of a chain until the end of the DB transaction,
+	// so concurrent writers compute their buckets from each other's committed transactions
+	LockFeeRollups(ctx context.Context, chainID int64) error
+	// Recomputes the buckets of a chain between the buckets of start_time and end_time from its transactions,
+	// deleting the buckets left without transactions
+	RefreshFeeRollups(ctx context.Context, arg RefreshFeeRollupsParams) (int64, error)
+	TruncateIngestedRanges(ctx context.Context, arg TruncateIngestedRangesParams) error
+	UpsertBlock(ctx context.Context, arg UpsertBlockParams) error
+	UpsertIngestionCheckpoint(ctx context.Context, arg UpsertIngestionCheckpointParams) error
+	UpsertPool(ctx context.Context, arg UpsertPoolParams) error
 }
 
 var _ Querier = (*Queries)(nil)
@@ -12,15 +12,63 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const deleteTransactionsFromBlock = `-- name: DeleteTransactionsFromBlock :execrows
+DELETE FROM transactions
+WHERE chain_id = $1
+  AND block_number >= $2
+`
+
+type DeleteTransactionsFromBlockParams struct {
+	ChainID     int64 `json:"chain_id"`
+	BlockNumber int64 `json:"block_number"`
+}
+
+func (q *Queries) DeleteTransactionsFromBlock(ctx context.Context, arg DeleteTransactionsFromBlockParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteTransactionsFromBlock, arg.ChainID, arg.BlockNumber)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const getFirstTransactionTimeFromBlock = `-- name: GetFirstTransactionTimeFromBlock :one
+SELECT timestamp
+FROM transactions
+WHERE chain_id = $1
+  AND block_number >= $2
+ORDER BY block_number
+LIMIT 1
+`
+
+type GetFirstTransactionTimeFromBlockParams struct {
+	ChainID     int64 `json:"chain_id"`
+	BlockNumber int64 `json:"block_number"`
+}
+
+func (q *Queries) GetFirstTransactionTimeFromBlock(ctx context.Context, arg GetFirstTransactionTimeFromBlockParams) (time.Time, error) {
+	row := q.db.QueryRow(ctx, getFirstTransactionTimeFromBlock, arg.ChainID, arg.BlockNumber)
+	var timestamp time.Time
+	err := row.Scan(&timestamp)
+	return timestamp, err
+}
+
 const getLatestTransactions = `-- name: GetLatestTransactions :many
-SELECT transaction_hash, block_number, timestamp, gas_used, gas_price_wei, transaction_fee_eth, transaction_fee_usdt, eth_usdt_price
+SELECT transaction_hash, block_number, timestamp, gas_used, gas_price_wei, transaction_fee_eth, transaction_fee_usdt, eth_usdt_price, pool_address, chain_id, price_source, price_spread, price_suspect, transaction_fee_wei, base_fee_per_gas_wei, max_fee_per_gas_wei, max_priority_fee_per_gas_wei, effective_tip_per_gas_wei, burned_fee_eth, burned_fee_usdt, tip_fee_eth, tip_fee_usdt
 FROM transactions
+WHERE ($1::text IS NULL OR pool_address = $1)
+  AND ($2::bigint IS NULL OR chain_id = $2)
 ORDER BY timestamp DESC
-LIMIT $1
+LIMIT $3
 `
 
-func (q *Queries) GetLatestTransactions(ctx context.Context, limit int32) ([]Transactions, error) {
-	rows, err := q.db.Query(ctx, getLatestTransactions, limit)
+type GetLatestTransactionsParams struct {
+	PoolAddress pgtype.Text `json:"pool_address"`
+	ChainID     pgtype.Int8 `json:"chain_id"`
+	Limit       int32       `json:"limit"`
+}
+
+func (q *Queries) GetLatestTransactions(ctx context.Context, arg GetLatestTransactionsParams) ([]Transactions, error) {
+	rows, err := q.db.Query(ctx, getLatestTransactions, arg.PoolAddress, arg.ChainID, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
@@ -37,6 +85,20 @@ func (q *Queries) GetLatestTransactions(ctx context.Context, limit int32) ([]Tra
 			&i.TransactionFeeEth,
 			&i.TransactionFeeUsdt,
 			&i.EthUsdtPrice,
+			&i.PoolAddress,
+			&i.ChainID,
+			&i.PriceSource,
+			&i.PriceSpread,
+			&i.PriceSuspect,
+			&i.TransactionFeeWei,
+			&i.BaseFeePerGasWei,
+			&i.MaxFeePerGasWei,
+			&i.MaxPriorityFeePerGasWei,
+			&i.EffectiveTipPerGasWei,
+			&i.BurnedFeeEth,
+			&i.BurnedFeeUsdt,
+			&i.TipFeeEth,
+			&i.TipFeeUsdt,
 		); err != nil {
 			return nil, err
 		}
@@ -57,7 +119,21 @@ SELECT
     gas_price_wei,
     transaction_fee_eth,
     transaction_fee_usdt,
-    eth_usdt_price
+    eth_usdt_price,
+    pool_address,
+    chain_id,
+    price_source,
+    price_spread,
+    price_suspect,
+    transaction_fee_wei,
+    base_fee_per_gas_wei,
+    max_fee_per_gas_wei,
+    max_priority_fee_per_gas_wei,
+    effective_tip_per_gas_wei,
+    burned_fee_eth,
+    burned_fee_usdt,
+    tip_fee_eth,
+    tip_fee_usdt
 FROM transactions
 WHERE transaction_hash = $1
 `
@@ -74,6 +150,20 @@ func (q *Queries) GetTransactionByHash(ctx context.Context, transactionHash stri
 		&i.TransactionFeeEth,
 		&i.TransactionFeeUsdt,
 		&i.EthUsdtPrice,
+		&i.PoolAddress,
+		&i.ChainID,
+		&i.PriceSource,
+		&i.PriceSpread,
+		&i.PriceSuspect,
+		&i.TransactionFeeWei,
+		&i.BaseFeePerGasWei,
+		&i.MaxFeePerGasWei,
+		&i.MaxPriorityFeePerGasWei,
+		&i.EffectiveTipPerGasWei,
+		&i.BurnedFeeEth,
+		&i.BurnedFeeUsdt,
+		&i.TipFeeEth,
+		&i.TipFeeUsdt,
 	)
 	return i, err
 }
@@ -87,7 +177,21 @@ SELECT
     gas_price_wei,
     transaction_fee_eth,
     transaction_fee_usdt,
-    eth_usdt_price
+    eth_usdt_price,
+    pool_address,
+    chain_id,
+    price_source,
+    price_spread,
+    price_suspect,
+    transaction_fee_wei,
+    base_fee_per_gas_wei,
+    max_fee_per_gas_wei,
+    max_priority_fee_per_gas_wei,
+    effective_tip_per_gas_wei,
+    burned_fee_eth,
+    burned_fee_usdt,
+    tip_fee_eth,
+    tip_fee_usdt
 FROM transactions
 WHERE block_number = $1
 ORDER BY timestamp DESC
@@ -111,6 +215,20 @@ func (q *Queries) GetTransactionsByBlockNumber(ctx context.Context, blockNumber
 			&i.TransactionFeeEth,
 			&i.TransactionFeeUsdt,
 			&i.EthUsdtPrice,
+			&i.PoolAddress,
+			&i.ChainID,
+			&i.PriceSource,
+			&i.PriceSpread,
+			&i.PriceSuspect,
+			&i.TransactionFeeWei,
+			&i.BaseFeePerGasWei,
+			&i.MaxFeePerGasWei,
+			&i.MaxPriorityFeePerGasWei,
+			&i.EffectiveTipPerGasWei,
+			&i.BurnedFeeEth,
+			&i.BurnedFeeUsdt,
+			&i.TipFeeEth,
+			&i.TipFeeUsdt,
 		); err != nil {
 			return nil, err
 		}
@@ -131,19 +249,37 @@ SELECT
     gas_price_wei,
     transaction_fee_eth,
     transaction_fee_usdt,
-    eth_usdt_price
+    eth_usdt_price,
+    pool_address,
+    chain_id,
+    price_source,
+    price_spread,
+    price_suspect,
+    transaction_fee_wei,
+    base_fee_per_gas_wei,
+    max_fee_per_gas_wei,
+    max_priority_fee_per_gas_wei,
+    effective_tip_per_gas_wei,
+    burned_fee_eth,
+    burned_fee_usdt,
+    tip_fee_eth,
+    tip_fee_usdt
 FROM transactions
 WHERE timestamp BETWEEN $1 AND $2
+  AND ($3::text IS NULL OR pool_address = $3)
+  AND ($4::bigint IS NULL OR chain_id = $4)
 ORDER BY timestamp DESC
 `
 
 type GetTransactionsByTimeRangeParams struct {
-	Timestamp   time.Time `json:"timestamp"`
-	Timestamp_2 time.Time `json:"timestamp_2"`
+	StartTime   time.Time   `json:"start_time"`
+	EndTime     time.Time   `json:"end_time"`
+	PoolAddress pgtype.Text `json:"pool_address"`
+	ChainID     pgtype.Int8 `json:"chain_id"`
 }
 
 func (q *Queries) GetTransactionsByTimeRange(ctx context.Context, arg GetTransactionsByTimeRangeParams) ([]Transactions, error) {
-	rows, err := q.db.Query(ctx, getTransactionsByTimeRange, arg.Timestamp, arg.Timestamp_2)
+	rows, err := q.db.Query(ctx, getTransactionsByTimeRange, arg.StartTime, arg.EndTime, arg.PoolAddress, arg.ChainID)
 	if err != nil {
 		return nil, err
 	}
@@ -160,6 +296,20 @@ func (q *Queries) GetTransactionsByTimeRange(ctx context.Context, arg GetTransac
 			&i.TransactionFeeEth,
 			&i.TransactionFeeUsdt,
 			&i.EthUsdtPrice,
+			&i.PoolAddress,
+			&i.ChainID,
+			&i.PriceSource,
+			&i.PriceSpread,
+			&i.PriceSuspect,
+			&i.TransactionFeeWei,
+			&i.BaseFeePerGasWei,
+			&i.MaxFeePerGasWei,
+			&i.MaxPriorityFeePerGasWei,
+			&i.EffectiveTipPerGasWei,
+			&i.BurnedFeeEth,
+			&i.BurnedFeeUsdt,
+			&i.TipFeeEth,
+			&i.TipFeeUsdt,
 		); err != nil {
 			return nil, err
 		}
@@ -180,21 +330,49 @@ INSERT INTO transactions (
     gas_price_wei,
     transaction_fee_eth,
     transaction_fee_usdt,
-    eth_usdt_price
+    eth_usdt_price,
+    pool_address,
+    chain_id,
+    price_source,
+    price_spread,
+    price_suspect,
+    transaction_fee_wei,
+    base_fee_per_gas_wei,
+    max_fee_per_gas_wei,
+    max_priority_fee_per_gas_wei,
+    effective_tip_per_gas_wei,
+    burned_fee_eth,
+    burned_fee_usdt,
+    tip_fee_eth,
+    tip_fee_usdt
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22
 )
 `
 
 type InsertTransactionParams struct {
-	TransactionHash    string        `json:"transaction_hash"`
-	BlockNumber        int64         `json:"block_number"`
-	Timestamp          time.Time     `json:"timestamp"`
-	GasUsed            int64         `json:"gas_used"`
-	GasPriceWei        int64         `json:"gas_price_wei"`
-	TransactionFeeEth  pgtype.Float8 `json:"transaction_fee_eth"`
-	TransactionFeeUsdt pgtype.Float8 `json:"transaction_fee_usdt"`
-	EthUsdtPrice       pgtype.Float8 `json:"eth_usdt_price"`
+	TransactionHash         string         `json:"transaction_hash"`
+	BlockNumber             int64          `json:"block_number"`
+	Timestamp               time.Time      `json:"timestamp"`
+	GasUsed                 int64          `json:"gas_used"`
+	GasPriceWei             pgtype.Numeric `json:"gas_price_wei"`
+	TransactionFeeEth       pgtype.Numeric `json:"transaction_fee_eth"`
+	TransactionFeeUsdt      pgtype.Numeric `json:"transaction_fee_usdt"`
+	EthUsdtPrice            pgtype.Numeric `json:"eth_usdt_price"`
+	PoolAddress             pgtype.Text    `json:"pool_address"`
+	ChainID                 int64          `json:"chain_id"`
+	PriceSource             pgtype.Text    `json:"price_source"`
+	PriceSpread             pgtype.Float8  `json:"price_spread"`
+	PriceSuspect            bool           `json:"price_suspect"`
+	TransactionFeeWei       pgtype.Numeric `json:"transaction_fee_wei"`
+	BaseFeePerGasWei        pgtype.Numeric `json:"base_fee_per_gas_wei"`
+	MaxFeePerGasWei         pgtype.Numeric `json:"max_fee_per_gas_wei"`
+	MaxPriorityFeePerGasWei pgtype.Numeric `json:"max_priority_fee_per_gas_wei"`
+	EffectiveTipPerGasWei   pgtype.Numeric `json:"effective_tip_per_gas_wei"`
+	BurnedFeeEth            pgtype.Numeric `json:"burned_fee_eth"`
+	BurnedFeeUsdt           pgtype.Numeric `json:"burned_fee_usdt"`
+	TipFeeEth               pgtype.Numeric `json:"tip_fee_eth"`
+	TipFeeUsdt              pgtype.Numeric `json:"tip_fee_usdt"`
 }
 
 func (q *Queries) InsertTransaction(ctx context.Context, arg InsertTransactionParams) error {
@@ -207,6 +385,20 @@ func (q *Queries) InsertTransaction(ctx context.Context, arg InsertTransactionPa
 		arg.TransactionFeeEth,
 		arg.TransactionFeeUsdt,
 		arg.EthUsdtPrice,
+		arg.PoolAddress,
+		arg.ChainID,
+		arg.PriceSource,
+		arg.PriceSpread,
+		arg.PriceSuspect,
+		arg.TransactionFeeWei,
+		arg.BaseFeePerGasWei,
+		arg.MaxFeePerGasWei,
+		arg.MaxPriorityFeePerGasWei,
+		arg.EffectiveTipPerGasWei,
+		arg.BurnedFeeEth,
+		arg.BurnedFeeUsdt,
+		arg.TipFeeEth,
+		arg.TipFeeUsdt,
 	)
 	return err
 }
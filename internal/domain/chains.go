@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"fmt"
+
+	"github.com/winQe/uniswap-fee-tracker/internal/client"
+	"github.com/winQe/uniswap-fee-tracker/internal/utils"
+)
+
+// ChainSource is a chain ingested by the TransactionManager, with the client serving it and its tracked pools
+type ChainSource struct {
+	ChainID       int64
+	Client        client.TransactionClient
+	PoolAddresses []string
+	Confirmations uint64 // Blocks below the head at which a block is considered final
+}
+
+// NewChainSources creates the transaction client of every configured chain, keyUsage tracks the daily usage of their API keys
+func NewChainSources(config utils.Config, keyUsage client.KeyUsageStore) ([]ChainSource, error) {
+	sources := make([]ChainSource, 0, len(config.Chains))
+	for _, chain := range config.Chains {
+		transactionClient, err := client.NewTransactionClient(config, chain, keyUsage)
+		if err != nil {
+			return nil, fmt.Errorf("error creating the transaction client of chain %s: %v", chain.Name, err)
+		}
+
+		sources = append(sources, ChainSource{
+			ChainID:       chain.ChainID,
+			Client:        transactionClient,
+			PoolAddresses: chain.PoolAddresses(),
+			Confirmations: chain.ConfirmationDepth(),
+		})
+	}
+	return sources, nil
+}
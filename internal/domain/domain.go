@@ -4,18 +4,24 @@ import (
 	"context"
 	"time"
 
+	"github.com/winQe/uniswap-fee-tracker/internal/client"
 	"github.com/winQe/uniswap-fee-tracker/internal/types"
 )
 
 // PriceManagerInterface defines interface for price manager
 type PriceManagerInterface interface {
-	GetETHUSDT(timestamp time.Time) (float64, error)
+	GetETHUSDT(ctx context.Context, timestamp time.Time) (*client.KlineData, error)
+	GetETHUSDTAtBlock(ctx context.Context, chainID int64, blockNumber uint64, timestamp time.Time) (*client.KlineData, error)
+	PrefetchETHUSDT(ctx context.Context, startTime time.Time, endTime time.Time) error
 }
 
 // TransactionManagerInterface defines interface for transaction manager
 type TransactionManagerInterface interface {
-	GetLatestBlockNumber() (uint64, error)
-	GetTransaction(hash string) (*types.TxWithPrice, error)
-	BatchProcessTransactions(startBlock uint64, endBlock uint64, ctx context.Context) ([]types.TxWithPrice, error)
-	BatchProcessTransactionsByTimestamp(startTime time.Time, endTime time.Time, ctx context.Context) ([]types.TxWithPrice, error)
+	ChainIDs() []int64
+	GetLatestBlockNumber(ctx context.Context, chainID int64) (uint64, error)
+	GetSafeBlockNumber(ctx context.Context, chainID int64) (uint64, error)
+	GetBlockHeader(ctx context.Context, chainID int64, blockNumber uint64) (*types.BlockHeader, error)
+	GetTransaction(ctx context.Context, hash string) (*types.TxWithPrice, error)
+	BatchProcessTransactions(ctx context.Context, chainID int64, startBlock uint64, endBlock uint64) ([]types.TxWithPrice, error)
+	BatchProcessTransactionsByTimestamp(ctx context.Context, startTime time.Time, endTime time.Time, onProgress types.ProgressFunc) ([]types.TxWithPrice, error)
 }
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"sync"
@@ -12,62 +13,70 @@ import (
 
 type conversionRate struct {
 	timestamp time.Time
-	rate      float64
+	rate      client.KlineData
+}
+
+// blockRate is the price of a block of a chain
+type blockRate struct {
+	chainID     int64
+	blockNumber uint64
+	rate        client.KlineData
 }
 
 // PriceManager aggregates the price from both cached rates and external APIs
 type PriceManager struct {
-	rateCache   cache.RateStore
-	priceClient client.PriceClient
-	lastPrice   conversionRate
-	mu          sync.RWMutex // Protects access to lastPrice
+	rateCache        cache.RateStore
+	priceClient      client.PriceClient
+	validityDuration time.Duration // How far from its timestamp lastPrice is reused
+	lastPrice        conversionRate
+	lastBlockPrice   *blockRate   // Price of the last block priced directly, reused for its other transactions
+	mu               sync.RWMutex // Protects access to lastPrice and lastBlockPrice
 }
 
 // NewPriceManager creates a PriceManager for handling logic with getting ETH-USDT conversion rate
+// The last price is reused within the resolution of the price client, block prices only for the same block.
 func NewPriceManager(rateStore cache.RateStore, priceClient client.PriceClient) *PriceManager {
 	return &PriceManager{
-		rateCache:   rateStore,
-		priceClient: priceClient,
-		lastPrice:   conversionRate{},
+		rateCache:        rateStore,
+		priceClient:      priceClient,
+		validityDuration: priceClient.Resolution(),
+		lastPrice:        conversionRate{},
 	}
 }
 
-// GetETHUSDTPrice retrieves the price of ETH to USDT.
+// GetETHUSDTPrice retrieves the price of ETH to USDT along with its source.
 // It first checks the lastPrice, then the cache, and finally fetches from the external API if needed.
-func (p *PriceManager) GetETHUSDT(timestamp time.Time) (float64, error) {
-	// Define a validity window for lastPrice (e.g., within the same minute)
-	const validityDuration = 15 * time.Minute
-
+func (p *PriceManager) GetETHUSDT(ctx context.Context, timestamp time.Time) (*client.KlineData, error) {
 	// Attempt to read the lastPrice with a read lock
 	p.mu.RLock()
-	if !p.lastPrice.timestamp.IsZero() && timestamp.Sub(p.lastPrice.timestamp) <= validityDuration {
+	if !p.lastPrice.timestamp.IsZero() && absDuration(timestamp.Sub(p.lastPrice.timestamp)) <= p.validityDuration {
 		rate := p.lastPrice.rate
 		p.mu.RUnlock()
-		return rate, nil
+		return &rate, nil
 	}
 	p.mu.RUnlock()
 
 	// lastPrice is stale, proceed to check the cache
-	price, err := p.rateCache.GetRate(timestamp)
+	price, err := p.rateCache.GetRate(ctx, timestamp)
 	if err == nil {
 		// Update lastPrice with the fetched rate
 		p.mu.Lock()
 		p.lastPrice = conversionRate{
 			timestamp: timestamp,
-			rate:      price,
+			rate:      *price,
 		}
 		p.mu.Unlock()
 		return price, nil
 	}
 
 	// Cache miss, fetch from external API
-	klineData, err := p.priceClient.GetETHUSDT(timestamp)
+	klineData, err := p.priceClient.GetETHUSDT(ctx, timestamp)
 	if err != nil {
-		return 0, fmt.Errorf("could not get ETH to USDT price from external API: %w", err)
+		return nil, fmt.Errorf("could not get ETH to USDT price from external API: %w", err)
 	}
 
 	// Store the fetched rate in the cache
-	err = p.rateCache.StoreRate(timestamp, klineData.ClosePrice)
+	err = p.rateCache.StoreRate(ctx, timestamp, *klineData)
 	if err != nil {
 		log.Printf("Warning: could not store price in cache: %v\n", err)
 	}
@@ -76,9 +85,70 @@ func (p *PriceManager) GetETHUSDT(timestamp time.Time) (float64, error) {
 	p.mu.Lock()
 	p.lastPrice = conversionRate{
 		timestamp: timestamp,
-		rate:      klineData.ClosePrice,
+		rate:      *klineData,
+	}
+	p.mu.Unlock()
+
+	return klineData, nil
+}
+
+// GetETHUSDTAtBlock retrieves the price of ETH to USDT at the block of a transaction, mined at timestamp.
+// Price clients reading the chain price the block directly instead of searching it by its timestamp.
+// Blocks mined seconds apart, or on other chains, can have different prices: block prices bypass lastPrice
+// and the rate cache, both keyed by time, and only the price of the last block is reused.
+func (p *PriceManager) GetETHUSDTAtBlock(ctx context.Context, chainID int64, blockNumber uint64, timestamp time.Time) (*client.KlineData, error) {
+	blockClient, ok := p.priceClient.(client.BlockPriceClient)
+	if !ok || !blockClient.ReadsBlocks() {
+		return p.GetETHUSDT(ctx, timestamp)
+	}
+
+	p.mu.RLock()
+	if last := p.lastBlockPrice; last != nil && last.chainID == chainID && last.blockNumber == blockNumber {
+		rate := last.rate
+		p.mu.RUnlock()
+		return &rate, nil
+	}
+	p.mu.RUnlock()
+
+	klineData, err := blockClient.GetETHUSDTAtBlock(ctx, chainID, blockNumber, timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("could not get ETH to USDT price from external API: %w", err)
 	}
+
+	p.mu.Lock()
+	p.lastBlockPrice = &blockRate{chainID: chainID, blockNumber: blockNumber, rate: *klineData}
 	p.mu.Unlock()
 
-	return klineData.ClosePrice, nil
+	return klineData, nil
+}
+
+// PrefetchETHUSDT loads every price of the time range into the cache in bulk, so the prices of a batch job
+// are resolved locally instead of with one request per transaction.
+// It is skipped when the price client cannot fetch ranges, e.g. when it aggregates several sources.
+func (p *PriceManager) PrefetchETHUSDT(ctx context.Context, startTime time.Time, endTime time.Time) error {
+	rangeClient, ok := p.priceClient.(client.RangePriceClient)
+	if !ok {
+		log.Printf("Skipping the prefetch of ETH to USDT prices, %T can't fetch them in bulk\n", p.priceClient)
+		return nil
+	}
+
+	prices, err := rangeClient.GetETHUSDTRange(ctx, startTime, endTime)
+	if err != nil {
+		return fmt.Errorf("could not prefetch ETH to USDT prices: %w", err)
+	}
+
+	if err := p.rateCache.StoreRates(ctx, prices); err != nil {
+		return fmt.Errorf("could not store prefetched prices in cache: %w", err)
+	}
+
+	log.Printf("Prefetched %d ETH to USDT prices between %v and %v\n", len(prices), startTime, endTime)
+	return nil
+}
+
+// absDuration returns the absolute value of d
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
 }
@@ -1,11 +1,13 @@
 package domain
 
 import (
+	"context"
 	"errors"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/winQe/uniswap-fee-tracker/internal/client"
 	"github.com/winQe/uniswap-fee-tracker/internal/mocks"
 )
@@ -18,14 +20,16 @@ func TestPriceManager_GetETHUSDT(t *testing.T) {
 		mockClient := new(mocks.MockPriceClient)
 
 		// Simulate cache hit, external API shouldn't be called
-		mockCache.On("GetRate", timestamp).Return(4800.75, nil)
-		mockClient.AssertNotCalled(t, "GetETHUSDT")
+		mockClient.On("Resolution").Return(15 * time.Minute)
+		mockCache.On("GetRate", mock.Anything, timestamp).Return(&client.KlineData{ClosePrice: 4800.75, Source: "binance"}, nil)
+		mockClient.AssertNotCalled(t, "GetETHUSDT", mock.Anything)
 
 		priceManager := NewPriceManager(mockCache, mockClient)
-		price, err := priceManager.GetETHUSDT(timestamp)
+		price, err := priceManager.GetETHUSDT(context.Background(), timestamp)
 
 		assert.NoError(t, err)
-		assert.Equal(t, 4800.75, price)
+		assert.Equal(t, 4800.75, price.ClosePrice)
+		assert.Equal(t, "binance", price.Source)
 
 		mockCache.AssertExpectations(t)
 		mockClient.AssertExpectations(t)
@@ -36,15 +40,18 @@ func TestPriceManager_GetETHUSDT(t *testing.T) {
 		mockClient := new(mocks.MockPriceClient)
 
 		// Simulate cache miss, valid external API response, and storing in cache
-		mockCache.On("GetRate", timestamp).Return(0.0, errors.New("cache miss"))
-		mockClient.On("GetETHUSDT", timestamp).Return(&client.KlineData{ClosePrice: 1850.00}, nil)
-		mockCache.On("StoreRate", timestamp, 1850.00).Return(nil)
+		kline := client.KlineData{ClosePrice: 1850.00, Source: "binance"}
+		mockClient.On("Resolution").Return(15 * time.Minute)
+		mockCache.On("GetRate", mock.Anything, timestamp).Return((*client.KlineData)(nil), errors.New("cache miss"))
+		mockClient.On("GetETHUSDT", mock.Anything, timestamp).Return(&kline, nil)
+		mockCache.On("StoreRate", mock.Anything, timestamp, kline).Return(nil)
 
 		priceManager := NewPriceManager(mockCache, mockClient)
-		price, err := priceManager.GetETHUSDT(timestamp)
+		price, err := priceManager.GetETHUSDT(context.Background(), timestamp)
 
 		assert.NoError(t, err)
-		assert.Equal(t, 1850.00, price)
+		assert.Equal(t, 1850.00, price.ClosePrice)
+		assert.Equal(t, "binance", price.Source)
 
 		mockCache.AssertExpectations(t)
 		mockClient.AssertExpectations(t)
@@ -55,14 +62,15 @@ func TestPriceManager_GetETHUSDT(t *testing.T) {
 		mockClient := new(mocks.MockPriceClient)
 
 		// Simulate cache miss, external API API failure
-		mockCache.On("GetRate", timestamp).Return(0.0, errors.New("cache miss"))
-		mockClient.On("GetETHUSDT", timestamp).Return((*client.KlineData)(nil), errors.New("external API error"))
+		mockClient.On("Resolution").Return(15 * time.Minute)
+		mockCache.On("GetRate", mock.Anything, timestamp).Return((*client.KlineData)(nil), errors.New("cache miss"))
+		mockClient.On("GetETHUSDT", mock.Anything, timestamp).Return((*client.KlineData)(nil), errors.New("external API error"))
 
 		priceManager := NewPriceManager(mockCache, mockClient)
-		price, err := priceManager.GetETHUSDT(timestamp)
+		price, err := priceManager.GetETHUSDT(context.Background(), timestamp)
 
 		assert.Error(t, err)
-		assert.Equal(t, 0.0, price)
+		assert.Nil(t, price)
 		assert.Contains(t, err.Error(), "external API error")
 
 		mockCache.AssertExpectations(t)
@@ -74,17 +82,187 @@ func TestPriceManager_GetETHUSDT(t *testing.T) {
 		mockClient := new(mocks.MockPriceClient)
 
 		// Simulate cache miss, valid external API response, but cache store fails
-		mockCache.On("GetRate", timestamp).Return(0.0, errors.New("cache miss"))
-		mockClient.On("GetETHUSDT", timestamp).Return(&client.KlineData{ClosePrice: 1850.00}, nil)
-		mockCache.On("StoreRate", timestamp, 1850.00).Return(errors.New("could not store in cache"))
+		kline := client.KlineData{ClosePrice: 1850.00, Source: "binance"}
+		mockClient.On("Resolution").Return(15 * time.Minute)
+		mockCache.On("GetRate", mock.Anything, timestamp).Return((*client.KlineData)(nil), errors.New("cache miss"))
+		mockClient.On("GetETHUSDT", mock.Anything, timestamp).Return(&kline, nil)
+		mockCache.On("StoreRate", mock.Anything, timestamp, kline).Return(errors.New("could not store in cache"))
 
 		priceManager := NewPriceManager(mockCache, mockClient)
-		price, err := priceManager.GetETHUSDT(timestamp)
+		price, err := priceManager.GetETHUSDT(context.Background(), timestamp)
 
 		assert.NoError(t, err)
-		assert.Equal(t, 1850.00, price)
+		assert.Equal(t, 1850.00, price.ClosePrice)
 
 		mockCache.AssertExpectations(t)
 		mockClient.AssertExpectations(t)
 	})
+
+	t.Run("last price reused within resolution", func(t *testing.T) {
+		mockCache := new(mocks.MockRateCache)
+		mockClient := new(mocks.MockPriceClient)
+
+		// Only the first timestamp reaches the cache, earlier and later timestamps within the resolution reuse it
+		mockClient.On("Resolution").Return(15 * time.Minute)
+		mockCache.On("GetRate", mock.Anything, timestamp).Return(&client.KlineData{ClosePrice: 4800.75, Source: "binance"}, nil).Once()
+
+		priceManager := NewPriceManager(mockCache, mockClient)
+		_, err := priceManager.GetETHUSDT(context.Background(), timestamp)
+		assert.NoError(t, err)
+
+		price, err := priceManager.GetETHUSDT(context.Background(), timestamp.Add(10*time.Minute))
+		assert.NoError(t, err)
+		assert.Equal(t, 4800.75, price.ClosePrice)
+
+		price, err = priceManager.GetETHUSDT(context.Background(), timestamp.Add(-10*time.Minute))
+		assert.NoError(t, err)
+		assert.Equal(t, 4800.75, price.ClosePrice)
+
+		// Outside of the resolution the price is looked up again
+		earlier := timestamp.Add(-time.Hour)
+		mockCache.On("GetRate", mock.Anything, earlier).Return(&client.KlineData{ClosePrice: 4700.00, Source: "binance"}, nil).Once()
+
+		price, err = priceManager.GetETHUSDT(context.Background(), earlier)
+		assert.NoError(t, err)
+		assert.Equal(t, 4700.00, price.ClosePrice)
+
+		mockCache.AssertExpectations(t)
+		mockClient.AssertExpectations(t)
+	})
+}
+
+func TestPriceManager_GetETHUSDTAtBlock(t *testing.T) {
+	timestamp := time.Unix(1727793983, 0)
+
+	t.Run("block price client", func(t *testing.T) {
+		// No expectation on the cache, block prices never go through it
+		mockCache := new(mocks.MockRateCache)
+		mockClient := new(mocks.MockBlockPriceClient)
+
+		kline := client.KlineData{ClosePrice: 2612.34, Source: "pool:0xpool"}
+		nextKline := client.KlineData{ClosePrice: 2615.02, Source: "pool:0xpool"}
+		otherChainKline := client.KlineData{ClosePrice: 2611.87, Source: "pool:0xpool"}
+		mockClient.On("Resolution").Return(12 * time.Second)
+		mockClient.On("ReadsBlocks").Return(true)
+		mockClient.On("GetETHUSDTAtBlock", mock.Anything, int64(1), uint64(20871331), timestamp).Return(&kline, nil)
+		mockClient.On("GetETHUSDTAtBlock", mock.Anything, int64(1), uint64(20871332), timestamp.Add(12*time.Second)).Return(&nextKline, nil)
+		mockClient.On("GetETHUSDTAtBlock", mock.Anything, int64(10), uint64(20871331), timestamp).Return(&otherChainKline, nil)
+
+		priceManager := NewPriceManager(mockCache, mockClient)
+		price, err := priceManager.GetETHUSDTAtBlock(context.Background(), 1, 20871331, timestamp)
+		assert.NoError(t, err)
+		assert.Equal(t, &kline, price)
+
+		// The same block is then answered from the last price
+		price, err = priceManager.GetETHUSDTAtBlock(context.Background(), 1, 20871331, timestamp)
+		assert.NoError(t, err)
+		assert.Equal(t, &kline, price)
+
+		// The next block, within the resolution of the client, has its own price
+		price, err = priceManager.GetETHUSDTAtBlock(context.Background(), 1, 20871332, timestamp.Add(12*time.Second))
+		assert.NoError(t, err)
+		assert.Equal(t, &nextKline, price)
+
+		// So does a block of another chain mined at the same time
+		price, err = priceManager.GetETHUSDTAtBlock(context.Background(), 10, 20871331, timestamp)
+		assert.NoError(t, err)
+		assert.Equal(t, &otherChainKline, price)
+
+		mockClient.AssertNumberOfCalls(t, "GetETHUSDTAtBlock", 3)
+		mockClient.AssertNotCalled(t, "GetETHUSDT", mock.Anything, mock.Anything)
+		mockCache.AssertExpectations(t)
+	})
+
+	t.Run("block price client pricing by time", func(t *testing.T) {
+		mockCache := new(mocks.MockRateCache)
+		mockClient := new(mocks.MockBlockPriceClient)
+
+		// A composite client without a source reading the chain goes through the cache like GetETHUSDT
+		kline := client.KlineData{ClosePrice: 2612.34, Source: "binance,chainlink:0xfeed"}
+		mockClient.On("Resolution").Return(time.Minute)
+		mockClient.On("ReadsBlocks").Return(false)
+		mockCache.On("GetRate", mock.Anything, timestamp).Return(&kline, nil)
+
+		priceManager := NewPriceManager(mockCache, mockClient)
+		price, err := priceManager.GetETHUSDTAtBlock(context.Background(), 1, 20871331, timestamp)
+		assert.NoError(t, err)
+		assert.Equal(t, &kline, price)
+
+		mockClient.AssertNotCalled(t, "GetETHUSDTAtBlock", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockCache.AssertExpectations(t)
+	})
+
+	t.Run("client without block support", func(t *testing.T) {
+		mockCache := new(mocks.MockRateCache)
+		mockClient := new(mocks.MockPriceClient)
+
+		// The block is priced by its timestamp
+		kline := client.KlineData{ClosePrice: 2612.34, Source: "binance"}
+		mockClient.On("Resolution").Return(time.Minute)
+		mockCache.On("GetRate", mock.Anything, timestamp).Return((*client.KlineData)(nil), errors.New("cache miss"))
+		mockClient.On("GetETHUSDT", mock.Anything, timestamp).Return(&kline, nil)
+		mockCache.On("StoreRate", mock.Anything, timestamp, kline).Return(nil)
+
+		priceManager := NewPriceManager(mockCache, mockClient)
+		price, err := priceManager.GetETHUSDTAtBlock(context.Background(), 1, 20871331, timestamp)
+		assert.NoError(t, err)
+		assert.Equal(t, &kline, price)
+
+		mockCache.AssertExpectations(t)
+		mockClient.AssertExpectations(t)
+	})
+}
+
+func TestPriceManager_PrefetchETHUSDT(t *testing.T) {
+	startTime := time.Unix(1727790000, 0)
+	endTime := startTime.Add(2 * time.Minute)
+
+	t.Run("range client fills the cache", func(t *testing.T) {
+		mockCache := new(mocks.MockRateCache)
+		mockClient := new(mocks.MockRangePriceClient)
+
+		prices := []client.PricePoint{
+			{Timestamp: startTime, KlineData: client.KlineData{ClosePrice: 2600.10, Source: "binance"}},
+			{Timestamp: startTime.Add(time.Minute), KlineData: client.KlineData{ClosePrice: 2601.20, Source: "binance"}},
+			{Timestamp: endTime, KlineData: client.KlineData{ClosePrice: 2602.30, Source: "binance"}},
+		}
+		mockClient.On("Resolution").Return(15 * time.Minute)
+		mockClient.On("GetETHUSDTRange", mock.Anything, startTime, endTime).Return(prices, nil)
+		mockCache.On("StoreRates", mock.Anything, prices).Return(nil)
+
+		priceManager := NewPriceManager(mockCache, mockClient)
+		err := priceManager.PrefetchETHUSDT(context.Background(), startTime, endTime)
+
+		assert.NoError(t, err)
+		mockCache.AssertExpectations(t)
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("range fetch error", func(t *testing.T) {
+		mockCache := new(mocks.MockRateCache)
+		mockClient := new(mocks.MockRangePriceClient)
+
+		mockClient.On("Resolution").Return(15 * time.Minute)
+		mockClient.On("GetETHUSDTRange", mock.Anything, startTime, endTime).Return([]client.PricePoint(nil), errors.New("external API error"))
+
+		priceManager := NewPriceManager(mockCache, mockClient)
+		err := priceManager.PrefetchETHUSDT(context.Background(), startTime, endTime)
+
+		assert.Error(t, err)
+		mockCache.AssertNotCalled(t, "StoreRates", mock.Anything)
+	})
+
+	t.Run("client without range support", func(t *testing.T) {
+		mockCache := new(mocks.MockRateCache)
+		mockClient := new(mocks.MockPriceClient)
+
+		// Prices are then fetched per transaction
+		mockClient.On("Resolution").Return(time.Duration(0))
+
+		priceManager := NewPriceManager(mockCache, mockClient)
+		err := priceManager.PrefetchETHUSDT(context.Background(), startTime, endTime)
+
+		assert.NoError(t, err)
+		mockCache.AssertNotCalled(t, "StoreRates", mock.Anything)
+	})
 }
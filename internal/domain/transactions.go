@@ -2,7 +2,10 @@ package domain
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/big"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -13,46 +16,225 @@ import (
 )
 
 // TransactionManager handles the business logic of computing the transaction price
+// Every request is routed to the client of the chain it targets.
 type TransactionManager struct {
-	transactionClient client.TransactionClient
-	priceManager      PriceManagerInterface
+	sources      []ChainSource
+	priceManager PriceManagerInterface
 }
 
-// NewTransactionManager creates and returns a new instance of TransactionManager
-func NewTransactionManager(transactionClient client.TransactionClient, priceManager PriceManagerInterface) *TransactionManager {
+// NewTransactionManager creates and returns a new instance of TransactionManager ingesting the given chains
+func NewTransactionManager(sources []ChainSource, priceManager PriceManagerInterface) *TransactionManager {
 	return &TransactionManager{
-		transactionClient: transactionClient,
-		priceManager:      priceManager,
+		sources:      sources,
+		priceManager: priceManager,
 	}
 }
 
-// GetLatestBlockNumber returns the latest transaction block number from the Uniswap V3 WETH-USDC pool
-func (tm *TransactionManager) GetLatestBlockNumber() (uint64, error) {
-	txData, err := tm.transactionClient.GetLatestTransaction()
+// ChainIDs returns the IDs of the chains the manager ingests
+func (tm *TransactionManager) ChainIDs() []int64 {
+	chainIDs := make([]int64, 0, len(tm.sources))
+	for _, source := range tm.sources {
+		chainIDs = append(chainIDs, source.ChainID)
+	}
+	return chainIDs
+}
+
+// GetLatestBlockNumber returns the most recent block of the chain with a transaction in any of its tracked pools.
+// Pools failing to report their latest transaction are skipped unless all of them fail.
+func (tm *TransactionManager) GetLatestBlockNumber(ctx context.Context, chainID int64) (uint64, error) {
+	source, err := tm.source(chainID)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get the latest transaction from the API client: %v", err)
+		return 0, err
 	}
-	return txData.BlockNumber, nil
+
+	var latestBlock uint64
+	var lastErr error
+	found := false
+
+	for _, poolAddress := range source.PoolAddresses {
+		txData, err := source.Client.GetLatestTransaction(ctx, poolAddress)
+		if err != nil {
+			fmt.Printf("Error getting the latest transaction of pool %s on chain %d: %v\n", poolAddress, chainID, err)
+			lastErr = err
+			continue
+		}
+
+		found = true
+		if txData.BlockNumber > latestBlock {
+			latestBlock = txData.BlockNumber
+		}
+	}
+
+	if !found {
+		return 0, fmt.Errorf("failed to get the latest transaction from the API client: %v", lastErr)
+	}
+	return latestBlock, nil
+}
+
+// GetSafeBlockNumber returns the most recent block of the chain buried under its confirmation depth.
+// Blocks up to it are unlikely to be reorganized and can be ingested.
+func (tm *TransactionManager) GetSafeBlockNumber(ctx context.Context, chainID int64) (uint64, error) {
+	source, err := tm.source(chainID)
+	if err != nil {
+		return 0, err
+	}
+
+	head, err := source.Client.GetBlockNumber(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get the chain head from the API client: %v", err)
+	}
+
+	if head < source.Confirmations {
+		return 0, nil
+	}
+	return head - source.Confirmations, nil
+}
+
+// GetBlockHeader returns the hash and parent hash of the block of the chain
+func (tm *TransactionManager) GetBlockHeader(ctx context.Context, chainID int64, blockNumber uint64) (*types.BlockHeader, error) {
+	source, err := tm.source(chainID)
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := source.Client.GetBlockHeader(ctx, blockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block %d from the API client: %v", blockNumber, err)
+	}
+	return header, nil
 }
 
 // GetTransaction queries transaction by hash and calculates its transaction price in USDT
-// It utilizes concurrent workers to efficiently retrieve and handle transactions in batches.
-func (tm *TransactionManager) GetTransaction(hash string) (*types.TxWithPrice, error) {
-	txData, err := tm.transactionClient.GetTransactionReceipt(hash)
+// The hash is looked up on every chain in order, the first chain knowing it wins.
+// A transaction that did not touch the tracked pools of its chain fails with a client.NotPoolTransactionError.
+func (tm *TransactionManager) GetTransaction(ctx context.Context, hash string) (*types.TxWithPrice, error) {
+	var lastErr error
+	for _, source := range tm.sources {
+		txData, err := source.Client.GetTransactionReceipt(ctx, hash)
+		var notPoolErr *client.NotPoolTransactionError
+		if errors.As(err, &notPoolErr) {
+			// The chain knows the transaction, the other chains won't
+			return nil, err
+		}
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		txData.ChainID = source.ChainID
+		return tm.processTransaction(ctx, *txData)
+	}
+
+	return nil, fmt.Errorf("failed to get transaction by hash from API client: %w", lastErr)
+}
+
+// BatchProcessTransactions fetches and processes transactions of every tracked pool of the chain within the given block range.
+// A transaction swapping in several tracked pools is returned once, tagged with the first pool and carrying all its swaps.
+func (tm *TransactionManager) BatchProcessTransactions(ctx context.Context, chainID int64, startBlock uint64, endBlock uint64) ([]types.TxWithPrice, error) {
+	source, err := tm.source(chainID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get transaction by hash from API client: %v", err)
+		return nil, err
 	}
+	return tm.batchProcessChain(ctx, source, startBlock, endBlock, nil)
+}
+
+// batchProcessChain fetches and processes transactions of every tracked pool of the chain, reporting the split plan to onProgress
+func (tm *TransactionManager) batchProcessChain(ctx context.Context, source ChainSource, startBlock uint64, endBlock uint64, onProgress types.ProgressFunc) ([]types.TxWithPrice, error) {
+	var allTransactions []types.TxWithPrice
+	indexByHash := make(map[string]int)
 
-	return tm.processTransaction(*txData)
+	for _, poolAddress := range source.PoolAddresses {
+		transactions, err := tm.batchProcessRange(ctx, source, poolAddress, startBlock, endBlock, onProgress)
+		if err != nil {
+			return allTransactions, err
+		}
+
+		for _, tx := range transactions {
+			hash := strings.ToLower(tx.Hash)
+			if i, exists := indexByHash[hash]; exists {
+				allTransactions[i].Swaps = mergeSwaps(allTransactions[i].Swaps, tx.Swaps)
+				continue
+			}
+			indexByHash[hash] = len(allTransactions)
+			allTransactions = append(allTransactions, tx)
+		}
+	}
+
+	return allTransactions, nil
 }
 
-// BatchProcessTransactions fetches and processes transactions within the given block range.
+// batchProcessRange fetches and processes transactions of a single pool within the given block range.
+// Ranges holding more results than the API pages through are split in two halves, recursively, until every half fits.
+func (tm *TransactionManager) batchProcessRange(ctx context.Context, source ChainSource, poolAddress string, startBlock uint64, endBlock uint64, onProgress types.ProgressFunc) ([]types.TxWithPrice, error) {
+	report := func(status string, transactions int) {
+		if onProgress != nil {
+			onProgress(types.RangeProgress{
+				ChainID:      source.ChainID,
+				PoolAddress:  poolAddress,
+				StartBlock:   startBlock,
+				EndBlock:     endBlock,
+				Status:       status,
+				Transactions: transactions,
+			})
+		}
+	}
+
+	// The range is paused while any of its workers waits for a circuit breaker
+	var pauseMu sync.Mutex
+	waiting := 0
+	pause := func(paused bool) {
+		pauseMu.Lock()
+		defer pauseMu.Unlock()
+		switch {
+		case paused:
+			waiting++
+			if waiting == 1 {
+				report(types.RangePaused, 0)
+			}
+		case waiting > 0:
+			waiting--
+			if waiting == 0 {
+				report(types.RangePending, 0)
+			}
+		}
+	}
+
+	report(types.RangePending, 0)
+	transactions, err := tm.batchProcessPool(ctx, source, poolAddress, startBlock, endBlock, pause)
+	if errors.Is(err, errResultWindowExceeded) && startBlock < endBlock {
+		report(types.RangeSplit, 0)
+		middle := startBlock + (endBlock-startBlock)/2
+
+		lower, err := tm.batchProcessRange(ctx, source, poolAddress, startBlock, middle, onProgress)
+		if err != nil {
+			return lower, err
+		}
+		upper, err := tm.batchProcessRange(ctx, source, poolAddress, middle+1, endBlock, onProgress)
+		return append(lower, upper...), err
+	}
+	if err != nil {
+		report(types.RangeFailed, len(transactions))
+		return transactions, fmt.Errorf("error fetching pool %s from block %d to %d: %w", poolAddress, startBlock, endBlock, err)
+	}
+
+	report(types.RangeDone, len(transactions))
+	return transactions, nil
+}
+
+// errResultWindowExceeded is returned when a block range holds more results than the API pages through
+var errResultWindowExceeded = fmt.Errorf("more than %d results in the block range", client.ResultWindow)
+
+// batchProcessPool fetches and processes transactions of a single pool within the given block range.
 // It utilizes concurrent workers to fetch and process transactions.
-func (tm *TransactionManager) BatchProcessTransactions(startBlock uint64, endBlock uint64, ctx context.Context) ([]types.TxWithPrice, error) {
+// Pages are dispatched up to the result window of the API, a range reaching it fails with errResultWindowExceeded
+// and a page failing for any other reason fails the range, so that it is never silently truncated.
+// Workers turned away by an open circuit breaker wait for it and retry, calling pause around the wait.
+func (tm *TransactionManager) batchProcessPool(ctx context.Context, source ChainSource, poolAddress string, startBlock uint64, endBlock uint64, pause func(paused bool)) ([]types.TxWithPrice, error) {
 	var allTransactions []types.TxWithPrice
 
 	batchSize := 100
 	numWorkers := 10
+	maxPages := client.ResultWindow / batchSize
 
 	pages := make(chan int)
 	results := make(chan types.TxWithPrice)
@@ -62,11 +244,36 @@ func (tm *TransactionManager) BatchProcessTransactions(startBlock uint64, endBlo
 
 	// Ensure stopSignal is closed only once
 	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			close(stopSignal)
+		})
+	}
 
 	// Set to track unique transaction hashes
 	uniqueHashes := make(map[string]struct{})
 	var mu sync.Mutex
 
+	// First error ending the range early, guarded by mu
+	var fetchErr error
+	fail := func(err error) {
+		mu.Lock()
+		if fetchErr == nil {
+			fetchErr = err
+		}
+		mu.Unlock()
+		stop()
+	}
+
+	// Last page that may hold results, guarded by mu. Pages past it are skipped once the end of the range is known.
+	lastPage := maxPages
+	stopAt := func(page int) {
+		mu.Lock()
+		lastPage = min(lastPage, page)
+		mu.Unlock()
+		stop()
+	}
+
 	// Worker function: fetches and processes transactions from pages channel
 	worker := func() {
 		defer wg.Done()
@@ -80,14 +287,30 @@ func (tm *TransactionManager) BatchProcessTransactions(startBlock uint64, endBlo
 				if !ok {
 					return
 				}
+				// The select picks at random between a closed stopSignal and a dispatched page,
+				// pages past the end of the range may still come through
+				mu.Lock()
+				past := page > lastPage
+				mu.Unlock()
+				if past {
+					continue
+				}
 				// Fetch transactions for the current page
-				transactions, err := tm.transactionClient.ListTransactions(&batchSize, &startBlock, &endBlock, &page)
+				transactions, err := source.Client.ListTransactions(ctx, poolAddress, &batchSize, &startBlock, &endBlock, &page)
+				for waitForBreaker(ctx, err, pause) {
+					transactions, err = source.Client.ListTransactions(ctx, poolAddress, &batchSize, &startBlock, &endBlock, &page)
+				}
 				if err != nil {
-					// If it's a "No transactions found" error, assume no more pages
-					if strings.Contains(err.Error(), "No transactions found") {
-						once.Do(func() {
-							close(stopSignal)
-						})
+					var windowErr *client.ResultWindowError
+					var notFoundErr *client.NotFoundError
+					switch {
+					case errors.As(err, &windowErr):
+						fail(errResultWindowExceeded)
+					case errors.As(err, &notFoundErr):
+						// Past the last page
+						stopAt(page)
+					default:
+						fail(fmt.Errorf("error fetching page %d: %w", page, err))
 					}
 					continue
 				}
@@ -103,7 +326,11 @@ func (tm *TransactionManager) BatchProcessTransactions(startBlock uint64, endBlo
 					uniqueHashes[tx.Hash] = struct{}{}
 					mu.Unlock()
 
-					txWithPrice, err := tm.processTransaction(tx)
+					tx.ChainID = source.ChainID
+					txWithPrice, err := tm.processTransaction(ctx, tx)
+					for waitForBreaker(ctx, err, pause) {
+						txWithPrice, err = tm.processTransaction(ctx, tx)
+					}
 					if err != nil {
 						fmt.Printf("Error processing transaction %s: %v\n", tx.Hash, err)
 						continue
@@ -115,12 +342,13 @@ func (tm *TransactionManager) BatchProcessTransactions(startBlock uint64, endBlo
 					}
 				}
 
-				// If fewer transactions than batchSize are returned, it's likely the last page
-				if len(transactions) < batchSize {
-					// Signal to stop further page dispatching
-					once.Do(func() {
-						close(stopSignal)
-					})
+				switch {
+				case len(transactions) < batchSize:
+					// If fewer transactions than batchSize are returned, it's likely the last page
+					stopAt(page)
+				case page == maxPages:
+					// The last page of the window is full, results past it can't be reached
+					fail(errResultWindowExceeded)
 				}
 			}
 		}
@@ -135,8 +363,7 @@ func (tm *TransactionManager) BatchProcessTransactions(startBlock uint64, endBlo
 	// Dispatcher: sends page numbers to pages channel
 	go func() {
 		defer close(pages)
-		page := 1
-		for {
+		for page := 1; page <= maxPages; page++ {
 			select {
 			case <-ctx.Done():
 				return
@@ -145,7 +372,6 @@ func (tm *TransactionManager) BatchProcessTransactions(startBlock uint64, endBlo
 			default:
 				select {
 				case pages <- page:
-					page++
 				case <-ctx.Done():
 					return
 				case <-stopSignal:
@@ -166,40 +392,168 @@ func (tm *TransactionManager) BatchProcessTransactions(startBlock uint64, endBlo
 		allTransactions = append(allTransactions, tx)
 	}
 
-	return allTransactions, nil
+	if fetchErr != nil {
+		return allTransactions, fetchErr
+	}
+	return allTransactions, ctx.Err()
+}
+
+// waitForBreaker waits until the open circuit breaker the call failed on lets requests through again.
+// It reports whether the call should be retried, false when the error is not an open breaker or the context is done.
+// pause, when set, is called with true before waiting and false once the wait is over.
+func waitForBreaker(ctx context.Context, err error, pause func(paused bool)) bool {
+	var circuitOpenErr *client.CircuitOpenError
+	if !errors.As(err, &circuitOpenErr) {
+		return false
+	}
+
+	if pause != nil {
+		pause(true)
+		defer pause(false)
+	}
+	fmt.Printf("Pausing until %v: %v\n", circuitOpenErr.RetryAt.Format(time.RFC3339), err)
+
+	timer := time.NewTimer(time.Until(circuitOpenErr.RetryAt))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
 }
 
 // processTransaction fetches transaction receipt and calculates fees
-func (tm *TransactionManager) processTransaction(tx types.TransactionData) (*types.TxWithPrice, error) {
-	// Fetch ETH-USDT conversion rate at the transaction's timestamp
-	ethUSDTConversionRate, err := tm.priceManager.GetETHUSDT(tx.Timestamp)
+func (tm *TransactionManager) processTransaction(ctx context.Context, tx types.TransactionData) (*types.TxWithPrice, error) {
+	// Fetch ETH-USDT conversion rate at the transaction's block
+	ethUSDTConversionRate, err := tm.priceManager.GetETHUSDTAtBlock(ctx, tx.ChainID, tx.BlockNumber, tx.Timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ETH-USDT conversion rate: %w", err)
+	}
+
+	price, err := ethUSDTConversionRate.ExactClosePrice()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get ETH-USDT conversion rate: %v", err)
+		return nil, fmt.Errorf("invalid ETH-USDT conversion rate: %v", err)
 	}
 
-	// Calculate fees
-	feeETH := utils.ConvertToETH(tx.GasPriceWei) * float64(tx.GasUsed)
-	feeUSDT := feeETH * ethUSDTConversionRate
+	// Calculate fees exactly, in wei first
+	feeWei := new(big.Int).Mul(tx.GasPriceWei, new(big.Int).SetUint64(tx.GasUsed))
+	feeETH := utils.WeiToETH(feeWei)
+	feeUSDT := new(big.Rat).Mul(feeETH, price)
 
-	return &types.TxWithPrice{
+	txWithPrice := &types.TxWithPrice{
 		TransactionData:    tx,
-		ETHUSDTPrice:       ethUSDTConversionRate,
+		ETHUSDTPrice:       price,
+		PriceSource:        ethUSDTConversionRate.Source,
+		PriceSpread:        ethUSDTConversionRate.Spread,
+		PriceSuspect:       ethUSDTConversionRate.Suspect,
+		TransactionFeeWei:  feeWei,
 		TransactionFeeETH:  feeETH,
 		TransactionFeeUSDT: feeUSDT,
-	}, nil
+	}
+	splitFee(txWithPrice, price)
+
+	return txWithPrice, nil
 }
 
-func (tm *TransactionManager) BatchProcessTransactionsByTimestamp(startTime time.Time, endTime time.Time, ctx context.Context) ([]types.TxWithPrice, error) {
+// splitFee breaks the fee down into the base fee burned and the priority tip paid to the block builder.
+// The effective tip is whatever the transaction paid per gas above the base fee, which for EIP-1559 transactions
+// is min(maxPriorityFeePerGas, maxFeePerGas - baseFeePerGas). Without a base fee there is nothing to split.
+func splitFee(tx *types.TxWithPrice, price *big.Rat) {
+	if tx.BaseFeePerGasWei == nil {
+		return
+	}
+
+	tip := new(big.Int).Sub(tx.GasPriceWei, tx.BaseFeePerGasWei)
+	if tip.Sign() < 0 {
+		// The effective gas price can't be under the base fee, guard against inconsistent sources
+		tip.SetInt64(0)
+	}
+
+	gasUsed := new(big.Int).SetUint64(tx.GasUsed)
+	burnedETH := utils.WeiToETH(new(big.Int).Mul(tx.BaseFeePerGasWei, gasUsed))
+	tipETH := utils.WeiToETH(new(big.Int).Mul(tip, gasUsed))
+
+	tx.EffectiveTipPerGasWei = tip
+	tx.BurnedFeeETH = burnedETH
+	tx.BurnedFeeUSDT = new(big.Rat).Mul(burnedETH, price)
+	tx.TipFeeETH = tipETH
+	tx.TipFeeUSDT = new(big.Rat).Mul(tipETH, price)
+}
+
+// mergeSwaps appends the swaps missing from existing, matching them by log index
+func mergeSwaps(existing []types.SwapEvent, swaps []types.SwapEvent) []types.SwapEvent {
+	seen := make(map[uint]struct{}, len(existing))
+	for _, swap := range existing {
+		seen[swap.LogIndex] = struct{}{}
+	}
+
+	for _, swap := range swaps {
+		if _, exists := seen[swap.LogIndex]; exists {
+			continue
+		}
+		seen[swap.LogIndex] = struct{}{}
+		existing = append(existing, swap)
+	}
+
+	sort.Slice(existing, func(i, j int) bool {
+		return existing[i].LogIndex < existing[j].LogIndex
+	})
+	return existing
+}
+
+// BatchProcessTransactionsByTimestamp fetches and processes the transactions of every chain within the given time range.
+// Block numbers differ per chain, so the range is resolved to blocks on each chain separately.
+// Chains that fail do not stop the others, their errors are returned together with the processed transactions.
+// onProgress, when set, is called with every block range fetched and split on the way.
+func (tm *TransactionManager) BatchProcessTransactionsByTimestamp(ctx context.Context, startTime time.Time, endTime time.Time, onProgress types.ProgressFunc) ([]types.TxWithPrice, error) {
+	// Load the prices of the whole window upfront, transactions missing from it are still priced one by one
+	if err := tm.priceManager.PrefetchETHUSDT(ctx, startTime, endTime); err != nil {
+		fmt.Printf("Failed to prefetch prices, falling back to per transaction prices: %v\n", err)
+	}
+
+	var allTransactions []types.TxWithPrice
+	var errs []error
+
+	for _, source := range tm.sources {
+		transactions, err := tm.batchProcessChainByTimestamp(ctx, source, startTime, endTime, onProgress)
+		allTransactions = append(allTransactions, transactions...)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("chain %d: %v", source.ChainID, err))
+		}
+	}
+
+	return allTransactions, errors.Join(errs...)
+}
+
+// batchProcessChainByTimestamp fetches and processes the transactions of a single chain within the given time range
+func (tm *TransactionManager) batchProcessChainByTimestamp(ctx context.Context, source ChainSource, startTime time.Time, endTime time.Time, onProgress types.ProgressFunc) ([]types.TxWithPrice, error) {
 	// Get starting and ending block number that is WITHIN the timestamp (after start and before end)
-	startBlock, err := tm.transactionClient.GetBlockNumberByTimestamp(startTime, false)
+	startBlock, err := source.Client.GetBlockNumberByTimestamp(ctx, startTime, false)
+	for waitForBreaker(ctx, err, nil) {
+		startBlock, err = source.Client.GetBlockNumberByTimestamp(ctx, startTime, false)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get the starting block number: %v", err)
 	}
 
-	endBlock, err := tm.transactionClient.GetBlockNumberByTimestamp(endTime, true)
+	endBlock, err := source.Client.GetBlockNumberByTimestamp(ctx, endTime, true)
+	for waitForBreaker(ctx, err, nil) {
+		endBlock, err = source.Client.GetBlockNumberByTimestamp(ctx, endTime, true)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get the ending block number: %v", err)
 	}
 
-	return tm.BatchProcessTransactions(startBlock, endBlock, ctx)
+	return tm.batchProcessChain(ctx, source, startBlock, endBlock, onProgress)
+}
+
+// source returns the chain source serving the chain ID
+func (tm *TransactionManager) source(chainID int64) (ChainSource, error) {
+	for _, source := range tm.sources {
+		if source.ChainID == chainID {
+			return source, nil
+		}
+	}
+	return ChainSource{}, fmt.Errorf("chain %d is not configured", chainID)
 }
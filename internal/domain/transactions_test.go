@@ -0,0 +1,364 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/winQe/uniswap-fee-tracker/internal/client"
+	"github.com/winQe/uniswap-fee-tracker/internal/mocks"
+	"github.com/winQe/uniswap-fee-tracker/internal/types"
+)
+
+const (
+	pool005 = "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640"
+	pool030 = "0x8ad599c3a0ff1de082011efddc58f1908eb6e6d8"
+)
+
+func TestTransactionManager_GetLatestBlockNumber(t *testing.T) {
+	t.Run("newest block across pools", func(t *testing.T) {
+		mockClient := new(mocks.MockTransactionClient)
+		mockClient.On("GetLatestTransaction", mock.Anything, pool005).Return(&types.TransactionData{BlockNumber: 100}, nil)
+		mockClient.On("GetLatestTransaction", mock.Anything, pool030).Return(&types.TransactionData{BlockNumber: 105}, nil)
+
+		tm := NewTransactionManager([]ChainSource{{ChainID: 1, Client: mockClient, PoolAddresses: []string{pool005, pool030}}}, new(mocks.MockPriceManager))
+		blockNumber, err := tm.GetLatestBlockNumber(context.Background(), 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(105), blockNumber)
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("failing pool is skipped", func(t *testing.T) {
+		mockClient := new(mocks.MockTransactionClient)
+		mockClient.On("GetLatestTransaction", mock.Anything, pool005).Return((*types.TransactionData)(nil), errors.New("API error"))
+		mockClient.On("GetLatestTransaction", mock.Anything, pool030).Return(&types.TransactionData{BlockNumber: 105}, nil)
+
+		tm := NewTransactionManager([]ChainSource{{ChainID: 1, Client: mockClient, PoolAddresses: []string{pool005, pool030}}}, new(mocks.MockPriceManager))
+		blockNumber, err := tm.GetLatestBlockNumber(context.Background(), 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(105), blockNumber)
+	})
+
+	t.Run("every pool fails", func(t *testing.T) {
+		mockClient := new(mocks.MockTransactionClient)
+		mockClient.On("GetLatestTransaction", mock.Anything, mock.Anything).Return((*types.TransactionData)(nil), errors.New("API error"))
+
+		tm := NewTransactionManager([]ChainSource{{ChainID: 1, Client: mockClient, PoolAddresses: []string{pool005, pool030}}}, new(mocks.MockPriceManager))
+		_, err := tm.GetLatestBlockNumber(context.Background(), 1)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("unknown chain", func(t *testing.T) {
+		tm := NewTransactionManager([]ChainSource{{ChainID: 1, Client: new(mocks.MockTransactionClient), PoolAddresses: []string{pool005}}}, new(mocks.MockPriceManager))
+		_, err := tm.GetLatestBlockNumber(context.Background(), 42161)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestTransactionManager_GetSafeBlockNumber(t *testing.T) {
+	t.Run("head minus the confirmation depth", func(t *testing.T) {
+		mockClient := new(mocks.MockTransactionClient)
+		mockClient.On("GetBlockNumber", mock.Anything).Return(uint64(20884100), nil)
+
+		tm := NewTransactionManager([]ChainSource{{ChainID: 1, Client: mockClient, Confirmations: 12}}, new(mocks.MockPriceManager))
+		safeBlock, err := tm.GetSafeBlockNumber(context.Background(), 1)
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(20884088), safeBlock)
+	})
+
+	t.Run("chain shorter than the confirmation depth", func(t *testing.T) {
+		mockClient := new(mocks.MockTransactionClient)
+		mockClient.On("GetBlockNumber", mock.Anything).Return(uint64(5), nil)
+
+		tm := NewTransactionManager([]ChainSource{{ChainID: 1, Client: mockClient, Confirmations: 12}}, new(mocks.MockPriceManager))
+		safeBlock, err := tm.GetSafeBlockNumber(context.Background(), 1)
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(0), safeBlock)
+	})
+}
+
+func TestTransactionManager_GetTransaction(t *testing.T) {
+	timestamp := time.Unix(1727793983, 0)
+
+	t.Run("priced at its block", func(t *testing.T) {
+		mockClient := new(mocks.MockTransactionClient)
+		mockClient.On("GetTransactionReceipt", mock.Anything, "0xaa").Return(&types.TransactionData{
+			BlockNumber: 20871331, Hash: "0xaa", GasUsed: 21000, GasPriceWei: big.NewInt(1000000000), Timestamp: timestamp, PoolAddress: pool005,
+		}, nil)
+
+		mockPriceManager := new(mocks.MockPriceManager)
+		mockPriceManager.On("GetETHUSDTAtBlock", mock.Anything, int64(1), uint64(20871331), timestamp).Return(&client.KlineData{ClosePrice: 2000.0, ClosePriceDecimal: "2000"}, nil)
+
+		tm := NewTransactionManager([]ChainSource{{ChainID: 1, Client: mockClient, PoolAddresses: []string{pool005}}}, mockPriceManager)
+		tx, err := tm.GetTransaction(context.Background(), "0xaa")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), tx.ChainID)
+		assert.Equal(t, "21/500", tx.TransactionFeeUSDT.RatString())
+		mockPriceManager.AssertExpectations(t)
+	})
+
+	t.Run("not a pool transaction", func(t *testing.T) {
+		mainnetClient := new(mocks.MockTransactionClient)
+		mainnetClient.On("GetTransactionReceipt", mock.Anything, "0xbb").Return((*types.TransactionData)(nil), &client.NotPoolTransactionError{Hash: "0xbb"})
+		arbitrumClient := new(mocks.MockTransactionClient)
+
+		tm := NewTransactionManager([]ChainSource{
+			{ChainID: 1, Client: mainnetClient, PoolAddresses: []string{pool005}},
+			{ChainID: 42161, Client: arbitrumClient},
+		}, new(mocks.MockPriceManager))
+		_, err := tm.GetTransaction(context.Background(), "0xbb")
+
+		var notPoolErr *client.NotPoolTransactionError
+		assert.ErrorAs(t, err, &notPoolErr)
+		// The other chains are not queried once a chain knows the transaction
+		arbitrumClient.AssertNotCalled(t, "GetTransactionReceipt", mock.Anything, mock.Anything)
+	})
+}
+
+func TestTransactionManager_BatchProcessTransactions(t *testing.T) {
+	timestamp := time.Unix(1727793983, 0)
+	gasPriceWei := big.NewInt(1000000000)
+
+	// 0xaa routes through both pools, 0xbb only trades in the 0.3% pool
+	routed005 := types.TransactionData{
+		BlockNumber: 101, Hash: "0xaa", GasUsed: 21000, GasPriceWei: gasPriceWei, Timestamp: timestamp, PoolAddress: pool005,
+		Swaps: []types.SwapEvent{{LogIndex: 3, PoolAddress: pool005}},
+	}
+	routed030 := types.TransactionData{
+		BlockNumber: 101, Hash: "0xAA", GasUsed: 21000, GasPriceWei: gasPriceWei, Timestamp: timestamp, PoolAddress: pool030,
+		Swaps: []types.SwapEvent{{LogIndex: 1, PoolAddress: pool030}},
+	}
+	single030 := types.TransactionData{
+		BlockNumber: 100, Hash: "0xbb", GasUsed: 21000, GasPriceWei: gasPriceWei, Timestamp: timestamp, PoolAddress: pool030,
+	}
+
+	mockClient := new(mocks.MockTransactionClient)
+	mockClient.On("ListTransactions", mock.Anything, pool005, mock.Anything, mock.Anything, mock.Anything, mock.MatchedBy(func(page *int) bool { return *page == 1 })).
+		Return([]types.TransactionData{routed005}, nil)
+	mockClient.On("ListTransactions", mock.Anything, pool030, mock.Anything, mock.Anything, mock.Anything, mock.MatchedBy(func(page *int) bool { return *page == 1 })).
+		Return([]types.TransactionData{routed030, single030}, nil)
+	mockClient.On("ListTransactions", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return([]types.TransactionData{}, nil)
+
+	mockPriceManager := new(mocks.MockPriceManager)
+	mockPriceManager.On("GetETHUSDTAtBlock", mock.Anything, mock.Anything, mock.Anything, timestamp).Return(&client.KlineData{ClosePrice: 2000.1, ClosePriceDecimal: "2000.1", Source: "binance,pool:" + pool005, Spread: 0.02, Suspect: true}, nil)
+
+	tm := NewTransactionManager([]ChainSource{{ChainID: 1, Client: mockClient, PoolAddresses: []string{pool005, pool030}}}, mockPriceManager)
+	transactions, err := tm.BatchProcessTransactions(context.Background(), 1, 100, 101)
+	assert.NoError(t, err)
+	assert.Len(t, transactions, 2)
+
+	// The routed transaction is kept once with the swaps of both pools
+	assert.Equal(t, "0xaa", transactions[0].Hash)
+	assert.Equal(t, pool005, transactions[0].PoolAddress)
+	assert.Equal(t, []types.SwapEvent{{LogIndex: 1, PoolAddress: pool030}, {LogIndex: 3, PoolAddress: pool005}}, transactions[0].Swaps)
+
+	assert.Equal(t, int64(1), transactions[0].ChainID)
+
+	assert.Equal(t, "0xbb", transactions[1].Hash)
+	assert.Equal(t, pool030, transactions[1].PoolAddress)
+	// Fees are exact, 21000 gas at 1 gwei is 0.000021 ETH
+	assert.Equal(t, "21000000000000", transactions[1].TransactionFeeWei.String())
+	assert.Equal(t, "0.000021000000000000", transactions[1].TransactionFeeETH.FloatString(18))
+	assert.Equal(t, "420021/10000000", transactions[1].TransactionFeeUSDT.RatString())
+	assert.Equal(t, "binance,pool:"+pool005, transactions[1].PriceSource)
+	assert.Equal(t, 0.02, transactions[1].PriceSpread)
+	assert.True(t, transactions[1].PriceSuspect)
+}
+
+// blockRange matches the block range of a ListTransactions call
+func blockRange(mockClient *mocks.MockTransactionClient, startBlock uint64, endBlock uint64) *mock.Call {
+	return mockClient.On("ListTransactions", mock.Anything, pool005, mock.Anything,
+		mock.MatchedBy(func(block *uint64) bool { return *block == startBlock }),
+		mock.MatchedBy(func(block *uint64) bool { return *block == endBlock }),
+		mock.Anything)
+}
+
+func TestTransactionManager_BatchProcessTransactions_SplitsRange(t *testing.T) {
+	timestamp := time.Unix(1727793983, 0)
+	windowErr := &client.ResultWindowError{Page: 101, Offset: 100}
+
+	// Blocks 100 to 400 and 251 to 400 hold more results than Etherscan pages through
+	mockClient := new(mocks.MockTransactionClient)
+	blockRange(mockClient, 100, 400).Return([]types.TransactionData(nil), windowErr)
+	blockRange(mockClient, 100, 250).Return([]types.TransactionData{
+		{BlockNumber: 200, Hash: "0xaa", GasUsed: 21000, GasPriceWei: big.NewInt(1000000000), Timestamp: timestamp},
+	}, nil)
+	blockRange(mockClient, 251, 400).Return([]types.TransactionData(nil), windowErr)
+	blockRange(mockClient, 251, 325).Return([]types.TransactionData{
+		{BlockNumber: 300, Hash: "0xbb", GasUsed: 21000, GasPriceWei: big.NewInt(1000000000), Timestamp: timestamp},
+	}, nil)
+	blockRange(mockClient, 326, 400).Return([]types.TransactionData{}, nil)
+
+	mockPriceManager := new(mocks.MockPriceManager)
+	mockPriceManager.On("GetETHUSDTAtBlock", mock.Anything, mock.Anything, mock.Anything, timestamp).Return(&client.KlineData{ClosePrice: 2000.0, ClosePriceDecimal: "2000"}, nil)
+
+	source := ChainSource{ChainID: 1, Client: mockClient, PoolAddresses: []string{pool005}}
+	tm := NewTransactionManager([]ChainSource{source}, mockPriceManager)
+
+	var plan []types.RangeProgress
+	transactions, err := tm.batchProcessChain(context.Background(), source, 100, 400, func(progress types.RangeProgress) {
+		plan = append(plan, progress)
+	})
+	assert.NoError(t, err)
+
+	hashes := []string{}
+	for _, tx := range transactions {
+		hashes = append(hashes, tx.Hash)
+	}
+	assert.ElementsMatch(t, []string{"0xaa", "0xbb"}, hashes)
+
+	// The split halves cover the whole range
+	assert.Equal(t, []types.RangeProgress{
+		{ChainID: 1, PoolAddress: pool005, StartBlock: 100, EndBlock: 400, Status: types.RangePending},
+		{ChainID: 1, PoolAddress: pool005, StartBlock: 100, EndBlock: 400, Status: types.RangeSplit},
+		{ChainID: 1, PoolAddress: pool005, StartBlock: 100, EndBlock: 250, Status: types.RangePending},
+		{ChainID: 1, PoolAddress: pool005, StartBlock: 100, EndBlock: 250, Status: types.RangeDone, Transactions: 1},
+		{ChainID: 1, PoolAddress: pool005, StartBlock: 251, EndBlock: 400, Status: types.RangePending},
+		{ChainID: 1, PoolAddress: pool005, StartBlock: 251, EndBlock: 400, Status: types.RangeSplit},
+		{ChainID: 1, PoolAddress: pool005, StartBlock: 251, EndBlock: 325, Status: types.RangePending},
+		{ChainID: 1, PoolAddress: pool005, StartBlock: 251, EndBlock: 325, Status: types.RangeDone, Transactions: 1},
+		{ChainID: 1, PoolAddress: pool005, StartBlock: 326, EndBlock: 400, Status: types.RangePending},
+		{ChainID: 1, PoolAddress: pool005, StartBlock: 326, EndBlock: 400, Status: types.RangeDone},
+	}, plan)
+}
+
+func TestTransactionManager_BatchProcessTransactions_Errors(t *testing.T) {
+	t.Run("single block over the result window", func(t *testing.T) {
+		mockClient := new(mocks.MockTransactionClient)
+		blockRange(mockClient, 100, 100).Return([]types.TransactionData(nil), &client.ResultWindowError{Page: 101, Offset: 100})
+
+		tm := NewTransactionManager([]ChainSource{{ChainID: 1, Client: mockClient, PoolAddresses: []string{pool005}}}, new(mocks.MockPriceManager))
+		_, err := tm.BatchProcessTransactions(context.Background(), 1, 100, 100)
+
+		assert.ErrorIs(t, err, errResultWindowExceeded)
+	})
+
+	t.Run("failing page is not skipped", func(t *testing.T) {
+		mockClient := new(mocks.MockTransactionClient)
+		blockRange(mockClient, 100, 200).Return([]types.TransactionData(nil), errors.New("Etherscan server error: NOTOK"))
+
+		tm := NewTransactionManager([]ChainSource{{ChainID: 1, Client: mockClient, PoolAddresses: []string{pool005}}}, new(mocks.MockPriceManager))
+		_, err := tm.BatchProcessTransactions(context.Background(), 1, 100, 200)
+
+		assert.ErrorContains(t, err, "NOTOK")
+	})
+}
+
+func TestTransactionManager_BatchProcessTransactions_PausesOnOpenBreaker(t *testing.T) {
+	timestamp := time.Unix(1727793983, 0)
+	circuitOpen := func() error {
+		return &client.CircuitOpenError{Name: "etherscan:1", RetryAt: time.Now().Add(20 * time.Millisecond)}
+	}
+
+	// The explorer and then the price source are down for a moment, nothing is dropped
+	mockClient := new(mocks.MockTransactionClient)
+	blockRange(mockClient, 100, 200).Return([]types.TransactionData(nil), circuitOpen()).Once()
+	blockRange(mockClient, 100, 200).Return([]types.TransactionData{
+		{BlockNumber: 150, Hash: "0xaa", GasUsed: 21000, GasPriceWei: big.NewInt(1000000000), Timestamp: timestamp},
+	}, nil)
+
+	mockPriceManager := new(mocks.MockPriceManager)
+	mockPriceManager.On("GetETHUSDTAtBlock", mock.Anything, mock.Anything, mock.Anything, timestamp).Return((*client.KlineData)(nil), circuitOpen()).Once()
+	mockPriceManager.On("GetETHUSDTAtBlock", mock.Anything, mock.Anything, mock.Anything, timestamp).Return(&client.KlineData{ClosePrice: 2000.0, ClosePriceDecimal: "2000"}, nil)
+
+	source := ChainSource{ChainID: 1, Client: mockClient, PoolAddresses: []string{pool005}}
+	tm := NewTransactionManager([]ChainSource{source}, mockPriceManager)
+
+	var statuses []string
+	transactions, err := tm.batchProcessChain(context.Background(), source, 100, 200, func(progress types.RangeProgress) {
+		statuses = append(statuses, progress.Status)
+	})
+	assert.NoError(t, err)
+	assert.Len(t, transactions, 1)
+
+	assert.Contains(t, statuses, types.RangePaused)
+	assert.Equal(t, types.RangeDone, statuses[len(statuses)-1])
+	mockPriceManager.AssertNumberOfCalls(t, "GetETHUSDTAtBlock", 2)
+}
+
+func TestTransactionManager_FeeBreakdown(t *testing.T) {
+	timestamp := time.Unix(1727793983, 0)
+	mockPriceManager := new(mocks.MockPriceManager)
+	mockPriceManager.On("GetETHUSDTAtBlock", mock.Anything, mock.Anything, mock.Anything, timestamp).Return(&client.KlineData{ClosePrice: 2000.0, ClosePriceDecimal: "2000"}, nil)
+	tm := NewTransactionManager(nil, mockPriceManager)
+
+	t.Run("EIP-1559 transaction", func(t *testing.T) {
+		// 100000 gas at 12 gwei, 10 gwei of which is the base fee
+		tx, err := tm.processTransaction(context.Background(), types.TransactionData{
+			Hash: "0xaa", GasUsed: 100000, GasPriceWei: big.NewInt(12000000000), Timestamp: timestamp,
+			BaseFeePerGasWei: big.NewInt(10000000000), MaxFeePerGasWei: big.NewInt(30000000000), MaxPriorityFeePerGasWei: big.NewInt(2000000000),
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, big.NewInt(2000000000), tx.EffectiveTipPerGasWei)
+		assert.Equal(t, "1/1000", tx.BurnedFeeETH.RatString())
+		assert.Equal(t, "2", tx.BurnedFeeUSDT.RatString())
+		assert.Equal(t, "1/5000", tx.TipFeeETH.RatString())
+		assert.Equal(t, "2/5", tx.TipFeeUSDT.RatString())
+
+		// The burned fee and the tip add up to the fee
+		total := new(big.Rat).Add(tx.BurnedFeeETH, tx.TipFeeETH)
+		assert.Equal(t, tx.TransactionFeeETH.RatString(), total.RatString())
+	})
+
+	t.Run("without base fee", func(t *testing.T) {
+		tx, err := tm.processTransaction(context.Background(), types.TransactionData{
+			Hash: "0xbb", GasUsed: 21000, GasPriceWei: big.NewInt(1000000000), Timestamp: timestamp,
+		})
+		assert.NoError(t, err)
+
+		assert.Nil(t, tx.EffectiveTipPerGasWei)
+		assert.Nil(t, tx.BurnedFeeETH)
+		assert.Nil(t, tx.TipFeeUSDT)
+	})
+}
+
+func TestTransactionManager_BatchProcessTransactionsByTimestamp(t *testing.T) {
+	startTime := time.Unix(1727790000, 0)
+	endTime := time.Unix(1727793600, 0)
+	arbitrumPool := "0xc6962004f452be9203591991d15f6b388e09e8d0"
+
+	// Each chain resolves the time range to its own block numbers
+	mainnetClient := new(mocks.MockTransactionClient)
+	mainnetClient.On("GetBlockNumberByTimestamp", mock.Anything, startTime, false).Return(uint64(100), nil)
+	mainnetClient.On("GetBlockNumberByTimestamp", mock.Anything, endTime, true).Return(uint64(400), nil)
+	mainnetClient.On("ListTransactions", mock.Anything, pool005, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return([]types.TransactionData{}, nil)
+
+	arbitrumClient := new(mocks.MockTransactionClient)
+	arbitrumClient.On("GetBlockNumberByTimestamp", mock.Anything, startTime, false).Return(uint64(250000000), nil)
+	arbitrumClient.On("GetBlockNumberByTimestamp", mock.Anything, endTime, true).Return(uint64(250014400), nil)
+	arbitrumClient.On("ListTransactions", mock.Anything, arbitrumPool, mock.Anything, mock.MatchedBy(func(block *uint64) bool { return *block == 250000000 }), mock.MatchedBy(func(block *uint64) bool { return *block == 250014400 }), mock.Anything).
+		Return([]types.TransactionData{{BlockNumber: 250000001, Hash: "0xcc", GasUsed: 21000, GasPriceWei: big.NewInt(10000000), Timestamp: startTime, PoolAddress: arbitrumPool}}, nil).Once()
+	arbitrumClient.On("ListTransactions", mock.Anything, arbitrumPool, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return([]types.TransactionData{}, nil)
+
+	mockPriceManager := new(mocks.MockPriceManager)
+	mockPriceManager.On("GetETHUSDTAtBlock", mock.Anything, int64(42161), uint64(250000001), startTime).Return(&client.KlineData{ClosePrice: 2000.1, ClosePriceDecimal: "2000.1", Source: "binance"}, nil)
+	mockPriceManager.On("PrefetchETHUSDT", mock.Anything, startTime, endTime).Return(nil).Once()
+
+	tm := NewTransactionManager([]ChainSource{
+		{ChainID: 1, Client: mainnetClient, PoolAddresses: []string{pool005}},
+		{ChainID: 42161, Client: arbitrumClient, PoolAddresses: []string{arbitrumPool}},
+	}, mockPriceManager)
+
+	transactions, err := tm.BatchProcessTransactionsByTimestamp(context.Background(), startTime, endTime, nil)
+	assert.NoError(t, err)
+	assert.Len(t, transactions, 1)
+	assert.Equal(t, "0xcc", transactions[0].Hash)
+	assert.Equal(t, int64(42161), transactions[0].ChainID)
+
+	// The prices of the window are prefetched once for every chain
+	mockPriceManager.AssertExpectations(t)
+	mainnetClient.AssertExpectations(t)
+	arbitrumClient.AssertExpectations(t)
+}
@@ -0,0 +1,197 @@
+package fakeupstream
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// klineWeight is the request weight of the klines endpoint
+const klineWeight = 2
+
+// Limits of the number of klines per request
+const (
+	defaultKlineLimit = 500
+	maxKlineLimit     = 1000
+)
+
+// klineIntervals are the intervals served, the 1M interval is left out since months have no fixed duration
+var klineIntervals = map[string]time.Duration{
+	"1s":  time.Second,
+	"1m":  time.Minute,
+	"3m":  3 * time.Minute,
+	"5m":  5 * time.Minute,
+	"15m": 15 * time.Minute,
+	"30m": 30 * time.Minute,
+	"1h":  time.Hour,
+	"2h":  2 * time.Hour,
+	"4h":  4 * time.Hour,
+	"6h":  6 * time.Hour,
+	"8h":  8 * time.Hour,
+	"12h": 12 * time.Hour,
+	"1d":  24 * time.Hour,
+	"3d":  3 * 24 * time.Hour,
+	"1w":  7 * 24 * time.Hour,
+}
+
+// handleKlines answers GET /api/v3/klines with klines of the synthetic price, whatever the symbol.
+// Klines open at or after startTime and at or before endTime, the latest ones when only endTime is set.
+//
+// https://developers.binance.com/docs/binance-spot-api-docs/rest-api/market-data-endpoints#klinecandlestick-data
+func (s *Server) handleKlines(w http.ResponseWriter, r *http.Request) {
+	if !s.takeWeight(w) {
+		return
+	}
+	if s.chance(s.config.RateLimitRate) {
+		writeBinanceRateLimited(w, s.usedWeight())
+		return
+	}
+
+	query := r.URL.Query()
+	if query.Get("symbol") == "" {
+		writeBinanceError(w, -1102, "Mandatory parameter 'symbol' was not sent, was empty/null, or malformed.")
+		return
+	}
+	interval, ok := klineIntervals[query.Get("interval")]
+	if !ok {
+		writeBinanceError(w, -1120, "Invalid interval.")
+		return
+	}
+
+	limit := defaultKlineLimit
+	if raw := query.Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			writeBinanceError(w, -1100, "Illegal characters found in parameter 'limit'; legal range is '^[0-9]{1,20}$'.")
+			return
+		}
+		limit = min(parsed, maxKlineLimit)
+	}
+
+	startTime, hasStart, err := millisParam(query.Get("startTime"))
+	if err != nil {
+		writeBinanceError(w, -1100, "Illegal characters found in parameter 'startTime'; legal range is '^[0-9]{1,20}$'.")
+		return
+	}
+	endTime, hasEnd, err := millisParam(query.Get("endTime"))
+	if err != nil {
+		writeBinanceError(w, -1100, "Illegal characters found in parameter 'endTime'; legal range is '^[0-9]{1,20}$'.")
+		return
+	}
+
+	// Klines only exist once they opened
+	now := s.chain.now()
+	if !hasEnd || endTime.After(now) {
+		endTime = now
+	}
+	last := endTime.Truncate(interval)
+
+	first := last.Add(-time.Duration(limit-1) * interval)
+	if hasStart {
+		first = startTime.Truncate(interval)
+		if first.Before(startTime) {
+			first = first.Add(interval)
+		}
+	}
+
+	klines := [][]interface{}{}
+	for open := first; !open.After(last) && len(klines) < limit; open = open.Add(interval) {
+		klines = append(klines, s.kline(open, interval))
+	}
+
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	json.NewEncoder(w).Encode(klines)
+}
+
+// kline builds the kline opening at open in the layout of Binance:
+// open time, open, high, low, close, volume, close time, quote volume, trades, taker base volume, taker quote volume, ignored
+func (s *Server) kline(open time.Time, interval time.Duration) []interface{} {
+	closeTime := open.Add(interval - time.Millisecond)
+	openPrice, closePrice := s.chain.price(open), s.chain.price(closeTime)
+	middle := s.chain.price(open.Add(interval / 2))
+	high := math.Max(math.Max(openPrice, closePrice), middle)
+	low := math.Min(math.Min(openPrice, closePrice), middle)
+
+	volume := 10 + 90*s.chain.unit(uint64(open.Unix()), 40)
+	trades := 100 + s.chain.random(uint64(open.Unix()), 41)%900
+
+	return []interface{}{
+		open.UnixMilli(),
+		decimal(openPrice),
+		decimal(high),
+		decimal(low),
+		decimal(closePrice),
+		decimal(volume),
+		closeTime.UnixMilli(),
+		decimal(volume * (openPrice + closePrice) / 2),
+		trades,
+		decimal(volume / 2),
+		decimal(volume / 2 * (openPrice + closePrice) / 2),
+		"0",
+	}
+}
+
+// takeWeight charges the request weight, answering with a 429 when the budget of the minute is spent
+func (s *Server) takeWeight(w http.ResponseWriter) bool {
+	if s.config.BinanceWeightPerMinute <= 0 {
+		return true
+	}
+
+	s.mu.Lock()
+	ok := s.weights.take(time.Now(), klineWeight, s.config.BinanceWeightPerMinute)
+	used := s.weights.used()
+	s.mu.Unlock()
+
+	if !ok {
+		writeBinanceRateLimited(w, used)
+		return false
+	}
+	w.Header().Set("X-MBX-USED-WEIGHT-1M", strconv.Itoa(used))
+	return true
+}
+
+// usedWeight returns the weight used within the last minute
+func (s *Server) usedWeight() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.weights.used()
+}
+
+// writeBinanceRateLimited answers a rate limited request like Binance, asking to retry after a second
+func writeBinanceRateLimited(w http.ResponseWriter, used int) {
+	w.Header().Set("Retry-After", "1")
+	w.Header().Set("X-MBX-USED-WEIGHT-1M", strconv.Itoa(used))
+	writeBinanceStatus(w, http.StatusTooManyRequests, -1003, "Too much request weight used; please use WebSocket Streams for live updates to avoid polling the API.")
+}
+
+// writeBinanceError answers a rejected request like Binance
+func writeBinanceError(w http.ResponseWriter, code int, message string) {
+	writeBinanceStatus(w, http.StatusBadRequest, code, message)
+}
+
+// writeBinanceStatus writes a Binance error with the status code
+func writeBinanceStatus(w http.ResponseWriter, status int, code int, message string) {
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{"code": code, "msg": message})
+}
+
+// millisParam parses an optional timestamp in milliseconds
+func millisParam(raw string) (time.Time, bool, error) {
+	if raw == "" {
+		return time.Time{}, false, nil
+	}
+	millis, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return time.UnixMilli(millis).UTC(), true, nil
+}
+
+// decimal formats the amount with the 8 decimals of Binance
+func decimal(amount float64) string {
+	return fmt.Sprintf("%.8f", amount)
+}
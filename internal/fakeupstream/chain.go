@@ -0,0 +1,241 @@
+package fakeupstream
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"time"
+)
+
+// Tokens of the synthetic pools, every pool trades USDC (token0) against WETH (token1) like the mainnet WETH/USDC pools
+const (
+	usdcAddress = "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"
+	wethAddress = "0xc02aaa39b223fe8d0a0e5c4f27ead9083c756cc2"
+)
+
+// Topics of the events emitted by a swap
+const (
+	swapEventTopic     = "0xc42079f94a6350d7e6235f29174924f928cc2ac818eb64fed8004e115fbcca67"
+	transferEventTopic = "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+)
+
+// logsPerSwap are the events of a swap transaction: the USDC transfer, the WETH transfer and the Swap
+const logsPerSwap = 3
+
+// routers send the synthetic swaps and receive their output
+var routers = []string{
+	"0x3fc91a3afd70395cd496c647d5a6cc9d4b2b7fad",
+	"0x1111111254eeb25477b68fb85ed929f73a960582",
+	"0x68b3465833fb72a70ecdf485e0e4c7bd8665fc45",
+	"0xe592427a0aece92de3edee1f18e0157c05861564",
+}
+
+// chain generates a deterministic chain from the config: the same seed always yields the same blocks, swaps and prices.
+// Blocks are produced every block time from the genesis block on, up to the current time.
+type chain struct {
+	config Config
+	now    func() time.Time
+}
+
+// block is a synthetic block along with the swaps it holds
+type block struct {
+	Number    uint64
+	Timestamp time.Time
+	BaseFee   *big.Int
+	Swaps     []swap
+}
+
+// swap is a synthetic swap transaction in one of the pools
+type swap struct {
+	Hash           string
+	BlockNumber    uint64
+	Timestamp      time.Time
+	TxIndex        int
+	Pool           string
+	From           string   // Account sending the transaction
+	Router         string   // Contract called by the transaction, it swaps in the pool and receives the output
+	Amount0        *big.Int // USDC delta of the pool, positive when the router sells USDC
+	Amount1        *big.Int // WETH delta of the pool
+	SqrtPriceX96   *big.Int
+	Liquidity      *big.Int
+	Tick           int64
+	GasUsed        uint64
+	GasPrice       *big.Int // Effective gas price, the base fee plus the priority fee
+	MaxFee         *big.Int
+	MaxPriorityFee *big.Int
+}
+
+// random returns a pseudo random number derived from the seed and the values, always the same for the same inputs
+func (c *chain) random(values ...uint64) uint64 {
+	x := uint64(c.config.Seed)
+	for _, value := range values {
+		x = splitmix64(x ^ value)
+	}
+	return splitmix64(x)
+}
+
+// unit returns a pseudo random number in [0, 1) derived from the values
+func (c *chain) unit(values ...uint64) float64 {
+	return float64(c.random(values...)>>11) / (1 << 53)
+}
+
+// splitmix64 scrambles x, see https://prng.di.unimi.it/splitmix64.c
+func splitmix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// head returns the most recent block mined
+func (c *chain) head() uint64 {
+	elapsed := c.now().Sub(c.config.GenesisTime)
+	if elapsed < 0 {
+		return c.config.GenesisBlock
+	}
+	return c.config.GenesisBlock + uint64(elapsed/c.config.BlockTime)
+}
+
+// blockTime returns the timestamp of the block
+func (c *chain) blockTime(number uint64) time.Time {
+	return c.config.GenesisTime.Add(time.Duration(number-c.config.GenesisBlock) * c.config.BlockTime)
+}
+
+// blockAt returns the last block mined at or before the timestamp, or the first one mined at or after it.
+// It fails when no block matches.
+func (c *chain) blockAt(timestamp time.Time, before bool) (uint64, bool) {
+	elapsed := timestamp.Sub(c.config.GenesisTime)
+	blocks := int64(elapsed / c.config.BlockTime)
+	if !before && elapsed%c.config.BlockTime != 0 && elapsed > 0 {
+		blocks++
+	}
+
+	switch {
+	case blocks < 0 && before:
+		return 0, false
+	case blocks < 0:
+		blocks = 0
+	}
+	number := c.config.GenesisBlock + uint64(blocks)
+	if number > c.head() {
+		if !before {
+			return 0, false
+		}
+		number = c.head()
+	}
+	return number, true
+}
+
+// block generates the block, false when it is before the genesis block or not mined yet
+func (c *chain) block(number uint64) (*block, bool) {
+	if number < c.config.GenesisBlock || number > c.head() {
+		return nil, false
+	}
+
+	b := &block{
+		Number:    number,
+		Timestamp: c.blockTime(number),
+		BaseFee:   gwei(c.config.BaseFeeGwei * (0.75 + 0.5*c.unit(number, 1))),
+	}
+
+	// Every pool gets the integer part of the swap rate, plus one more swap with the probability of its fraction
+	whole, fraction := math.Modf(c.config.SwapsPerBlock)
+	for p, pool := range c.config.Pools {
+		count := int(whole)
+		if c.unit(number, 2, uint64(p)) < fraction {
+			count++
+		}
+		for i := 0; i < count; i++ {
+			b.Swaps = append(b.Swaps, c.swap(b, len(b.Swaps), pool))
+		}
+	}
+	return b, true
+}
+
+// swap generates the swap of the block at the transaction index
+func (c *chain) swap(b *block, txIndex int, pool string) swap {
+	r := func(k uint64) float64 { return c.unit(b.Number, uint64(txIndex), k) }
+	price := c.price(b.Timestamp)
+
+	// 0.01 to 5 ETH at the price of the block
+	eth := 0.01 + 4.99*r(1)
+	wethAmount := new(big.Int).Mul(big.NewInt(int64(eth*1e6)), big.NewInt(1e12))
+	usdcAmount := big.NewInt(int64(eth * price * 1e6))
+
+	amount0, amount1 := usdcAmount, new(big.Int).Neg(wethAmount)
+	if r(2) < 0.5 {
+		// The router sells ETH
+		amount0, amount1 = new(big.Int).Neg(usdcAmount), wethAmount
+	}
+
+	// The pool price is WETH per USDC in raw units, 1e12 / price given the decimals
+	rawPrice := 1e12 / price
+	sqrtPrice, _ := new(big.Float).SetMantExp(big.NewFloat(math.Sqrt(rawPrice)), 96).Int(nil)
+
+	priorityFee := gwei(0.01 + 2*r(4))
+	maxFee := new(big.Int).Add(new(big.Int).Mul(b.BaseFee, big.NewInt(2)), priorityFee)
+
+	return swap{
+		Hash:           c.txHash(b.Number, txIndex),
+		BlockNumber:    b.Number,
+		Timestamp:      b.Timestamp,
+		TxIndex:        txIndex,
+		Pool:           pool,
+		From:           fmt.Sprintf("0x%016x%016x%08x", c.random(b.Number, uint64(txIndex), 7), c.random(b.Number, uint64(txIndex), 8), uint32(c.random(b.Number, uint64(txIndex), 9))),
+		Router:         routers[c.random(b.Number, uint64(txIndex), 5)%uint64(len(routers))],
+		Amount0:        amount0,
+		Amount1:        amount1,
+		SqrtPriceX96:   sqrtPrice,
+		Liquidity:      new(big.Int).SetUint64(2e16 + c.random(b.Number, uint64(txIndex), 6)%1e16),
+		Tick:           int64(math.Floor(math.Log(rawPrice) / math.Log(1.0001))),
+		GasUsed:        110000 + uint64(r(3)*140000),
+		GasPrice:       new(big.Int).Add(b.BaseFee, priorityFee),
+		MaxFee:         maxFee,
+		MaxPriorityFee: priorityFee,
+	}
+}
+
+// findSwap returns the swap of the transaction hash, false when the hash is not one of the chain
+func (c *chain) findSwap(hash string) (*block, *swap, bool) {
+	if len(hash) != 66 {
+		return nil, nil, false
+	}
+	number, err := strconv.ParseUint(hash[2:18], 16, 64)
+	if err != nil {
+		return nil, nil, false
+	}
+	txIndex, err := strconv.ParseUint(hash[18:22], 16, 16)
+	if err != nil || c.txHash(number, int(txIndex)) != hash {
+		return nil, nil, false
+	}
+
+	b, ok := c.block(number)
+	if !ok || int(txIndex) >= len(b.Swaps) {
+		return nil, nil, false
+	}
+	return b, &b.Swaps[txIndex], true
+}
+
+// price returns the ETH/USDT price at the time, moving around the base price along a sine wave with some noise per minute
+func (c *chain) price(t time.Time) float64 {
+	phase := 2 * math.Pi * float64(t.UnixNano()) / float64(c.config.PricePeriod)
+	noise := 0.002 * (c.unit(uint64(t.Unix()/60), 30) - 0.5)
+	return c.config.BasePrice * (1 + c.config.PriceAmplitude*math.Sin(phase)) * (1 + noise)
+}
+
+// txHash encodes the block number and transaction index in the hash, so that transactions can be found by hash
+func (c *chain) txHash(number uint64, txIndex int) string {
+	return fmt.Sprintf("0x%016x%04x%016x%016x%012x", number, txIndex,
+		c.random(number, uint64(txIndex), 10), c.random(number, uint64(txIndex), 11), c.random(number, uint64(txIndex), 12)&0xffffffffffff)
+}
+
+// blockHash returns the hash of the block
+func (c *chain) blockHash(number uint64) string {
+	return fmt.Sprintf("0x%016x%016x%016x%016x", c.random(number, 20), c.random(number, 21), c.random(number, 22), c.random(number, 23))
+}
+
+// gwei converts an amount of gwei to wei
+func gwei(amount float64) *big.Int {
+	return big.NewInt(int64(amount * 1e9))
+}
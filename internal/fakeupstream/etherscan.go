@@ -0,0 +1,471 @@
+package fakeupstream
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Limits of the Etherscan pagination
+const (
+	maxResultWindow = 10000 // Largest page times offset of tokentx
+	maxLogsPerPage  = 1000  // Largest page of getLogs
+)
+
+// etherscanResponse is the envelope of the non proxy Etherscan modules
+type etherscanResponse struct {
+	Status  string      `json:"status"`
+	Message string      `json:"message"`
+	Result  interface{} `json:"result"`
+}
+
+// rpcResponse is a JSON-RPC response of the proxy module
+type rpcResponse struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      int         `json:"id"`
+	Result  interface{} `json:"result"`
+	Error   *rpcError   `json:"error,omitempty"`
+}
+
+// rpcError is the error of a JSON-RPC response
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// handleEtherscan answers GET /api like Etherscan: tokentx, getblocknobytime, getLogs and the proxy module.
+// Every call needs an API key, and each key is limited to the configured requests per second.
+func (s *Server) handleEtherscan(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	key := query.Get("apikey")
+	if key == "" {
+		writeEtherscan(w, "0", "NOTOK", "Missing/Invalid API Key")
+		return
+	}
+	if !s.takeCall(key) || s.chance(s.config.RateLimitRate) {
+		writeEtherscan(w, "0", "NOTOK", fmt.Sprintf("Max calls per sec rate limit reached (%d/sec)", max(s.config.EtherscanRPS, 1)))
+		return
+	}
+
+	switch module, action := query.Get("module"), query.Get("action"); {
+	case module == "account" && action == "tokentx":
+		s.handleTokenTx(w, query)
+	case module == "block" && action == "getblocknobytime":
+		s.handleBlockByTime(w, query)
+	case module == "logs" && action == "getLogs":
+		s.handleLogs(w, query)
+	case module == "proxy":
+		s.handleProxy(w, action, query)
+	default:
+		writeEtherscan(w, "0", "NOTOK", "Error! Missing Or invalid Module name")
+	}
+}
+
+// takeCall counts a call of the key, false when the key already spent its calls of the second
+func (s *Server) takeCall(key string) bool {
+	if s.config.EtherscanRPS <= 0 {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	calls, ok := s.keys[key]
+	if !ok {
+		calls = &window{span: time.Second}
+		s.keys[key] = calls
+	}
+	return calls.take(time.Now(), 1, s.config.EtherscanRPS)
+}
+
+// handleTokenTx lists the token transfers of the pool, two per swap: the USDC and the WETH leg.
+// https://docs.etherscan.io/api-endpoints/accounts#get-a-list-of-erc20-token-transfer-events-by-address
+func (s *Server) handleTokenTx(w http.ResponseWriter, query url.Values) {
+	page, offset, ok := s.pagination(w, query, maxResultWindow)
+	if !ok {
+		return
+	}
+	if page*offset > maxResultWindow {
+		writeEtherscan(w, "0", "NOTOK", "Result window is too large, PageNo x Offset size must be less than or equal to 10000")
+		return
+	}
+
+	fromBlock, toBlock, ok := s.blockRange(w, query, "startblock", "endblock")
+	if !ok {
+		return
+	}
+
+	// Transfers are listed up to the end of the requested page
+	var transfers []map[string]string
+	desc := query.Get("sort") == "desc"
+	s.eachSwap(query.Get("address"), fromBlock, toBlock, desc, func(b *block, sw *swap) bool {
+		legs := s.tokenTransfers(b, sw)
+		if desc {
+			legs[0], legs[1] = legs[1], legs[0]
+		}
+		transfers = append(transfers, legs...)
+		return len(transfers) < page*offset
+	})
+
+	start := (page - 1) * offset
+	if start >= len(transfers) {
+		writeEtherscan(w, "0", "No transactions found", []interface{}{})
+		return
+	}
+	writeEtherscan(w, "1", "OK", transfers[start:min(start+offset, len(transfers))])
+}
+
+// tokenTransfers returns the USDC and WETH transfers of the swap as listed by tokentx
+func (s *Server) tokenTransfers(b *block, sw *swap) []map[string]string {
+	transfer := func(token string, symbol string, name string, decimals int, amount *big.Int, logIndex int) map[string]string {
+		from, to := sw.Router, sw.Pool
+		if amount.Sign() < 0 {
+			from, to = sw.Pool, sw.Router
+		}
+		return map[string]string{
+			"blockNumber":       strconv.FormatUint(b.Number, 10),
+			"timeStamp":         strconv.FormatInt(b.Timestamp.Unix(), 10),
+			"hash":              sw.Hash,
+			"nonce":             strconv.FormatUint(s.chain.random(b.Number, uint64(sw.TxIndex), 13)%100000, 10),
+			"blockHash":         s.chain.blockHash(b.Number),
+			"from":              from,
+			"contractAddress":   token,
+			"to":                to,
+			"value":             new(big.Int).Abs(amount).String(),
+			"tokenName":         name,
+			"tokenSymbol":       symbol,
+			"tokenDecimal":      strconv.Itoa(decimals),
+			"transactionIndex":  strconv.Itoa(sw.TxIndex),
+			"gas":               strconv.FormatUint(sw.GasUsed*3/2, 10),
+			"gasPrice":          sw.GasPrice.String(),
+			"gasUsed":           strconv.FormatUint(sw.GasUsed, 10),
+			"cumulativeGasUsed": strconv.FormatUint(sw.GasUsed*uint64(sw.TxIndex+1), 10),
+			"input":             "deprecated",
+			"logIndex":          strconv.Itoa(sw.TxIndex*logsPerSwap + logIndex),
+			"confirmations":     strconv.FormatUint(s.chain.head()-b.Number, 10),
+		}
+	}
+
+	return []map[string]string{
+		transfer(usdcAddress, "USDC", "USDC", 6, sw.Amount0, 0),
+		transfer(wethAddress, "WETH", "Wrapped Ether", 18, sw.Amount1, 1),
+	}
+}
+
+// handleBlockByTime returns the block mined closest before or after the timestamp.
+// https://docs.etherscan.io/api-endpoints/blocks#get-block-number-by-timestamp
+func (s *Server) handleBlockByTime(w http.ResponseWriter, query url.Values) {
+	timestamp, err := strconv.ParseInt(query.Get("timestamp"), 10, 64)
+	if err != nil {
+		writeEtherscan(w, "0", "NOTOK", "Error! Invalid timestamp")
+		return
+	}
+	closest := query.Get("closest")
+	if closest != "before" && closest != "after" {
+		writeEtherscan(w, "0", "NOTOK", "Error! Invalid closest value")
+		return
+	}
+
+	number, ok := s.chain.blockAt(time.Unix(timestamp, 0), closest == "before")
+	if !ok {
+		writeEtherscan(w, "0", "NOTOK", "Error! No closest block found")
+		return
+	}
+	writeEtherscan(w, "1", "OK", strconv.FormatUint(number, 10))
+}
+
+// handleLogs returns the Swap events of the pool in ascending order.
+// https://docs.etherscan.io/api-endpoints/logs
+func (s *Server) handleLogs(w http.ResponseWriter, query url.Values) {
+	page, offset, ok := s.pagination(w, query, maxLogsPerPage)
+	if !ok {
+		return
+	}
+	offset = min(offset, maxLogsPerPage)
+
+	fromBlock, toBlock, ok := s.blockRange(w, query, "fromBlock", "toBlock")
+	if !ok {
+		return
+	}
+	if topic := query.Get("topic0"); topic != "" && !strings.EqualFold(topic, swapEventTopic) {
+		writeEtherscan(w, "0", "No records found", []interface{}{})
+		return
+	}
+
+	var logs []map[string]interface{}
+	s.eachSwap(query.Get("address"), fromBlock, toBlock, false, func(b *block, sw *swap) bool {
+		log := s.swapLog(b, sw)
+		log["timeStamp"] = hexutil.EncodeUint64(uint64(b.Timestamp.Unix()))
+		log["gasPrice"] = hexutil.EncodeBig(sw.GasPrice)
+		log["gasUsed"] = hexutil.EncodeUint64(sw.GasUsed)
+		logs = append(logs, log)
+		return len(logs) < page*offset
+	})
+
+	start := (page - 1) * offset
+	if start >= len(logs) {
+		writeEtherscan(w, "0", "No records found", []interface{}{})
+		return
+	}
+	writeEtherscan(w, "1", "OK", logs[start:min(start+offset, len(logs))])
+}
+
+// handleProxy answers the JSON-RPC methods of the proxy module the tracker calls.
+// https://docs.etherscan.io/api-endpoints/geth-parity-proxy
+func (s *Server) handleProxy(w http.ResponseWriter, action string, query url.Values) {
+	var result interface{}
+
+	switch action {
+	case "eth_blockNumber":
+		result = hexutil.EncodeUint64(s.chain.head())
+	case "eth_getBlockByNumber":
+		number := s.chain.head()
+		if tag := query.Get("tag"); tag != "latest" {
+			var err error
+			if number, err = hexutil.DecodeUint64(tag); err != nil {
+				writeRPC(w, nil, &rpcError{Code: -32602, Message: "invalid argument 0: hex string without 0x prefix"})
+				return
+			}
+		}
+		if b, ok := s.chain.block(number); ok {
+			result = s.blockObject(b, query.Get("boolean") == "true")
+		}
+	case "eth_getTransactionByHash":
+		if b, sw, ok := s.chain.findSwap(strings.ToLower(query.Get("txhash"))); ok {
+			result = s.transactionObject(b, sw)
+		}
+	case "eth_getTransactionReceipt":
+		if b, sw, ok := s.chain.findSwap(strings.ToLower(query.Get("txhash"))); ok {
+			result = s.receiptObject(b, sw)
+		}
+	default:
+		writeRPC(w, nil, &rpcError{Code: -32601, Message: fmt.Sprintf("the method %s does not exist/is not available", action)})
+		return
+	}
+
+	writeRPC(w, result, nil)
+}
+
+// blockObject returns the block as eth_getBlockByNumber does, with its transactions or their hashes
+func (s *Server) blockObject(b *block, full bool) map[string]interface{} {
+	transactions := make([]interface{}, 0, len(b.Swaps))
+	gasUsed := uint64(0)
+	for i := range b.Swaps {
+		gasUsed += b.Swaps[i].GasUsed
+		if full {
+			transactions = append(transactions, s.transactionObject(b, &b.Swaps[i]))
+		} else {
+			transactions = append(transactions, b.Swaps[i].Hash)
+		}
+	}
+
+	return map[string]interface{}{
+		"number":        hexutil.EncodeUint64(b.Number),
+		"hash":          s.chain.blockHash(b.Number),
+		"parentHash":    s.chain.blockHash(b.Number - 1),
+		"timestamp":     hexutil.EncodeUint64(uint64(b.Timestamp.Unix())),
+		"baseFeePerGas": hexutil.EncodeBig(b.BaseFee),
+		"gasLimit":      hexutil.EncodeUint64(30000000),
+		"gasUsed":       hexutil.EncodeUint64(gasUsed),
+		"miner":         "0x95222290dd7278aa3ddd389cc1e1d165cc4bafe5",
+		"transactions":  transactions,
+	}
+}
+
+// transactionObject returns the swap transaction as eth_getTransactionByHash does
+func (s *Server) transactionObject(b *block, sw *swap) map[string]interface{} {
+	return map[string]interface{}{
+		"hash":                 sw.Hash,
+		"blockHash":            s.chain.blockHash(b.Number),
+		"blockNumber":          hexutil.EncodeUint64(b.Number),
+		"transactionIndex":     hexutil.EncodeUint64(uint64(sw.TxIndex)),
+		"type":                 "0x2",
+		"from":                 sw.From,
+		"to":                   sw.Router,
+		"gas":                  hexutil.EncodeUint64(sw.GasUsed * 3 / 2),
+		"gasPrice":             hexutil.EncodeBig(sw.GasPrice),
+		"maxFeePerGas":         hexutil.EncodeBig(sw.MaxFee),
+		"maxPriorityFeePerGas": hexutil.EncodeBig(sw.MaxPriorityFee),
+		"nonce":                hexutil.EncodeUint64(s.chain.random(b.Number, uint64(sw.TxIndex), 13) % 100000),
+		"value":                "0x0",
+		"input":                "0x",
+		"chainId":              "0x1",
+	}
+}
+
+// receiptObject returns the receipt of the swap transaction with its transfer and Swap logs
+func (s *Server) receiptObject(b *block, sw *swap) map[string]interface{} {
+	transfer := func(token string, amount *big.Int, logIndex int) map[string]interface{} {
+		from, to := sw.Router, sw.Pool
+		if amount.Sign() < 0 {
+			from, to = sw.Pool, sw.Router
+		}
+		return s.logObject(b, sw, token, []string{transferEventTopic, addressTopic(from), addressTopic(to)},
+			word(new(big.Int).Abs(amount)), logIndex)
+	}
+
+	cumulativeGas := uint64(0)
+	for i := 0; i <= sw.TxIndex; i++ {
+		cumulativeGas += b.Swaps[i].GasUsed
+	}
+
+	return map[string]interface{}{
+		"blockNumber":       hexutil.EncodeUint64(b.Number),
+		"blockHash":         s.chain.blockHash(b.Number),
+		"transactionHash":   sw.Hash,
+		"transactionIndex":  hexutil.EncodeUint64(uint64(sw.TxIndex)),
+		"type":              "0x2",
+		"status":            "0x1",
+		"from":              sw.From,
+		"to":                sw.Router,
+		"contractAddress":   nil,
+		"gasUsed":           hexutil.EncodeUint64(sw.GasUsed),
+		"cumulativeGasUsed": hexutil.EncodeUint64(cumulativeGas),
+		"effectiveGasPrice": hexutil.EncodeBig(sw.GasPrice),
+		"logs": []map[string]interface{}{
+			transfer(usdcAddress, sw.Amount0, 0),
+			transfer(wethAddress, sw.Amount1, 1),
+			s.swapLog(b, sw),
+		},
+	}
+}
+
+// swapLog returns the Swap event of the swap, emitted by the pool after both transfers
+func (s *Server) swapLog(b *block, sw *swap) map[string]interface{} {
+	data := word(sw.Amount0) + word(sw.Amount1)[2:] + word(sw.SqrtPriceX96)[2:] + word(sw.Liquidity)[2:] + word(big.NewInt(sw.Tick))[2:]
+	return s.logObject(b, sw, sw.Pool, []string{swapEventTopic, addressTopic(sw.Router), addressTopic(sw.Router)}, data, logsPerSwap-1)
+}
+
+// logObject returns an event log of the swap transaction
+func (s *Server) logObject(b *block, sw *swap, address string, topics []string, data string, logIndex int) map[string]interface{} {
+	return map[string]interface{}{
+		"address":          address,
+		"topics":           topics,
+		"data":             data,
+		"blockNumber":      hexutil.EncodeUint64(b.Number),
+		"blockHash":        s.chain.blockHash(b.Number),
+		"transactionHash":  sw.Hash,
+		"transactionIndex": hexutil.EncodeUint64(uint64(sw.TxIndex)),
+		"logIndex":         hexutil.EncodeUint64(uint64(sw.TxIndex*logsPerSwap + logIndex)),
+		"removed":          false,
+	}
+}
+
+// eachSwap calls fn with the swaps of the pool between fromBlock and toBlock (inclusive) until it returns false
+func (s *Server) eachSwap(pool string, fromBlock uint64, toBlock uint64, desc bool, fn func(*block, *swap) bool) {
+	if !s.isPool(pool) || fromBlock > toBlock {
+		return
+	}
+
+	visit := func(number uint64) bool {
+		b, ok := s.chain.block(number)
+		if !ok {
+			return true
+		}
+		for i := range b.Swaps {
+			if strings.EqualFold(b.Swaps[i].Pool, pool) && !fn(b, &b.Swaps[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	for i := uint64(0); i <= toBlock-fromBlock; i++ {
+		number := fromBlock + i
+		if desc {
+			number = toBlock - i
+		}
+		if !visit(number) {
+			return
+		}
+	}
+}
+
+// isPool reports whether the address is one of the pools of the chain
+func (s *Server) isPool(address string) bool {
+	for _, pool := range s.config.Pools {
+		if strings.EqualFold(pool, address) {
+			return true
+		}
+	}
+	return false
+}
+
+// blockRange parses the block range of the query, clamped to the blocks of the chain
+func (s *Server) blockRange(w http.ResponseWriter, query url.Values, fromParam string, toParam string) (uint64, uint64, bool) {
+	fromBlock, toBlock := s.config.GenesisBlock, s.chain.head()
+
+	if raw := query.Get(fromParam); raw != "" {
+		from, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			writeEtherscan(w, "0", "NOTOK", fmt.Sprintf("Error! Invalid %s", fromParam))
+			return 0, 0, false
+		}
+		fromBlock = max(fromBlock, from)
+	}
+	if raw := query.Get(toParam); raw != "" && raw != "latest" {
+		to, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			writeEtherscan(w, "0", "NOTOK", fmt.Sprintf("Error! Invalid %s", toParam))
+			return 0, 0, false
+		}
+		toBlock = min(toBlock, to)
+	}
+	return fromBlock, toBlock, true
+}
+
+// pagination parses the page and offset of the query, a single page of size records by default
+func (s *Server) pagination(w http.ResponseWriter, query url.Values, size int) (int, int, bool) {
+	page, offset := 1, size
+
+	if raw := query.Get("page"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			writeEtherscan(w, "0", "NOTOK", "Error! Invalid page number")
+			return 0, 0, false
+		}
+		page = parsed
+	}
+	if raw := query.Get("offset"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			writeEtherscan(w, "0", "NOTOK", "Error! Invalid offset")
+			return 0, 0, false
+		}
+		offset = parsed
+	}
+	return page, offset, true
+}
+
+// writeEtherscan writes a response of the non proxy modules
+func writeEtherscan(w http.ResponseWriter, status string, message string, result interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(etherscanResponse{Status: status, Message: message, Result: result})
+}
+
+// writeRPC writes a JSON-RPC response of the proxy module, a nil result is encoded as null
+func writeRPC(w http.ResponseWriter, result interface{}, rpcErr *rpcError) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rpcResponse{JSONRPC: "2.0", ID: 1, Result: result, Error: rpcErr})
+}
+
+// word encodes the integer as a 32-byte ABI word, negative values in two's complement
+func word(value *big.Int) string {
+	encoded := new(big.Int).Set(value)
+	if encoded.Sign() < 0 {
+		encoded.Add(encoded, new(big.Int).Lsh(big.NewInt(1), 256))
+	}
+	return fmt.Sprintf("0x%064x", encoded)
+}
+
+// addressTopic pads the address to a 32-byte topic
+func addressTopic(address string) string {
+	return "0x" + strings.Repeat("0", 24) + strings.TrimPrefix(address, "0x")
+}
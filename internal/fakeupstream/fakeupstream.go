@@ -0,0 +1,182 @@
+// Package fakeupstream serves stand-ins of the Etherscan and Binance APIs used by the tracker,
+// answered from a synthetic chain or from recorded cassettes, with configurable faults.
+package fakeupstream
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/winQe/uniswap-fee-tracker/internal/client"
+)
+
+// DefaultPoolAddress is the pool of the synthetic chain when none is configured, the WETH/USDC 0.05% pool
+const DefaultPoolAddress = "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640"
+
+// Config configures the synthetic chain and the faults of the server
+type Config struct {
+	Seed           int64         // Seeds the chain, the same seed always generates the same blocks, swaps and prices
+	GenesisBlock   uint64        // First block of the chain
+	GenesisTime    time.Time     // Timestamp of the first block
+	BlockTime      time.Duration // Time between two blocks
+	Pools          []string      // Pools swapped in
+	SwapsPerBlock  float64       // Average swaps per block in each pool
+	BasePrice      float64       // ETH/USDT price the price moves around
+	PriceAmplitude float64       // Relative swing of the price
+	PricePeriod    time.Duration // Period of the price swing
+	BaseFeeGwei    float64       // Average base fee of the blocks
+
+	ErrorRate              float64       // Share of requests failing with a 503
+	RateLimitRate          float64       // Share of requests rejected as rate limited
+	EtherscanRPS           int           // Requests per second allowed to each Etherscan key, unlimited when 0
+	BinanceWeightPerMinute int           // Request weight allowed per minute on Binance, unlimited when 0
+	Latency                time.Duration // Delay before every response
+
+	Fixtures []string // Cassettes answering the requests they recorded before the synthetic chain does
+}
+
+// DefaultConfig returns a chain of 12 second blocks starting on 2024-06-01, swapping every other block
+// around 2500 USDT, with the limits of the free Etherscan and Binance tiers and no faults.
+func DefaultConfig() Config {
+	return Config{
+		Seed:                   1,
+		GenesisBlock:           20000000,
+		GenesisTime:            time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+		BlockTime:              12 * time.Second,
+		Pools:                  []string{DefaultPoolAddress},
+		SwapsPerBlock:          0.5,
+		BasePrice:              2500,
+		PriceAmplitude:         0.05,
+		PricePeriod:            24 * time.Hour,
+		BaseFeeGwei:            10,
+		EtherscanRPS:           5,
+		BinanceWeightPerMinute: 6000,
+	}
+}
+
+// Server answers the Etherscan API on /api and the Binance klines on /api/v3/klines
+type Server struct {
+	config   Config
+	chain    *chain
+	fixtures *fixtures
+	mux      *http.ServeMux
+
+	mu      sync.Mutex
+	random  *rand.Rand
+	keys    map[string]*window // Etherscan calls of the last second per key
+	weights *window            // Binance weight of the last minute
+}
+
+// NewServer initializes the server of the config, loading its fixtures
+func NewServer(config Config) (*Server, error) {
+	if config.BlockTime <= 0 {
+		return nil, fmt.Errorf("block time must be positive")
+	}
+	if config.PricePeriod <= 0 {
+		return nil, fmt.Errorf("price period must be positive")
+	}
+	if config.SwapsPerBlock < 0 {
+		return nil, fmt.Errorf("swaps per block must not be negative")
+	}
+
+	fixtures, err := loadFixtures(config.Fixtures)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		config:   config,
+		chain:    &chain{config: config, now: time.Now},
+		fixtures: fixtures,
+		mux:      http.NewServeMux(),
+		random:   rand.New(rand.NewSource(config.Seed)),
+		keys:     make(map[string]*window),
+		weights:  &window{span: time.Minute},
+	}
+	s.mux.HandleFunc("/api", s.handleEtherscan)
+	s.mux.HandleFunc("/api/v3/klines", s.handleKlines)
+	return s, nil
+}
+
+// ServeHTTP answers the request from the fixtures when they recorded it, or from the synthetic chain
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.config.Latency > 0 {
+		select {
+		case <-time.After(s.config.Latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	response, ok, err := s.fixtures.match(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if ok {
+		writeRecorded(w, response)
+		return
+	}
+
+	if s.chance(s.config.ErrorRate) {
+		http.Error(w, "injected failure", http.StatusServiceUnavailable)
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// chance draws whether an event of the given probability happens
+func (s *Server) chance(probability float64) bool {
+	if probability <= 0 {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.random.Float64() < probability
+}
+
+// window counts the usage of a sliding time span
+type window struct {
+	span  time.Duration
+	times []time.Time
+	costs []int
+}
+
+// take records the cost when the span has room for it under the limit
+func (w *window) take(now time.Time, cost int, limit int) bool {
+	for len(w.times) > 0 && now.Sub(w.times[0]) >= w.span {
+		w.times, w.costs = w.times[1:], w.costs[1:]
+	}
+	if w.used()+cost > limit {
+		return false
+	}
+	w.times = append(w.times, now)
+	w.costs = append(w.costs, cost)
+	return true
+}
+
+// used returns the cost recorded within the span
+func (w *window) used() int {
+	total := 0
+	for _, cost := range w.costs {
+		total += cost
+	}
+	return total
+}
+
+// writeRecorded writes a response of a fixture
+func writeRecorded(w http.ResponseWriter, response client.CassetteResponse) {
+	for name, values := range response.Header {
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+	w.WriteHeader(response.StatusCode)
+	if len(response.JSON) > 0 {
+		w.Write(response.JSON)
+		return
+	}
+	w.Write([]byte(response.Body))
+}
@@ -0,0 +1,234 @@
+package fakeupstream
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/winQe/uniswap-fee-tracker/internal/client"
+	"github.com/winQe/uniswap-fee-tracker/internal/utils"
+)
+
+var fastRetryPolicy = client.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond, Timeout: time.Second}
+
+// createServer serves the config on a chain whose head is 100 blocks past the genesis block
+func createServer(t *testing.T, config Config) *httptest.Server {
+	fake, err := NewServer(config)
+	assert.NoError(t, err)
+	fake.chain.now = func() time.Time { return config.GenesisTime.Add(100 * config.BlockTime) }
+
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// createEtherscanClient returns a client of the fake explorer, chainID keeps its circuit breaker apart from other tests
+func createEtherscanClient(server *httptest.Server, chainID int64) *client.EtherscanClient {
+	return client.NewEtherscanClient(utils.ChainConfig{
+		Name:              "fake",
+		ChainID:           chainID,
+		ExplorerURL:       server.URL + "/api",
+		APIKey:            "key",
+		RequestsPerSecond: 1000,
+		RequestsPerDay:    1000000,
+		Pools:             []utils.PoolConfig{{Address: DefaultPoolAddress}},
+	}, nil, fastRetryPolicy, client.DefaultBreakerConfig)
+}
+
+// getEtherscan sends the raw query to the fake explorer
+func getEtherscan(t *testing.T, server *httptest.Server, query string) etherscanResponse {
+	resp, err := http.Get(server.URL + "/api?" + query)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var body etherscanResponse
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	return body
+}
+
+func TestServer_EtherscanClient(t *testing.T) {
+	config := DefaultConfig()
+	config.SwapsPerBlock = 2
+	config.EtherscanRPS = 0
+	server := createServer(t, config)
+	etherscan := createEtherscanClient(server, 900001)
+	ctx := context.Background()
+
+	head, err := etherscan.GetBlockNumber(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, config.GenesisBlock+100, head)
+
+	// Block 10 was mined 2 minutes after the genesis block
+	number, err := etherscan.GetBlockNumberByTimestamp(ctx, config.GenesisTime.Add(2*time.Minute), true)
+	assert.NoError(t, err)
+	assert.Equal(t, config.GenesisBlock+10, number)
+	number, err = etherscan.GetBlockNumberByTimestamp(ctx, config.GenesisTime.Add(2*time.Minute+time.Second), false)
+	assert.NoError(t, err)
+	assert.Equal(t, config.GenesisBlock+11, number)
+
+	// Two transfers per swap, newest first
+	offset, page := 10, 1
+	startBlock, endBlock := config.GenesisBlock, config.GenesisBlock+50
+	transactions, err := etherscan.ListTransactions(ctx, DefaultPoolAddress, &offset, &startBlock, &endBlock, &page)
+	assert.NoError(t, err)
+	assert.Len(t, transactions, 10)
+	assert.Equal(t, transactions[0].Hash, transactions[1].Hash)
+	assert.LessOrEqual(t, transactions[0].BlockNumber, endBlock)
+	assert.GreaterOrEqual(t, transactions[0].BlockNumber, transactions[len(transactions)-1].BlockNumber)
+
+	// Every listed transaction holds its swap and fees, matching its receipt
+	for _, tx := range transactions {
+		assert.Len(t, tx.Swaps, 1)
+		assert.Equal(t, DefaultPoolAddress, tx.Swaps[0].PoolAddress)
+		assert.NotNil(t, tx.BaseFeePerGasWei)
+		assert.NotNil(t, tx.MaxFeePerGasWei)
+		assert.Equal(t, -1, tx.BaseFeePerGasWei.Cmp(tx.GasPriceWei), "the gas price includes a priority fee")
+
+		receipt, err := etherscan.GetTransactionReceipt(ctx, tx.Hash)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		assert.Equal(t, tx.BlockNumber, receipt.BlockNumber)
+		assert.Equal(t, tx.GasUsed, receipt.GasUsed)
+		assert.Equal(t, tx.GasPriceWei, receipt.GasPriceWei)
+		assert.Equal(t, tx.Timestamp.Unix(), receipt.Timestamp.Unix())
+		assert.Equal(t, tx.Swaps, receipt.Swaps)
+	}
+
+	// Amounts of a swap have opposite signs
+	swap := transactions[0].Swaps[0]
+	assert.Equal(t, -1, swap.Amount0.Sign()*swap.Amount1.Sign())
+
+	var notFoundErr *client.TransactionNotFoundError
+	_, err = etherscan.GetTransactionReceipt(ctx, "0x"+transactions[0].Hash[2:60]+"ffffff")
+	assert.ErrorAs(t, err, &notFoundErr)
+
+	// The same seed always generates the same chain
+	again, err := etherscan.ListTransactions(ctx, DefaultPoolAddress, &offset, &startBlock, &endBlock, &page)
+	assert.NoError(t, err)
+	assert.Equal(t, transactions[0].Hash, again[0].Hash)
+}
+
+func TestServer_KlineClient(t *testing.T) {
+	config := DefaultConfig()
+	server := createServer(t, config)
+
+	klineClient, err := client.NewKlineClient(client.KlineConfig{BaseURL: server.URL, Interval: "1m", Retry: fastRetryPolicy})
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	start := config.GenesisTime.Add(5 * time.Minute)
+	points, err := klineClient.GetETHUSDTRange(ctx, start, start.Add(9*time.Minute))
+	assert.NoError(t, err)
+	assert.Len(t, points, 10)
+	for i, point := range points {
+		assert.Equal(t, start.Add(time.Duration(i)*time.Minute).Unix(), point.Timestamp.Unix())
+		assert.InDelta(t, config.BasePrice, point.ClosePrice, config.BasePrice*(config.PriceAmplitude+0.01))
+	}
+
+	// The price of a timestamp is the close of the kline containing it
+	price, err := klineClient.GetETHUSDT(ctx, start.Add(3*time.Minute+20*time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, points[3].ClosePriceDecimal, price.ClosePriceDecimal)
+
+	// Klines of the future do not exist yet
+	points, err = klineClient.GetETHUSDTRange(ctx, config.GenesisTime.Add(time.Hour), config.GenesisTime.Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Empty(t, points)
+}
+
+func TestServer_Faults(t *testing.T) {
+	config := DefaultConfig()
+	config.ErrorRate = 1
+	server := createServer(t, config)
+
+	klineClient, err := client.NewKlineClient(client.KlineConfig{BaseURL: server.URL, Interval: "1m", Retry: fastRetryPolicy})
+	assert.NoError(t, err)
+	_, err = klineClient.GetETHUSDT(context.Background(), config.GenesisTime.Add(time.Minute))
+	var upstreamErr *client.UpstreamError
+	assert.ErrorAs(t, err, &upstreamErr)
+
+	// Binance answers 429 once the weight of the minute is spent
+	config = DefaultConfig()
+	config.BinanceWeightPerMinute = 2
+	server = createServer(t, config)
+	resp, err := http.Get(server.URL + "/api/v3/klines?symbol=ETHUSDT&interval=1m&limit=1")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, err = http.Get(server.URL + "/api/v3/klines?symbol=ETHUSDT&interval=1m&limit=1")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "1", resp.Header.Get("Retry-After"))
+
+	// Etherscan limits every key on its own
+	config = DefaultConfig()
+	config.EtherscanRPS = 1
+	server = createServer(t, config)
+	byTime := "module=block&action=getblocknobytime&timestamp=1717200000&closest=before"
+	assert.Equal(t, "1", getEtherscan(t, server, byTime+"&apikey=a").Status)
+	limited := getEtherscan(t, server, byTime+"&apikey=a")
+	assert.Equal(t, "0", limited.Status)
+	assert.Equal(t, "Max calls per sec rate limit reached (1/sec)", limited.Result)
+	assert.Equal(t, "1", getEtherscan(t, server, byTime+"&apikey=b").Status)
+	assert.Equal(t, "Missing/Invalid API Key", getEtherscan(t, server, "module=proxy&action=eth_blockNumber").Result)
+}
+
+func TestServer_Pagination(t *testing.T) {
+	config := DefaultConfig()
+	config.EtherscanRPS = 0
+	server := createServer(t, config)
+
+	tooLarge := getEtherscan(t, server, "module=account&action=tokentx&address="+DefaultPoolAddress+"&page=3&offset=5000&apikey=a")
+	assert.Equal(t, "0", tooLarge.Status)
+	assert.Equal(t, "Result window is too large, PageNo x Offset size must be less than or equal to 10000", tooLarge.Result)
+
+	// Transactions of other addresses and past the last page are not found
+	other := getEtherscan(t, server, "module=account&action=tokentx&address=0x0000000000000000000000000000000000000001&apikey=a")
+	assert.Equal(t, "No transactions found", other.Message)
+	beyond := getEtherscan(t, server, "module=account&action=tokentx&address="+DefaultPoolAddress+"&page=100&offset=100&apikey=a")
+	assert.Equal(t, "No transactions found", beyond.Message)
+
+	noBlock := getEtherscan(t, server, "module=block&action=getblocknobytime&timestamp=1893456000&closest=after&apikey=a")
+	assert.Equal(t, "Error! No closest block found", noBlock.Result)
+}
+
+func TestServer_Fixtures(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	fixture := `{"interactions": [
+		{"request": {"method": "GET", "url": "https://api.etherscan.io/api?action=eth_blockNumber&apikey=REDACTED&module=proxy"},
+		 "response": {"status_code": 200, "json": {"jsonrpc": "2.0", "id": 1, "result": "0x10"}}},
+		{"request": {"method": "GET", "url": "https://api.etherscan.io/api?action=eth_blockNumber&apikey=REDACTED&module=proxy"},
+		 "response": {"status_code": 200, "json": {"jsonrpc": "2.0", "id": 1, "result": "0x11"}}}
+	]}`
+	assert.NoError(t, os.WriteFile(path, []byte(fixture), 0o644))
+
+	config := DefaultConfig()
+	config.EtherscanRPS = 0
+	config.Fixtures = []string{path}
+	server := createServer(t, config)
+	etherscan := createEtherscanClient(server, 900002)
+	ctx := context.Background()
+
+	// Recorded responses are served in order, the last one over and over
+	for _, expected := range []uint64{16, 17, 17} {
+		number, err := etherscan.GetBlockNumber(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, number)
+	}
+
+	// Requests not recorded are answered by the synthetic chain
+	number, err := etherscan.GetBlockNumberByTimestamp(ctx, config.GenesisTime, false)
+	assert.NoError(t, err)
+	assert.Equal(t, config.GenesisBlock, number)
+
+	_, err = NewServer(Config{BlockTime: time.Second, PricePeriod: time.Hour, Fixtures: []string{filepath.Join(t.TempDir(), "missing.json")}})
+	assert.Error(t, err)
+}
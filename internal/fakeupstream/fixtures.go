@@ -0,0 +1,78 @@
+package fakeupstream
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/winQe/uniswap-fee-tracker/internal/client"
+)
+
+// fixtures answers the requests recorded in cassettes, whatever host they were recorded against.
+// The responses recorded for a request are served in order, the last one over and over once they are exhausted.
+type fixtures struct {
+	mu        sync.Mutex
+	responses map[string][]client.CassetteResponse
+	served    map[string]int
+}
+
+// loadFixtures reads the cassettes at paths
+func loadFixtures(paths []string) (*fixtures, error) {
+	f := &fixtures{
+		responses: make(map[string][]client.CassetteResponse),
+		served:    make(map[string]int),
+	}
+
+	for _, path := range paths {
+		interactions, err := client.ReadCassette(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, interaction := range interactions {
+			recorded, err := url.Parse(interaction.Request.URL)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing URL recorded in %s: %v", path, err)
+			}
+			key := fixtureKey(interaction.Request.Method, recorded, interaction.Request.Body)
+			f.responses[key] = append(f.responses[key], interaction.Response)
+		}
+	}
+	return f, nil
+}
+
+// match returns the next response recorded for the request, false when none was
+func (f *fixtures) match(r *http.Request) (client.CassetteResponse, bool, error) {
+	if len(f.responses) == 0 {
+		return client.CassetteResponse{}, false, nil
+	}
+
+	var body []byte
+	if r.Body != nil {
+		var err error
+		if body, err = io.ReadAll(r.Body); err != nil {
+			return client.CassetteResponse{}, false, fmt.Errorf("error reading request body: %v", err)
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	key := fixtureKey(r.Method, r.URL, string(body))
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	responses, ok := f.responses[key]
+	if !ok {
+		return client.CassetteResponse{}, false, nil
+	}
+	i := min(f.served[key], len(responses)-1)
+	f.served[key]++
+	return responses[i], true, nil
+}
+
+// fixtureKey identifies a request by its method, path, redacted query and body, leaving out the host
+func fixtureKey(method string, u *url.URL, body string) string {
+	path := url.URL{Path: u.Path, RawQuery: u.RawQuery}
+	return method + " " + client.RedactURL(&path) + " " + body
+}
@@ -1,9 +1,11 @@
 package mocks
 
 import (
+	"context"
 	"time"
 
 	"github.com/stretchr/testify/mock"
+	"github.com/winQe/uniswap-fee-tracker/internal/client"
 )
 
 // MockRateCache is a mock implementation of the RateCache interface.
@@ -12,14 +14,20 @@ type MockRateCache struct {
 }
 
 // GetRate mocks the GetRate method of RateCache.
-func (m *MockRateCache) GetRate(timestamp time.Time) (float64, error) {
-	args := m.Called(timestamp)
-	return args.Get(0).(float64), args.Error(1)
+func (m *MockRateCache) GetRate(ctx context.Context, timestamp time.Time) (*client.KlineData, error) {
+	args := m.Called(ctx, timestamp)
+	return args.Get(0).(*client.KlineData), args.Error(1)
+}
+
+// StoreRates mocks the StoreRates method of RateCache.
+func (m *MockRateCache) StoreRates(ctx context.Context, prices []client.PricePoint) error {
+	args := m.Called(ctx, prices)
+	return args.Error(0)
 }
 
 // StoreRate mocks the StoreRate method of RateCache.
-func (m *MockRateCache) StoreRate(timestamp time.Time, value float64) error {
-	args := m.Called(timestamp, value)
+func (m *MockRateCache) StoreRate(ctx context.Context, timestamp time.Time, value client.KlineData) error {
+	args := m.Called(ctx, timestamp, value)
 	return args.Error(0)
 }
 
@@ -28,17 +36,32 @@ type MockJobsStore struct {
 	mock.Mock
 }
 
-func (m *MockJobsStore) SetJob(id string, data []byte) error {
-	args := m.Called(id, data)
+func (m *MockJobsStore) SetJob(ctx context.Context, id string, data []byte) error {
+	args := m.Called(ctx, id, data)
 	return args.Error(0)
 }
 
-func (m *MockJobsStore) GetJob(id string) ([]byte, error) {
-	args := m.Called(id)
+func (m *MockJobsStore) GetJob(ctx context.Context, id string) ([]byte, error) {
+	args := m.Called(ctx, id)
 	return args.Get(0).([]byte), args.Error(1)
 }
 
-func (m *MockJobsStore) GetAllJobs() ([][]byte, error) {
-	args := m.Called()
+func (m *MockJobsStore) GetAllJobs(ctx context.Context) ([][]byte, error) {
+	args := m.Called(ctx)
 	return args.Get(0).([][]byte), args.Error(1)
 }
+
+// MockTxMissStore is a mock implementation of the TxMissStore interface.
+type MockTxMissStore struct {
+	mock.Mock
+}
+
+func (m *MockTxMissStore) StoreMiss(ctx context.Context, txHash string, reason string) error {
+	args := m.Called(ctx, txHash, reason)
+	return args.Error(0)
+}
+
+func (m *MockTxMissStore) GetMiss(ctx context.Context, txHash string) (string, error) {
+	args := m.Called(ctx, txHash)
+	return args.String(0), args.Error(1)
+}
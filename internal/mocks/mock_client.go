@@ -1,6 +1,7 @@
 package mocks
 
 import (
+	"context"
 	"time"
 
 	"github.com/stretchr/testify/mock"
@@ -15,18 +16,82 @@ type MockPriceClient struct {
 }
 
 // GetETHUSDT mocks the GetETHUSDT method of PriceClient.
-func (m *MockPriceClient) GetETHUSDT(timestamp time.Time) (*client.KlineData, error) {
-	args := m.Called(timestamp)
+func (m *MockPriceClient) GetETHUSDT(ctx context.Context, timestamp time.Time) (*client.KlineData, error) {
+	args := m.Called(ctx, timestamp)
 	return args.Get(0).(*client.KlineData), args.Error(1)
 }
 
+// Resolution mocks the Resolution method of PriceClient.
+func (m *MockPriceClient) Resolution() time.Duration {
+	args := m.Called()
+	return args.Get(0).(time.Duration)
+}
+
+// MockRangePriceClient is a mock implementation of the RangePriceClient interface.
+type MockRangePriceClient struct {
+	MockPriceClient
+}
+
+// GetETHUSDTRange mocks the GetETHUSDTRange method of RangePriceClient.
+func (m *MockRangePriceClient) GetETHUSDTRange(ctx context.Context, startTime time.Time, endTime time.Time) ([]client.PricePoint, error) {
+	args := m.Called(ctx, startTime, endTime)
+	return args.Get(0).([]client.PricePoint), args.Error(1)
+}
+
+// MockBlockPriceClient is a mock implementation of the BlockPriceClient interface.
+type MockBlockPriceClient struct {
+	MockPriceClient
+}
+
+// GetETHUSDTAtBlock mocks the GetETHUSDTAtBlock method of BlockPriceClient.
+func (m *MockBlockPriceClient) GetETHUSDTAtBlock(ctx context.Context, chainID int64, blockNumber uint64, timestamp time.Time) (*client.KlineData, error) {
+	args := m.Called(ctx, chainID, blockNumber, timestamp)
+	return args.Get(0).(*client.KlineData), args.Error(1)
+}
+
+// ReadsBlocks mocks the ReadsBlocks method of BlockPriceClient.
+func (m *MockBlockPriceClient) ReadsBlocks() bool {
+	args := m.Called()
+	return args.Bool(0)
+}
+
 // MockTransactionClient is a mock implementation of the TransactionClient interface.
 type MockTransactionClient struct {
 	mock.Mock
 }
 
+// GetTransactionReceipt mocks the GetTransactionReceipt method.
+func (m *MockTransactionClient) GetTransactionReceipt(ctx context.Context, hash string) (*types.TransactionData, error) {
+	args := m.Called(ctx, hash)
+	return args.Get(0).(*types.TransactionData), args.Error(1)
+}
+
+// GetLatestTransaction mocks the GetLatestTransaction method.
+func (m *MockTransactionClient) GetLatestTransaction(ctx context.Context, poolAddress string) (*types.TransactionData, error) {
+	args := m.Called(ctx, poolAddress)
+	return args.Get(0).(*types.TransactionData), args.Error(1)
+}
+
 // ListTransactions mocks the ListTransactions method.
-func (m *MockTransactionClient) ListTransactions(batchSize *uint64, startBlock *uint64, endBlock *uint64, page *int) ([]types.TransactionData, error) {
-	args := m.Called(batchSize, startBlock, endBlock, page)
+func (m *MockTransactionClient) ListTransactions(ctx context.Context, poolAddress string, offset *int, startBlock *uint64, endBlock *uint64, page *int) ([]types.TransactionData, error) {
+	args := m.Called(ctx, poolAddress, offset, startBlock, endBlock, page)
 	return args.Get(0).([]types.TransactionData), args.Error(1)
 }
+
+// GetBlockNumberByTimestamp mocks the GetBlockNumberByTimestamp method.
+func (m *MockTransactionClient) GetBlockNumberByTimestamp(ctx context.Context, timestamp time.Time, before bool) (uint64, error) {
+	args := m.Called(ctx, timestamp, before)
+	return args.Get(0).(uint64), args.Error(1)
+}
+
+// GetBlockNumber mocks the GetBlockNumber method.
+func (m *MockTransactionClient) GetBlockNumber(ctx context.Context) (uint64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(uint64), args.Error(1)
+}
+
+// GetBlockHeader mocks the GetBlockHeader method.
+func (m *MockTransactionClient) GetBlockHeader(ctx context.Context, blockNumber uint64) (*types.BlockHeader, error) {
+	args := m.Called(ctx, blockNumber)
+	return args.Get(0).(*types.BlockHeader), args.Error(1)
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/mock"
+	"github.com/winQe/uniswap-fee-tracker/internal/client"
 	"github.com/winQe/uniswap-fee-tracker/internal/types"
 )
 
@@ -13,26 +14,67 @@ type MockTransactionManager struct {
 	mock.Mock
 }
 
-// GetLatestBlockNumber mocks the GetLatestBlockNumber method
-func (m *MockTransactionManager) GetLatestBlockNumber() (uint64, error) {
+// ChainIDs mocks the ChainIDs method
+func (m *MockTransactionManager) ChainIDs() []int64 {
 	args := m.Called()
+	return args.Get(0).([]int64)
+}
+
+// GetLatestBlockNumber mocks the GetLatestBlockNumber method
+func (m *MockTransactionManager) GetLatestBlockNumber(ctx context.Context, chainID int64) (uint64, error) {
+	args := m.Called(ctx, chainID)
 	return args.Get(0).(uint64), args.Error(1)
 }
 
+// GetSafeBlockNumber mocks the GetSafeBlockNumber method
+func (m *MockTransactionManager) GetSafeBlockNumber(ctx context.Context, chainID int64) (uint64, error) {
+	args := m.Called(ctx, chainID)
+	return args.Get(0).(uint64), args.Error(1)
+}
+
+// GetBlockHeader mocks the GetBlockHeader method
+func (m *MockTransactionManager) GetBlockHeader(ctx context.Context, chainID int64, blockNumber uint64) (*types.BlockHeader, error) {
+	args := m.Called(ctx, chainID, blockNumber)
+	return args.Get(0).(*types.BlockHeader), args.Error(1)
+}
+
 // GetTransaction mocks the GetTransaction method
-func (m *MockTransactionManager) GetTransaction(hash string) (*types.TxWithPrice, error) {
-	args := m.Called(hash)
+func (m *MockTransactionManager) GetTransaction(ctx context.Context, hash string) (*types.TxWithPrice, error) {
+	args := m.Called(ctx, hash)
 	return args.Get(0).(*types.TxWithPrice), args.Error(1)
 }
 
 // BatchProcessTransactions mocks the BatchProcessTransactions method
-func (m *MockTransactionManager) BatchProcessTransactions(startBlock uint64, endBlock uint64, ctx context.Context) ([]types.TxWithPrice, error) {
-	args := m.Called(startBlock, endBlock, ctx)
+func (m *MockTransactionManager) BatchProcessTransactions(ctx context.Context, chainID int64, startBlock uint64, endBlock uint64) ([]types.TxWithPrice, error) {
+	args := m.Called(ctx, chainID, startBlock, endBlock)
 	return args.Get(0).([]types.TxWithPrice), args.Error(1)
 }
 
 // BatchProcessTransactionsByTimestamp mocks the BatchProcessTransactionsByTimestamp method
-func (m *MockTransactionManager) BatchProcessTransactionsByTimestamp(startTime time.Time, endTime time.Time, ctx context.Context) ([]types.TxWithPrice, error) {
-	args := m.Called(startTime, endTime, ctx)
+func (m *MockTransactionManager) BatchProcessTransactionsByTimestamp(ctx context.Context, startTime time.Time, endTime time.Time, onProgress types.ProgressFunc) ([]types.TxWithPrice, error) {
+	args := m.Called(ctx, startTime, endTime, onProgress)
 	return args.Get(0).([]types.TxWithPrice), args.Error(1)
 }
+
+// MockPriceManager is a mock implementation of the PriceManagerInterface
+type MockPriceManager struct {
+	mock.Mock
+}
+
+// GetETHUSDT mocks the GetETHUSDT method
+func (m *MockPriceManager) GetETHUSDT(ctx context.Context, timestamp time.Time) (*client.KlineData, error) {
+	args := m.Called(ctx, timestamp)
+	return args.Get(0).(*client.KlineData), args.Error(1)
+}
+
+// GetETHUSDTAtBlock mocks the GetETHUSDTAtBlock method
+func (m *MockPriceManager) GetETHUSDTAtBlock(ctx context.Context, chainID int64, blockNumber uint64, timestamp time.Time) (*client.KlineData, error) {
+	args := m.Called(ctx, chainID, blockNumber, timestamp)
+	return args.Get(0).(*client.KlineData), args.Error(1)
+}
+
+// PrefetchETHUSDT mocks the PrefetchETHUSDT method
+func (m *MockPriceManager) PrefetchETHUSDT(ctx context.Context, startTime time.Time, endTime time.Time) error {
+	args := m.Called(ctx, startTime, endTime)
+	return args.Error(0)
+}
@@ -2,6 +2,7 @@ package mocks
 
 import (
 	"context"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 	db "github.com/winQe/uniswap-fee-tracker/internal/db/sqlc"
@@ -17,8 +18,8 @@ func (m *MockQuerier) GetTransactionByHash(ctx context.Context, hash string) (db
 	return args.Get(0).(db.Transactions), args.Error(1)
 }
 
-func (m *MockQuerier) GetLatestTransactions(ctx context.Context, limit int32) ([]db.Transactions, error) {
-	args := m.Called(ctx, limit)
+func (m *MockQuerier) GetLatestTransactions(ctx context.Context, params db.GetLatestTransactionsParams) ([]db.Transactions, error) {
+	args := m.Called(ctx, params)
 	return args.Get(0).([]db.Transactions), args.Error(1)
 }
 
@@ -35,3 +36,111 @@ func (m *MockQuerier) GetTransactionsByBlockNumber(ctx context.Context, blockNum
 func (m *MockQuerier) InsertTransaction(ctx context.Context, arg db.InsertTransactionParams) error {
 	return nil
 }
+
+func (m *MockQuerier) GetSwapsByTransactionHash(ctx context.Context, hash string) ([]db.Swaps, error) {
+	args := m.Called(ctx, hash)
+	return args.Get(0).([]db.Swaps), args.Error(1)
+}
+
+func (m *MockQuerier) GetSwapsByTransactionHashes(ctx context.Context, hashes []string) ([]db.Swaps, error) {
+	args := m.Called(ctx, hashes)
+	return args.Get(0).([]db.Swaps), args.Error(1)
+}
+
+func (m *MockQuerier) InsertSwap(ctx context.Context, arg db.InsertSwapParams) error {
+	return nil
+}
+
+func (m *MockQuerier) ListPools(ctx context.Context) ([]db.Pools, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]db.Pools), args.Error(1)
+}
+
+func (m *MockQuerier) UpsertPool(ctx context.Context, arg db.UpsertPoolParams) error {
+	return nil
+}
+
+func (m *MockQuerier) UpsertBlock(ctx context.Context, arg db.UpsertBlockParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) ListRecentBlocks(ctx context.Context, arg db.ListRecentBlocksParams) ([]db.Blocks, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).([]db.Blocks), args.Error(1)
+}
+
+func (m *MockQuerier) DeleteBlocksFrom(ctx context.Context, arg db.DeleteBlocksFromParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) DeleteTransactionsFromBlock(ctx context.Context, arg db.DeleteTransactionsFromBlockParams) (int64, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) GetIngestionCheckpoint(ctx context.Context, chainID int64) (db.IngestionCheckpoints, error) {
+	args := m.Called(ctx, chainID)
+	return args.Get(0).(db.IngestionCheckpoints), args.Error(1)
+}
+
+func (m *MockQuerier) UpsertIngestionCheckpoint(ctx context.Context, arg db.UpsertIngestionCheckpointParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) ExtendIngestedRange(ctx context.Context, arg db.ExtendIngestedRangeParams) (int64, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) InsertIngestedRange(ctx context.Context, arg db.InsertIngestedRangeParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) ListIngestedRanges(ctx context.Context, chainID int64) ([]db.IngestedRanges, error) {
+	args := m.Called(ctx, chainID)
+	return args.Get(0).([]db.IngestedRanges), args.Error(1)
+}
+
+func (m *MockQuerier) DeleteIngestedRangesFrom(ctx context.Context, arg db.DeleteIngestedRangesFromParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) TruncateIngestedRanges(ctx context.Context, arg db.TruncateIngestedRangesParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) GetFirstTransactionTimeFromBlock(ctx context.Context, arg db.GetFirstTransactionTimeFromBlockParams) (time.Time, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(time.Time), args.Error(1)
+}
+
+func (m *MockQuerier) RefreshFeeRollups(ctx context.Context, arg db.RefreshFeeRollupsParams) (int64, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) LockFeeRollups(ctx context.Context, chainID int64) error {
+	args := m.Called(ctx, chainID)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) ListFeeRollups(ctx context.Context, arg db.ListFeeRollupsParams) ([]db.FeeRollups, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).([]db.FeeRollups), args.Error(1)
+}
+
+// MockBulkWriter is a mock implementation of the db.BulkWriter interface.
+type MockBulkWriter struct {
+	mock.Mock
+}
+
+func (m *MockBulkWriter) UpsertTransactions(ctx context.Context, rows []db.TransactionRow) (db.UpsertResult, error) {
+	args := m.Called(ctx, rows)
+	return args.Get(0).(db.UpsertResult), args.Error(1)
+}
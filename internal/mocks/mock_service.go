@@ -1,6 +1,8 @@
 package mocks
 
 import (
+	"context"
+
 	"github.com/stretchr/testify/mock"
 )
 
@@ -17,8 +19,8 @@ func NewMockBatchDataProcessor() *MockBatchDataProcessor {
 	}
 }
 
-func (m *MockBatchDataProcessor) ProcessBatchJob(jobID string, startTime, endTime int64) error {
-	m.Called(jobID, startTime, endTime)
+func (m *MockBatchDataProcessor) ProcessBatchJob(ctx context.Context, jobID string, startTime, endTime int64) error {
+	m.Called(ctx, jobID, startTime, endTime)
 	// Signal that the method was called
 	m.CalledChan <- struct{}{}
 	return nil
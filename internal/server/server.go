@@ -1,7 +1,12 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
+	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/winQe/uniswap-fee-tracker/internal/api"
@@ -12,26 +17,47 @@ type Server struct {
 	port            string
 	txHandler       *api.TransactionHandler
 	batchJobHandler *api.BatchJobHandler
+	coverageHandler *api.CoverageHandler
+	healthHandler   *api.HealthHandler
+	statsHandler    *api.StatsHandler
 }
 
 // Server represents the API server and route handlers
-func NewServer(port string, txHandler *api.TransactionHandler, batchJobHandler *api.BatchJobHandler) *Server {
+func NewServer(port string, txHandler *api.TransactionHandler, batchJobHandler *api.BatchJobHandler, coverageHandler *api.CoverageHandler, healthHandler *api.HealthHandler, statsHandler *api.StatsHandler) *Server {
 	return &Server{
 		port:            port,
 		txHandler:       txHandler,
 		batchJobHandler: batchJobHandler,
+		coverageHandler: coverageHandler,
+		healthHandler:   healthHandler,
+		statsHandler:    statsHandler,
 	}
 }
 
 // Run starts the HTTP server, registers API routes, and binds the server to the specified port.
-func (s *Server) Run() error {
+// It shuts the server down once ctx is canceled, letting the in-flight requests end.
+func (s *Server) Run(ctx context.Context) error {
 	router := gin.Default()
+	// Handlers pass the gin context down to the clients, so that it ends with the request
+	router.ContextWithFallback = true
 
 	v1 := router.Group("/api/v1")
 	{
-		api.RegisterRoutes(v1, s.txHandler, s.batchJobHandler)
+		api.RegisterRoutes(v1, s.txHandler, s.batchJobHandler, s.coverageHandler, s.healthHandler, s.statsHandler)
 	}
 
 	serverAddr := fmt.Sprintf("0.0.0.0:%s", s.port)
-	return router.Run(serverAddr)
+	server := &http.Server{Addr: serverAddr, Handler: router}
+	go func() {
+		<-ctx.Done()
+		log.Println("Shutting down the API server")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
 }
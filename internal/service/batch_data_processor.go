@@ -2,76 +2,92 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"time"
 
-	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/winQe/uniswap-fee-tracker/internal/cache"
 	db "github.com/winQe/uniswap-fee-tracker/internal/db/sqlc"
 	"github.com/winQe/uniswap-fee-tracker/internal/domain"
+	"github.com/winQe/uniswap-fee-tracker/internal/types"
 	"github.com/winQe/uniswap-fee-tracker/internal/utils"
 )
 
 // BatchDataProcessor defines the interface for processing batch jobs with GoRoutines
 type BatchDataProcessor interface {
-	ProcessBatchJob(jobID string, startTime, endTime int64) error
+	ProcessBatchJob(ctx context.Context, jobID string, startTime, endTime int64) error
 }
 
 // BatchDataProcessorImpl is the concrete implementation of BatchDataProcessor.
 type BatchDataProcessorImpl struct {
-	txDbQuery db.Querier
+	txWriter  db.BulkWriter
 	jobCache  cache.JobsStore
 	txManager domain.TransactionManagerInterface
 }
 
 // NewBatchDataProcessor initializes a new BatchDataProcessorImpl.
-func NewBatchDataProcessor(txDbQuery db.Querier, jobCache cache.JobsStore, txManager domain.TransactionManagerInterface) *BatchDataProcessorImpl {
+func NewBatchDataProcessor(txWriter db.BulkWriter, jobCache cache.JobsStore, txManager domain.TransactionManagerInterface) *BatchDataProcessorImpl {
 	return &BatchDataProcessorImpl{
-		txDbQuery: txDbQuery,
+		txWriter:  txWriter,
 		jobCache:  jobCache,
 		txManager: txManager,
 	}
 }
 
 // ProcessBatchJob processes the batch job asynchronously.
-func (bdp *BatchDataProcessorImpl) ProcessBatchJob(jobID string, startTime, endTime int64) error {
+// Canceling the context aborts the in-flight requests, the job status is still updated.
+func (bdp *BatchDataProcessorImpl) ProcessBatchJob(ctx context.Context, jobID string, startTime, endTime int64) error {
+	// Job updates and fetched transactions are stored even once the job is canceled or times out
+	storeCtx := context.WithoutCancel(ctx)
+
 	// Update job status to 'running'
-	bdp.updateJobStatus(jobID, "running", "")
+	bdp.updateJobStatus(storeCtx, jobID, "running", "")
 
 	// Create a new context for the batch processing
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
 	defer cancel()
 
 	// Convert unix time to time.Time
 	startTs := time.Unix(startTime, 0)
 	endTs := time.Unix(endTime, 0)
 
-	// Execute the batch processing
-	result, err := bdp.txManager.BatchProcessTransactionsByTimestamp(startTs, endTs, ctx)
-	for _, tx := range result {
-		err := bdp.txDbQuery.InsertTransaction(context.Background(), db.InsertTransactionParams{
-			TransactionHash:    tx.Hash,
-			BlockNumber:        int64(tx.BlockNumber),
-			Timestamp:          tx.Timestamp,
-			GasUsed:            int64(tx.GasUsed),
-			GasPriceWei:        tx.GasPriceWei.Int64(),
-			TransactionFeeEth:  pgtype.Float8{Float64: tx.TransactionFeeETH, Valid: true},
-			TransactionFeeUsdt: pgtype.Float8{Float64: tx.TransactionFeeUSDT, Valid: true},
-			EthUsdtPrice:       pgtype.Float8{Float64: tx.ETHUSDTPrice, Valid: true},
+	// Execute the batch processing, keeping the job's split plan up to date.
+	// The job is paused while a range waits for the circuit breaker of an upstream.
+	var plan []types.RangeProgress
+	onProgress := func(progress types.RangeProgress) {
+		plan = updatePlan(plan, progress)
+		bdp.updateJob(storeCtx, jobID, func(job *cache.BatchJob) {
+			job.Progress = plan
+			job.Status = planStatus(plan)
 		})
-		if err != nil {
-			log.Printf("Error inserting transaction %s into DB: %v\n", tx.Hash, err)
-			continue
+	}
+	result, err := bdp.txManager.BatchProcessTransactionsByTimestamp(ctx, startTs, endTs, onProgress)
+
+	// Transactions stored by an overlapping job are skipped, or updated when they changed
+	stored, storeErr := StoreTransactions(storeCtx, bdp.txWriter, result)
+	log.Printf("Stored the transactions of job %s: %d inserted, %d updated, %d skipped\n", jobID, stored.Inserted, stored.Updated, stored.Skipped)
+	bdp.updateJob(storeCtx, jobID, func(job *cache.BatchJob) {
+		job.Stored = &stored
+	})
+	if storeErr != nil {
+		log.Printf("Error storing the transactions of job %s: %v\n", jobID, storeErr)
+		if err == nil {
+			err = fmt.Errorf("error storing transactions: %w", storeErr)
 		}
 	}
 
 	if err != nil {
-		// Update job status to 'failed' with error message
-		bdp.updateJobStatus(jobID, "failed", err.Error())
+		// Update job status to 'canceled', or 'failed' with error message
+		status := "failed"
+		if errors.Is(ctx.Err(), context.Canceled) {
+			status = "canceled"
+		}
+		bdp.updateJobStatus(storeCtx, jobID, status, err.Error())
 		return err
 	}
 	// Update job status to 'completed' with a success message
-	if err := bdp.updateJobStatus(jobID, "completed", "Batch job completed successfully."); err != nil {
+	if err := bdp.updateJobStatus(storeCtx, jobID, "completed", "Batch job completed successfully."); err != nil {
 		return err
 	}
 
@@ -79,9 +95,19 @@ func (bdp *BatchDataProcessorImpl) ProcessBatchJob(jobID string, startTime, endT
 }
 
 // updateJobStatus updates the status and result of a batch job in Redis.
-func (bdp *BatchDataProcessorImpl) updateJobStatus(jobID, status, result string) error {
+func (bdp *BatchDataProcessorImpl) updateJobStatus(ctx context.Context, jobID, status, result string) error {
+	return bdp.updateJob(ctx, jobID, func(job *cache.BatchJob) {
+		job.Status = status
+		if result != "" {
+			job.Result = result
+		}
+	})
+}
+
+// updateJob applies the update to a batch job in Redis.
+func (bdp *BatchDataProcessorImpl) updateJob(ctx context.Context, jobID string, update func(job *cache.BatchJob)) error {
 	// Retrieve the current job data
-	jobData, err := bdp.jobCache.GetJob(jobID)
+	jobData, err := bdp.jobCache.GetJob(ctx, jobID)
 	if err != nil {
 		log.Printf("Failed to retrieve job %s for status update: %v", jobID, err)
 		return err
@@ -93,12 +119,8 @@ func (bdp *BatchDataProcessorImpl) updateJobStatus(jobID, status, result string)
 		return err
 	}
 
-	// Update status and result
-	job.Status = status
+	update(&job)
 	job.UpdatedAt = time.Now().Unix()
-	if result != "" {
-		job.Result = result
-	}
 
 	// Serialize updated job
 	updatedJobData, err := utils.SerializeToJSON(job)
@@ -108,7 +130,7 @@ func (bdp *BatchDataProcessorImpl) updateJobStatus(jobID, status, result string)
 	}
 
 	// Store the updated job data back in Redis
-	err = bdp.jobCache.SetJob(job.ID, updatedJobData)
+	err = bdp.jobCache.SetJob(ctx, job.ID, updatedJobData)
 	if err != nil {
 		log.Printf("Failed to update job %s in Redis: %v", job.ID, err)
 		return err
@@ -116,3 +138,25 @@ func (bdp *BatchDataProcessorImpl) updateJobStatus(jobID, status, result string)
 
 	return nil
 }
+
+// planStatus is the status of a running job, paused while any of its ranges is
+func planStatus(plan []types.RangeProgress) string {
+	for _, r := range plan {
+		if r.Status == types.RangePaused {
+			return "paused"
+		}
+	}
+	return "running"
+}
+
+// updatePlan records the state of a block range, ranges are listed in the order they were first reported
+func updatePlan(plan []types.RangeProgress, progress types.RangeProgress) []types.RangeProgress {
+	for i, r := range plan {
+		if r.ChainID == progress.ChainID && r.PoolAddress == progress.PoolAddress &&
+			r.StartBlock == progress.StartBlock && r.EndBlock == progress.EndBlock {
+			plan[i] = progress
+			return plan
+		}
+	}
+	return append(plan, progress)
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/winQe/uniswap-fee-tracker/internal/cache"
+	"github.com/winQe/uniswap-fee-tracker/internal/client"
+	db "github.com/winQe/uniswap-fee-tracker/internal/db/sqlc"
+	"github.com/winQe/uniswap-fee-tracker/internal/domain"
+	"github.com/winQe/uniswap-fee-tracker/internal/mocks"
+	"github.com/winQe/uniswap-fee-tracker/internal/types"
+	"github.com/winQe/uniswap-fee-tracker/internal/utils"
+)
+
+// loadCassette opens the fixture testdata/cassettes/<name>.json in the mode of CASSETTE_MODE, saving it once the test is over
+func loadCassette(t *testing.T, name string) *client.Cassette {
+	cassette, err := client.NewCassette(filepath.Join("testdata", "cassettes", name+".json"), client.CassetteModeFromEnv())
+	if err != nil {
+		t.Fatalf("error loading cassette: %v", err)
+	}
+	t.Cleanup(func() {
+		assert.NoError(t, cassette.Save())
+	})
+	return cassette
+}
+
+// memoryRateStore is a RateStore keeping the rates in memory, a rate covers the minute following its timestamp
+type memoryRateStore struct {
+	mu    sync.Mutex
+	rates []client.PricePoint
+}
+
+func (s *memoryRateStore) StoreRate(ctx context.Context, timestamp time.Time, price client.KlineData) error {
+	return s.StoreRates(ctx, []client.PricePoint{{Timestamp: timestamp, KlineData: price}})
+}
+
+func (s *memoryRateStore) StoreRates(ctx context.Context, prices []client.PricePoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rates = append(s.rates, prices...)
+	sort.Slice(s.rates, func(i, j int) bool { return s.rates[i].Timestamp.Before(s.rates[j].Timestamp) })
+	return nil
+}
+
+func (s *memoryRateStore) GetRate(ctx context.Context, timestamp time.Time) (*client.KlineData, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.rates) - 1; i >= 0; i-- {
+		if !s.rates[i].Timestamp.After(timestamp) && timestamp.Sub(s.rates[i].Timestamp) < time.Minute {
+			rate := s.rates[i].KlineData
+			return &rate, nil
+		}
+	}
+	return nil, fmt.Errorf("no rate at %v", timestamp)
+}
+
+// storingWriter keeps the transactions and swaps written through it
+type storingWriter struct {
+	transactions []db.InsertTransactionParams
+	swaps        []db.InsertSwapParams
+}
+
+func (w *storingWriter) UpsertTransactions(ctx context.Context, rows []db.TransactionRow) (db.UpsertResult, error) {
+	for _, row := range rows {
+		w.transactions = append(w.transactions, row.Transaction)
+		w.swaps = append(w.swaps, row.Swaps...)
+	}
+	return db.UpsertResult{Inserted: len(rows)}, nil
+}
+
+// TestProcessBatchJob_Replay runs a batch job end to end against the Etherscan and Binance responses of the batch_job cassette.
+// Record it again with CASSETTE_MODE=record and ETHERSCAN_API_KEY set.
+func TestProcessBatchJob_Replay(t *testing.T) {
+	const poolAddress = "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640"
+	cassette := loadCassette(t, "batch_job")
+
+	apiKey := "replay"
+	if cassette.Mode() == client.CassetteRecord {
+		apiKey = os.Getenv("ETHERSCAN_API_KEY")
+	}
+	chain := utils.ChainConfig{
+		Name:              "ethereum",
+		ChainID:           1,
+		ExplorerURL:       utils.DefaultExplorerURL,
+		APIKey:            apiKey,
+		RequestsPerSecond: 5,
+		RequestsPerDay:    100000,
+		Pools:             []utils.PoolConfig{{Address: poolAddress}},
+	}
+	etherscanClient := client.NewEtherscanClient(chain, nil, client.DefaultRetryPolicy, client.DefaultBreakerConfig)
+	etherscanClient.UseTransport(cassette)
+
+	klineClient, err := client.NewKlineClient(client.KlineConfig{Interval: "1m"})
+	assert.NoError(t, err)
+	klineClient.UseTransport(cassette)
+
+	txManager := domain.NewTransactionManager(
+		[]domain.ChainSource{{ChainID: 1, Client: etherscanClient, PoolAddresses: chain.PoolAddresses()}},
+		domain.NewPriceManager(&memoryRateStore{}, klineClient),
+	)
+
+	// 2024-10-01 13:40:00 to 13:42:00 UTC
+	startTime, endTime := int64(1727790000), int64(1727790120)
+	job, err := utils.SerializeToJSON(cache.BatchJob{ID: "job-1", Status: "pending", StartTime: startTime, EndTime: endTime})
+	assert.NoError(t, err)
+
+	var statuses []string
+	jobStore := new(mocks.MockJobsStore)
+	jobStore.On("GetJob", mock.Anything, "job-1").Return(job, nil)
+	jobStore.On("SetJob", mock.Anything, "job-1", mock.Anything).Run(func(args mock.Arguments) {
+		var updated cache.BatchJob
+		assert.NoError(t, utils.DeserializeFromJSON(args.Get(2).([]byte), &updated))
+		statuses = append(statuses, updated.Status)
+	}).Return(nil)
+
+	writer := &storingWriter{}
+	processor := NewBatchDataProcessor(writer, jobStore, txManager)
+
+	err = processor.ProcessBatchJob(context.Background(), "job-1", startTime, endTime)
+	assert.NoError(t, err)
+	assert.Equal(t, "running", statuses[0])
+	assert.Equal(t, "completed", statuses[len(statuses)-1])
+
+	// Every transaction once, though tokentx lists each of its transfers
+	stored := make(map[string]db.InsertTransactionParams)
+	for _, tx := range writer.transactions {
+		stored[tx.TransactionHash] = tx
+	}
+	assert.Len(t, writer.transactions, 3)
+	assert.Len(t, writer.swaps, 3)
+
+	expected := []struct {
+		hash      string
+		block     int64
+		price     string
+		feeETH    string
+		feeUSDT   string
+		burnedETH string
+		tipETH    string
+	}{
+		{"0xa1f3c0d2b7e4a5968c1d0e2f3a4b5c6d7e8f9a0b1c2d3e4f5a6b7c8d9e0f1a2b", 20871421, "2612.34", "0.002214276", "5.78444176584", "0.0016238024", "0.0005904736"},
+		{"0xb27d4e6f8091a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708192a3b", 20871421, "2612.34", "0.001206538", "3.15188747892", "0.0011176352", "0.0000889028"},
+		{"0xc3e5f7091b2d4f6a8c0e2f4a6b8d0f2a4c6e8a0c2e4a6c8e0a2c4e6a8c0e2a4c", 20871429, "2613.1", "0.002069229", "5.4071022999", "0.0018370716", "0.0002321574"},
+	}
+	for _, want := range expected {
+		tx, ok := stored[want.hash]
+		if !assert.True(t, ok, "transaction %s not stored", want.hash) {
+			continue
+		}
+		assert.Equal(t, want.block, tx.BlockNumber)
+		assert.Equal(t, int64(1), tx.ChainID)
+		assert.Equal(t, poolAddress, tx.PoolAddress.String)
+		assert.Equal(t, client.PriceSourceBinance, tx.PriceSource.String)
+		assert.Equal(t, want.price, utils.NumericToString(tx.EthUsdtPrice))
+		assert.Equal(t, want.feeETH, utils.NumericToString(tx.TransactionFeeEth))
+		assert.Equal(t, want.feeUSDT, utils.NumericToString(tx.TransactionFeeUsdt))
+		assert.Equal(t, want.burnedETH, utils.NumericToString(tx.BurnedFeeEth))
+		assert.Equal(t, want.tipETH, utils.NumericToString(tx.TipFeeEth))
+	}
+
+	// The plan holds the single range of the pool, resolved from the timestamps
+	jobStore.AssertCalled(t, "SetJob", mock.Anything, "job-1", mock.MatchedBy(func(data []byte) bool {
+		var updated cache.BatchJob
+		utils.DeserializeFromJSON(data, &updated)
+		return updated.Status == "running" && len(updated.Progress) == 1 &&
+			updated.Progress[0].StartBlock == 20871421 && updated.Progress[0].EndBlock == 20871430 &&
+			updated.Progress[0].Status == types.RangeDone && updated.Progress[0].Transactions == 3
+	}))
+	// The job reports the counts of the write
+	jobStore.AssertCalled(t, "SetJob", mock.Anything, "job-1", mock.MatchedBy(func(data []byte) bool {
+		var updated cache.BatchJob
+		utils.DeserializeFromJSON(data, &updated)
+		return updated.Stored != nil && *updated.Stored == db.UpsertResult{Inserted: 3}
+	}))
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	db "github.com/winQe/uniswap-fee-tracker/internal/db/sqlc"
+)
+
+// rebuildStep bounds the span of the transactions aggregated by a single refresh of a rebuild
+const rebuildStep = 24 * time.Hour
+
+// RebuildFeeRollups recomputes the fee rollups of the chain of every bucket size from the stored transactions,
+// from the buckets holding start to the buckets holding end. The range is rebuilt one UTC day at a time.
+func RebuildFeeRollups(ctx context.Context, dbQuerier db.Querier, chainID int64, start time.Time, end time.Time) error {
+	if end.Before(start) {
+		return fmt.Errorf("end %v is before start %v", end, start)
+	}
+
+	for day := start.Truncate(rebuildStep); !day.After(end); day = day.Add(rebuildStep) {
+		stepStart := day
+		if stepStart.Before(start) {
+			stepStart = start
+		}
+		stepEnd := day.Add(rebuildStep - time.Nanosecond)
+		if stepEnd.After(end) {
+			stepEnd = end
+		}
+
+		if err := db.RefreshFeeRollupsBetween(ctx, dbQuerier, chainID, stepStart, stepEnd); err != nil {
+			return fmt.Errorf("error rebuilding the fee rollups of chain %d on %s: %w", chainID, day.UTC().Format(time.DateOnly), err)
+		}
+	}
+
+	log.Printf("Rebuilt the fee rollups of chain %d from %v to %v\n", chainID, start.UTC(), end.UTC())
+	return nil
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	db "github.com/winQe/uniswap-fee-tracker/internal/db/sqlc"
+	"github.com/winQe/uniswap-fee-tracker/internal/mocks"
+)
+
+func TestRebuildFeeRollups(t *testing.T) {
+	mockQuerier := new(mocks.MockQuerier)
+
+	var refreshed []db.RefreshFeeRollupsParams
+	mockQuerier.On("RefreshFeeRollups", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		refreshed = append(refreshed, args.Get(1).(db.RefreshFeeRollupsParams))
+	}).Return(int64(1), nil)
+
+	// 2024-10-01 13:40:00 to 2024-10-03 06:00:00 UTC
+	start, end := time.Unix(1727790000, 0).UTC(), time.Unix(1727935200, 0).UTC()
+	err := RebuildFeeRollups(context.Background(), mockQuerier, 1, start, end)
+	assert.NoError(t, err)
+
+	// One step per UTC day, every bucket size refreshed in each
+	days := []struct{ start, end time.Time }{
+		{start, time.Date(2024, 10, 1, 23, 59, 59, 999999999, time.UTC)},
+		{time.Date(2024, 10, 2, 0, 0, 0, 0, time.UTC), time.Date(2024, 10, 2, 23, 59, 59, 999999999, time.UTC)},
+		{time.Date(2024, 10, 3, 0, 0, 0, 0, time.UTC), end},
+	}
+	if assert.Len(t, refreshed, len(days)*len(db.FeeRollupBucketSizes)) {
+		for i, day := range days {
+			for j, bucketSize := range db.FeeRollupBucketSizes {
+				assert.Equal(t, db.RefreshFeeRollupsParams{BucketSize: bucketSize, StartTime: day.start, EndTime: day.end, ChainID: 1}, refreshed[i*len(db.FeeRollupBucketSizes)+j])
+			}
+		}
+	}
+
+	assert.Error(t, RebuildFeeRollups(context.Background(), mockQuerier, 1, end, start))
+}
+
+func TestRebuildFeeRollups_Error(t *testing.T) {
+	mockQuerier := new(mocks.MockQuerier)
+	mockQuerier.On("RefreshFeeRollups", mock.Anything, mock.Anything).Return(int64(0), errors.New("canceling statement due to statement timeout")).Once()
+
+	err := RebuildFeeRollups(context.Background(), mockQuerier, 1, time.Unix(1727790000, 0), time.Unix(1727935200, 0))
+
+	// The rebuild stops at the failed day
+	assert.ErrorContains(t, err, "on 2024-10-01")
+	mockQuerier.AssertNumberOfCalls(t, "RefreshFeeRollups", 1)
+}
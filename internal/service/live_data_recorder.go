@@ -2,40 +2,154 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"sync/atomic"
 	"time"
 
-	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5"
 	db "github.com/winQe/uniswap-fee-tracker/internal/db/sqlc"
 	"github.com/winQe/uniswap-fee-tracker/internal/domain"
+	"github.com/winQe/uniswap-fee-tracker/internal/types"
 )
 
-// LiveDataRecorder records
+// maxIngestRange bounds the blocks processed in one go, so a long backfill is checkpointed as it progresses
+const maxIngestRange = 5000
+
+// maxReorgLookback bounds the number of stored blocks compared against the chain when searching for the fork point
+const maxReorgLookback = 64
+
+// ReorgEvent describes a chain reorganization detected by the LiveDataRecorder.
+// Every transaction from ForkBlock onwards was deleted and is ingested again from the new chain.
+type ReorgEvent struct {
+	ChainID             int64
+	ForkBlock           uint64 // First block replaced by the new chain
+	Depth               uint64 // Number of processed blocks rolled back
+	OldHash             string // Stored hash of the last processed block
+	NewHash             string // Hash the chain now reports for the last processed block
+	RemovedTransactions int64
+	DetectedAt          time.Time
+}
+
+// pollInterval is how often chains are checked for new blocks when no head source pushes them
+const pollInterval = 60 * time.Second
+
+// defaultHeadInterval is how often at most a chain is recorded on pushed heads, the heads pushed in between are covered by one run
+const defaultHeadInterval = 30 * time.Second
+
+// HeadSource pushes the new heads of a chain, e.g. a WebSocket newHeads subscription
+type HeadSource interface {
+	// Run calls onHead with the number of every new head until the context is canceled
+	Run(ctx context.Context, onHead func(blockNumber uint64))
+	// Connected reports whether heads are currently pushed, chains are polled while they aren't
+	Connected() bool
+}
+
+// LiveDataRecorder records the transactions of every chain as blocks reach their confirmation depth.
+// It stores the header of the last block of every processed range and checks the next block links to it,
+// rolling back and re-ingesting the reorganized range when it doesn't.
 type LiveDataRecorder struct {
-	lastBlockNumber    uint64
+	lastBlockNumbers   map[int64]uint64 // Last processed block per chain ID
 	transactionManager domain.TransactionManagerInterface
 	dbQuerier          db.Querier
+	txWriter           db.BulkWriter
+	reorgHandlers      []func(ReorgEvent)
+	headSources        map[int64]HeadSource // Chains whose new heads are pushed instead of polled
+	headInterval       time.Duration        // Minimum time between two recordings of a chain on pushed heads
 }
 
 // NewLiveDataRecorder initializes a new LiveDataRecorder instance.
-func NewLiveDataRecorder(dbQuerier db.Querier, transactionManager domain.TransactionManagerInterface) *LiveDataRecorder {
-	lastBlockNumber, err := transactionManager.GetLatestBlockNumber()
-	if err != nil {
-		log.Fatalf("Failed to get the latest block number: %v\n", err)
+// Every chain resumes from its checkpoint. Chains without one start from their latest pool transaction,
+// or their confirmed head when that transaction is not confirmed yet.
+func NewLiveDataRecorder(ctx context.Context, dbQuerier db.Querier, txWriter db.BulkWriter, transactionManager domain.TransactionManagerInterface) *LiveDataRecorder {
+	lastBlockNumbers := make(map[int64]uint64)
+	for _, chainID := range transactionManager.ChainIDs() {
+		checkpoint, err := dbQuerier.GetIngestionCheckpoint(ctx, chainID)
+		if err == nil {
+			log.Printf("Resuming chain %d from its checkpoint at block %d.\n", chainID, checkpoint.LastBlockNumber)
+			lastBlockNumbers[chainID] = uint64(checkpoint.LastBlockNumber)
+			continue
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			log.Fatalf("Failed to get the checkpoint of chain %d: %v\n", chainID, err)
+		}
+
+		lastBlockNumber, err := transactionManager.GetLatestBlockNumber(ctx, chainID)
+		if err != nil {
+			log.Fatalf("Failed to get the latest block number of chain %d: %v\n", chainID, err)
+		}
+		safeBlockNumber, err := transactionManager.GetSafeBlockNumber(ctx, chainID)
+		if err != nil {
+			log.Fatalf("Failed to get the confirmed block number of chain %d: %v\n", chainID, err)
+		}
+		if safeBlockNumber < lastBlockNumber {
+			lastBlockNumber = safeBlockNumber
+		}
+
+		// The header of the starting block is the baseline the first processed range is checked against
+		if err := storeBlockHeader(ctx, dbQuerier, transactionManager, chainID, lastBlockNumber); err != nil {
+			log.Fatalf("Failed to store block %d of chain %d: %v\n", lastBlockNumber, chainID, err)
+		}
+		if err := saveCheckpoint(ctx, dbQuerier, chainID, lastBlockNumber); err != nil {
+			log.Fatalf("Failed to save the checkpoint of chain %d: %v\n", chainID, err)
+		}
+		lastBlockNumbers[chainID] = lastBlockNumber
 	}
+
 	return &LiveDataRecorder{
-		lastBlockNumber:    lastBlockNumber,
+		lastBlockNumbers:   lastBlockNumbers,
 		transactionManager: transactionManager,
 		dbQuerier:          dbQuerier,
+		txWriter:           txWriter,
+		headSources:        make(map[int64]HeadSource),
+		headInterval:       defaultHeadInterval,
 	}
 }
 
-// Run starts the Recorder to execute tasks every 60 seconds.
-// It listens for context cancellation to gracefully shut down.
+// UseHeadSource records the chain as soon as its head source pushes a new head.
+// The chain is still polled whenever the source is disconnected.
+func (ldr *LiveDataRecorder) UseHeadSource(chainID int64, source HeadSource) {
+	ldr.headSources[chainID] = source
+}
+
+// DebounceHeads records the chains with pushed heads at most once per interval, instead of on every head.
+// A head pushed sooner is recorded once the interval is over, along with the heads pushed in the meantime.
+func (ldr *LiveDataRecorder) DebounceHeads(interval time.Duration) {
+	ldr.headInterval = interval
+}
+
+// OnReorg registers a handler called with every detected reorg, e.g. to raise an alert.
+// Reorgs are logged whether or not a handler is registered.
+func (ldr *LiveDataRecorder) OnReorg(handler func(ReorgEvent)) {
+	ldr.reorgHandlers = append(ldr.reorgHandlers, handler)
+}
+
+// Run first backfills every chain from its checkpoint to its confirmed head, then records chains as their head sources
+// push new heads, at most once per head interval, and polls the others every 60 seconds. It listens for context cancellation to gracefully shut down.
 func (ldr *LiveDataRecorder) Run(ctx context.Context) {
-	ticker := time.NewTicker(60 * time.Second)
+	log.Println("LiveDataRecorder backfilling up to the confirmed heads.")
+	ldr.recordNewTransactions(ctx)
+
+	// Each chain has at most one pending notification, further heads are covered by it
+	newHeads := make(chan int64, len(ldr.headSources))
+	pending := make(map[int64]*atomic.Bool, len(ldr.headSources))
+	for chainID, source := range ldr.headSources {
+		chainPending := new(atomic.Bool)
+		pending[chainID] = chainPending
+		go source.Run(ctx, func(blockNumber uint64) {
+			if chainPending.CompareAndSwap(false, true) {
+				newHeads <- chainID
+			}
+		})
+	}
+
+	ticker := time.NewTicker(pollInterval)
 	defer ticker.Stop()
 
+	// Last recording of every chain on a pushed head
+	lastRecorded := make(map[int64]time.Time, len(ldr.headSources))
+
 	log.Println("LiveDataRecorder started.")
 
 	for {
@@ -43,57 +157,305 @@ func (ldr *LiveDataRecorder) Run(ctx context.Context) {
 		case <-ctx.Done():
 			log.Println("LiveDataRecorder shutting down.")
 			return
+		case chainID := <-newHeads:
+			if wait := ldr.headInterval - time.Since(lastRecorded[chainID]); wait > 0 {
+				// The notification stays pending and is sent again once the interval is over,
+				// the buffer always has room for it as a chain has at most one
+				time.AfterFunc(wait, func() { newHeads <- chainID })
+				continue
+			}
+			pending[chainID].Store(false)
+			lastRecorded[chainID] = time.Now()
+			ldr.recordChain(ctx, chainID)
 		case <-ticker.C:
-			ldr.recordNewTransactions()
+			ldr.recordPolledTransactions(ctx)
 		}
 	}
 }
 
-// executeTask contains the logic to fetch and process new transactions.
-func (ldr *LiveDataRecorder) recordNewTransactions() {
+// recordNewTransactions fetches and processes new transactions of every chain, until every chain is caught up or fails.
+func (ldr *LiveDataRecorder) recordNewTransactions(ctx context.Context) {
 	log.Println("Fetching and processing new transactions.")
 
-	latestBlock, err := ldr.transactionManager.GetLatestBlockNumber()
+	for _, chainID := range ldr.transactionManager.ChainIDs() {
+		ldr.recordChain(ctx, chainID)
+	}
+}
+
+// recordPolledTransactions fetches and processes new transactions of the chains whose heads are not currently pushed.
+func (ldr *LiveDataRecorder) recordPolledTransactions(ctx context.Context) {
+	for _, chainID := range ldr.transactionManager.ChainIDs() {
+		if source, ok := ldr.headSources[chainID]; ok && source.Connected() {
+			continue
+		}
+		log.Printf("Polling chain %d for new transactions.\n", chainID)
+		ldr.recordChain(ctx, chainID)
+	}
+}
+
+// recordChain fetches and processes new transactions of the chain until it is caught up or fails.
+func (ldr *LiveDataRecorder) recordChain(ctx context.Context, chainID int64) {
+	for ldr.recordNewChainTransactions(ctx, chainID) {
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// recordNewChainTransactions fetches and processes the transactions of the chain since its last processed block,
+// up to its last confirmed block and at most maxIngestRange blocks. A reorg below the last processed block is rolled back first.
+// It returns whether confirmed blocks remain to be processed.
+func (ldr *LiveDataRecorder) recordNewChainTransactions(ctx context.Context, chainID int64) bool {
+	safeBlock, err := ldr.transactionManager.GetSafeBlockNumber(ctx, chainID)
 	if err != nil {
-		log.Printf("Error fetching latest block number: %v\n", err)
-		return
+		log.Printf("Error fetching confirmed block number of chain %d: %v\n", chainID, err)
+		return false
 	}
 
-	// Fetch and process transactions from lastBlockNumber+1 to latestBlock.
-	if latestBlock > ldr.lastBlockNumber {
-		startBlock := ldr.lastBlockNumber + 1
-		endBlock := latestBlock
+	lastBlockNumber := ldr.lastBlockNumbers[chainID]
+	if safeBlock <= lastBlockNumber {
+		log.Printf("No new transactions to process on chain %d.\n", chainID)
+		return false
+	}
 
-		transactions, err := ldr.transactionManager.BatchProcessTransactions(startBlock, endBlock, context.Background())
+	lastBlockNumber, err = ldr.checkReorg(ctx, chainID, lastBlockNumber)
+	if err != nil {
+		log.Printf("Error checking chain %d for reorgs: %v\n", chainID, err)
+		return false
+	}
+	ldr.lastBlockNumbers[chainID] = lastBlockNumber
+
+	// Fetch and process transactions from lastBlockNumber+1 to safeBlock, in chunks.
+	startBlock := lastBlockNumber + 1
+	endBlock := safeBlock
+	if endBlock-startBlock >= maxIngestRange {
+		endBlock = startBlock + maxIngestRange - 1
+	}
+
+	// The end of the range is the baseline of the next one, stored before the transactions so a failure retries the range.
+	// It is pinned before the range is fetched and checked again after, so a reorg in between can't mix two forks.
+	endHeader, err := ldr.transactionManager.GetBlockHeader(ctx, chainID, endBlock)
+	if err != nil {
+		log.Printf("Error fetching block %d of chain %d: %v\n", endBlock, chainID, err)
+		return false
+	}
+	if err := upsertBlockHeader(ctx, ldr.dbQuerier, chainID, endHeader); err != nil {
+		log.Printf("Error storing block %d of chain %d: %v\n", endBlock, chainID, err)
+		return false
+	}
+
+	transactions, err := ldr.transactionManager.BatchProcessTransactions(ctx, chainID, startBlock, endBlock)
+	if err != nil {
+		log.Printf("Error processing transactions of chain %d from block %d to %d: %v\n", chainID, startBlock, endBlock, err)
+		return false
+	}
+
+	current, err := ldr.transactionManager.GetBlockHeader(ctx, chainID, endBlock)
+	if err != nil {
+		log.Printf("Error fetching block %d of chain %d: %v\n", endBlock, chainID, err)
+		return false
+	}
+	if current.Hash != endHeader.Hash {
+		// The range is fetched again from the new fork, once the reorg is checked
+		log.Printf("Block %d of chain %d changed from %s to %s while its range was fetched, discarding it\n", endBlock, chainID, endHeader.Hash, current.Hash)
+		return false
+	}
+
+	// Writes are idempotent, a failed write leaves the range to be stored again
+	stored, err := StoreTransactions(ctx, ldr.txWriter, transactions)
+	if err != nil {
+		log.Printf("Error storing transactions of chain %d from block %d to %d: %v\n", chainID, startBlock, endBlock, err)
+		return false
+	}
+
+	// Persist the progress, then update the last processed block number.
+	if err := ldr.markIngested(ctx, chainID, startBlock, endBlock); err != nil {
+		log.Printf("Error saving the progress of chain %d up to block %d: %v\n", chainID, endBlock, err)
+		return false
+	}
+	ldr.lastBlockNumbers[chainID] = endBlock
+	numTxProcessed := len(transactions)
+	log.Printf("Processed %d transactions of chain %d up to block %d: %d inserted, %d updated, %d skipped.\n",
+		numTxProcessed, chainID, endBlock, stored.Inserted, stored.Updated, stored.Skipped)
+
+	return endBlock < safeBlock
+}
+
+// markIngested records the block range as covered and moves the checkpoint of the chain to its end.
+// A range following the previous one extends it, so coverage stays one range per contiguous span.
+func (ldr *LiveDataRecorder) markIngested(ctx context.Context, chainID int64, startBlock uint64, endBlock uint64) error {
+	extended, err := ldr.dbQuerier.ExtendIngestedRange(ctx, db.ExtendIngestedRangeParams{
+		NewEndBlock: int64(endBlock),
+		ChainID:     chainID,
+		EndBlock:    int64(startBlock) - 1,
+	})
+	if err != nil {
+		return fmt.Errorf("error extending the ingested range: %v", err)
+	}
+	if extended == 0 {
+		err := ldr.dbQuerier.InsertIngestedRange(ctx, db.InsertIngestedRangeParams{
+			ChainID:    chainID,
+			StartBlock: int64(startBlock),
+			EndBlock:   int64(endBlock),
+		})
 		if err != nil {
-			log.Printf("Error processing transactions from block %d to %d: %v\n", startBlock, endBlock, err)
-			return
+			return fmt.Errorf("error inserting the ingested range: %v", err)
 		}
+	}
 
-		// Insert to DB
-		// TODO: Try bulk insert if sqlc supports it
-		for _, tx := range transactions {
-			err := ldr.dbQuerier.InsertTransaction(context.Background(), db.InsertTransactionParams{
-				TransactionHash:    tx.Hash,
-				BlockNumber:        int64(tx.BlockNumber),
-				Timestamp:          tx.Timestamp,
-				GasUsed:            int64(tx.GasUsed),
-				GasPriceWei:        tx.GasPriceWei.Int64(),
-				TransactionFeeEth:  pgtype.Float8{Float64: tx.TransactionFeeETH, Valid: true},
-				TransactionFeeUsdt: pgtype.Float8{Float64: tx.TransactionFeeUSDT, Valid: true},
-				EthUsdtPrice:       pgtype.Float8{Float64: tx.ETHUSDTPrice, Valid: true},
-			})
-			if err != nil {
-				log.Printf("Error inserting transaction %s into DB: %v\n", tx.Hash, err)
-				continue
-			}
+	return saveCheckpoint(ctx, ldr.dbQuerier, chainID, endBlock)
+}
+
+// checkReorg checks the block after the last processed one still links to its stored header.
+// On a mismatch it rolls the chain back to the last stored block the chain still agrees with and returns it,
+// otherwise it returns lastBlockNumber unchanged.
+func (ldr *LiveDataRecorder) checkReorg(ctx context.Context, chainID int64, lastBlockNumber uint64) (uint64, error) {
+	stored, err := ldr.dbQuerier.ListRecentBlocks(ctx, db.ListRecentBlocksParams{
+		ChainID:     chainID,
+		BlockNumber: int64(lastBlockNumber),
+		Limit:       maxReorgLookback,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error listing stored blocks: %v", err)
+	}
+	if len(stored) == 0 || uint64(stored[0].BlockNumber) != lastBlockNumber {
+		// Nothing to compare against
+		return lastBlockNumber, nil
+	}
+
+	next, err := ldr.transactionManager.GetBlockHeader(ctx, chainID, lastBlockNumber+1)
+	if err != nil {
+		return 0, err
+	}
+	if next.ParentHash == stored[0].BlockHash {
+		return lastBlockNumber, nil
+	}
+
+	ancestor, err := ldr.findCommonAncestor(ctx, chainID, stored)
+	if err != nil {
+		return 0, err
+	}
+	forkBlock := ancestor + 1
+
+	// The fee rollups from the first removed transaction onwards are recomputed without the removed transactions
+	firstRemoved, err := ldr.dbQuerier.GetFirstTransactionTimeFromBlock(ctx, db.GetFirstTransactionTimeFromBlockParams{
+		ChainID:     chainID,
+		BlockNumber: int64(forkBlock),
+	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf("error getting the first transaction from block %d: %v", forkBlock, err)
+	}
+
+	removed, err := ldr.dbQuerier.DeleteTransactionsFromBlock(ctx, db.DeleteTransactionsFromBlockParams{
+		ChainID:     chainID,
+		BlockNumber: int64(forkBlock),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error deleting transactions from block %d: %v", forkBlock, err)
+	}
+	if removed > 0 {
+		if err := db.RefreshFeeRollupsBetween(ctx, ldr.dbQuerier, chainID, firstRemoved, time.Now()); err != nil {
+			return 0, err
 		}
+	}
+	err = ldr.dbQuerier.DeleteBlocksFrom(ctx, db.DeleteBlocksFromParams{
+		ChainID:     chainID,
+		BlockNumber: int64(forkBlock),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error deleting blocks from block %d: %v", forkBlock, err)
+	}
+	if err := ldr.rollbackCoverage(ctx, chainID, forkBlock); err != nil {
+		return 0, err
+	}
+
+	ldr.emitReorg(ReorgEvent{
+		ChainID:             chainID,
+		ForkBlock:           forkBlock,
+		Depth:               lastBlockNumber - ancestor,
+		OldHash:             stored[0].BlockHash,
+		NewHash:             next.ParentHash,
+		RemovedTransactions: removed,
+		DetectedAt:          time.Now(),
+	})
+	return ancestor, nil
+}
+
+// findCommonAncestor walks the stored blocks, most recent first, down to the first one whose hash the chain still reports.
+// The first stored block is the one known to be replaced. When no stored block matches, the reorg is deeper than
+// the lookback and the chain is rolled back to just before the oldest stored block.
+func (ldr *LiveDataRecorder) findCommonAncestor(ctx context.Context, chainID int64, stored []db.Blocks) (uint64, error) {
+	for _, block := range stored[1:] {
+		header, err := ldr.transactionManager.GetBlockHeader(ctx, chainID, uint64(block.BlockNumber))
+		if err != nil {
+			return 0, err
+		}
+		if header.Hash == block.BlockHash {
+			return uint64(block.BlockNumber), nil
+		}
+	}
+
+	oldest := uint64(stored[len(stored)-1].BlockNumber)
+	log.Printf("Reorg on chain %d is deeper than the %d stored blocks, rolling back to block %d\n", chainID, len(stored), oldest)
+	if oldest == 0 {
+		return 0, nil
+	}
+	return oldest - 1, nil
+}
+
+// rollbackCoverage removes the blocks from forkBlock onwards from the ingested ranges and moves the checkpoint before them
+func (ldr *LiveDataRecorder) rollbackCoverage(ctx context.Context, chainID int64, forkBlock uint64) error {
+	err := ldr.dbQuerier.DeleteIngestedRangesFrom(ctx, db.DeleteIngestedRangesFromParams{
+		ChainID:    chainID,
+		StartBlock: int64(forkBlock),
+	})
+	if err != nil {
+		return fmt.Errorf("error deleting ingested ranges from block %d: %v", forkBlock, err)
+	}
+	err = ldr.dbQuerier.TruncateIngestedRanges(ctx, db.TruncateIngestedRangesParams{
+		FromBlock: int64(forkBlock),
+		ChainID:   chainID,
+	})
+	if err != nil {
+		return fmt.Errorf("error truncating ingested ranges at block %d: %v", forkBlock, err)
+	}
 
-		// Update the last processed block number.
-		ldr.lastBlockNumber = endBlock
-		numTxProcessed := len(transactions)
-		log.Printf("Processed %d transactions up to block %d.\n", numTxProcessed, endBlock)
-	} else {
-		log.Println("No new transactions to process.")
+	return saveCheckpoint(ctx, ldr.dbQuerier, chainID, forkBlock-1)
+}
+
+// emitReorg logs the reorg and passes it to the registered handlers
+func (ldr *LiveDataRecorder) emitReorg(event ReorgEvent) {
+	log.Printf("REORG chain=%d fork_block=%d depth=%d old_hash=%s new_hash=%s removed_transactions=%d\n",
+		event.ChainID, event.ForkBlock, event.Depth, event.OldHash, event.NewHash, event.RemovedTransactions)
+
+	for _, handler := range ldr.reorgHandlers {
+		handler(event)
 	}
 }
+
+// storeBlockHeader fetches the header of the block and stores it
+func storeBlockHeader(ctx context.Context, dbQuerier db.Querier, transactionManager domain.TransactionManagerInterface, chainID int64, blockNumber uint64) error {
+	header, err := transactionManager.GetBlockHeader(ctx, chainID, blockNumber)
+	if err != nil {
+		return err
+	}
+	return upsertBlockHeader(ctx, dbQuerier, chainID, header)
+}
+
+// upsertBlockHeader stores the header of a block of the chain
+func upsertBlockHeader(ctx context.Context, dbQuerier db.Querier, chainID int64, header *types.BlockHeader) error {
+	return dbQuerier.UpsertBlock(ctx, db.UpsertBlockParams{
+		ChainID:     chainID,
+		BlockNumber: int64(header.Number),
+		BlockHash:   header.Hash,
+		ParentHash:  header.ParentHash,
+	})
+}
+
+// saveCheckpoint persists the last processed block of the chain
+func saveCheckpoint(ctx context.Context, dbQuerier db.Querier, chainID int64, blockNumber uint64) error {
+	return dbQuerier.UpsertIngestionCheckpoint(ctx, db.UpsertIngestionCheckpointParams{
+		ChainID:         chainID,
+		LastBlockNumber: int64(blockNumber),
+	})
+}
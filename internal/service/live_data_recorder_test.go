@@ -0,0 +1,368 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	db "github.com/winQe/uniswap-fee-tracker/internal/db/sqlc"
+	"github.com/winQe/uniswap-fee-tracker/internal/mocks"
+	"github.com/winQe/uniswap-fee-tracker/internal/types"
+)
+
+// initializeRecorder sets up a LiveDataRecorder of chain 1 that processed up to lastBlockNumber
+func initializeRecorder(lastBlockNumber uint64) (*LiveDataRecorder, *mocks.MockQuerier, *mocks.MockTransactionManager) {
+	mockQuerier := new(mocks.MockQuerier)
+	mockManager := new(mocks.MockTransactionManager)
+
+	return &LiveDataRecorder{
+		lastBlockNumbers:   map[int64]uint64{1: lastBlockNumber},
+		transactionManager: mockManager,
+		dbQuerier:          mockQuerier,
+		txWriter:           new(mocks.MockBulkWriter),
+		headSources:        make(map[int64]HeadSource),
+	}, mockQuerier, mockManager
+}
+
+func TestRecordNewChainTransactions(t *testing.T) {
+	recorder, mockQuerier, mockManager := initializeRecorder(100)
+
+	mockManager.On("GetSafeBlockNumber", mock.Anything, int64(1)).Return(uint64(110), nil)
+	mockQuerier.On("ListRecentBlocks", mock.Anything, db.ListRecentBlocksParams{ChainID: 1, BlockNumber: 100, Limit: maxReorgLookback}).
+		Return([]db.Blocks{{ChainID: 1, BlockNumber: 100, BlockHash: "0xa100", ParentHash: "0xa099"}}, nil)
+	mockManager.On("GetBlockHeader", mock.Anything, int64(1), uint64(101)).Return(&types.BlockHeader{Number: 101, Hash: "0xa101", ParentHash: "0xa100"}, nil)
+	mockManager.On("BatchProcessTransactions", mock.Anything, int64(1), uint64(101), uint64(110)).Return([]types.TxWithPrice{}, nil)
+	mockManager.On("GetBlockHeader", mock.Anything, int64(1), uint64(110)).Return(&types.BlockHeader{Number: 110, Hash: "0xa110", ParentHash: "0xa109"}, nil)
+	mockQuerier.On("UpsertBlock", mock.Anything, db.UpsertBlockParams{ChainID: 1, BlockNumber: 110, BlockHash: "0xa110", ParentHash: "0xa109"}).Return(nil)
+	// The range continues the previous one and the checkpoint moves to its end
+	mockQuerier.On("ExtendIngestedRange", mock.Anything, db.ExtendIngestedRangeParams{NewEndBlock: 110, ChainID: 1, EndBlock: 100}).Return(int64(1), nil)
+	mockQuerier.On("UpsertIngestionCheckpoint", mock.Anything, db.UpsertIngestionCheckpointParams{ChainID: 1, LastBlockNumber: 110}).Return(nil)
+
+	more := recorder.recordNewChainTransactions(context.Background(), 1)
+
+	assert.False(t, more)
+	assert.Equal(t, uint64(110), recorder.lastBlockNumbers[1])
+	mockQuerier.AssertNotCalled(t, "InsertIngestedRange", mock.Anything, mock.Anything)
+	mockQuerier.AssertNotCalled(t, "DeleteTransactionsFromBlock", mock.Anything, mock.Anything)
+	mockManager.AssertExpectations(t)
+	mockQuerier.AssertExpectations(t)
+}
+
+func TestRecordNewChainTransactions_StoresTransactions(t *testing.T) {
+	transactions := []types.TxWithPrice{
+		{TransactionData: types.TransactionData{ChainID: 1, BlockNumber: 105, Hash: "0x1", GasUsed: 21000}},
+		{TransactionData: types.TransactionData{ChainID: 1, BlockNumber: 108, Hash: "0x2", GasUsed: 42000}},
+	}
+
+	for _, storeErr := range []error{nil, errors.New("connection reset")} {
+		recorder, mockQuerier, mockManager := initializeRecorder(100)
+		mockWriter := new(mocks.MockBulkWriter)
+		recorder.txWriter = mockWriter
+
+		mockManager.On("GetSafeBlockNumber", mock.Anything, int64(1)).Return(uint64(110), nil)
+		mockQuerier.On("ListRecentBlocks", mock.Anything, mock.Anything).
+			Return([]db.Blocks{{ChainID: 1, BlockNumber: 100, BlockHash: "0xa100", ParentHash: "0xa099"}}, nil)
+		mockManager.On("GetBlockHeader", mock.Anything, int64(1), uint64(101)).Return(&types.BlockHeader{Number: 101, Hash: "0xa101", ParentHash: "0xa100"}, nil)
+		mockManager.On("BatchProcessTransactions", mock.Anything, int64(1), uint64(101), uint64(110)).Return(transactions, nil)
+		mockManager.On("GetBlockHeader", mock.Anything, int64(1), uint64(110)).Return(&types.BlockHeader{Number: 110, Hash: "0xa110", ParentHash: "0xa109"}, nil)
+		mockQuerier.On("UpsertBlock", mock.Anything, mock.Anything).Return(nil)
+		mockWriter.On("UpsertTransactions", mock.Anything, mock.MatchedBy(func(rows []db.TransactionRow) bool {
+			return len(rows) == 2 && rows[0].Transaction.TransactionHash == "0x1" && rows[1].Transaction.TransactionHash == "0x2"
+		})).Return(db.UpsertResult{Inserted: 1, Skipped: 1}, storeErr)
+		mockQuerier.On("ExtendIngestedRange", mock.Anything, mock.Anything).Return(int64(1), nil)
+		mockQuerier.On("UpsertIngestionCheckpoint", mock.Anything, mock.Anything).Return(nil)
+
+		recorder.recordNewChainTransactions(context.Background(), 1)
+
+		mockWriter.AssertExpectations(t)
+		if storeErr == nil {
+			assert.Equal(t, uint64(110), recorder.lastBlockNumbers[1])
+			mockQuerier.AssertCalled(t, "UpsertIngestionCheckpoint", mock.Anything, db.UpsertIngestionCheckpointParams{ChainID: 1, LastBlockNumber: 110})
+			continue
+		}
+
+		// A failed write leaves the range to be processed again
+		assert.Equal(t, uint64(100), recorder.lastBlockNumbers[1])
+		mockQuerier.AssertNotCalled(t, "ExtendIngestedRange", mock.Anything, mock.Anything)
+		mockQuerier.AssertNotCalled(t, "UpsertIngestionCheckpoint", mock.Anything, mock.Anything)
+	}
+}
+
+func TestRecordNewChainTransactions_WaitsForConfirmations(t *testing.T) {
+	recorder, mockQuerier, mockManager := initializeRecorder(100)
+
+	mockManager.On("GetSafeBlockNumber", mock.Anything, int64(1)).Return(uint64(100), nil)
+
+	more := recorder.recordNewChainTransactions(context.Background(), 1)
+
+	assert.False(t, more)
+	assert.Equal(t, uint64(100), recorder.lastBlockNumbers[1])
+	mockManager.AssertNotCalled(t, "BatchProcessTransactions", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockQuerier.AssertNotCalled(t, "ListRecentBlocks", mock.Anything, mock.Anything)
+}
+
+func TestRecordNewChainTransactions_Reorg(t *testing.T) {
+	recorder, mockQuerier, mockManager := initializeRecorder(100)
+
+	var events []ReorgEvent
+	recorder.OnReorg(func(event ReorgEvent) {
+		events = append(events, event)
+	})
+
+	mockManager.On("GetSafeBlockNumber", mock.Anything, int64(1)).Return(uint64(110), nil)
+	mockQuerier.On("ListRecentBlocks", mock.Anything, db.ListRecentBlocksParams{ChainID: 1, BlockNumber: 100, Limit: maxReorgLookback}).
+		Return([]db.Blocks{
+			{ChainID: 1, BlockNumber: 100, BlockHash: "0xa100", ParentHash: "0xa099"},
+			{ChainID: 1, BlockNumber: 95, BlockHash: "0xa095", ParentHash: "0xa094"},
+			{ChainID: 1, BlockNumber: 90, BlockHash: "0xa090", ParentHash: "0xa089"},
+		}, nil)
+	// Blocks 91 to 100 were replaced, the chain still agrees with block 90
+	mockManager.On("GetBlockHeader", mock.Anything, int64(1), uint64(101)).Return(&types.BlockHeader{Number: 101, Hash: "0xb101", ParentHash: "0xb100"}, nil)
+	mockManager.On("GetBlockHeader", mock.Anything, int64(1), uint64(95)).Return(&types.BlockHeader{Number: 95, Hash: "0xb095", ParentHash: "0xb094"}, nil)
+	mockManager.On("GetBlockHeader", mock.Anything, int64(1), uint64(90)).Return(&types.BlockHeader{Number: 90, Hash: "0xa090", ParentHash: "0xa089"}, nil)
+	mockQuerier.On("GetFirstTransactionTimeFromBlock", mock.Anything, db.GetFirstTransactionTimeFromBlockParams{ChainID: 1, BlockNumber: 91}).Return(time.Unix(1727790000, 0), nil)
+	mockQuerier.On("DeleteTransactionsFromBlock", mock.Anything, db.DeleteTransactionsFromBlockParams{ChainID: 1, BlockNumber: 91}).Return(int64(3), nil)
+	// The fee rollups of every bucket size are recomputed from the first removed transaction
+	for _, bucketSize := range db.FeeRollupBucketSizes {
+		mockQuerier.On("RefreshFeeRollups", mock.Anything, mock.MatchedBy(func(arg db.RefreshFeeRollupsParams) bool {
+			return arg.BucketSize == bucketSize && arg.ChainID == 1 && arg.StartTime.Equal(time.Unix(1727790000, 0)) && arg.EndTime.After(arg.StartTime)
+		})).Return(int64(1), nil).Once()
+	}
+	mockQuerier.On("DeleteBlocksFrom", mock.Anything, db.DeleteBlocksFromParams{ChainID: 1, BlockNumber: 91}).Return(nil)
+	mockQuerier.On("DeleteIngestedRangesFrom", mock.Anything, db.DeleteIngestedRangesFromParams{ChainID: 1, StartBlock: 91}).Return(nil)
+	mockQuerier.On("TruncateIngestedRanges", mock.Anything, db.TruncateIngestedRangesParams{FromBlock: 91, ChainID: 1}).Return(nil)
+	mockQuerier.On("UpsertIngestionCheckpoint", mock.Anything, db.UpsertIngestionCheckpointParams{ChainID: 1, LastBlockNumber: 90}).Return(nil)
+
+	// The rolled back range is ingested again along with the new blocks
+	mockManager.On("BatchProcessTransactions", mock.Anything, int64(1), uint64(91), uint64(110)).Return([]types.TxWithPrice{}, nil)
+	mockManager.On("GetBlockHeader", mock.Anything, int64(1), uint64(110)).Return(&types.BlockHeader{Number: 110, Hash: "0xb110", ParentHash: "0xb109"}, nil)
+	mockQuerier.On("UpsertBlock", mock.Anything, db.UpsertBlockParams{ChainID: 1, BlockNumber: 110, BlockHash: "0xb110", ParentHash: "0xb109"}).Return(nil)
+	mockQuerier.On("ExtendIngestedRange", mock.Anything, db.ExtendIngestedRangeParams{NewEndBlock: 110, ChainID: 1, EndBlock: 90}).Return(int64(1), nil)
+	mockQuerier.On("UpsertIngestionCheckpoint", mock.Anything, db.UpsertIngestionCheckpointParams{ChainID: 1, LastBlockNumber: 110}).Return(nil)
+
+	more := recorder.recordNewChainTransactions(context.Background(), 1)
+
+	assert.False(t, more)
+	assert.Equal(t, uint64(110), recorder.lastBlockNumbers[1])
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, int64(1), events[0].ChainID)
+		assert.Equal(t, uint64(91), events[0].ForkBlock)
+		assert.Equal(t, uint64(10), events[0].Depth)
+		assert.Equal(t, "0xa100", events[0].OldHash)
+		assert.Equal(t, "0xb100", events[0].NewHash)
+		assert.Equal(t, int64(3), events[0].RemovedTransactions)
+	}
+	mockManager.AssertExpectations(t)
+	mockQuerier.AssertExpectations(t)
+}
+
+func TestRecordNewChainTransactions_ReorgDeeperThanLookback(t *testing.T) {
+	recorder, mockQuerier, mockManager := initializeRecorder(100)
+
+	mockManager.On("GetSafeBlockNumber", mock.Anything, int64(1)).Return(uint64(110), nil)
+	mockQuerier.On("ListRecentBlocks", mock.Anything, mock.Anything).
+		Return([]db.Blocks{
+			{ChainID: 1, BlockNumber: 100, BlockHash: "0xa100", ParentHash: "0xa099"},
+			{ChainID: 1, BlockNumber: 95, BlockHash: "0xa095", ParentHash: "0xa094"},
+		}, nil)
+	mockManager.On("GetBlockHeader", mock.Anything, int64(1), uint64(101)).Return(&types.BlockHeader{Number: 101, Hash: "0xb101", ParentHash: "0xb100"}, nil)
+	mockManager.On("GetBlockHeader", mock.Anything, int64(1), uint64(95)).Return(&types.BlockHeader{Number: 95, Hash: "0xb095", ParentHash: "0xb094"}, nil)
+	mockQuerier.On("GetFirstTransactionTimeFromBlock", mock.Anything, mock.Anything).Return(time.Time{}, pgx.ErrNoRows)
+	mockQuerier.On("DeleteTransactionsFromBlock", mock.Anything, db.DeleteTransactionsFromBlockParams{ChainID: 1, BlockNumber: 95}).Return(int64(0), nil)
+	mockQuerier.On("DeleteBlocksFrom", mock.Anything, db.DeleteBlocksFromParams{ChainID: 1, BlockNumber: 95}).Return(nil)
+	mockQuerier.On("DeleteIngestedRangesFrom", mock.Anything, mock.Anything).Return(nil)
+	mockQuerier.On("TruncateIngestedRanges", mock.Anything, mock.Anything).Return(nil)
+	mockQuerier.On("UpsertIngestionCheckpoint", mock.Anything, mock.Anything).Return(nil)
+	mockQuerier.On("ExtendIngestedRange", mock.Anything, mock.Anything).Return(int64(1), nil)
+	mockManager.On("BatchProcessTransactions", mock.Anything, int64(1), uint64(95), uint64(110)).Return([]types.TxWithPrice{}, nil)
+	mockManager.On("GetBlockHeader", mock.Anything, int64(1), uint64(110)).Return(&types.BlockHeader{Number: 110, Hash: "0xb110", ParentHash: "0xb109"}, nil)
+	mockQuerier.On("UpsertBlock", mock.Anything, mock.Anything).Return(nil)
+
+	more := recorder.recordNewChainTransactions(context.Background(), 1)
+
+	// Without a matching stored block the oldest one is re-ingested too
+	assert.False(t, more)
+	assert.Equal(t, uint64(110), recorder.lastBlockNumbers[1])
+	mockQuerier.AssertNotCalled(t, "RefreshFeeRollups", mock.Anything, mock.Anything)
+	mockManager.AssertExpectations(t)
+	mockQuerier.AssertExpectations(t)
+}
+
+func TestRecordNewChainTransactions_EndBlockReorgedDuringFetch(t *testing.T) {
+	recorder, mockQuerier, mockManager := initializeRecorder(100)
+
+	mockManager.On("GetSafeBlockNumber", mock.Anything, int64(1)).Return(uint64(110), nil)
+	mockQuerier.On("ListRecentBlocks", mock.Anything, mock.Anything).Return([]db.Blocks{}, nil)
+	// The end block is pinned before the range is fetched, and replaced by another fork while it is
+	mockManager.On("GetBlockHeader", mock.Anything, int64(1), uint64(110)).Return(&types.BlockHeader{Number: 110, Hash: "0xa110", ParentHash: "0xa109"}, nil).Once()
+	mockQuerier.On("UpsertBlock", mock.Anything, db.UpsertBlockParams{ChainID: 1, BlockNumber: 110, BlockHash: "0xa110", ParentHash: "0xa109"}).Return(nil)
+	mockManager.On("BatchProcessTransactions", mock.Anything, int64(1), uint64(101), uint64(110)).
+		Return([]types.TxWithPrice{{TransactionData: types.TransactionData{ChainID: 1, BlockNumber: 105, Hash: "0x1"}}}, nil)
+	mockManager.On("GetBlockHeader", mock.Anything, int64(1), uint64(110)).Return(&types.BlockHeader{Number: 110, Hash: "0xb110", ParentHash: "0xb109"}, nil).Once()
+
+	more := recorder.recordNewChainTransactions(context.Background(), 1)
+
+	// The range is discarded, neither stored nor checkpointed
+	assert.False(t, more)
+	assert.Equal(t, uint64(100), recorder.lastBlockNumbers[1])
+	recorder.txWriter.(*mocks.MockBulkWriter).AssertNotCalled(t, "UpsertTransactions", mock.Anything, mock.Anything)
+	mockQuerier.AssertNotCalled(t, "UpsertIngestionCheckpoint", mock.Anything, mock.Anything)
+	mockManager.AssertExpectations(t)
+	mockQuerier.AssertExpectations(t)
+}
+
+func TestRecordNewChainTransactions_Backfill(t *testing.T) {
+	recorder, mockQuerier, mockManager := initializeRecorder(100)
+
+	// The recorder was down for longer than a single range, the first range starts a new span
+	mockManager.On("GetSafeBlockNumber", mock.Anything, int64(1)).Return(uint64(100+maxIngestRange+10), nil)
+	mockQuerier.On("ListRecentBlocks", mock.Anything, mock.Anything).Return([]db.Blocks{}, nil)
+	mockManager.On("BatchProcessTransactions", mock.Anything, int64(1), uint64(101), uint64(100+maxIngestRange)).Return([]types.TxWithPrice{}, nil)
+	mockManager.On("GetBlockHeader", mock.Anything, int64(1), uint64(100+maxIngestRange)).Return(&types.BlockHeader{Number: 100 + maxIngestRange, Hash: "0xa", ParentHash: "0xb"}, nil)
+	mockQuerier.On("UpsertBlock", mock.Anything, mock.Anything).Return(nil)
+	mockQuerier.On("ExtendIngestedRange", mock.Anything, mock.Anything).Return(int64(0), nil)
+	mockQuerier.On("InsertIngestedRange", mock.Anything, db.InsertIngestedRangeParams{ChainID: 1, StartBlock: 101, EndBlock: 100 + maxIngestRange}).Return(nil)
+	mockQuerier.On("UpsertIngestionCheckpoint", mock.Anything, db.UpsertIngestionCheckpointParams{ChainID: 1, LastBlockNumber: 100 + maxIngestRange}).Return(nil)
+
+	more := recorder.recordNewChainTransactions(context.Background(), 1)
+
+	assert.True(t, more)
+	assert.Equal(t, uint64(100+maxIngestRange), recorder.lastBlockNumbers[1])
+	mockManager.AssertExpectations(t)
+	mockQuerier.AssertExpectations(t)
+}
+
+// fakeHeadSource pushes the heads sent on its channel
+type fakeHeadSource struct {
+	heads     chan uint64
+	connected bool
+}
+
+func (f *fakeHeadSource) Run(ctx context.Context, onHead func(blockNumber uint64)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case head := <-f.heads:
+			onHead(head)
+		}
+	}
+}
+
+func (f *fakeHeadSource) Connected() bool {
+	return f.connected
+}
+
+func TestRun_HeadSource(t *testing.T) {
+	recorder, _, mockManager := initializeRecorder(100)
+	source := &fakeHeadSource{heads: make(chan uint64), connected: true}
+	recorder.UseHeadSource(1, source)
+
+	checks := make(chan struct{}, 10)
+	mockManager.On("ChainIDs").Return([]int64{1})
+	mockManager.On("GetSafeBlockNumber", mock.Anything, int64(1)).Return(uint64(100), nil).Run(func(mock.Arguments) {
+		checks <- struct{}{}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go recorder.Run(ctx)
+
+	// The startup backfill checks the chain once
+	waitForCheck(t, checks)
+
+	// A pushed head is recorded right away instead of on the next poll
+	source.heads <- 112
+	waitForCheck(t, checks)
+}
+
+func TestRun_DebouncedHeads(t *testing.T) {
+	recorder, _, mockManager := initializeRecorder(100)
+	recorder.DebounceHeads(300 * time.Millisecond)
+	source := &fakeHeadSource{heads: make(chan uint64), connected: true}
+	recorder.UseHeadSource(1, source)
+
+	checks := make(chan struct{}, 10)
+	mockManager.On("ChainIDs").Return([]int64{1})
+	mockManager.On("GetSafeBlockNumber", mock.Anything, int64(1)).Return(uint64(100), nil).Run(func(mock.Arguments) {
+		checks <- struct{}{}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go recorder.Run(ctx)
+	waitForCheck(t, checks)
+
+	// The first head is recorded right away
+	source.heads <- 112
+	waitForCheck(t, checks)
+
+	// The heads pushed within the interval are recorded once, when it is over
+	source.heads <- 113
+	source.heads <- 114
+	select {
+	case <-checks:
+		t.Fatal("a head was recorded within the interval")
+	case <-time.After(100 * time.Millisecond):
+	}
+	waitForCheck(t, checks)
+	select {
+	case <-checks:
+		t.Fatal("the debounced heads were recorded more than once")
+	case <-time.After(400 * time.Millisecond):
+	}
+}
+
+func TestRecordPolledTransactions(t *testing.T) {
+	recorder, _, mockManager := initializeRecorder(100)
+	recorder.lastBlockNumbers[42161] = 260000000
+	recorder.UseHeadSource(1, &fakeHeadSource{connected: true})
+	recorder.UseHeadSource(42161, &fakeHeadSource{connected: false})
+
+	mockManager.On("ChainIDs").Return([]int64{1, 42161})
+	mockManager.On("GetSafeBlockNumber", mock.Anything, int64(42161)).Return(uint64(260000000), nil)
+
+	recorder.recordPolledTransactions(context.Background())
+
+	// Only the chain whose subscription is down is polled
+	mockManager.AssertNotCalled(t, "GetSafeBlockNumber", mock.Anything, int64(1))
+	mockManager.AssertExpectations(t)
+}
+
+// waitForCheck waits for the recorder to check the head of the chain
+func waitForCheck(t *testing.T, checks chan struct{}) {
+	select {
+	case <-checks:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the recorder to check the chain")
+	}
+}
+
+func TestNewLiveDataRecorder(t *testing.T) {
+	mockQuerier := new(mocks.MockQuerier)
+	mockManager := new(mocks.MockTransactionManager)
+
+	mockManager.On("ChainIDs").Return([]int64{1, 42161})
+
+	// Mainnet resumes from its checkpoint
+	mockQuerier.On("GetIngestionCheckpoint", mock.Anything, int64(1)).Return(db.IngestionCheckpoints{ChainID: 1, LastBlockNumber: 20884000}, nil)
+
+	// Arbitrum starts from its latest confirmed pool transaction
+	mockQuerier.On("GetIngestionCheckpoint", mock.Anything, int64(42161)).Return(db.IngestionCheckpoints{}, pgx.ErrNoRows)
+	mockManager.On("GetLatestBlockNumber", mock.Anything, int64(42161)).Return(uint64(260000100), nil)
+	mockManager.On("GetSafeBlockNumber", mock.Anything, int64(42161)).Return(uint64(260000050), nil)
+	mockManager.On("GetBlockHeader", mock.Anything, int64(42161), uint64(260000050)).Return(&types.BlockHeader{Number: 260000050, Hash: "0xa", ParentHash: "0xb"}, nil)
+	mockQuerier.On("UpsertBlock", mock.Anything, db.UpsertBlockParams{ChainID: 42161, BlockNumber: 260000050, BlockHash: "0xa", ParentHash: "0xb"}).Return(nil)
+	mockQuerier.On("UpsertIngestionCheckpoint", mock.Anything, db.UpsertIngestionCheckpointParams{ChainID: 42161, LastBlockNumber: 260000050}).Return(nil)
+
+	recorder := NewLiveDataRecorder(context.Background(), mockQuerier, new(mocks.MockBulkWriter), mockManager)
+
+	assert.Equal(t, map[int64]uint64{1: 20884000, 42161: 260000050}, recorder.lastBlockNumbers)
+	mockManager.AssertNotCalled(t, "GetLatestBlockNumber", mock.Anything, int64(1))
+	mockManager.AssertExpectations(t)
+	mockQuerier.AssertExpectations(t)
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/winQe/uniswap-fee-tracker/internal/db/sqlc"
+	"github.com/winQe/uniswap-fee-tracker/internal/types"
+	"github.com/winQe/uniswap-fee-tracker/internal/utils"
+)
+
+// maxDecimals bounds the decimals of stored prices and fees.
+// USDT fees have the 18 decimals of ETH plus the decimals of the price, so prices with up to 20 decimals are stored exactly.
+const maxDecimals = 38
+
+// StoreTransactions writes the processed transactions and their decoded swaps into the DB in bulk, refreshing the fee rollups.
+// Transactions already stored are updated when they changed and skipped otherwise, so ranges can be stored again.
+func StoreTransactions(ctx context.Context, writer db.BulkWriter, transactions []types.TxWithPrice) (db.UpsertResult, error) {
+	if len(transactions) == 0 {
+		return db.UpsertResult{}, nil
+	}
+
+	rows := make([]db.TransactionRow, 0, len(transactions))
+	for _, tx := range transactions {
+		rows = append(rows, transactionRow(tx))
+	}
+	return writer.UpsertTransactions(ctx, rows)
+}
+
+// transactionRow converts the processed transaction and its swaps to their DB rows
+func transactionRow(tx types.TxWithPrice) db.TransactionRow {
+	row := db.TransactionRow{
+		Transaction: db.InsertTransactionParams{
+			TransactionHash:         tx.Hash,
+			BlockNumber:             int64(tx.BlockNumber),
+			Timestamp:               tx.Timestamp,
+			GasUsed:                 int64(tx.GasUsed),
+			GasPriceWei:             utils.BigIntToNumeric(tx.GasPriceWei),
+			TransactionFeeEth:       utils.RatToNumeric(tx.TransactionFeeETH, maxDecimals),
+			TransactionFeeUsdt:      utils.RatToNumeric(tx.TransactionFeeUSDT, maxDecimals),
+			EthUsdtPrice:            utils.RatToNumeric(tx.ETHUSDTPrice, maxDecimals),
+			PoolAddress:             pgtype.Text{String: tx.PoolAddress, Valid: tx.PoolAddress != ""},
+			ChainID:                 tx.ChainID,
+			PriceSource:             pgtype.Text{String: tx.PriceSource, Valid: tx.PriceSource != ""},
+			PriceSpread:             pgtype.Float8{Float64: tx.PriceSpread, Valid: true},
+			PriceSuspect:            tx.PriceSuspect,
+			TransactionFeeWei:       utils.BigIntToNumeric(tx.TransactionFeeWei),
+			BaseFeePerGasWei:        utils.BigIntToNumeric(tx.BaseFeePerGasWei),
+			MaxFeePerGasWei:         utils.BigIntToNumeric(tx.MaxFeePerGasWei),
+			MaxPriorityFeePerGasWei: utils.BigIntToNumeric(tx.MaxPriorityFeePerGasWei),
+			EffectiveTipPerGasWei:   utils.BigIntToNumeric(tx.EffectiveTipPerGasWei),
+			BurnedFeeEth:            utils.RatToNumeric(tx.BurnedFeeETH, maxDecimals),
+			BurnedFeeUsdt:           utils.RatToNumeric(tx.BurnedFeeUSDT, maxDecimals),
+			TipFeeEth:               utils.RatToNumeric(tx.TipFeeETH, maxDecimals),
+			TipFeeUsdt:              utils.RatToNumeric(tx.TipFeeUSDT, maxDecimals),
+		},
+	}
+
+	for _, swap := range tx.Swaps {
+		row.Swaps = append(row.Swaps, db.InsertSwapParams{
+			TransactionHash: tx.Hash,
+			LogIndex:        int32(swap.LogIndex),
+			PoolAddress:     swap.PoolAddress,
+			Sender:          swap.Sender,
+			Recipient:       swap.Recipient,
+			Amount0:         utils.BigIntToNumeric(swap.Amount0),
+			Amount1:         utils.BigIntToNumeric(swap.Amount1),
+			SqrtPriceX96:    utils.BigIntToNumeric(swap.SqrtPriceX96),
+			Liquidity:       utils.BigIntToNumeric(swap.Liquidity),
+			Tick:            swap.Tick,
+		})
+	}
+
+	return row
+}
+
+// RegisterPools upserts the configured pools into the pool registry
+func RegisterPools(ctx context.Context, dbQuerier db.Querier, pools []utils.PoolConfig) error {
+	for _, pool := range pools {
+		err := dbQuerier.UpsertPool(ctx, db.UpsertPoolParams{
+			Address: pool.Address,
+			ChainID: pool.ChainID,
+			Token0:  pool.Token0,
+			Token1:  pool.Token1,
+			FeeTier: pool.FeeTier,
+		})
+		if err != nil {
+			return fmt.Errorf("error registering pool %s: %w", pool.Address, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+package types
+
+// BlockHeader identifies a block and links it to its parent, hashes are lowercase
+type BlockHeader struct {
+	Number     uint64
+	Hash       string
+	ParentHash string
+}
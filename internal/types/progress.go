@@ -0,0 +1,23 @@
+package types
+
+// States of a block range in the split plan of a batch job
+const (
+	RangePending = "pending"
+	RangeSplit   = "split" // Holds more results than the API pages through, replaced by its two halves
+	RangeDone    = "done"
+	RangeFailed  = "failed"
+	RangePaused  = "paused" // Waiting for the circuit breaker of an upstream to close
+)
+
+// RangeProgress reports the state of a block range of a pool fetched by a batch job
+type RangeProgress struct {
+	ChainID      int64  `json:"chain_id"`
+	PoolAddress  string `json:"pool_address"`
+	StartBlock   uint64 `json:"start_block"`
+	EndBlock     uint64 `json:"end_block"`
+	Status       string `json:"status"`
+	Transactions int    `json:"transactions"` // Transactions found in the range, once done
+}
+
+// ProgressFunc is called whenever a block range changes state
+type ProgressFunc func(progress RangeProgress)
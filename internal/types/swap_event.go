@@ -0,0 +1,24 @@
+package types
+
+import "math/big"
+
+// SwapEvent represents a decoded Uniswap V3 Swap log emitted by the pool
+type SwapEvent struct {
+	LogIndex     uint
+	PoolAddress  string
+	Sender       string
+	Recipient    string
+	Amount0      *big.Int // Signed token0 delta of the pool, negative when the token left the pool
+	Amount1      *big.Int // Signed token1 delta of the pool, negative when the token left the pool
+	SqrtPriceX96 *big.Int // Pool price after the swap, as a Q64.96 square root of token1/token0
+	Liquidity    *big.Int
+	Tick         int32
+}
+
+// LogEvent represents a raw event log emitted by a transaction
+type LogEvent struct {
+	LogIndex uint
+	Address  string // Lowercase address of the emitting contract
+	Topics   []string
+	Data     string // Hex encoded non-indexed fields
+}
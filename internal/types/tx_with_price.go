@@ -5,19 +5,49 @@ import (
 	"time"
 )
 
+// Execution status of a transaction, as reported by its receipt
+const (
+	TxStatusFailed  uint64 = 0
+	TxStatusSuccess uint64 = 1
+)
+
 // TransactionData represents the simplified transaction result from the API calls
 type TransactionData struct {
+	ChainID     int64
 	BlockNumber uint64
 	Hash        string
+	Status      uint64 // TxStatusSuccess, or TxStatusFailed when the transaction reverted
+	From        string // Lowercase, empty when the source does not report it
+	To          string // Lowercase, empty for contract creations or when the source does not report it
 	GasUsed     uint64
-	GasPriceWei *big.Int
+	GasPriceWei *big.Int // Effective gas price paid
 	Timestamp   time.Time
+	PoolAddress string      // Tracked pool the transaction was found in, lowercase
+	Swaps       []SwapEvent // Swap events of the tracked pools emitted by the transaction
+	Logs        []LogEvent  // Every log emitted by the transaction, only set when read from its receipt
+
+	// EIP-1559 fee fields, nil when unknown. Blocks before London have no base fee and legacy transactions no fee caps.
+	BaseFeePerGasWei        *big.Int
+	MaxFeePerGasWei         *big.Int
+	MaxPriorityFeePerGasWei *big.Int
 }
 
-// TxWithPrice holds the processed transaction data
+// TxWithPrice holds the processed transaction data.
+// Prices and fees are exact, computed without floating point.
 type TxWithPrice struct {
 	TransactionData
-	ETHUSDTPrice       float64
-	TransactionFeeETH  float64
-	TransactionFeeUSDT float64
+	ETHUSDTPrice       *big.Rat
+	PriceSource        string  // Sources of ETHUSDTPrice, e.g. binance or binance,pool:<address> when aggregated
+	PriceSpread        float64 // Relative spread between the aggregated price sources
+	PriceSuspect       bool    // Whether the price sources disagree beyond the divergence threshold
+	TransactionFeeWei  *big.Int
+	TransactionFeeETH  *big.Rat
+	TransactionFeeUSDT *big.Rat
+
+	// EIP-1559 breakdown of the fee between the burned base fee and the priority tip, nil without a base fee
+	EffectiveTipPerGasWei *big.Int
+	BurnedFeeETH          *big.Rat
+	BurnedFeeUSDT         *big.Rat
+	TipFeeETH             *big.Rat
+	TipFeeUSDT            *big.Rat
 }
@@ -0,0 +1,174 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+)
+
+// Defaults of the Ethereum mainnet chain used when no chains file is configured
+const (
+	defaultChainID     = 1
+	defaultChainName   = "ethereum"
+	DefaultExplorerURL = "https://api.etherscan.io/api"
+)
+
+// Etherscan free plan limits, used when a chain does not set its own
+const (
+	defaultRequestsPerSecond = 5
+	defaultRequestsPerDay    = 100000
+)
+
+// ChainConfig describes a chain served by an Etherscan-family explorer and the pools tracked on it
+type ChainConfig struct {
+	Name              string       `json:"name"`
+	ChainID           int64        `json:"chain_id"`
+	ExplorerURL       string       `json:"explorer_url"` // e.g. https://api.arbiscan.io/api
+	APIKey            string       `json:"api_key"`
+	APIKeys           []string     `json:"api_keys"`            // More keys to spread requests across, along with api_key
+	RPCURL            string       `json:"rpc_url"`             // Only used by the rpc transaction client
+	WSURL             string       `json:"ws_url"`              // JSON-RPC WebSocket endpoint, new blocks are pushed through it when set
+	RequestsPerSecond float64      `json:"requests_per_second"` // Per API key
+	RequestsPerDay    int          `json:"requests_per_day"`    // Per API key
+	Confirmations     *uint64      `json:"confirmations"`       // Blocks to wait before ingesting a block, CONFIRMATIONS when unset
+	Pools             []PoolConfig `json:"pools"`
+}
+
+// ConfirmationDepth returns the number of blocks to wait before ingesting a block
+func (c ChainConfig) ConfirmationDepth() uint64 {
+	if c.Confirmations == nil {
+		return 0
+	}
+	return *c.Confirmations
+}
+
+// Keys returns the explorer API keys of the chain, api_key first, without duplicates
+func (c ChainConfig) Keys() []string {
+	var keys []string
+	seen := make(map[string]struct{})
+	for _, key := range append([]string{c.APIKey}, c.APIKeys...) {
+		if _, exists := seen[key]; exists || key == "" {
+			continue
+		}
+		seen[key] = struct{}{}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// PoolAddresses returns the addresses of every pool tracked on the chain
+func (c ChainConfig) PoolAddresses() []string {
+	addresses := make([]string, 0, len(c.Pools))
+	for _, pool := range c.Pools {
+		addresses = append(addresses, pool.Address)
+	}
+	return addresses
+}
+
+// loadChains reads the chains from CHAINS_FILE.
+// Without a file, Ethereum mainnet is the only chain, configured from the legacy environment variables.
+func loadChains(config Config) ([]ChainConfig, error) {
+	if config.ChainsFile == "" {
+		return loadDefaultChain(config)
+	}
+
+	data, err := os.ReadFile(config.ChainsFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading chains file: %v", err)
+	}
+
+	var chains []ChainConfig
+	if err := DeserializeFromJSON(data, &chains); err != nil {
+		return nil, fmt.Errorf("error parsing chains file: %v", err)
+	}
+	if len(chains) == 0 {
+		return nil, fmt.Errorf("chains file %s lists no chains", config.ChainsFile)
+	}
+
+	chainIDs := make(map[int64]struct{})
+	for i := range chains {
+		chain := &chains[i]
+
+		if chain.ChainID == 0 {
+			return nil, fmt.Errorf("chain %d has no chain_id", i)
+		}
+		if _, exists := chainIDs[chain.ChainID]; exists {
+			return nil, fmt.Errorf("chain %d is listed twice", chain.ChainID)
+		}
+		chainIDs[chain.ChainID] = struct{}{}
+
+		if chain.Name == "" {
+			chain.Name = fmt.Sprintf("chain-%d", chain.ChainID)
+		}
+		if chain.RequestsPerSecond <= 0 {
+			chain.RequestsPerSecond = defaultRequestsPerSecond
+		}
+		if chain.RequestsPerDay <= 0 {
+			chain.RequestsPerDay = defaultRequestsPerDay
+		}
+		if chain.Confirmations == nil {
+			confirmations := config.Confirmations
+			chain.Confirmations = &confirmations
+		}
+
+		switch config.TransactionClient {
+		case TransactionClientEtherscan:
+			if chain.ExplorerURL == "" {
+				return nil, fmt.Errorf("chain %s: explorer_url is required", chain.Name)
+			}
+			if len(chain.Keys()) == 0 {
+				return nil, fmt.Errorf("chain %s: api_key or api_keys is required", chain.Name)
+			}
+		case TransactionClientRPC:
+			if chain.RPCURL == "" {
+				return nil, fmt.Errorf("chain %s: rpc_url is required", chain.Name)
+			}
+		}
+
+		if len(chain.Pools) == 0 {
+			return nil, fmt.Errorf("chain %s lists no pools", chain.Name)
+		}
+		if err := normalizePools(chain.Pools, chain.ChainID); err != nil {
+			return nil, err
+		}
+	}
+
+	return chains, nil
+}
+
+// loadDefaultChain builds the Ethereum mainnet chain from the legacy environment variables
+func loadDefaultChain(config Config) ([]ChainConfig, error) {
+	switch config.TransactionClient {
+	case TransactionClientEtherscan:
+		if config.EtherscanAPIKey == "" && len(config.EtherscanAPIKeys) == 0 {
+			return nil, fmt.Errorf("ETHERSCAN_API_KEY or ETHERSCAN_API_KEYS is required")
+		}
+	case TransactionClientRPC:
+		if config.EthRPCURL == "" {
+			return nil, fmt.Errorf("ETH_RPC_URL is required")
+		}
+	}
+
+	pools, err := loadPools(config.PoolsFile, config.WETHUSDCPoolAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	explorerURL := DefaultExplorerURL
+	if config.EtherscanBaseURL != "" {
+		explorerURL = config.EtherscanBaseURL
+	}
+
+	return []ChainConfig{{
+		Name:              defaultChainName,
+		ChainID:           defaultChainID,
+		ExplorerURL:       explorerURL,
+		APIKey:            config.EtherscanAPIKey,
+		APIKeys:           config.EtherscanAPIKeys,
+		RPCURL:            config.EthRPCURL,
+		WSURL:             config.EthWSURL,
+		RequestsPerSecond: defaultRequestsPerSecond,
+		RequestsPerDay:    defaultRequestsPerDay,
+		Confirmations:     &config.Confirmations,
+		Pools:             pools,
+	}}, nil
+}
@@ -3,21 +3,82 @@ package utils
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// Supported backends for fetching pool transactions
+const (
+	TransactionClientEtherscan = "etherscan"
+	TransactionClientRPC       = "rpc"
+)
+
+// Supported sources of the ETH/USDT price
+const (
+	PriceSourceBinance   = "binance"
+	PriceSourcePool      = "pool"
+	PriceSourceChainlink = "chainlink"
+)
+
+// Defaults of the pool price source, the Ethereum mainnet WETH-USDC 0.05% pool
+const (
+	defaultPricePoolAddress = "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640"
+	defaultWETHAddress      = "0xc02aaa39b223fe8d0a0e5c4f27ead9083c756cc2"
+)
+
+// defaultPriceDivergenceThreshold flags prices whose sources disagree by more than 1%
+const defaultPriceDivergenceThreshold = 0.01
+
+// defaultConfirmations is the depth below the chain head at which blocks are considered final, about 2.5 minutes on Ethereum
+const defaultConfirmations = 12
+
+// defaultChainlinkAggregatorAddress is the Ethereum mainnet Chainlink ETH/USD aggregator proxy
+const defaultChainlinkAggregatorAddress = "0x5f4ec3df9cbd43714fe2740f5e3616155c5b8419"
+
 type Config struct {
-	DBUser              string
-	DBPassword          string
-	DBAddress           string
-	DBPort              string
-	DBName              string
-	RedisURL            string
-	RedisPassword       string
-	EtherscanAPIKey     string
-	ServerPort          string
-	WETHUSDCPoolAddress string
+	DBUser                     string
+	DBPassword                 string
+	DBAddress                  string
+	DBPort                     string
+	DBName                     string
+	RedisURL                   string
+	RedisPassword              string
+	EtherscanAPIKey            string
+	EtherscanAPIKeys           []string // More keys of the default chain, requests are spread across all of them
+	EtherscanBaseURL           string   // Overrides the explorer endpoint of the default chain, e.g. to point at a local stand-in
+	ServerPort                 string
+	WETHUSDCPoolAddress        string
+	TransactionClient          string
+	EthRPCURL                  string
+	EthWSURL                   string
+	PoolsFile                  string
+	ChainsFile                 string
+	Chains                     []ChainConfig
+	PriceSources               []string
+	PriceDivergenceThreshold   float64
+	PricePoolAddress           string
+	PriceRPCURL                string
+	WETHAddress                string
+	ChainlinkAggregatorAddress string
+	BinanceTestnet             bool
+	BinanceBaseURL             string
+	BinanceSymbol              string
+	BinanceInterval            string
+	Confirmations              uint64        // Default confirmation depth of the chains that do not set their own
+	HeadInterval               time.Duration // Minimum time between two recordings of a chain on pushed heads, zero for the recorder default
+
+	// Retry policy of the explorer and Binance requests, zero values fall back to the client defaults
+	HTTPMaxAttempts    int
+	HTTPTimeout        time.Duration
+	HTTPRetryBaseDelay time.Duration
+	HTTPRetryMaxDelay  time.Duration
+
+	// Circuit breakers of the explorer and Binance, zero values fall back to the client defaults
+	BreakerFailureThreshold int
+	BreakerOpenDuration     time.Duration
 }
 
 // LoadConfig reads configuration from a .env file and environment variables.
@@ -39,8 +100,94 @@ func LoadConfig() (Config, error) {
 	config.RedisURL = os.Getenv("REDIS_URL")
 	config.RedisPassword = os.Getenv("REDIS_PASSWORD")
 	config.EtherscanAPIKey = os.Getenv("ETHERSCAN_API_KEY")
+	config.EtherscanAPIKeys = parseKeys(os.Getenv("ETHERSCAN_API_KEYS"))
+	config.EtherscanBaseURL = os.Getenv("ETHERSCAN_BASE_URL")
 	config.ServerPort = os.Getenv("SERVER_PORT")
 	config.WETHUSDCPoolAddress = os.Getenv("WETH_USDT_POOL_ADDRESS")
+	config.TransactionClient = os.Getenv("TRANSACTION_CLIENT")
+	config.EthRPCURL = os.Getenv("ETH_RPC_URL")
+	config.EthWSURL = os.Getenv("ETH_WS_URL")
+	config.PoolsFile = os.Getenv("POOLS_FILE")
+	config.ChainsFile = os.Getenv("CHAINS_FILE")
+	config.PriceSources = parseList(os.Getenv("PRICE_SOURCE"))
+	config.PricePoolAddress = os.Getenv("PRICE_POOL_ADDRESS")
+	config.PriceRPCURL = os.Getenv("PRICE_RPC_URL")
+	config.WETHAddress = os.Getenv("WETH_ADDRESS")
+	config.ChainlinkAggregatorAddress = os.Getenv("CHAINLINK_AGGREGATOR_ADDRESS")
+	config.BinanceBaseURL = os.Getenv("BINANCE_BASE_URL")
+	config.BinanceSymbol = strings.ToUpper(os.Getenv("BINANCE_SYMBOL"))
+	config.BinanceInterval = os.Getenv("BINANCE_INTERVAL")
+
+	// Etherscan remains the default backend
+	if config.TransactionClient == "" {
+		config.TransactionClient = TransactionClientEtherscan
+	}
+
+	// Binance remains the default price source
+	if len(config.PriceSources) == 0 {
+		config.PriceSources = []string{PriceSourceBinance}
+	}
+	// Binance mainnet remains the default market, the symbol and interval default in the kline client
+	if testnet := os.Getenv("BINANCE_TESTNET"); testnet != "" {
+		config.BinanceTestnet, err = strconv.ParseBool(testnet)
+		if err != nil {
+			return config, fmt.Errorf("BINANCE_TESTNET must be true or false")
+		}
+	}
+	config.PriceDivergenceThreshold = defaultPriceDivergenceThreshold
+	if threshold := os.Getenv("PRICE_DIVERGENCE_THRESHOLD"); threshold != "" {
+		config.PriceDivergenceThreshold, err = strconv.ParseFloat(threshold, 64)
+		if err != nil || config.PriceDivergenceThreshold <= 0 {
+			return config, fmt.Errorf("PRICE_DIVERGENCE_THRESHOLD must be a positive number")
+		}
+	}
+	if config.PricePoolAddress == "" {
+		config.PricePoolAddress = defaultPricePoolAddress
+	}
+	if config.PriceRPCURL == "" {
+		config.PriceRPCURL = config.EthRPCURL
+	}
+	if config.WETHAddress == "" {
+		config.WETHAddress = defaultWETHAddress
+	}
+	if config.ChainlinkAggregatorAddress == "" {
+		config.ChainlinkAggregatorAddress = defaultChainlinkAggregatorAddress
+	}
+
+	config.Confirmations = defaultConfirmations
+	if confirmations := os.Getenv("CONFIRMATIONS"); confirmations != "" {
+		config.Confirmations, err = strconv.ParseUint(confirmations, 10, 64)
+		if err != nil {
+			return config, fmt.Errorf("CONFIRMATIONS must be a non-negative integer")
+		}
+	}
+
+	if attempts := os.Getenv("HTTP_MAX_ATTEMPTS"); attempts != "" {
+		config.HTTPMaxAttempts, err = strconv.Atoi(attempts)
+		if err != nil || config.HTTPMaxAttempts < 1 {
+			return config, fmt.Errorf("HTTP_MAX_ATTEMPTS must be a positive integer")
+		}
+	}
+	if threshold := os.Getenv("BREAKER_FAILURE_THRESHOLD"); threshold != "" {
+		config.BreakerFailureThreshold, err = strconv.Atoi(threshold)
+		if err != nil || config.BreakerFailureThreshold < 1 {
+			return config, fmt.Errorf("BREAKER_FAILURE_THRESHOLD must be a positive integer")
+		}
+	}
+	for name, duration := range map[string]*time.Duration{
+		"HTTP_TIMEOUT":          &config.HTTPTimeout,
+		"HTTP_RETRY_BASE_DELAY": &config.HTTPRetryBaseDelay,
+		"HTTP_RETRY_MAX_DELAY":  &config.HTTPRetryMaxDelay,
+		"BREAKER_OPEN_DURATION": &config.BreakerOpenDuration,
+		"HEAD_INTERVAL":         &config.HeadInterval,
+	} {
+		if value := os.Getenv(name); value != "" {
+			*duration, err = time.ParseDuration(value)
+			if err != nil || *duration <= 0 {
+				return config, fmt.Errorf("%s must be a positive duration, e.g. 30s", name)
+			}
+		}
+	}
 
 	// Validate required fields
 	if config.DBUser == "" {
@@ -61,15 +208,67 @@ func LoadConfig() (Config, error) {
 	if config.RedisURL == "" {
 		return config, fmt.Errorf("REDIS_URL is required")
 	}
-	if config.EtherscanAPIKey == "" {
-		return config, fmt.Errorf("ETHERSCAN_API_KEY is required")
+	if config.TransactionClient != TransactionClientEtherscan && config.TransactionClient != TransactionClientRPC {
+		return config, fmt.Errorf("TRANSACTION_CLIENT must be %q or %q", TransactionClientEtherscan, TransactionClientRPC)
 	}
 	if config.ServerPort == "" {
 		return config, fmt.Errorf("SERVER_PORT is required")
 	}
-	if config.WETHUSDCPoolAddress == "" {
-		return config, fmt.Errorf("WETH_USDT_POOL_ADDRESS is required")
+	seenSources := make(map[string]bool)
+	for _, source := range config.PriceSources {
+		switch source {
+		case PriceSourceBinance:
+		case PriceSourcePool, PriceSourceChainlink:
+			if config.PriceRPCURL == "" {
+				return config, fmt.Errorf("PRICE_RPC_URL or ETH_RPC_URL is required")
+			}
+		default:
+			return config, fmt.Errorf("PRICE_SOURCE must list %q, %q or %q", PriceSourceBinance, PriceSourcePool, PriceSourceChainlink)
+		}
+		if seenSources[source] {
+			return config, fmt.Errorf("PRICE_SOURCE lists %q twice", source)
+		}
+		seenSources[source] = true
+	}
+
+	// Every chain needs its explorer or node credentials and at least one pool
+	config.Chains, err = loadChains(config)
+	if err != nil {
+		return config, err
 	}
 
 	return config, nil
 }
+
+// Pools returns the pools tracked across every chain
+func (c Config) Pools() []PoolConfig {
+	var pools []PoolConfig
+	for _, chain := range c.Chains {
+		pools = append(pools, chain.Pools...)
+	}
+	return pools
+}
+
+// parseKeys splits a comma separated list of keys into its trimmed, non-empty items, keys are case sensitive
+func parseKeys(value string) []string {
+	var keys []string
+	for _, key := range strings.Split(value, ",") {
+		key = strings.TrimSpace(key)
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// parseList splits a comma separated setting into its trimmed, lowercase, non-empty items
+func parseList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
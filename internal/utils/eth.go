@@ -16,13 +16,28 @@ func SanitizeTransactionHash(hash string) string {
 		return ""
 	}
 
-	return hash
+	// Hashes are stored lowercase
+	return strings.ToLower(hash)
 }
 
-func ConvertToETH(gasPriceWei *big.Int) float64 {
-	ethValue := new(big.Float).SetInt(gasPriceWei)
-	ethValue.Mul(ethValue, big.NewFloat(1e-18))
+func SanitizeAddress(address string) string {
+	// Remove any whitespace
+	address = strings.TrimSpace(address)
+
+	// Ensure the address is in the correct format (0x followed by 40 hexadecimal characters)
+	regex := regexp.MustCompile(`^0x[a-fA-F0-9]{40}$`)
+	if !regex.MatchString(address) {
+		return ""
+	}
+
+	// Addresses are stored lowercase so lookups are case insensitive
+	return strings.ToLower(address)
+}
+
+// ETHDecimals is the number of decimals of ETH, 1 ETH = 10^18 wei
+const ETHDecimals = 18
 
-	result, _ := ethValue.Float64()
-	return result
+// WeiToETH converts an amount of wei to ETH exactly
+func WeiToETH(wei *big.Int) *big.Rat {
+	return new(big.Rat).SetFrac(wei, new(big.Int).Exp(big.NewInt(10), big.NewInt(ETHDecimals), nil))
 }
@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// BigIntToNumeric converts an integer to a NUMERIC column value. nil maps to NULL.
+func BigIntToNumeric(value *big.Int) pgtype.Numeric {
+	if value == nil {
+		return pgtype.Numeric{}
+	}
+	return pgtype.Numeric{Int: new(big.Int).Set(value), Valid: true}
+}
+
+// NumericToString formats a NUMERIC column value as a plain decimal string. NULL maps to "".
+func NumericToString(value pgtype.Numeric) string {
+	if !value.Valid || value.NaN || value.Int == nil {
+		return ""
+	}
+
+	digits := new(big.Int).Abs(value.Int).String()
+	if value.Exp > 0 {
+		digits += strings.Repeat("0", int(value.Exp))
+	} else if value.Exp < 0 {
+		scale := int(-value.Exp)
+		if len(digits) <= scale {
+			digits = strings.Repeat("0", scale-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+	}
+
+	if value.Int.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+// RatToNumeric converts a rational to a NUMERIC column value with at most scale decimals,
+// rounding half away from zero. Trailing zeros are dropped. nil maps to NULL.
+func RatToNumeric(value *big.Rat, scale int32) pgtype.Numeric {
+	if value == nil {
+		return pgtype.Numeric{}
+	}
+
+	rounded := RoundRat(value, scale)
+	pow := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)
+	digits := new(big.Int).Mul(rounded.Num(), pow)
+	digits.Quo(digits, rounded.Denom())
+
+	exp := -scale
+	ten := big.NewInt(10)
+	remainder := new(big.Int)
+	for exp < 0 && digits.Sign() != 0 {
+		quotient, r := new(big.Int).QuoRem(digits, ten, remainder)
+		if r.Sign() != 0 {
+			break
+		}
+		digits = quotient
+		exp++
+	}
+	if digits.Sign() == 0 {
+		exp = 0
+	}
+
+	return pgtype.Numeric{Int: digits, Exp: exp, Valid: true}
+}
+
+// RatToDecimal formats a rational as a plain decimal string with at most scale decimals, rounding half away from zero
+func RatToDecimal(value *big.Rat, scale int32) string {
+	return NumericToString(RatToNumeric(value, scale))
+}
+
+// RoundRat rounds a rational to scale decimals, half away from zero
+func RoundRat(value *big.Rat, scale int32) *big.Rat {
+	pow := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)
+
+	// Scale up, add a half away from zero, truncate and scale back down
+	scaled := new(big.Rat).Mul(value, new(big.Rat).SetInt(pow))
+	half := big.NewRat(1, 2)
+	if scaled.Sign() < 0 {
+		half.Neg(half)
+	}
+	scaled.Add(scaled, half)
+
+	truncated := new(big.Int).Quo(scaled.Num(), scaled.Denom())
+	return new(big.Rat).SetFrac(truncated, pow)
+}
+
+// NumericToRat converts a NUMERIC column value to a rational. NULL maps to nil.
+func NumericToRat(value pgtype.Numeric) *big.Rat {
+	if !value.Valid || value.NaN || value.Int == nil {
+		return nil
+	}
+
+	pow := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs32(value.Exp))), nil)
+	if value.Exp >= 0 {
+		return new(big.Rat).SetInt(new(big.Int).Mul(value.Int, pow))
+	}
+	return new(big.Rat).SetFrac(value.Int, pow)
+}
+
+// NumericToFloat64 converts a NUMERIC column value to the closest float64. NULL maps to 0.
+func NumericToFloat64(value pgtype.Numeric) float64 {
+	rat := NumericToRat(value)
+	if rat == nil {
+		return 0
+	}
+	result, _ := rat.Float64()
+	return result
+}
+
+// ParseDecimal parses a decimal string such as "2612.34" into an exact rational
+func ParseDecimal(value string) (*big.Rat, error) {
+	rat, ok := new(big.Rat).SetString(strings.TrimSpace(value))
+	if !ok {
+		return nil, fmt.Errorf("invalid decimal %q", value)
+	}
+	return rat, nil
+}
+
+// FloatToDecimal formats a float64 as the shortest decimal string that parses back to it
+func FloatToDecimal(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// abs32 returns the absolute value of x
+func abs32(x int32) int32 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Default registry entry used when no pools file is configured
+const (
+	defaultPoolToken0  = "USDC"
+	defaultPoolToken1  = "WETH"
+	defaultPoolFeeTier = 500
+)
+
+// PoolConfig describes a tracked Uniswap V3 pool
+type PoolConfig struct {
+	Address string `json:"address"`
+	ChainID int64  `json:"chain_id"`
+	Token0  string `json:"token0"`
+	Token1  string `json:"token1"`
+	FeeTier int32  `json:"fee_tier"` // In hundredths of a bip, 500 = 0.05%
+}
+
+// loadPools reads the pool registry of the default chain from a JSON file.
+// Without a file, the registry only holds the WETH-USDC pool from WETH_USDT_POOL_ADDRESS.
+func loadPools(poolsFile string, defaultPoolAddress string) ([]PoolConfig, error) {
+	if poolsFile == "" {
+		if defaultPoolAddress == "" {
+			return nil, fmt.Errorf("WETH_USDT_POOL_ADDRESS or POOLS_FILE is required")
+		}
+		return []PoolConfig{{
+			Address: strings.ToLower(defaultPoolAddress),
+			ChainID: defaultChainID,
+			Token0:  defaultPoolToken0,
+			Token1:  defaultPoolToken1,
+			FeeTier: defaultPoolFeeTier,
+		}}, nil
+	}
+
+	data, err := os.ReadFile(poolsFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading pools file: %v", err)
+	}
+
+	var pools []PoolConfig
+	if err := DeserializeFromJSON(data, &pools); err != nil {
+		return nil, fmt.Errorf("error parsing pools file: %v", err)
+	}
+	if len(pools) == 0 {
+		return nil, fmt.Errorf("pools file %s lists no pools", poolsFile)
+	}
+
+	if err := normalizePools(pools, defaultChainID); err != nil {
+		return nil, err
+	}
+	return pools, nil
+}
+
+// normalizePools lowercases the pool addresses and assigns them to the chain.
+// A pool address is unique within its chain, CREATE2 pools share it across chains.
+func normalizePools(pools []PoolConfig, chainID int64) error {
+	seen := make(map[string]struct{})
+	for i := range pools {
+		// Addresses are compared and stored lowercase
+		pools[i].Address = SanitizeAddress(pools[i].Address)
+		if pools[i].Address == "" {
+			return fmt.Errorf("pool %d of chain %d has an invalid address", i, chainID)
+		}
+		if _, exists := seen[pools[i].Address]; exists {
+			return fmt.Errorf("pool %s is listed twice on chain %d", pools[i].Address, chainID)
+		}
+		seen[pools[i].Address] = struct{}{}
+
+		if pools[i].ChainID == 0 {
+			pools[i].ChainID = chainID
+		}
+		if pools[i].ChainID != chainID {
+			return fmt.Errorf("pool %s is listed under chain %d but declares chain %d", pools[i].Address, chainID, pools[i].ChainID)
+		}
+	}
+	return nil
+}